@@ -9,7 +9,7 @@ import (
 type zoneAddr struct {
 	Zone      string
 	Port      string
-	Transport string // dns, tls or grpc
+	Transport string // dns, tls, quic, grpc or https
 	Address   string // used for bound zoneAddr - validation of overlapping
 }
 
@@ -49,7 +49,24 @@ func newOverlapZone() *zoneOverlap {
 // registerAndCheck adds a new zoneAddr for validation, it returns information about existing or overlapping with already registered
 // we consider that an unbound address is overlapping all bound addresses for same zone, same port
 func (zo *zoneOverlap) registerAndCheck(z zoneAddr) (existingZone *zoneAddr, overlappingZone *zoneAddr) {
+	existingZone, overlappingZone = zo.check(z)
+	if existingZone != nil || overlappingZone != nil {
+		return existingZone, overlappingZone
+	}
+	zo.registerNoCheck(z)
+	return nil, nil
+}
+
+// registerNoCheck adds a new zoneAddr for validation, it does not check for existing or overlapping zones.
+func (zo *zoneOverlap) registerNoCheck(z zoneAddr) {
+	uz := zoneAddr{Zone: z.Zone, Address: "", Port: z.Port, Transport: z.Transport}
+	zo.registeredAddr[z] = z
+	zo.unboundOverlap[uz] = z
+}
 
+// check checks if a zoneAddr is already registered or overlaps with a registered zoneAddr, without
+// registering it.
+func (zo *zoneOverlap) check(z zoneAddr) (existingZone *zoneAddr, overlappingZone *zoneAddr) {
 	if exist, ok := zo.registeredAddr[z]; ok {
 		// exact same zone already registered
 		return &exist, nil
@@ -65,8 +82,5 @@ func (zo *zoneOverlap) registerAndCheck(z zoneAddr) (existingZone *zoneAddr, ove
 			return nil, &uz
 		}
 	}
-	// there is no overlap, keep the current zoneAddr for future checks
-	zo.registeredAddr[z] = z
-	zo.unboundOverlap[uz] = z
 	return nil, nil
 }
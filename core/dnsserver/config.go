@@ -1,12 +1,14 @@
 package dnsserver
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net/http"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/request"
 )
 
 // Config configuration for a single server.
@@ -40,18 +42,35 @@ type Config struct {
 	// TLSConfig when listening for encrypted connections (gRPC, DNS-over-TLS).
 	TLSConfig *tls.Config
 
+	// TsigSecret holds the TSIG secrets keyed by the (lowercased, fully qualified) key name. The server
+	// uses these to verify signed requests and to sign the replies to those.
+	TsigSecret map[string]string
+
+	// FilterFuncs is used to further filter access to this server block. All of them must return true
+	// for a query to be handled by it. These are filled from the plugins that implement Viewer.
+	FilterFuncs []FilterFunc
+
+	// ViewName is the name of the view defined in this server block, if any.
+	ViewName string
+
 	// Plugin stack.
 	Plugin []plugin.Plugin
 
 	// Compiled plugin stack.
 	pluginChain plugin.Handler
 
+	// metaCollector is the first plugin in the stack that implements MetadataCollector, if any.
+	metaCollector MetadataCollector
+
 	// Plugin interested in announcing that they exist, so other plugin can call methods
 	// on them should register themselves here. The name should be the name as return by the
 	// Handler's Name method.
 	registry map[string]plugin.Handler
 }
 
+// FilterFunc is a function that filters requests from the Config.
+type FilterFunc func(context.Context, *request.Request) bool
+
 // keyForConfig builds a key for identifying the configs during setup time
 func keyForConfig(blocIndex int, blocKeyIndex int) string {
 	return fmt.Sprintf("%d:%d", blocIndex, blocKeyIndex)
@@ -0,0 +1,68 @@
+package dnsserver
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// DoQWriter is a dns.ResponseWriter that writes the reply to a single QUIC stream, see RFC 9250.
+type DoQWriter struct {
+	localAddr  net.Addr
+	remoteAddr net.Addr
+	stream     quic.Stream
+	Msg        *dns.Msg
+}
+
+// Write writes b prefixed with its 2-octet length to the stream.
+func (w *DoQWriter) Write(b []byte) (int, error) {
+	return w.stream.Write(addPrefix(b))
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *DoQWriter) WriteMsg(m *dns.Msg) error {
+	w.Msg = m
+	bytes, err := m.Pack()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bytes)
+	return err
+}
+
+// Close sends the STOP_SENDING signal to the client, indicating we will not read from the stream anymore.
+func (w *DoQWriter) Close() error {
+	w.stream.CancelRead(0)
+	return w.stream.Close()
+}
+
+// LocalAddr returns the local address. DoQ is stream oriented so this is a *net.TCPAddr, just like
+// for DNS-over-gRPC and DNS-over-HTTPS, which makes the reply sizes behave as they do for TCP.
+func (w *DoQWriter) LocalAddr() net.Addr { return w.localAddr }
+
+// RemoteAddr returns the remote address, this is a *net.TCPAddr, see LocalAddr.
+func (w *DoQWriter) RemoteAddr() net.Addr { return w.remoteAddr }
+
+// These methods implement the dns.ResponseWriter interface from Go DNS.
+func (w *DoQWriter) TsigStatus() error     { return nil }
+func (w *DoQWriter) TsigTimersOnly(b bool) {}
+func (w *DoQWriter) Hijack()               {}
+
+// addPrefix adds a 2-byte length prefix to b.
+func addPrefix(b []byte) []byte {
+	m := make([]byte, 2+len(b))
+	binary.BigEndian.PutUint16(m, uint16(len(b)))
+	copy(m[2:], b)
+	return m
+}
+
+// streamAddr converts a (QUIC) UDP address to a TCP address.
+func streamAddr(a net.Addr) net.Addr {
+	u, ok := a.(*net.UDPAddr)
+	if !ok {
+		return a
+	}
+	return &net.TCPAddr{IP: u.IP, Port: u.Port, Zone: u.Zone}
+}
@@ -36,12 +36,13 @@ type Server struct {
 	server [2]*dns.Server // 0 is a net.Listener, 1 is a net.PacketConn (a *UDPConn) in our case.
 	m      sync.Mutex     // protects the servers
 
-	zones        map[string]*Config // zones keyed by their address
-	dnsWg        sync.WaitGroup     // used to wait on outstanding connections
-	graceTimeout time.Duration      // the maximum duration of a graceful shutdown
-	trace        trace.Trace        // the trace plugin for the server
-	debug        bool               // disable recover()
-	classChaos   bool               // allow non-INET class queries
+	zones        map[string][]*Config // zones keyed by their address
+	dnsWg        sync.WaitGroup       // used to wait on outstanding connections
+	graceTimeout time.Duration        // the maximum duration of a graceful shutdown
+	trace        trace.Trace          // the trace plugin for the server
+	debug        bool                 // disable recover()
+	classChaos   bool                 // allow non-INET class queries
+	tsigSecret   map[string]string    // TSIG secrets of all zones, nil if there are none
 }
 
 // NewServer returns a new CoreDNS server and compiles all plugins in to it. By default CH class
@@ -50,7 +51,7 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 
 	s := &Server{
 		Addr:         addr,
-		zones:        make(map[string]*Config),
+		zones:        make(map[string][]*Config),
 		graceTimeout: 5 * time.Second,
 	}
 
@@ -67,17 +68,45 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 			s.debug = true
 			log.D.Set()
 		}
-		// set the config per zone
-		s.zones[site.Zone] = site
+		for name, secret := range site.TsigSecret {
+			if s.tsigSecret == nil {
+				s.tsigSecret = make(map[string]string)
+			}
+			if sec, ok := s.tsigSecret[name]; ok && sec != secret {
+				return nil, fmt.Errorf("TSIG key %s is defined more than once with different secrets", name)
+			}
+			s.tsigSecret[name] = secret
+		}
+		// set the config per zone, multiple configs for the same zone are only possible
+		// when they define views, they are tried in order.
+		s.zones[site.Zone] = append(s.zones[site.Zone], site)
 
 		// compile custom plugin for everything
-		var stack plugin.Handler
+		var (
+			stack         plugin.Handler
+			filters       []FilterFunc
+			viewName      string
+			metaCollector MetadataCollector
+		)
 		for i := len(site.Plugin) - 1; i >= 0; i-- {
 			stack = site.Plugin[i](stack)
 
 			// register the *handler* also
 			site.registerHandler(stack)
 
+			// If the plugin is a Viewer, its filter limits the queries this site handles.
+			if vf, ok := stack.(Viewer); ok {
+				if viewName != "" {
+					return nil, fmt.Errorf("multiple views defined in server block for %s", site.Zone)
+				}
+				viewName = vf.ViewName()
+				filters = append(filters, vf.Filter)
+			}
+			// This loop runs backwards, so the first MetadataCollector in the stack wins.
+			if mc, ok := stack.(MetadataCollector); ok {
+				metaCollector = mc
+			}
+
 			if s.trace == nil && stack.Name() == "trace" {
 				// we have to stash away the plugin, not the
 				// Tracer object, because the Tracer won't be initialized yet
@@ -91,6 +120,9 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 			}
 		}
 		site.pluginChain = stack
+		site.FilterFuncs = filters
+		site.ViewName = viewName
+		site.metaCollector = metaCollector
 	}
 
 	if !s.debug {
@@ -108,11 +140,13 @@ var _ caddy.GracefulServer = &Server{}
 // This implements caddy.TCPServer interface.
 func (s *Server) Serve(l net.Listener) error {
 	s.m.Lock()
-	s.server[tcp] = &dns.Server{Listener: l, Net: "tcp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
-		ctx := context.WithValue(context.Background(), Key{}, s)
-		ctx = context.WithValue(ctx, LoopKey{}, 0)
-		s.ServeDNS(ctx, w, r)
-	})}
+	s.server[tcp] = &dns.Server{Listener: l, Net: "tcp", TsigSecret: s.tsigSecret, MsgAcceptFunc: s.msgAcceptFunc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			ctx := context.WithValue(context.Background(), Key{}, s)
+			ctx = context.WithValue(ctx, LoopKey{}, 0)
+			ctx = context.WithValue(ctx, TsigKey{}, true)
+			s.ServeDNS(ctx, w, r)
+		})}
 	s.m.Unlock()
 
 	return s.server[tcp].ActivateAndServe()
@@ -122,16 +156,34 @@ func (s *Server) Serve(l net.Listener) error {
 // This implements caddy.UDPServer interface.
 func (s *Server) ServePacket(p net.PacketConn) error {
 	s.m.Lock()
-	s.server[udp] = &dns.Server{PacketConn: p, Net: "udp", Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
-		ctx := context.WithValue(context.Background(), Key{}, s)
-		ctx = context.WithValue(ctx, LoopKey{}, 0)
-		s.ServeDNS(ctx, w, r)
-	})}
+	s.server[udp] = &dns.Server{PacketConn: p, Net: "udp", TsigSecret: s.tsigSecret, MsgAcceptFunc: s.msgAcceptFunc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			ctx := context.WithValue(context.Background(), Key{}, s)
+			ctx = context.WithValue(ctx, LoopKey{}, 0)
+			ctx = context.WithValue(ctx, TsigKey{}, true)
+			s.ServeDNS(ctx, w, r)
+		})}
 	s.m.Unlock()
 
 	return s.server[udp].ActivateAndServe()
 }
 
+// msgAcceptFunc is the dns.MsgAcceptFunc for the server. On top of what the default one accepts, it
+// accepts dynamic updates (RFC 2136) when the server has TSIG secrets to authenticate them with. A
+// server without secrets keeps rejecting updates with NOTIMP.
+func (s *Server) msgAcceptFunc(dh dns.Header) dns.MsgAcceptAction {
+	opcode := int(dh.Bits>>11) & 0xF
+	isResponse := dh.Bits&(1<<15) != 0
+	if opcode != dns.OpcodeUpdate || isResponse || len(s.tsigSecret) == 0 {
+		return dns.DefaultMsgAcceptFunc(dh)
+	}
+	// The zone section must contain a single zone, the other sections can hold any number of records.
+	if dh.Qdcount != 1 {
+		return dns.MsgReject
+	}
+	return dns.MsgAccept
+}
+
 // Listen implements caddy.TCPServer interface.
 func (s *Server) Listen() (net.Listener, error) {
 	l, err := reuseport.Listen("tcp", s.Addr[len(transport.DNS+"://"):])
@@ -238,18 +290,19 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		off       int
 		end       bool
 		dshandler *Config
+		dsctx     context.Context
 	)
 
 	for {
-		if h, ok := s.zones[q[off:]]; ok {
+		if h, hctx := s.match(ctx, q[off:], w, r); h != nil {
 			if h.pluginChain == nil { // zone defined, but has not got any plugins
 				errorAndMetricsFunc(s.Addr, w, r, dns.RcodeRefused)
 				return
 			}
 			if r.Question[0].Qtype != dns.TypeDS {
-				rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
+				rcode, err := h.pluginChain.ServeDNS(hctx, w, r)
 				if !plugin.ClientWrite(rcode) {
-					errorFunc(s.Addr, w, r, rcode)
+					errorFunc(s.Addr, w, r, rcode, err)
 				}
 				return
 			}
@@ -258,7 +311,7 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 			// queries to a possibly grand parent, but there is no way for us to know at this point
 			// if there is an actual delegation from grandparent -> parent -> zone.
 			// In all fairness: direct DS queries should not be needed.
-			dshandler = h
+			dshandler, dsctx = h, hctx
 		}
 		off, end = dns.NextLabel(q, off)
 		if end {
@@ -268,18 +321,18 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 
 	if r.Question[0].Qtype == dns.TypeDS && dshandler != nil && dshandler.pluginChain != nil {
 		// DS request, and we found a zone, use the handler for the query.
-		rcode, _ := dshandler.pluginChain.ServeDNS(ctx, w, r)
+		rcode, err := dshandler.pluginChain.ServeDNS(dsctx, w, r)
 		if !plugin.ClientWrite(rcode) {
-			errorFunc(s.Addr, w, r, rcode)
+			errorFunc(s.Addr, w, r, rcode, err)
 		}
 		return
 	}
 
 	// Wildcard match, if we have found nothing try the root zone as a last resort.
-	if h, ok := s.zones["."]; ok && h.pluginChain != nil {
-		rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
+	if h, hctx := s.match(ctx, ".", w, r); h != nil && h.pluginChain != nil {
+		rcode, err := h.pluginChain.ServeDNS(hctx, w, r)
 		if !plugin.ClientWrite(rcode) {
-			errorFunc(s.Addr, w, r, rcode)
+			errorFunc(s.Addr, w, r, rcode, err)
 		}
 		return
 	}
@@ -288,6 +341,41 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	errorAndMetricsFunc(s.Addr, w, r, dns.RcodeRefused)
 }
 
+// match returns the first Config for zone whose filter functions all return true for the request, together
+// with the context that should be used for it. If there is no such Config nil is returned.
+func (s *Server) match(ctx context.Context, zone string, w dns.ResponseWriter, r *dns.Msg) (*Config, context.Context) {
+	z, ok := s.zones[zone]
+	if !ok {
+		return nil, ctx
+	}
+	for _, h := range z {
+		if len(h.FilterFuncs) == 0 {
+			return h, ctx
+		}
+		state := request.Request{W: w, Req: r}
+		// Collect the metadata now, so the filters can use it. Use a new context for each Config,
+		// the metadata of a Config that doesn't match shouldn't leak into the next.
+		hctx := ctx
+		if h.metaCollector != nil {
+			hctx = h.metaCollector.Collect(ctx, state)
+		}
+		if passAllFilterFuncs(hctx, h.FilterFuncs, &state) {
+			return h, hctx
+		}
+	}
+	return nil, ctx
+}
+
+// passAllFilterFuncs returns true if all filter funcs evaluate to true for the given request.
+func passAllFilterFuncs(ctx context.Context, filterFuncs []FilterFunc, req *request.Request) bool {
+	for _, ff := range filterFuncs {
+		if !ff(ctx, req) {
+			return false
+		}
+	}
+	return true
+}
+
 // OnStartupComplete lists the sites served by this server
 // and any relevant information, assuming Quiet is false.
 func (s *Server) OnStartupComplete() {
@@ -311,11 +399,15 @@ func (s *Server) Tracer() ot.Tracer {
 }
 
 // errorFunc responds to an DNS request with an error.
-func errorFunc(server string, w dns.ResponseWriter, r *dns.Msg, rc int) {
+func errorFunc(server string, w dns.ResponseWriter, r *dns.Msg, rc int, err error) {
 	state := request.Request{W: w, Req: r}
 
 	answer := new(dns.Msg)
 	answer.SetRcode(r, rc)
+	if code, ok := edns.ErrorCode(err); ok && r.IsEdns0() != nil {
+		// Don't leak the details of err to the client, the errors plugin logs them.
+		edns.SetExtendedError(answer, code, dns.ExtendedErrorCodeToString[code])
+	}
 	state.SizeAndDo(answer)
 
 	w.WriteMsg(answer)
@@ -344,8 +436,19 @@ type (
 
 	// LoopKey is the context key to detect server wide loops.
 	LoopKey struct{}
+
+	// TsigKey is the context key that is set to true when the server verifies TSIG signatures, so the
+	// TsigStatus of the response writer can be trusted. Only the servers for DNS and DNS-over-TLS do.
+	TsigKey struct{}
 )
 
+// TsigVerified returns true when the server that received the request verified its TSIG signature. It returns
+// false when ctx doesn't say so, e.g. for the other transports.
+func TsigVerified(ctx context.Context) bool {
+	v, _ := ctx.Value(TsigKey{}).(bool)
+	return v
+}
+
 // EnableChaos is a map with plugin names for which we should open CH class queries as we block these by default.
 var EnableChaos = map[string]struct{}{
 	"chaos":   {},
@@ -0,0 +1,229 @@
+package dnsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/reuseport"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// Error codes used when closing a DoQ connection, see RFC 9250, section 4.3.
+const (
+	// DoQCodeNoError is used when the connection or stream needs to be closed, but there is no error to signal.
+	DoQCodeNoError quic.ApplicationErrorCode = 0
+	// DoQCodeInternalError signals that the DoQ implementation encountered an internal error and is incapable of
+	// pursuing the transaction or the connection.
+	DoQCodeInternalError quic.ApplicationErrorCode = 1
+	// DoQCodeProtocolError signals that the DoQ implementation encountered a protocol error and is forcibly aborting
+	// the connection.
+	DoQCodeProtocolError quic.ApplicationErrorCode = 2
+)
+
+// ServerQUIC represents an instance of a DNS-over-QUIC server.
+type ServerQUIC struct {
+	*Server
+	listenAddr   net.Addr
+	tlsConfig    *tls.Config
+	quicConfig   *quic.Config
+	quicListener *quic.Listener
+}
+
+// NewServerQUIC returns a new CoreDNS QUIC server and compiles all plugins in to it.
+func NewServerQUIC(addr string, group []*Config) (*ServerQUIC, error) {
+	s, err := NewServer(addr, group)
+	if err != nil {
+		return nil, err
+	}
+	// The *tls* plugin must make sure that multiple conflicting
+	// TLS configuration returns an error: it can only be specified once.
+	var tlsConfig *tls.Config
+	for _, z := range s.zones {
+		for _, conf := range z {
+			// Should we error if some configs *don't* have TLS?
+			tlsConfig = conf.TLSConfig
+		}
+	}
+	if tlsConfig == nil {
+		return nil, fmt.Errorf("DoQ requires TLS to be configured, see the tls plugin")
+	}
+	// The ALPN token "doq" identifies DNS-over-QUIC, see RFC 9250, section 4.1.1. Clone the
+	// config, so we don't interfere with other servers that share it.
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.NextProtos = []string{"doq"}
+
+	quicConfig := &quic.Config{
+		MaxIdleTimeout:        30 * time.Second,
+		MaxIncomingStreams:    math.MaxUint16,
+		MaxIncomingUniStreams: math.MaxUint16,
+	}
+
+	return &ServerQUIC{Server: s, tlsConfig: tlsConfig, quicConfig: quicConfig}, nil
+}
+
+// Compile-time check to ensure ServerQUIC implements the caddy.GracefulServer interface
+var _ caddy.GracefulServer = &ServerQUIC{}
+
+// Serve implements caddy.TCPServer interface.
+func (s *ServerQUIC) Serve(l net.Listener) error { return nil }
+
+// ServePacket implements caddy.UDPServer interface.
+func (s *ServerQUIC) ServePacket(p net.PacketConn) error {
+	s.m.Lock()
+	s.listenAddr = s.quicListener.Addr()
+	ln := s.quicListener
+	s.m.Unlock()
+
+	for {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			if errors.Is(err, quic.ErrServerClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.serveQUICConnection(conn)
+	}
+}
+
+// Listen implements caddy.TCPServer interface.
+func (s *ServerQUIC) Listen() (net.Listener, error) { return nil, nil }
+
+// ListenPacket implements caddy.UDPServer interface.
+func (s *ServerQUIC) ListenPacket() (net.PacketConn, error) {
+	p, err := reuseport.ListenPacket("udp", s.Addr[len(transport.QUIC+"://"):])
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := quic.Listen(p, s.tlsConfig, s.quicConfig)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+
+	s.m.Lock()
+	s.quicListener = ln
+	s.m.Unlock()
+
+	return p, nil
+}
+
+// OnStartupComplete lists the sites served by this server
+// and any relevant information, assuming Quiet is false.
+func (s *ServerQUIC) OnStartupComplete() {
+	if Quiet {
+		return
+	}
+
+	out := startUpZones(transport.QUIC+"://", s.Addr, s.zones)
+	if out != "" {
+		fmt.Print(out)
+	}
+}
+
+// Stop stops the server. It blocks until the server is totally stopped.
+func (s *ServerQUIC) Stop() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.quicListener != nil {
+		return s.quicListener.Close()
+	}
+	return nil
+}
+
+// Shutdown stops the server (non gracefully).
+func (s *ServerQUIC) Shutdown() error { return s.Stop() }
+
+// serveQUICConnection accepts streams on conn until the connection is closed. Each stream carries
+// a single query.
+func (s *ServerQUIC) serveQUICConnection(conn quic.Connection) {
+	for {
+		stream, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			// Connection closed by the client, or timed out.
+			return
+		}
+		go s.serveQUICStream(conn, stream)
+	}
+}
+
+// serveQUICStream reads a single length prefixed DNS message from stream and sends it through the plugin chain.
+func (s *ServerQUIC) serveQUICStream(conn quic.Connection, stream quic.Stream) {
+	buf, err := readDOQMessage(stream)
+	if err != nil {
+		// Malformed length prefix or short read; this is a protocol error, see RFC 9250, section 4.3.3.
+		conn.CloseWithError(DoQCodeProtocolError, "")
+		return
+	}
+
+	req := new(dns.Msg)
+	if err := req.Unpack(buf); err != nil {
+		log.Debugf("Failed to unpack DoQ message from %s: %s", conn.RemoteAddr(), err)
+		conn.CloseWithError(DoQCodeProtocolError, "")
+		return
+	}
+
+	if !validDOQMessage(req) {
+		conn.CloseWithError(DoQCodeProtocolError, "")
+		return
+	}
+
+	w := &DoQWriter{
+		localAddr:  streamAddr(conn.LocalAddr()),
+		remoteAddr: streamAddr(conn.RemoteAddr()),
+		stream:     stream,
+	}
+
+	ctx := context.WithValue(stream.Context(), Key{}, s.Server)
+	ctx = context.WithValue(ctx, LoopKey{}, 0)
+	s.ServeDNS(ctx, w, req)
+
+	// There is only a single message per stream, close the sending side to signal we're done.
+	stream.Close()
+}
+
+// readDOQMessage reads a single 2-octet length prefixed DNS message from r.
+func readDOQMessage(r io.Reader) ([]byte, error) {
+	l := make([]byte, 2)
+	if _, err := io.ReadFull(r, l); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint16(l)
+	if size == 0 {
+		return nil, errors.New("zero length DoQ message")
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// validDOQMessage checks the restrictions RFC 9250 puts on queries: the message ID must be 0 (section 4.2.1)
+// and the edns-tcp-keepalive option must not be used (section 5.5.2).
+func validDOQMessage(m *dns.Msg) bool {
+	if m.Id != 0 {
+		return false
+	}
+	if opt := m.IsEdns0(); opt != nil {
+		for _, o := range opt.Option {
+			if o.Option() == dns.EDNS0TCPKEEPALIVE {
+				return false
+			}
+		}
+	}
+	return true
+}
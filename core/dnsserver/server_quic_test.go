@@ -0,0 +1,58 @@
+package dnsserver
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestValidDOQMessage(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.Id = 0
+	if !validDOQMessage(m) {
+		t.Errorf("Expected message with ID 0 to be valid")
+	}
+
+	m.Id = 1
+	if validDOQMessage(m) {
+		t.Errorf("Expected message with ID 1 to be invalid")
+	}
+
+	m.Id = 0
+	m.SetEdns0(4096, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_TCP_KEEPALIVE{Code: dns.EDNS0TCPKEEPALIVE})
+	if validDOQMessage(m) {
+		t.Errorf("Expected message with edns-tcp-keepalive to be invalid")
+	}
+}
+
+func TestReadDOQMessage(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	buf, _ := m.Pack()
+
+	got, err := readDOQMessage(bytes.NewReader(addPrefix(buf)))
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !bytes.Equal(got, buf) {
+		t.Errorf("Expected message to be read back unchanged")
+	}
+
+	if _, err := readDOQMessage(bytes.NewReader([]byte{0, 0})); err == nil {
+		t.Errorf("Expected error for zero length message")
+	}
+	if _, err := readDOQMessage(bytes.NewReader(addPrefix(buf)[:10])); err == nil {
+		t.Errorf("Expected error for short message")
+	}
+}
+
+func TestNewServerQUICRequiresTLS(t *testing.T) {
+	c := &Config{Zone: "example.com.", Transport: "quic", ListenHosts: []string{"127.0.0.1"}, Port: "853"}
+	if _, err := NewServerQUIC("quic://127.0.0.1:853", []*Config{c}); err == nil {
+		t.Errorf("Expected error when TLS is not configured")
+	}
+}
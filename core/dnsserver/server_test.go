@@ -2,11 +2,15 @@ package dnsserver
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
 )
@@ -93,3 +97,95 @@ func BenchmarkCoreServeDNS(b *testing.B) {
 		s.ServeDNS(ctx, w, m)
 	}
 }
+
+type testViewPlugin struct {
+	testPlugin
+	name string
+	pass bool
+}
+
+func (tp testViewPlugin) Filter(ctx context.Context, state *request.Request) bool { return tp.pass }
+func (tp testViewPlugin) ViewName() string                                        { return tp.name }
+
+func TestViews(t *testing.T) {
+	s, err := NewServer("127.0.0.1:53", []*Config{
+		testConfig("dns", testViewPlugin{name: "no", pass: false}),
+		testConfig("dns", testViewPlugin{name: "yes", pass: true}),
+		testConfig("dns", testPlugin{}),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+
+	z := s.zones["example.com."]
+	if len(z) != 3 {
+		t.Fatalf("Expected 3 configs for example.com., got %d", len(z))
+	}
+	if z[0].ViewName != "no" || len(z[0].FilterFuncs) != 1 {
+		t.Errorf("Expected view %q with 1 filter, got %q with %d", "no", z[0].ViewName, len(z[0].FilterFuncs))
+	}
+	if len(z[2].FilterFuncs) != 0 {
+		t.Errorf("Expected no filters for config without view, got %d", len(z[2].FilterFuncs))
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.com.", dns.TypeA)
+	if h, _ := s.match(context.TODO(), "example.com.", &test.ResponseWriter{}, m); h != z[1] {
+		t.Errorf("Expected the config of view %q to match", "yes")
+	}
+}
+
+func TestMultipleViews(t *testing.T) {
+	c := testConfig("dns", testViewPlugin{name: "one"})
+	c.AddPlugin(func(next plugin.Handler) plugin.Handler { return testViewPlugin{name: "two"} })
+
+	if _, err := NewServer("127.0.0.1:53", []*Config{c}); err == nil {
+		t.Errorf("Expected error for multiple views in one server block, got none")
+	}
+}
+
+func TestMsgAcceptFunc(t *testing.T) {
+	update := dns.Header{Bits: uint16(dns.OpcodeUpdate) << 11, Qdcount: 1, Ancount: 2, Nscount: 3}
+
+	s, err := NewServer("127.0.0.1:53", []*Config{testConfig("dns", testPlugin{})})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+	if a := s.msgAcceptFunc(update); a != dns.MsgRejectNotImplemented {
+		t.Errorf("Expected updates to be rejected without TSIG secrets, got %d", a)
+	}
+
+	c := testConfig("dns", testPlugin{})
+	c.TsigSecret = map[string]string{"update.": "c2VjcmV0"}
+	s, err = NewServer("127.0.0.1:53", []*Config{c})
+	if err != nil {
+		t.Fatalf("Expected no error for NewServer, got %s", err)
+	}
+	if a := s.msgAcceptFunc(update); a != dns.MsgAccept {
+		t.Errorf("Expected updates to be accepted with TSIG secrets, got %d", a)
+	}
+
+	c1 := testConfig("dns", testPlugin{})
+	c1.TsigSecret = map[string]string{"update.": "b3RoZXI="}
+	if _, err := NewServer("127.0.0.1:53", []*Config{c, c1}); err == nil {
+		t.Errorf("Expected error for conflicting TSIG secrets, got none")
+	}
+}
+
+func TestErrorFuncExtendedError(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	err := edns.NewError(dns.ExtendedErrorCodeNoReachableAuthority, errors.New("dial tcp 10.0.0.1:53: connection refused"))
+	errorFunc("127.0.0.1:53", rec, m, dns.RcodeServerFailure, err)
+
+	e := edns.ExtendedError(rec.Msg)
+	if e == nil || e.InfoCode != dns.ExtendedErrorCodeNoReachableAuthority {
+		t.Fatalf("Expected extended error %d, got %v", dns.ExtendedErrorCodeNoReachableAuthority, e)
+	}
+	if e.ExtraText != "No Reachable Authority" {
+		t.Errorf("Expected the extra text of the info code, got %q", e.ExtraText)
+	}
+}
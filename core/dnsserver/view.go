@@ -0,0 +1,27 @@
+package dnsserver
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/request"
+)
+
+// Viewer is implemented by plugins that restrict the queries a server block handles. When a server block
+// contains such a plugin, its Filter is added to the block's FilterFuncs when the servers are made. When
+// a query comes in, it is routed to the first server block for the zone whose filter functions all
+// return true; this allows multiple server blocks to serve the same zone on the same address, for
+// instance to give different answers to internal and external clients.
+type Viewer interface {
+	// Filter returns true if the server should use the server block in which the implementing plugin resides.
+	Filter(ctx context.Context, req *request.Request) bool
+
+	// ViewName returns the name of the view.
+	ViewName() string
+}
+
+// MetadataCollector is a plugin that can retrieve metadata functions from all metadata providing plugins. When a
+// server block defines a view, the metadata is collected before the view's filters are evaluated, so
+// these can use it.
+type MetadataCollector interface {
+	Collect(context.Context, request.Request) context.Context
+}
@@ -13,6 +13,7 @@ var Directives = []string{
 	"metadata",
 	"cancel",
 	"tls",
+	"view",
 	"reload",
 	"nsid",
 	"bufsize",
@@ -30,6 +31,8 @@ var Directives = []string{
 	"local",
 	"dns64",
 	"acl",
+	"rrl",
+	"rpz",
 	"any",
 	"chaos",
 	"loadbalance",
@@ -53,6 +56,7 @@ var Directives = []string{
 	"loop",
 	"forward",
 	"grpc",
+	"recursive",
 	"erratic",
 	"whoami",
 	"on",
@@ -3,9 +3,13 @@ package acl
 import (
 	"context"
 	"net"
+	"regexp"
+	"time"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/request"
 
 	"github.com/infobloxopen/go-trees/iptree"
@@ -17,6 +21,8 @@ type ACL struct {
 	Next plugin.Handler
 
 	Rules []rule
+
+	transport string // transport of the server block, see plugin/pkg/transport.
 }
 
 // rule defines a list of Zones and some ACL policies which will be
@@ -31,11 +37,18 @@ type action int
 
 // policy defines the ACL policy for DNS queries.
 // A policy performs the specified action (block/allow) on all DNS queries
-// matched by source IP or QTYPE.
+// matched by source IP, QTYPE, query name, transport, flags, metadata and
+// query rate.
 type policy struct {
-	action action
-	qtypes map[uint16]struct{}
-	filter *iptree.Tree
+	action     action
+	qtypes     map[uint16]struct{}
+	filter     *iptree.Tree
+	names      []string            // the query name must be in one of these zones, when not empty.
+	regexps    []*regexp.Regexp    // the query name must match one of these, when not empty.
+	transports map[string]struct{} // the query must use one of these transports, when not empty.
+	flags      []flag              // the query must have all these flags.
+	metadata   map[string]string   // the metadata must have all these values.
+	budget     *budget             // the client must exceed this query rate, when not nil.
 }
 
 const (
@@ -47,6 +60,12 @@ const (
 	actionBlock
 	// actionFilter returns empty sets for queries towards protected DNS zones.
 	actionFilter
+	// actionDrop doesn't reply to queries.
+	actionDrop
+	// actionRefuse returns REFUSED, without saying why.
+	actionRefuse
+	// actionLog only counts the queries, and goes on with the next policy.
+	actionLog
 )
 
 // ServeDNS implements the plugin.Handler interface.
@@ -61,12 +80,15 @@ RulesCheckLoop:
 			continue
 		}
 
-		action := matchWithPolicies(rule.policies, w, r)
+		action := matchWithPolicies(ctx, rule.policies, state, a.transport, zone)
 		switch action {
 		case actionBlock:
 			{
 				m := new(dns.Msg)
 				m.SetRcode(r, dns.RcodeRefused)
+				if r.IsEdns0() != nil {
+					edns.SetExtendedError(m, dns.ExtendedErrorCodeProhibited, "")
+				}
 				w.WriteMsg(m)
 				RequestBlockCount.WithLabelValues(metrics.WithServer(ctx), zone).Inc()
 				return dns.RcodeSuccess, nil
@@ -79,10 +101,27 @@ RulesCheckLoop:
 			{
 				m := new(dns.Msg)
 				m.SetRcode(r, dns.RcodeSuccess)
+				if r.IsEdns0() != nil {
+					edns.SetExtendedError(m, dns.ExtendedErrorCodeBlocked, "")
+				}
 				w.WriteMsg(m)
 				RequestFilterCount.WithLabelValues(metrics.WithServer(ctx), zone).Inc()
 				return dns.RcodeSuccess, nil
 			}
+		case actionDrop:
+			{
+				RequestDropCount.WithLabelValues(metrics.WithServer(ctx), zone).Inc()
+				// Nothing is written, the client times out.
+				return dns.RcodeSuccess, nil
+			}
+		case actionRefuse:
+			{
+				m := new(dns.Msg)
+				m.SetRcode(r, dns.RcodeRefused)
+				w.WriteMsg(m)
+				RequestBlockCount.WithLabelValues(metrics.WithServer(ctx), zone).Inc()
+				return dns.RcodeSuccess, nil
+			}
 		}
 
 	}
@@ -92,10 +131,9 @@ RulesCheckLoop:
 }
 
 // matchWithPolicies matches the DNS query with a list of ACL polices and returns suitable
-// action against the query.
-func matchWithPolicies(policies []policy, w dns.ResponseWriter, r *dns.Msg) action {
-	state := request.Request{W: w, Req: r}
-
+// action against the query. The queries matched by a log policy are counted, and matched
+// against the next policies.
+func matchWithPolicies(ctx context.Context, policies []policy, state request.Request, transport, zone string) action {
 	ip := net.ParseIP(state.IP())
 	qtype := state.QType()
 	for _, policy := range policies {
@@ -111,12 +149,65 @@ func matchWithPolicies(policies []policy, w dns.ResponseWriter, r *dns.Msg) acti
 			continue
 		}
 
+		if !policy.match(ctx, state, transport) {
+			continue
+		}
+
 		// matched.
+		if policy.action == actionLog {
+			RequestLogCount.WithLabelValues(metrics.WithServer(ctx), zone).Inc()
+			continue
+		}
 		return policy.action
 	}
 	return actionNone
 }
 
+// match matches the query in state with the name, transport, flags, metadata and rate of p.
+func (p policy) match(ctx context.Context, state request.Request, transport string) bool {
+	qname := state.Name()
+	if len(p.names) > 0 && plugin.Zones(p.names).Matches(qname) == "" {
+		return false
+	}
+	if len(p.regexps) > 0 {
+		matched := false
+		for _, re := range p.regexps {
+			if re.MatchString(qname) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(p.transports) > 0 {
+		// Plain DNS is either udp or tcp.
+		if transport == "" || transport == "dns" {
+			transport = state.Proto()
+		}
+		if _, ok := p.transports[transport]; !ok {
+			return false
+		}
+	}
+	for _, f := range p.flags {
+		if !f.match(state.Req) {
+			return false
+		}
+	}
+	for label, value := range p.metadata {
+		f := metadata.ValueFunc(ctx, label)
+		if f == nil || f() != value {
+			return false
+		}
+	}
+	// The rate is checked last, so only the queries matching the rest of the policy are counted.
+	if p.budget != nil && !p.budget.exceeded(state.IP(), time.Now()) {
+		return false
+	}
+	return true
+}
+
 // Name implements the plugin.Handler interface.
 func (a ACL) Name() string {
 	return "acl"
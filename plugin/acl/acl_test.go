@@ -5,6 +5,10 @@ import (
 	"testing"
 
 	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -420,3 +424,210 @@ func TestACLServeDNS(t *testing.T) {
 		})
 	}
 }
+
+func TestACLExtendedError(t *testing.T) {
+	tests := []struct {
+		config   string
+		wantCode uint16
+	}{
+		{"acl example.org {\nblock type A net 192.168.0.0/16\n}", dns.ExtendedErrorCodeProhibited},
+		{"acl example.org {\nfilter type A net 192.168.0.0/16\n}", dns.ExtendedErrorCodeBlocked},
+	}
+
+	for i, tc := range tests {
+		a, err := parse(caddy.NewTestController("dns", tc.config))
+		if err != nil {
+			t.Fatalf("Test %d: cannot parse acl from config: %v", i, err)
+		}
+		a.Next = test.NextHandler(dns.RcodeSuccess, nil)
+
+		m := new(dns.Msg)
+		m.SetQuestion("www.example.org.", dns.TypeA)
+		m.SetEdns0(4096, false)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: "192.168.0.2"})
+		a.ServeDNS(context.TODO(), rec, m)
+
+		if e := edns.ExtendedError(rec.Msg); e == nil || e.InfoCode != tc.wantCode {
+			t.Errorf("Test %d: expected extended error %d, got %v", i, tc.wantCode, e)
+		}
+	}
+}
+
+// replyHandler answers every query with NOERROR.
+var replyHandler = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+})
+
+func TestACLMatch(t *testing.T) {
+	type args struct {
+		domain    string
+		transport string
+		tcp       bool
+		do        bool
+		namespace string
+	}
+	tests := []struct {
+		name      string
+		config    string
+		args      args
+		wantRcode int
+		wantWrite bool
+	}{
+		{
+			"Name BLOCKED",
+			`acl {
+				block name example.org
+			}`,
+			args{domain: "www.example.org."},
+			dns.RcodeRefused, true,
+		},
+		{
+			"Name ALLOWED",
+			`acl {
+				block name example.org
+			}`,
+			args{domain: "www.example.net."},
+			dns.RcodeSuccess, true,
+		},
+		{
+			"Regex DROPPED",
+			`acl {
+				drop regex ^[a-z0-9]{16,}\.example\.org\.$
+			}`,
+			args{domain: "a1b2c3d4e5f6g7h8i9.example.org."},
+			dns.RcodeSuccess, false,
+		},
+		{
+			"Regex ALLOWED",
+			`acl {
+				drop regex ^[a-z0-9]{16,}\.example\.org\.$
+			}`,
+			args{domain: "www.example.org."},
+			dns.RcodeSuccess, true,
+		},
+		{
+			"Transport REFUSED",
+			`acl {
+				refuse transport udp
+			}`,
+			args{domain: "www.example.org."},
+			dns.RcodeRefused, true,
+		},
+		{
+			"Transport ALLOWED",
+			`acl {
+				refuse transport udp
+			}`,
+			args{domain: "www.example.org.", tcp: true},
+			dns.RcodeSuccess, true,
+		},
+		{
+			"Transport TLS BLOCKED",
+			`acl {
+				block transport tls
+			}`,
+			args{domain: "www.example.org.", transport: "tls", tcp: true},
+			dns.RcodeRefused, true,
+		},
+		{
+			"Flag BLOCKED",
+			`acl {
+				block flag do
+			}`,
+			args{domain: "www.example.org.", do: true},
+			dns.RcodeRefused, true,
+		},
+		{
+			"Flag negated ALLOWED",
+			`acl {
+				block flag !edns
+			}`,
+			args{domain: "www.example.org.", do: true},
+			dns.RcodeSuccess, true,
+		},
+		{
+			"Metadata BLOCKED",
+			`acl {
+				block metadata kubernetes/client-namespace=test
+			}`,
+			args{domain: "www.example.org.", namespace: "test"},
+			dns.RcodeRefused, true,
+		},
+		{
+			"Metadata ALLOWED",
+			`acl {
+				block metadata kubernetes/client-namespace=test
+			}`,
+			args{domain: "www.example.org.", namespace: "default"},
+			dns.RcodeSuccess, true,
+		},
+		{
+			"Log ALLOWED",
+			`acl {
+				log name example.org
+			}`,
+			args{domain: "www.example.org."},
+			dns.RcodeSuccess, true,
+		},
+		{
+			"Log then BLOCKED",
+			`acl {
+				log name example.org
+				block
+			}`,
+			args{domain: "www.example.org."},
+			dns.RcodeRefused, true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := parse(NewTestControllerWithZones(tt.config, []string{"."}))
+			if err != nil {
+				t.Fatalf("Error: Cannot parse acl from config: %v", err)
+			}
+			a.transport = tt.args.transport
+			a.Next = replyHandler
+
+			ctx := context.TODO()
+			if tt.args.namespace != "" {
+				ctx = metadata.ContextWithMetadata(ctx)
+				metadata.SetValueFunc(ctx, "kubernetes/client-namespace", func() string { return tt.args.namespace })
+			}
+			m := new(dns.Msg)
+			m.SetQuestion(tt.args.domain, dns.TypeA)
+			if tt.args.do {
+				m.SetEdns0(4096, true)
+			}
+			rec := dnstest.NewRecorder(&test.ResponseWriter{TCP: tt.args.tcp})
+			a.ServeDNS(ctx, rec, m)
+			if (rec.Msg != nil) != tt.wantWrite {
+				t.Fatalf("Error: acl.ServeDNS() wrote %v, want a reply %t", rec.Msg, tt.wantWrite)
+			}
+			if tt.wantWrite && rec.Rcode != tt.wantRcode {
+				t.Errorf("Error: acl.ServeDNS() Rcode = %v, want %v", rec.Rcode, tt.wantRcode)
+			}
+		})
+	}
+}
+
+func TestACLRate(t *testing.T) {
+	a, err := parse(NewTestControllerWithZones("acl {\nrefuse rate 2\n}", []string{"."}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Next = replyHandler
+
+	for i, want := range []int{dns.RcodeSuccess, dns.RcodeSuccess, dns.RcodeRefused, dns.RcodeRefused} {
+		m := new(dns.Msg)
+		m.SetQuestion("www.example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		a.ServeDNS(context.TODO(), rec, m)
+		if rec.Rcode != want {
+			t.Errorf("Query %d: expected rcode %d, got %d", i, want, rec.Rcode)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package acl
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// flag is a header or EDNS0 flag of the query, that must be set, or unset when negated.
+type flag struct {
+	name   string // edns, do, cd, rd or ad.
+	negate bool
+}
+
+func parseFlag(token string) (flag, bool) {
+	f := flag{name: strings.ToLower(token)}
+	if strings.HasPrefix(f.name, "!") {
+		f.negate = true
+		f.name = f.name[1:]
+	}
+	switch f.name {
+	case "edns", "do", "cd", "rd", "ad":
+		return f, true
+	}
+	return f, false
+}
+
+func (f flag) match(r *dns.Msg) bool {
+	set := false
+	switch f.name {
+	case "edns":
+		set = r.IsEdns0() != nil
+	case "do":
+		o := r.IsEdns0()
+		set = o != nil && o.Do()
+	case "cd":
+		set = r.CheckingDisabled
+	case "rd":
+		set = r.RecursionDesired
+	case "ad":
+		set = r.AuthenticatedData
+	}
+	return set != f.negate
+}
+
+// budget counts the queries per client address in windows of a second.
+type budget struct {
+	qps int
+
+	sync.Mutex
+	window time.Time
+	counts map[string]int
+}
+
+func newBudget(qps int) *budget { return &budget{qps: qps, counts: map[string]int{}} }
+
+// exceeded counts a query from ip and returns true when ip sent more than qps queries in the current window.
+func (b *budget) exceeded(ip string, now time.Time) bool {
+	b.Lock()
+	defer b.Unlock()
+	if now.Sub(b.window) >= time.Second {
+		b.window = now
+		b.counts = map[string]int{}
+	}
+	b.counts[ip]++
+	return b.counts[ip] > b.qps
+}
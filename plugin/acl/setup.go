@@ -2,11 +2,15 @@ package acl
 
 import (
 	"net"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 
 	"github.com/infobloxopen/go-trees/iptree"
 	"github.com/miekg/dns"
@@ -40,7 +44,7 @@ func setup(c *caddy.Controller) error {
 }
 
 func parse(c *caddy.Controller) (ACL, error) {
-	a := ACL{}
+	a := ACL{transport: dnsserver.GetConfig(c).Transport}
 	for c.Next() {
 		r := rule{}
 		args := c.RemainingArgs()
@@ -56,8 +60,14 @@ func parse(c *caddy.Controller) (ACL, error) {
 				p.action = actionBlock
 			} else if action == "filter" {
 				p.action = actionFilter
+			} else if action == "drop" {
+				p.action = actionDrop
+			} else if action == "refuse" {
+				p.action = actionRefuse
+			} else if action == "log" {
+				p.action = actionLog
 			} else {
-				return a, c.Errf("unexpected token %q; expect 'allow', 'block', or 'filter'", c.Val())
+				return a, c.Errf("unexpected token %q; expect 'allow', 'block', 'filter', 'drop', 'refuse' or 'log'", c.Val())
 			}
 
 			p.qtypes = make(map[uint16]struct{})
@@ -69,7 +79,7 @@ func parse(c *caddy.Controller) (ACL, error) {
 			remainingTokens := c.RemainingArgs()
 			for len(remainingTokens) > 0 {
 				if !isPreservedIdentifier(remainingTokens[0]) {
-					return a, c.Errf("unexpected token %q; expect 'type | net'", remainingTokens[0])
+					return a, c.Errf("unexpected token %q; expect 'type | net | name | regex | transport | flag | metadata | rate'", remainingTokens[0])
 				}
 				section := strings.ToLower(remainingTokens[0])
 
@@ -112,8 +122,57 @@ func parse(c *caddy.Controller) (ACL, error) {
 						}
 						p.filter.InplaceInsertNet(source, struct{}{})
 					}
+				case "name":
+					for _, token := range tokens {
+						p.names = append(p.names, plugin.Host(token).NormalizeExact()...)
+					}
+				case "regex":
+					for _, token := range tokens {
+						re, err := regexp.Compile(token)
+						if err != nil {
+							return a, c.Errf("illegal regular expression %q: %v", token, err)
+						}
+						p.regexps = append(p.regexps, re)
+					}
+				case "transport":
+					p.transports = make(map[string]struct{})
+					for _, token := range tokens {
+						token = strings.ToLower(token)
+						switch token {
+						case "udp", "tcp", transport.TLS, transport.HTTPS, transport.GRPC, transport.QUIC:
+							p.transports[token] = struct{}{}
+						default:
+							return a, c.Errf("unexpected token %q; expect 'udp | tcp | tls | https | grpc | quic'", token)
+						}
+					}
+				case "flag":
+					for _, token := range tokens {
+						f, ok := parseFlag(token)
+						if !ok {
+							return a, c.Errf("unexpected token %q; expect 'edns | do | cd | rd | ad', optionally negated with '!'", token)
+						}
+						p.flags = append(p.flags, f)
+					}
+				case "metadata":
+					p.metadata = make(map[string]string)
+					for _, token := range tokens {
+						i := strings.Index(token, "=")
+						if i < 0 || !metadata.IsLabel(token[:i]) {
+							return a, c.Errf("unexpected token %q; expect 'LABEL=VALUE'", token)
+						}
+						p.metadata[token[:i]] = token[i+1:]
+					}
+				case "rate":
+					if len(tokens) != 1 {
+						return a, c.Errf("expect one query rate in %q section", section)
+					}
+					qps, err := strconv.Atoi(tokens[0])
+					if err != nil || qps <= 0 {
+						return a, c.Errf("illegal query rate %q", tokens[0])
+					}
+					p.budget = newBudget(qps)
 				default:
-					return a, c.Errf("unexpected token %q; expect 'type | net'", section)
+					return a, c.Errf("unexpected token %q; expect 'type | net | name | regex | transport | flag | metadata | rate'", section)
 				}
 			}
 
@@ -135,8 +194,11 @@ func parse(c *caddy.Controller) (ACL, error) {
 }
 
 func isPreservedIdentifier(token string) bool {
-	identifier := strings.ToLower(token)
-	return identifier == "type" || identifier == "net"
+	switch strings.ToLower(token) {
+	case "type", "net", "name", "regex", "transport", "flag", "metadata", "rate":
+		return true
+	}
+	return false
 }
 
 // normalize appends '/32' for any single IPv4 address and '/128' for IPv6.
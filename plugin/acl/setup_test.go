@@ -213,6 +213,64 @@ func TestSetup(t *testing.T) {
 			}`,
 			true,
 		},
+		{
+			"Match 1",
+			`acl {
+				drop name example.org regex ^[a-z0-9]{20,}\. transport udp tcp
+				refuse flag do !cd metadata kubernetes/client-namespace=default
+				log rate 100
+			}`,
+			false,
+		},
+		{
+			"Illegal action",
+			`acl {
+				reject net 192.168.0.0/16
+			}`,
+			true,
+		},
+		{
+			"Illegal regex",
+			`acl {
+				block regex (a
+			}`,
+			true,
+		},
+		{
+			"Illegal transport",
+			`acl {
+				block transport doh
+			}`,
+			true,
+		},
+		{
+			"Illegal flag",
+			`acl {
+				block flag qr
+			}`,
+			true,
+		},
+		{
+			"Illegal metadata",
+			`acl {
+				block metadata namespace=default
+			}`,
+			true,
+		},
+		{
+			"Illegal rate",
+			`acl {
+				block rate 0
+			}`,
+			true,
+		},
+		{
+			"Illegal rate 2",
+			`acl {
+				block rate 10 20
+			}`,
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
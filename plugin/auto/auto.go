@@ -33,6 +33,7 @@ type (
 		re        *regexp.Regexp
 
 		ReloadInterval time.Duration
+		IXFRHistory    int                // Number of changes kept for incremental zone transfers.
 		upstream       *upstream.Upstream // Upstream for looking up names during the resolution process.
 	}
 )
@@ -52,6 +52,7 @@ func (a Auto) Walk() error {
 		}
 
 		zo.ReloadInterval = a.loader.ReloadInterval
+		zo.IXFRHistory = a.loader.IXFRHistory
 		zo.Upstream = a.loader.upstream
 
 		a.Zones.Add(zo, origin, a.transfer)
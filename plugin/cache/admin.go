@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/reuseport"
+	"github.com/coredns/coredns/plugin/pkg/uniq"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	// caches holds the caches of all server blocks, the admin API works on all of them.
+	caches    = &registry{}
+	adminAddr = uniq.New()
+)
+
+// registry is a list of caches.
+type registry struct {
+	sync.RWMutex
+	cs []*Cache
+}
+
+func (r *registry) add(c *Cache) {
+	r.Lock()
+	defer r.Unlock()
+	r.cs = append(r.cs, c)
+}
+
+func (r *registry) remove(c *Cache) {
+	r.Lock()
+	defer r.Unlock()
+	for i := range r.cs {
+		if r.cs[i] == c {
+			r.cs = append(r.cs[:i], r.cs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *registry) list() []*Cache {
+	r.RLock()
+	defer r.RUnlock()
+	return append([]*Cache{}, r.cs...)
+}
+
+// admin is the HTTP admin API of the cache, it lists the entries of the caches and purges them.
+type admin struct {
+	Addr  string
+	token string       // when not empty, requests must have it as the bearer token.
+	allow []*net.IPNet // when not empty, requests must come from these networks.
+
+	sync.Mutex
+	ln   net.Listener
+	done bool
+}
+
+func (a *admin) onStartup() error {
+	ln, err := reuseport.Listen("tcp", a.Addr)
+	if err != nil {
+		return err
+	}
+
+	a.Lock()
+	a.ln = ln
+	a.done = true
+	a.Unlock()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cache/entries", a.authorize(a.entries))
+	mux.HandleFunc("/cache/purge", a.authorize(a.purge))
+	go func() { http.Serve(ln, mux) }()
+
+	return nil
+}
+
+func (a *admin) onFinalShutdown() error {
+	a.Lock()
+	defer a.Unlock()
+	if !a.done {
+		return nil
+	}
+
+	adminAddr.Unset(a.Addr)
+
+	a.ln.Close()
+	a.done = false
+	return nil
+}
+
+// authorize checks the token and the address of the request before calling f.
+func (a *admin) authorize(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(a.allow) > 0 {
+			host, _, _ := net.SplitHostPort(r.RemoteAddr)
+			if !contains(a.allow, net.ParseIP(host)) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+		}
+		if a.token != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+		}
+		f(w, r)
+	}
+}
+
+// Entry is a cache entry as listed by the admin API.
+type Entry struct {
+	Name  string   `json:"name"`
+	Type  string   `json:"type"`
+	Class string   `json:"class"` // Success or Denial.
+	TTL   int      `json:"ttl"`   // remaining TTL, negative when stale.
+	Zones []string `json:"zones"` // the zones of the cache that holds the entry.
+}
+
+// entries lists the entries whose name starts with the prefix parameter.
+func (a *admin) entries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	prefix := strings.ToLower(r.URL.Query().Get("prefix"))
+
+	list := []Entry{}
+	for _, c := range caches.list() {
+		now := c.now()
+		walk := func(ca *cache.Cache, class string) {
+			ca.Walk(func(items map[uint64]interface{}, key uint64) bool {
+				i, ok := items[key].(*item)
+				if ok && strings.HasPrefix(i.name, prefix) {
+					list = append(list, Entry{Name: i.name, Type: dns.Type(i.qtype).String(), Class: class, TTL: i.ttl(now), Zones: c.Zones})
+				}
+				return true
+			})
+		}
+		walk(c.pcache, Success)
+		walk(c.ncache, Denial)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Type < list[j].Type
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// purge removes the entries for the exact name parameter, for the names at or below the zone parameter, or all
+// entries when the all parameter is true.
+func (a *admin) purge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	var match func(string) bool
+	switch {
+	case q.Get("name") != "":
+		name := strings.ToLower(dns.Fqdn(q.Get("name")))
+		match = func(s string) bool { return s == name }
+	case q.Get("zone") != "":
+		zone := strings.ToLower(dns.Fqdn(q.Get("zone")))
+		match = func(s string) bool { return dns.IsSubDomain(zone, s) }
+	case q.Get("all") == "true":
+		match = func(string) bool { return true }
+	default:
+		http.Error(w, "one of name, zone or all=true is required", http.StatusBadRequest)
+		return
+	}
+
+	n := 0
+	for _, c := range caches.list() {
+		n += c.purge(match)
+	}
+	log.Infof("Purged %d entries from the cache", n)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Purged int `json:"purged"`
+	}{n})
+}
+
+// purge removes the entries whose name matches from c, and the NSEC and NSEC3 records of the zones these names
+// may be in. The number of removed entries is returned.
+func (c *Cache) purge(match func(string) bool) int {
+	n := 0
+	names := map[string]bool{}
+	for _, ca := range []*cache.Cache{c.pcache, c.ncache} {
+		ca.Walk(func(items map[uint64]interface{}, key uint64) bool {
+			if i, ok := items[key].(*item); ok && match(i.name) {
+				delete(items, key)
+				names[i.name] = true
+				n++
+			}
+			return true
+		})
+	}
+	if c.nsec != nil {
+		c.nsec.remove(func(zone string) bool {
+			if match(zone) {
+				return true
+			}
+			for name := range names {
+				if dns.IsSubDomain(zone, name) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	return n
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultAdminAllow are the networks that may use the admin API when neither a token nor networks are configured.
+var defaultAdminAllow = []string{"127.0.0.0/8", "::1/128"}
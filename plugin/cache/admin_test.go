@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestAdmin(t *testing.T) {
+	next := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if r.Question[0].Name == "nx.example.org." {
+			m.Rcode = dns.RcodeNameError
+			m.Ns = []dns.RR{test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300")}
+		} else {
+			m.Answer = []dns.RR{test.A(r.Question[0].Name + " 3600 IN A 127.0.0.1")}
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	c1, c2 := New(), New()
+	c1.Next, c2.Next = next, next
+	caches.add(c1)
+	caches.add(c2)
+	defer caches.remove(c1)
+	defer caches.remove(c2)
+
+	fill := func() {
+		for _, name := range []string{"a.example.org.", "nx.example.org.", "b.example.net."} {
+			m := new(dns.Msg)
+			m.SetQuestion(name, dns.TypeA)
+			c1.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+		}
+		m := new(dns.Msg)
+		m.SetQuestion("a.example.org.", dns.TypeAAAA)
+		c2.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+	}
+	fill()
+
+	a := &admin{token: "secret"}
+	entries := func(prefix string) []Entry {
+		req := httptest.NewRequest(http.MethodGet, "/cache/entries?prefix="+prefix, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		a.authorize(a.entries)(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		list := []Entry{}
+		if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+
+	list := entries("a.example.org.")
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(list))
+	}
+	if list[0].Type != "A" || list[1].Type != "AAAA" || list[0].Class != Success || list[0].TTL != 3600 {
+		t.Errorf("Unexpected entries: %v", list)
+	}
+	if list := entries("nx."); len(list) != 1 || list[0].Class != Denial {
+		t.Errorf("Expected 1 denial entry, got %v", list)
+	}
+	if list := entries(""); len(list) != 4 {
+		t.Errorf("Expected 4 entries, got %d", len(list))
+	}
+
+	tests := []struct {
+		query        string
+		expectPurged int
+		expectLeft   int
+	}{
+		{"name=a.example.org", 2, 2},
+		{"zone=example.org.", 1, 1},
+		{"all=true", 1, 0},
+	}
+	for i, tc := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/cache/purge?"+tc.query, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		a.authorize(a.purge)(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Test %d: expected status %d, got %d", i, http.StatusOK, rec.Code)
+		}
+		res := struct {
+			Purged int `json:"purged"`
+		}{}
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Purged != tc.expectPurged {
+			t.Errorf("Test %d: expected %d purged entries, got %d", i, tc.expectPurged, res.Purged)
+		}
+		if left := len(entries("")); left != tc.expectLeft {
+			t.Errorf("Test %d: expected %d entries left, got %d", i, tc.expectLeft, left)
+		}
+	}
+}
+
+func TestAdminAuthorize(t *testing.T) {
+	_, n, _ := net.ParseCIDR("10.0.0.0/8")
+	a := &admin{token: "secret", allow: []*net.IPNet{n}}
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	tests := []struct {
+		remote     string
+		auth       string
+		expectCode int
+	}{
+		{"10.0.0.1:5353", "Bearer secret", http.StatusOK},
+		{"10.0.0.1:5353", "Bearer wrong", http.StatusUnauthorized},
+		{"10.0.0.1:5353", "", http.StatusUnauthorized},
+		{"192.168.0.1:5353", "Bearer secret", http.StatusForbidden},
+	}
+	for i, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/cache/entries", nil)
+		req.RemoteAddr = tc.remote
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		rec := httptest.NewRecorder()
+		a.authorize(ok)(rec, req)
+		if rec.Code != tc.expectCode {
+			t.Errorf("Test %d: expected status %d, got %d", i, tc.expectCode, rec.Code)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/cache"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/request"
 
@@ -36,7 +37,28 @@ type Cache struct {
 	duration   time.Duration
 	percentage int
 
-	staleUpTo time.Duration
+	// Serve stale, see RFC 8767.
+	staleUpTo      time.Duration
+	staleImmediate bool          // serve stale entries at once, and refresh them in the background.
+	staleTimeout   time.Duration // client response timer.
+	staleTTL       uint32
+	staleBackoff   time.Duration // failure recheck timer.
+
+	// Eviction policy of the success and denial caches.
+	policy cache.Policy
+
+	// Aggressive use of NSEC and NSEC3 records, nil when disabled.
+	nsec *nsec
+
+	// Persistence of the cache to disk, disabled when persist is empty.
+	persist         string
+	persistInterval time.Duration
+
+	// HTTP admin API, disabled when nil.
+	admin *admin
+
+	// Scope prefix lengths of the answers stored per client subnet.
+	scopes scopes
 
 	// Testing.
 	now func() time.Time
@@ -46,26 +68,29 @@ type Cache struct {
 // caller to set the Next handler.
 func New() *Cache {
 	return &Cache{
-		Zones:      []string{"."},
-		pcap:       defaultCap,
-		pcache:     cache.New(defaultCap),
-		pttl:       maxTTL,
-		minpttl:    minTTL,
-		ncap:       defaultCap,
-		ncache:     cache.New(defaultCap),
-		nttl:       maxNTTL,
-		minnttl:    minNTTL,
-		prefetch:   0,
-		duration:   1 * time.Minute,
-		percentage: 10,
-		now:        time.Now,
+		Zones:        []string{"."},
+		pcap:         defaultCap,
+		pcache:       cache.New(defaultCap),
+		pttl:         maxTTL,
+		minpttl:      minTTL,
+		ncap:         defaultCap,
+		ncache:       cache.New(defaultCap),
+		nttl:         maxNTTL,
+		minnttl:      minNTTL,
+		prefetch:     0,
+		duration:     1 * time.Minute,
+		percentage:   10,
+		staleTimeout: defaultStaleTimeout,
+		staleTTL:     defaultStaleTTL,
+		staleBackoff: defaultStaleBackoff,
+		now:          time.Now,
 	}
 }
 
 // key returns key under which we store the item, -1 will be returned if we don't store the message.
 // Currently we do not cache Truncated, errors zone transfers or dynamic update messages.
-// qname holds the already lowercased qname.
-func key(qname string, m *dns.Msg, t response.Type) (bool, uint64) {
+// qname holds the already lowercased qname, do and cd are the DO and CD bits of the request.
+func key(qname string, m *dns.Msg, t response.Type, do, cd bool) (bool, uint64) {
 	// We don't store truncated responses.
 	if m.Truncated {
 		return false, 0
@@ -75,17 +100,31 @@ func key(qname string, m *dns.Msg, t response.Type) (bool, uint64) {
 		return false, 0
 	}
 
-	return true, hash(qname, m.Question[0].Qtype)
+	return true, hash(qname, m.Question[0].Qtype, do, cd)
 }
 
-func hash(qname string, qtype uint16) uint64 {
+// hash returns the key for qname and qtype. The DO and CD bits of the request are part of it, so DNSSEC and
+// non-DNSSEC clients, and clients that do and don't want the answer validated, don't share answers.
+func hash(qname string, qtype uint16, do, cd bool) uint64 {
 	h := fnv.New64()
 	h.Write([]byte{byte(qtype >> 8)})
 	h.Write([]byte{byte(qtype)})
+	h.Write([]byte{flags(do, cd)})
 	h.Write([]byte(qname))
 	return h.Sum64()
 }
 
+func flags(do, cd bool) byte {
+	var b byte
+	if do {
+		b |= 1
+	}
+	if cd {
+		b |= 2
+	}
+	return b
+}
+
 func computeTTL(msgTTL, minTTL, maxTTL time.Duration) time.Duration {
 	ttl := msgTTL
 	if ttl < minTTL {
@@ -104,8 +143,9 @@ type ResponseWriter struct {
 	state  request.Request
 	server string // Server handling the request.
 
-	do         bool // When true the original request had the DO bit set.
-	prefetch   bool // When true write nothing back to the client.
+	do         bool  // When true the original request had the DO bit set.
+	prefetch   bool  // When true write nothing back to the client.
+	stale      *item // The stale item that is refreshed, failures don't replace it.
 	remoteAddr net.Addr
 }
 
@@ -128,6 +168,7 @@ func newPrefetchResponseWriter(server string, state request.Request, c *Cache) *
 		Cache:          c,
 		state:          state,
 		server:         server,
+		do:             state.Do(),
 		prefetch:       true,
 		remoteAddr:     addr,
 	}
@@ -143,10 +184,20 @@ func (w *ResponseWriter) RemoteAddr() net.Addr {
 
 // WriteMsg implements the dns.ResponseWriter interface.
 func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	if w.stale != nil && (res.Rcode == dns.RcodeServerFailure || res.Rcode == dns.RcodeRefused) {
+		w.stale.fail(w.now())
+		return nil
+	}
+
 	mt, _ := response.Typify(res, w.now().UTC())
 
 	// key returns empty string for anything we don't want to cache.
-	hasKey, key := key(w.state.Name(), res, mt)
+	hasKey, key := key(w.state.Name(), res, mt, w.do, w.state.Req.CheckingDisabled)
+	// An answer that depends on the client's subnet is stored for that subnet only.
+	subnet, skey, family, scope := subnetKey(w.state, res, w.do, w.state.Req.CheckingDisabled)
+	if subnet {
+		key = skey
+	}
 
 	msgTTL := dnsutil.MinimalTTL(res, mt)
 	var duration time.Duration
@@ -161,7 +212,13 @@ func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
 
 	if hasKey && duration > 0 {
 		if w.state.Match(res) {
+			if subnet {
+				w.scopes.add(family, scope)
+			}
 			w.set(res, key, mt, duration)
+			if w.nsec != nil && (mt == response.NameError || mt == response.NoData) && res.AuthenticatedData && !w.state.Req.CheckingDisabled {
+				w.nsec.add(res, w.now(), w.nttl)
+			}
 			cacheSize.WithLabelValues(w.server, Success).Set(float64(w.pcache.Len()))
 			cacheSize.WithLabelValues(w.server, Denial).Set(float64(w.ncache.Len()))
 		} else {
@@ -177,9 +234,17 @@ func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
 	// Apply capped TTL to this reply to avoid jarring TTL experience 1799 -> 8 (e.g.)
 	// We also may need to filter out DNSSEC records, see toMsg() for similar code.
 	ttl := uint32(duration.Seconds())
-	res.Answer = filterRRSlice(res.Answer, ttl, w.do, false)
-	res.Ns = filterRRSlice(res.Ns, ttl, w.do, false)
-	res.Extra = filterRRSlice(res.Extra, ttl, w.do, false)
+	upstream := edns.Subnet(res)
+	// The records are copied, as the cached item shares them.
+	res.Answer = filterRRSlice(res.Answer, ttl, w.do, true)
+	res.Ns = filterRRSlice(res.Ns, ttl, w.do, true)
+	res.Extra = filterRRSlice(res.Extra, ttl, w.do, true)
+	if !w.do && !w.state.Req.AuthenticatedData {
+		res.AuthenticatedData = false
+	}
+	if upstream != nil {
+		setSubnet(w.state.Req, res, upstream.SourceScope)
+	}
 
 	return w.ResponseWriter.WriteMsg(res)
 }
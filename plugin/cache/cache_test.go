@@ -8,6 +8,7 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/coredns/coredns/request"
@@ -57,7 +58,7 @@ var cacheTestCases = []cacheTestCase{
 			Answer: []dns.RR{
 				test.MX("miek.nl.	3601	IN	MX	1 aspmx.l.google.com."),
 				test.MX("miek.nl.	3601	IN	MX	10 aspmx2.googlemail.com."),
-				// RRSIG must be here, because we are always doing DNSSEC lookups, and miek.nl MX is tested later in this list as well.
+				// RRSIG must be here, because we are always doing DNSSEC lookups.
 				test.RRSIG("miek.nl.	3600	IN	RRSIG	MX 8 2 1800 20160521031301 20160421031301 12051 miek.nl. lAaEzB5teQLLKyDenatmyhca7blLRg9DoGNrhe3NReBZN5C5/pMQk8Jc u25hv2fW23/SLm5IC2zaDpp2Fzgm6Jf7e90/yLcwQPuE7JjS55WMF+HE LEh7Z6AEb+Iq4BWmNhUz6gPxD4d9eRMs7EAzk13o1NYi5/JhfL6IlaYy qkc="),
 			},
 		},
@@ -123,10 +124,12 @@ var cacheTestCases = []cacheTestCase{
 		Case: test.Case{
 			Qname: "miek.nl.", Qtype: dns.TypeMX,
 			Do: true,
+			// The DO bit is part of the key, so this is not answered from the miek.nl MX above, and the
+			// signature must be valid at the time used in TestCache to be cached.
 			Answer: []dns.RR{
 				test.MX("miek.nl.	3600	IN	MX	1 aspmx.l.google.com."),
 				test.MX("miek.nl.	3600	IN	MX	10 aspmx2.googlemail.com."),
-				test.RRSIG("miek.nl.	3600	IN	RRSIG	MX 8 2 1800 20160521031301 20160421031301 12051 miek.nl. lAaEzB5teQLLKyDenatmyhca7blLRg9DoGNrhe3NReBZN5C5/pMQk8Jc u25hv2fW23/SLm5IC2zaDpp2Fzgm6Jf7e90/yLcwQPuE7JjS55WMF+HE LEh7Z6AEb+Iq4BWmNhUz6gPxD4d9eRMs7EAzk13o1NYi5/JhfL6IlaYy qkc="),
+				test.RRSIG("miek.nl.	3600	IN	RRSIG	MX 8 2 1800 20170521031301 20170421031301 12051 miek.nl. lAaEzB5teQLLKyDenatmyhca7blLRg9DoGNrhe3NReBZN5C5/pMQk8Jc u25hv2fW23/SLm5IC2zaDpp2Fzgm6Jf7e90/yLcwQPuE7JjS55WMF+HE LEh7Z6AEb+Iq4BWmNhUz6gPxD4d9eRMs7EAzk13o1NYi5/JhfL6IlaYy qkc="),
 			},
 		},
 		in: test.Case{
@@ -135,7 +138,7 @@ var cacheTestCases = []cacheTestCase{
 			Answer: []dns.RR{
 				test.MX("miek.nl.	3600	IN	MX	1 aspmx.l.google.com."),
 				test.MX("miek.nl.	3600	IN	MX	10 aspmx2.googlemail.com."),
-				test.RRSIG("miek.nl.	1800	IN	RRSIG	MX 8 2 1800 20160521031301 20160421031301 12051 miek.nl. lAaEzB5teQLLKyDenatmyhca7blLRg9DoGNrhe3NReBZN5C5/pMQk8Jc u25hv2fW23/SLm5IC2zaDpp2Fzgm6Jf7e90/yLcwQPuE7JjS55WMF+HE LEh7Z6AEb+Iq4BWmNhUz6gPxD4d9eRMs7EAzk13o1NYi5/JhfL6IlaYy qkc="),
+				test.RRSIG("miek.nl.	1800	IN	RRSIG	MX 8 2 1800 20170521031301 20170421031301 12051 miek.nl. lAaEzB5teQLLKyDenatmyhca7blLRg9DoGNrhe3NReBZN5C5/pMQk8Jc u25hv2fW23/SLm5IC2zaDpp2Fzgm6Jf7e90/yLcwQPuE7JjS55WMF+HE LEh7Z6AEb+Iq4BWmNhUz6gPxD4d9eRMs7EAzk13o1NYi5/JhfL6IlaYy qkc="),
 			},
 		},
 		shouldCache: true,
@@ -198,7 +201,7 @@ func TestCache(t *testing.T) {
 		state := request.Request{W: &test.ResponseWriter{}, Req: m}
 
 		mt, _ := response.Typify(m, utc)
-		valid, k := key(state.Name(), m, mt)
+		valid, k := key(state.Name(), m, mt, state.Do(), m.CheckingDisabled)
 
 		if valid {
 			crr.set(m, k, mt, c.pttl)
@@ -260,6 +263,7 @@ func TestServeFromStaleCache(t *testing.T) {
 
 	req := new(dns.Msg)
 	req.SetQuestion("cached.org.", dns.TypeA)
+	req.SetEdns0(4096, false)
 	ctx := context.TODO()
 
 	// Cache example.org.
@@ -297,12 +301,18 @@ func TestServeFromStaleCache(t *testing.T) {
 		if ret, _ := c.ServeDNS(ctx, rec, r); ret != tt.expectedResult {
 			t.Errorf("Test %d: expecting %v; got %v", i, tt.expectedResult, ret)
 		}
+		if tt.expectedResult == 0 {
+			if e := edns.ExtendedError(rec.Msg); e == nil || e.InfoCode != dns.ExtendedErrorCodeStaleAnswer {
+				t.Errorf("Test %d: expected a stale answer extended error, got %v", i, e)
+			}
+		}
 	}
 }
 
 func TestNegativeStaleMaskingPositiveCache(t *testing.T) {
 	c := New()
 	c.staleUpTo = time.Minute * 10
+	c.staleImmediate = true // Serve the stale entry and refresh it in the background.
 	c.Next = nxDomainBackend(60)
 
 	req := new(dns.Msg)
@@ -74,6 +74,90 @@ func dnssecHandler() plugin.Handler {
 	})
 }
 
+func TestAuthenticatedData(t *testing.T) {
+	tests := []struct {
+		do, ad   bool
+		expectAD bool
+	}{
+		{do: true, expectAD: true},
+		{ad: true, expectAD: true},
+		{},
+	}
+
+	c := New()
+	for _, cached := range []bool{false, true} {
+		for i, tc := range tests {
+			if !cached {
+				c = New()
+				c.Next = adHandler()
+			}
+			m := new(dns.Msg)
+			m.SetQuestion("example.org.", dns.TypeA)
+			m.AuthenticatedData = tc.ad
+			if tc.do {
+				m.SetEdns0(4096, true)
+			}
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+			c.ServeDNS(context.TODO(), rec, m)
+			if rec.Msg.AuthenticatedData != tc.expectAD {
+				t.Errorf("Test %d (cached %t): expected AD bit %t, got %t", i, cached, tc.expectAD, rec.Msg.AuthenticatedData)
+			}
+		}
+	}
+}
+
+func TestKeyFlags(t *testing.T) {
+	c := New()
+	queries := 0
+	next := adHandler()
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		queries++
+		return next.ServeDNS(ctx, w, r)
+	})
+
+	// Answers to requests with and without the DO and CD bits are stored separately.
+	tests := []struct {
+		do, cd        bool
+		expectQueries int
+	}{
+		{cd: true, expectQueries: 1},
+		{cd: true, expectQueries: 1},
+		{expectQueries: 2},
+		{expectQueries: 2},
+		{do: true, expectQueries: 3},
+		{do: true, cd: true, expectQueries: 4},
+		{do: true, expectQueries: 4},
+	}
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		m.CheckingDisabled = tc.cd
+		if tc.do {
+			m.SetEdns0(4096, true)
+		}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(context.TODO(), rec, m)
+
+		if queries != tc.expectQueries {
+			t.Errorf("Test %d: expected %d queries upstream, got %d", i, tc.expectQueries, queries)
+		}
+		if rec.Msg.CheckingDisabled != tc.cd {
+			t.Errorf("Test %d: expected CD bit %t, got %t", i, tc.cd, rec.Msg.CheckingDisabled)
+		}
+	}
+}
+
+func adHandler() plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.AuthenticatedData = true
+		m.Answer = []dns.RR{test.A("example.org. 3600 IN A 127.0.0.53")}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
 func TestFliterRRSlice(t *testing.T) {
 	rrs := []dns.RR{
 		test.CNAME("invent.example.org.		1781	IN	CNAME	leptone.example.org."),
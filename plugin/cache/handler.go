@@ -7,6 +7,7 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -39,20 +40,39 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		ttl = i.ttl(now)
 	}
 	if i == nil {
+		if c.nsec != nil && !r.CheckingDisabled {
+			if m := c.nsec.synthesize(state, now); m != nil {
+				nsecSynthesized.WithLabelValues(server).Inc()
+				setSubnet(r, m, 0)
+				w.WriteMsg(m)
+				return dns.RcodeSuccess, nil
+			}
+		}
 		crr := &ResponseWriter{ResponseWriter: w, Cache: c, state: state, server: server, do: do}
 		return c.doRefresh(ctx, state, crr)
 	}
 	if ttl < 0 {
+		failed := i.failedWithin(now, c.staleBackoff)
+		if !c.staleImmediate && !failed && c.verifyStale(ctx, state, i, server) {
+			return dns.RcodeSuccess, nil
+		}
 		servedStale.WithLabelValues(server).Inc()
-		// Adjust the time to get a 0 TTL in the reply built from a stale item.
-		now = now.Add(time.Duration(ttl) * time.Second)
-		cw := newPrefetchResponseWriter(server, state, c)
-		go c.doPrefetch(ctx, state, cw, i, now)
+		// Adjust the time to get the stale TTL in the reply built from a stale item.
+		now = now.Add(time.Duration(ttl)*time.Second - time.Duration(c.staleTTL)*time.Second)
+		if c.staleImmediate && !failed {
+			cw := newPrefetchResponseWriter(server, state, c)
+			cw.stale = i
+			go c.doPrefetch(ctx, state, cw, i, now)
+		}
 	} else if c.shouldPrefetch(i, now) {
 		cw := newPrefetchResponseWriter(server, state, c)
 		go c.doPrefetch(ctx, state, cw, i, now)
 	}
 	resp := i.toMsg(r, now, do)
+	setSubnet(r, resp, i.scope)
+	if ttl < 0 && r.IsEdns0() != nil {
+		edns.SetExtendedError(resp, dns.ExtendedErrorCodeStaleAnswer, "")
+	}
 	w.WriteMsg(resp)
 
 	return dns.RcodeSuccess, nil
@@ -71,10 +91,17 @@ func (c *Cache) doPrefetch(ctx context.Context, state request.Request, cw *Respo
 }
 
 func (c *Cache) doRefresh(ctx context.Context, state request.Request, cw *ResponseWriter) (int, error) {
+	r := state.Req
 	if !state.Do() {
-		setDo(state.Req)
+		// The request in state is left as is, the cache keys depend on its DO bit.
+		r = r.Copy()
+		setDo(r)
 	}
-	return plugin.NextOrFailure(c.Name(), c.Next, ctx, cw, state.Req)
+	rcode, err := plugin.NextOrFailure(c.Name(), c.Next, ctx, cw, r)
+	if cw.stale != nil && !plugin.ClientWrite(rcode) {
+		cw.stale.fail(c.now())
+	}
+	return rcode, err
 }
 
 func (c *Cache) shouldPrefetch(i *item, now time.Time) bool {
@@ -90,16 +117,16 @@ func (c *Cache) shouldPrefetch(i *item, now time.Time) bool {
 func (c *Cache) Name() string { return "cache" }
 
 func (c *Cache) get(now time.Time, state request.Request, server string) (*item, bool) {
-	k := hash(state.Name(), state.QType())
-
-	if i, ok := c.ncache.Get(k); ok && i.(*item).ttl(now) > 0 {
-		cacheHits.WithLabelValues(server, Denial).Inc()
-		return i.(*item), true
-	}
+	for _, k := range c.keys(state) {
+		if i, ok := c.ncache.Get(k); ok && i.(*item).ttl(now) > 0 {
+			cacheHits.WithLabelValues(server, Denial).Inc()
+			return i.(*item), true
+		}
 
-	if i, ok := c.pcache.Get(k); ok && i.(*item).ttl(now) > 0 {
-		cacheHits.WithLabelValues(server, Success).Inc()
-		return i.(*item), true
+		if i, ok := c.pcache.Get(k); ok && i.(*item).ttl(now) > 0 {
+			cacheHits.WithLabelValues(server, Success).Inc()
+			return i.(*item), true
+		}
 	}
 	cacheMisses.WithLabelValues(server).Inc()
 	return nil, false
@@ -107,20 +134,20 @@ func (c *Cache) get(now time.Time, state request.Request, server string) (*item,
 
 // getIgnoreTTL unconditionally returns an item if it exists in the cache.
 func (c *Cache) getIgnoreTTL(now time.Time, state request.Request, server string) *item {
-	k := hash(state.Name(), state.QType())
-
-	if i, ok := c.ncache.Get(k); ok {
-		ttl := i.(*item).ttl(now)
-		if ttl > 0 || (c.staleUpTo > 0 && -ttl < int(c.staleUpTo.Seconds())) {
-			cacheHits.WithLabelValues(server, Denial).Inc()
-			return i.(*item)
+	for _, k := range c.keys(state) {
+		if i, ok := c.ncache.Get(k); ok {
+			ttl := i.(*item).ttl(now)
+			if ttl > 0 || (c.staleUpTo > 0 && -ttl < int(c.staleUpTo.Seconds())) {
+				cacheHits.WithLabelValues(server, Denial).Inc()
+				return i.(*item)
+			}
 		}
-	}
-	if i, ok := c.pcache.Get(k); ok {
-		ttl := i.(*item).ttl(now)
-		if ttl > 0 || (c.staleUpTo > 0 && -ttl < int(c.staleUpTo.Seconds())) {
-			cacheHits.WithLabelValues(server, Success).Inc()
-			return i.(*item)
+		if i, ok := c.pcache.Get(k); ok {
+			ttl := i.(*item).ttl(now)
+			if ttl > 0 || (c.staleUpTo > 0 && -ttl < int(c.staleUpTo.Seconds())) {
+				cacheHits.WithLabelValues(server, Success).Inc()
+				return i.(*item)
+			}
 		}
 	}
 	cacheMisses.WithLabelValues(server).Inc()
@@ -128,12 +155,13 @@ func (c *Cache) getIgnoreTTL(now time.Time, state request.Request, server string
 }
 
 func (c *Cache) exists(state request.Request) *item {
-	k := hash(state.Name(), state.QType())
-	if i, ok := c.ncache.Get(k); ok {
-		return i.(*item)
-	}
-	if i, ok := c.pcache.Get(k); ok {
-		return i.(*item)
+	for _, k := range c.keys(state) {
+		if i, ok := c.ncache.Get(k); ok {
+			return i.(*item)
+		}
+		if i, ok := c.pcache.Get(k); ok {
+			return i.(*item)
+		}
 	}
 	return nil
 }
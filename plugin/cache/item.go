@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/coredns/coredns/plugin/cache/freq"
@@ -9,6 +11,11 @@ import (
 )
 
 type item struct {
+	failed int64 // when refreshing the stale item last failed, in Unix nanoseconds; accessed atomically.
+
+	name  string // the lowercased qname.
+	qtype uint16
+
 	Rcode              int
 	AuthenticatedData  bool
 	RecursionAvailable bool
@@ -18,12 +25,17 @@ type item struct {
 
 	origTTL uint32
 	stored  time.Time
+	scope   uint8 // scope prefix length of the EDNS Client Subnet option in the response.
 
 	*freq.Freq
 }
 
 func newItem(m *dns.Msg, now time.Time, d time.Duration) *item {
 	i := new(item)
+	if len(m.Question) > 0 {
+		i.name = strings.ToLower(m.Question[0].Name)
+		i.qtype = m.Question[0].Qtype
+	}
 	i.Rcode = m.Rcode
 	i.AuthenticatedData = m.AuthenticatedData
 	i.RecursionAvailable = m.RecursionAvailable
@@ -33,7 +45,12 @@ func newItem(m *dns.Msg, now time.Time, d time.Duration) *item {
 	// Don't copy OPT records as these are hop-by-hop.
 	j := 0
 	for _, e := range m.Extra {
-		if e.Header().Rrtype == dns.TypeOPT {
+		if o, ok := e.(*dns.OPT); ok {
+			for _, opt := range o.Option {
+				if s, ok := opt.(*dns.EDNS0_SUBNET); ok {
+					i.scope = s.SourceScope
+				}
+			}
 			continue
 		}
 		i.Extra[j] = e
@@ -65,8 +82,9 @@ func (i *item) toMsg(m *dns.Msg, now time.Time, do bool) *dns.Msg {
 	// just set it to true.
 	m1.Authoritative = true
 	m1.AuthenticatedData = i.AuthenticatedData
-	if !do {
-		m1.AuthenticatedData = false // when DNSSEC was not wanted, it can't be authenticated data.
+	if !do && !m.AuthenticatedData {
+		// When DNSSEC was not wanted, it can't be authenticated data, unless the client asked for the AD bit, RFC 6840.
+		m1.AuthenticatedData = false
 	}
 	m1.RecursionAvailable = i.RecursionAvailable
 	m1.Rcode = i.Rcode
@@ -87,3 +105,12 @@ func (i *item) ttl(now time.Time) int {
 	ttl := int(i.origTTL) - int(now.UTC().Sub(i.stored).Seconds())
 	return ttl
 }
+
+// fail records that refreshing the stale item failed at now.
+func (i *item) fail(now time.Time) { atomic.StoreInt64(&i.failed, now.UnixNano()) }
+
+// failedWithin returns true when refreshing the stale item failed less than d before now.
+func (i *item) failedWithin(now time.Time, d time.Duration) bool {
+	f := atomic.LoadInt64(&i.failed)
+	return f != 0 && now.Sub(time.Unix(0, f)) < d
+}
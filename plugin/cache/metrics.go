@@ -50,6 +50,13 @@ var (
 		Name:      "served_stale_total",
 		Help:      "The number of requests served from stale cache entries.",
 	}, []string{"server"})
+	// nsecSynthesized is the number of denial of existence responses synthesized from NSEC and NSEC3 records.
+	nsecSynthesized = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "cache",
+		Name:      "nsec_synthesized_total",
+		Help:      "The number of denial of existence responses synthesized from NSEC and NSEC3 records.",
+	}, []string{"server"})
 	// evictions is the counter of cache evictions.
 	evictions = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: plugin.Namespace,
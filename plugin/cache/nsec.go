@@ -0,0 +1,266 @@
+package cache
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/validator"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// nsec holds the NSEC and NSEC3 records, with their signatures, of validated denial of existence responses per
+// zone, so the answers for other names in the ranges they cover can be synthesized without going upstream, see
+// RFC 8198.
+type nsec struct {
+	sync.RWMutex
+	zones map[string]*nsecZone
+	size  int // number of records held.
+	cap   int
+}
+
+type nsecZone struct {
+	soa     []dns.RR // the SOA record and its signatures.
+	records map[string]*nsecRecord
+	owners  []string   // the owner names of records in canonical order.
+	nsec3   *dns.NSEC3 // an NSEC3 record of the zone, for its hash parameters; nil if the zone uses NSEC.
+}
+
+type nsecRecord struct {
+	rr     dns.RR // the NSEC or NSEC3 record.
+	sigs   []dns.RR
+	expire time.Time
+}
+
+func newNSEC(cap int) *nsec { return &nsec{zones: map[string]*nsecZone{}, cap: cap} }
+
+// add stores the NSEC and NSEC3 records of m, a validated NXDOMAIN or NODATA response. They are kept for at most
+// max, and for no longer than the negative TTL of the zone, see RFC 8198, section 5.4.
+func (n *nsec) add(m *dns.Msg, now time.Time, max time.Duration) {
+	var soa *dns.SOA
+	for _, rr := range m.Ns {
+		if s, ok := rr.(*dns.SOA); ok {
+			soa = s
+			break
+		}
+	}
+	if soa == nil {
+		return
+	}
+	zone := strings.ToLower(soa.Hdr.Name)
+	ttl := time.Duration(minUint32(soa.Hdr.Ttl, soa.Minttl)) * time.Second
+	if ttl > max {
+		ttl = max
+	}
+	soaSigs := signatures(m.Ns, soa.Hdr.Name, dns.TypeSOA)
+	if len(soaSigs) == 0 {
+		return
+	}
+
+	n.Lock()
+	defer n.Unlock()
+	for _, rr := range m.Ns {
+		t := rr.Header().Rrtype
+		if t != dns.TypeNSEC && t != dns.TypeNSEC3 {
+			continue
+		}
+		owner := strings.ToLower(rr.Header().Name)
+		if !dns.IsSubDomain(zone, owner) {
+			continue
+		}
+		sigs := signatures(m.Ns, owner, t)
+		if len(sigs) == 0 {
+			continue
+		}
+
+		z, ok := n.zones[zone]
+		if !ok {
+			z = &nsecZone{records: map[string]*nsecRecord{}}
+			n.zones[zone] = z
+		}
+		if _, ok := z.records[owner]; !ok {
+			if n.size >= n.cap {
+				n.purge(now)
+			}
+			if n.size >= n.cap {
+				return
+			}
+			n.size++
+			z.insert(owner)
+		}
+		z.soa = append([]dns.RR{soa}, soaSigs...)
+		d := time.Duration(rr.Header().Ttl) * time.Second
+		if d > ttl {
+			d = ttl
+		}
+		z.records[owner] = &nsecRecord{rr: rr, sigs: sigs, expire: now.Add(d)}
+		if x, ok := rr.(*dns.NSEC3); ok {
+			z.nsec3 = x
+		}
+	}
+}
+
+// insert adds owner to the owner names in canonical order.
+func (z *nsecZone) insert(owner string) {
+	i := sort.Search(len(z.owners), func(i int) bool { return validator.Compare(z.owners[i], owner) >= 0 })
+	z.owners = append(z.owners, "")
+	copy(z.owners[i+1:], z.owners[i:])
+	z.owners[i] = owner
+}
+
+// delete removes owner from the owner names.
+func (z *nsecZone) delete(owner string) {
+	i := sort.Search(len(z.owners), func(i int) bool { return validator.Compare(z.owners[i], owner) >= 0 })
+	if i < len(z.owners) && z.owners[i] == owner {
+		z.owners = append(z.owners[:i], z.owners[i+1:]...)
+	}
+}
+
+// closest returns the owner name of the record that matches or may cover name: the last one that sorts before, or
+// is equal to name. If there is none, that is the last owner name, as the record of the last name in the zone
+// covers the names after it and before the apex.
+func (z *nsecZone) closest(name string) string {
+	i := sort.Search(len(z.owners), func(i int) bool { return validator.Compare(z.owners[i], name) > 0 })
+	if i == 0 {
+		i = len(z.owners)
+	}
+	return z.owners[i-1]
+}
+
+// candidates returns the records that may be part of a denial of existence for qname in zone, that have not
+// expired: the ones that match or may cover qname, its ancestors in the zone and the wildcards below them. For NSEC3
+// the hashes of these names are looked up.
+func (z *nsecZone) candidates(qname, zone string, now time.Time) []dns.RR {
+	var rrs []dns.RR
+	seen := map[string]bool{}
+	add := func(name string) {
+		if z.nsec3 != nil {
+			name = strings.ToLower(dns.HashName(name, z.nsec3.Hash, z.nsec3.Iterations, z.nsec3.Salt)) + "." + zone
+		}
+		owner := z.closest(name)
+		if seen[owner] {
+			return
+		}
+		seen[owner] = true
+		if r := z.records[owner]; now.Before(r.expire) {
+			rrs = append(rrs, r.rr)
+		}
+	}
+	for off, end := 0, false; !end; off, end = dns.NextLabel(qname, off) {
+		add(qname[off:])
+		if qname[off:] == "." {
+			add("*.")
+		} else {
+			add("*." + qname[off:])
+		}
+		if qname[off:] == zone {
+			break
+		}
+	}
+	return rrs
+}
+
+// purge removes the expired records.
+func (n *nsec) purge(now time.Time) {
+	for zone, z := range n.zones {
+		for owner, r := range z.records {
+			if !now.Before(r.expire) {
+				delete(z.records, owner)
+				z.delete(owner)
+				n.size--
+			}
+		}
+		if len(z.records) == 0 {
+			delete(n.zones, zone)
+		}
+	}
+}
+
+// remove removes the records of the zones that match.
+func (n *nsec) remove(match func(zone string) bool) {
+	n.Lock()
+	defer n.Unlock()
+	for zone, z := range n.zones {
+		if match(zone) {
+			n.size -= len(z.records)
+			delete(n.zones, zone)
+		}
+	}
+}
+
+// deny returns the rcode and the authority section of the denial of existence for the query in state, that is
+// proven by the records held for its zone, with the TTL those records have left. False is returned if there is
+// no proof.
+func (n *nsec) deny(state request.Request, now time.Time) (int, []dns.RR, uint32, bool) {
+	qname := state.Name()
+
+	n.RLock()
+	defer n.RUnlock()
+	var z *nsecZone
+	zone := ""
+	for origin, x := range n.zones {
+		if dns.IsSubDomain(origin, qname) && len(origin) > len(zone) {
+			z, zone = x, origin
+		}
+	}
+	if z == nil {
+		return 0, nil, 0, false
+	}
+
+	rcode, proof, ok := validator.Deny(qname, state.QType(), z.candidates(qname, zone, now))
+	if !ok {
+		return 0, nil, 0, false
+	}
+
+	ns := append([]dns.RR{}, z.soa...)
+	ttl := time.Duration(-1)
+	for _, rr := range proof {
+		r := z.records[strings.ToLower(rr.Header().Name)]
+		if left := r.expire.Sub(now); ttl < 0 || left < ttl {
+			ttl = left
+		}
+		ns = append(ns, r.rr)
+		ns = append(ns, r.sigs...)
+	}
+	return rcode, ns, uint32(ttl.Seconds()), true
+}
+
+// synthesize returns the reply to the request in state from the records held by n, or nil if the records don't
+// prove that the name or type doesn't exist.
+func (n *nsec) synthesize(state request.Request, now time.Time) *dns.Msg {
+	rcode, ns, ttl, ok := n.deny(state, now)
+	if !ok {
+		return nil
+	}
+	do := state.Do()
+
+	m := new(dns.Msg)
+	m.SetRcode(state.Req, rcode)
+	// See toMsg for why these are set.
+	m.Authoritative = true
+	m.RecursionAvailable = true
+	m.AuthenticatedData = do || state.Req.AuthenticatedData
+	m.Ns = filterRRSlice(ns, ttl, do, true)
+	return m
+}
+
+// signatures returns the RRSIG records in rrs for the records of type qtype at name.
+func signatures(rrs []dns.RR, name string, qtype uint16) []dns.RR {
+	var sigs []dns.RR
+	for _, rr := range rrs {
+		if s, ok := rr.(*dns.RRSIG); ok && s.TypeCovered == qtype && strings.EqualFold(s.Hdr.Name, name) {
+			sigs = append(sigs, s)
+		}
+	}
+	return sigs
+}
+
+func minUint32(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
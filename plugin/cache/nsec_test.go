@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestAggressiveNSECSynthesis(t *testing.T) {
+	c := New()
+	c.nsec = newNSEC(defaultCap)
+	queries := 0
+	c.Next = nsecHandler(&queries)
+
+	tests := []struct {
+		qname         string
+		qtype         uint16
+		do, cd        bool
+		expectRcode   int
+		expectQueries int
+		expectNSEC    bool
+	}{
+		{qname: "b.example.org.", qtype: dns.TypeA, do: true, expectRcode: dns.RcodeNameError, expectQueries: 1, expectNSEC: true},
+		// Synthesized from the NSEC records of the first answer.
+		{qname: "bb.example.org.", qtype: dns.TypeA, do: true, expectRcode: dns.RcodeNameError, expectQueries: 1, expectNSEC: true},
+		{qname: "x.bc.example.org.", qtype: dns.TypeAAAA, expectRcode: dns.RcodeNameError, expectQueries: 1},
+		// The NSEC record of a.example.org. shows it only has an A record.
+		{qname: "a.example.org.", qtype: dns.TypeTXT, do: true, expectRcode: dns.RcodeSuccess, expectQueries: 1, expectNSEC: true},
+		// Not covered by the NSEC records.
+		{qname: "d.example.org.", qtype: dns.TypeA, do: true, expectRcode: dns.RcodeNameError, expectQueries: 2, expectNSEC: true},
+		// Checking disabled.
+		{qname: "bd.example.org.", qtype: dns.TypeA, cd: true, expectRcode: dns.RcodeNameError, expectQueries: 3},
+		// Not validated, so not stored.
+		{qname: "unsigned.org.", qtype: dns.TypeA, expectRcode: dns.RcodeNameError, expectQueries: 4},
+		{qname: "x.unsigned.org.", qtype: dns.TypeA, expectRcode: dns.RcodeNameError, expectQueries: 5},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		m.CheckingDisabled = tc.cd
+		if tc.do {
+			m.SetEdns0(4096, true)
+		}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(context.TODO(), rec, m)
+
+		if rec.Msg.Rcode != tc.expectRcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.expectRcode], dns.RcodeToString[rec.Msg.Rcode])
+		}
+		if queries != tc.expectQueries {
+			t.Errorf("Test %d: expected %d queries upstream, got %d", i, tc.expectQueries, queries)
+		}
+		nsec := false
+		for _, rr := range rec.Msg.Ns {
+			if rr.Header().Rrtype == dns.TypeNSEC {
+				nsec = true
+			}
+		}
+		if nsec != tc.expectNSEC {
+			t.Errorf("Test %d: expected NSEC records %t, got %t", i, tc.expectNSEC, nsec)
+		}
+		if len(rec.Msg.Ns) == 0 || rec.Msg.Ns[0].Header().Rrtype != dns.TypeSOA {
+			t.Errorf("Test %d: expected SOA record in the authority section, got %v", i, rec.Msg.Ns)
+		}
+	}
+}
+
+// nsecHandler returns signed, and validated, NXDOMAIN and NODATA responses for example.org., where a.example.org.
+// and c.example.org. exist, and unvalidated ones for unsigned.org.
+func nsecHandler(queries *int) plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		*queries++
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		qname := r.Question[0].Name
+		if dns.IsSubDomain("unsigned.org.", qname) {
+			m.Ns = []dns.RR{test.SOA("unsigned.org. 300 IN SOA ns.unsigned.org. hostmaster.unsigned.org. 1 3600 600 86400 300")}
+			w.WriteMsg(m)
+			return dns.RcodeSuccess, nil
+		}
+
+		m.AuthenticatedData = true
+		m.Ns = []dns.RR{
+			test.SOA("example.org. 300 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300"),
+			test.RRSIG("example.org. 300 IN RRSIG SOA 13 2 300 20990101000000 20200101000000 12051 example.org. dGVzdA=="),
+		}
+		nsec := map[string][]dns.RR{
+			"example.org.": {
+				test.NSEC("example.org. 300 IN NSEC a.example.org. NS SOA RRSIG NSEC DNSKEY"),
+				test.RRSIG("example.org. 300 IN RRSIG NSEC 13 2 300 20990101000000 20200101000000 12051 example.org. dGVzdA=="),
+			},
+			"a.example.org.": {
+				test.NSEC("a.example.org. 300 IN NSEC c.example.org. A RRSIG NSEC"),
+				test.RRSIG("a.example.org. 300 IN RRSIG NSEC 13 3 300 20990101000000 20200101000000 12051 example.org. dGVzdA=="),
+			},
+			"c.example.org.": {
+				test.NSEC("c.example.org. 300 IN NSEC example.org. A RRSIG NSEC"),
+				test.RRSIG("c.example.org. 300 IN RRSIG NSEC 13 3 300 20990101000000 20200101000000 12051 example.org. dGVzdA=="),
+			},
+		}
+		switch qname {
+		case "a.example.org.":
+			m.Rcode = dns.RcodeSuccess
+			m.Ns = append(m.Ns, nsec["a.example.org."]...)
+		case "d.example.org.":
+			m.Ns = append(m.Ns, nsec["c.example.org."]...)
+			m.Ns = append(m.Ns, nsec["example.org."]...)
+		default:
+			m.Ns = append(m.Ns, nsec["a.example.org."]...)
+			m.Ns = append(m.Ns, nsec["example.org."]...)
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+func TestNSECDenyLargeZone(t *testing.T) {
+	n := newNSEC(defaultCap)
+	now := time.Now()
+
+	// A zone with the names n0000 to n0999, the NSEC records are added in one response each, in random order.
+	names := []string{"example.org."}
+	for i := 0; i < 1000; i++ {
+		names = append(names, fmt.Sprintf("n%04d.example.org.", i))
+	}
+	for _, i := range rand.Perm(len(names)) {
+		next := names[(i+1)%len(names)]
+		m := nsecResponse(fmt.Sprintf("%s 300 IN NSEC %s A RRSIG NSEC", names[i], next))
+		n.add(m, now, time.Hour)
+	}
+	if l := len(n.zones["example.org."].owners); l != len(names) {
+		t.Fatalf("Expected %d owner names, got %d", len(names), l)
+	}
+
+	tests := []struct {
+		qname       string
+		qtype       uint16
+		expectRcode int
+		expectOK    bool
+	}{
+		{"n0500a.example.org.", dns.TypeA, dns.RcodeNameError, true},
+		{"x.n0500.example.org.", dns.TypeA, dns.RcodeNameError, true},
+		{"n0999x.example.org.", dns.TypeA, dns.RcodeNameError, true},
+		{"n0500.example.org.", dns.TypeTXT, dns.RcodeSuccess, true},
+		{"n0500.example.org.", dns.TypeA, 0, false},
+	}
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rcode, ns, _, ok := n.deny(request.Request{W: &test.ResponseWriter{}, Req: m}, now)
+		if ok != tc.expectOK {
+			t.Errorf("Test %d: expected a proof %t, got %t", i, tc.expectOK, ok)
+			continue
+		}
+		if ok && rcode != tc.expectRcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.expectRcode], dns.RcodeToString[rcode])
+		}
+		// The SOA, and at most two NSEC records, each with their signature.
+		if len(ns) > 6 {
+			t.Errorf("Test %d: expected a short proof, got %v", i, ns)
+		}
+	}
+}
+
+func TestNSEC3Deny(t *testing.T) {
+	n := newNSEC(defaultCap)
+	now := time.Now()
+
+	hash := func(name string) string { return strings.ToLower(dns.HashName(name, dns.SHA1, 0, "")) }
+	hashes := []string{hash("example.org."), hash("a.example.org."), hash("c.example.org.")}
+	sort.Strings(hashes)
+	for i, h := range hashes {
+		m := nsecResponse(fmt.Sprintf("%s.example.org. 300 IN NSEC3 1 0 0 - %s A RRSIG", h, hashes[(i+1)%len(hashes)]))
+		n.add(m, now, time.Hour)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("b.example.org.", dns.TypeA)
+	rcode, _, _, ok := n.deny(request.Request{W: &test.ResponseWriter{}, Req: m}, now)
+	if !ok || rcode != dns.RcodeNameError {
+		t.Errorf("Expected NXDOMAIN, got %s (%t)", dns.RcodeToString[rcode], ok)
+	}
+
+	m.SetQuestion("a.example.org.", dns.TypeTXT)
+	rcode, _, _, ok = n.deny(request.Request{W: &test.ResponseWriter{}, Req: m}, now)
+	if !ok || rcode != dns.RcodeSuccess {
+		t.Errorf("Expected NODATA, got %s (%t)", dns.RcodeToString[rcode], ok)
+	}
+}
+
+// nsecResponse returns a validated NXDOMAIN response for example.org. with the signed record rr.
+func nsecResponse(rr string) *dns.Msg {
+	x, _ := dns.NewRR(rr)
+	m := new(dns.Msg)
+	m.Rcode = dns.RcodeNameError
+	m.AuthenticatedData = true
+	sig := &dns.RRSIG{Hdr: dns.RR_Header{Name: x.Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 300}, TypeCovered: x.Header().Rrtype}
+	m.Ns = []dns.RR{
+		test.SOA("example.org. 300 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300"),
+		test.RRSIG("example.org. 300 IN RRSIG SOA 13 2 300 20990101000000 20200101000000 12051 example.org. dGVzdA=="),
+		x, sig,
+	}
+	return m
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+)
+
+// snapshot is the format of the file the cache is persisted to. Version is incremented with every incompatible
+// change; a file with another version is ignored.
+type snapshot struct {
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	ScopeV4 []int     `json:"scope_v4,omitempty"`
+	ScopeV6 []int     `json:"scope_v6,omitempty"`
+	Entries []entry   `json:"entries"`
+}
+
+// entry is an item in a snapshot.
+type entry struct {
+	Key   uint64 `json:"key"`
+	Type  string `json:"type"` // Success or Denial.
+	TTL   int    `json:"ttl"`  // remaining TTL at the time of the snapshot.
+	Scope uint8  `json:"scope,omitempty"`
+	Msg   []byte `json:"msg"` // the question, rcode, flags and records of the item as a message in wire format.
+}
+
+// persistKey is the key under which the use of a persist file is recorded in the caddy instance's storage.
+type persistKey string
+
+const (
+	snapshotVersion = 1
+
+	defaultPersistInterval = 5 * time.Minute
+)
+
+// save writes the items that have not expired to the persist file. The file is written under a temporary name
+// first, so a crash doesn't leave a partial file behind.
+func (c *Cache) save() error {
+	now := c.now()
+	s := snapshot{Version: snapshotVersion, Time: now.UTC()}
+	for _, x := range c.scopes.list(1) {
+		s.ScopeV4 = append(s.ScopeV4, int(x))
+	}
+	for _, x := range c.scopes.list(2) {
+		s.ScopeV6 = append(s.ScopeV6, int(x))
+	}
+
+	walk := func(ca *cache.Cache, typ string) error {
+		var err error
+		ca.Walk(func(items map[uint64]interface{}, key uint64) bool {
+			i, ok := items[key].(*item)
+			if !ok {
+				return true
+			}
+			ttl := i.ttl(now)
+			if ttl <= 0 {
+				return true
+			}
+			buf, e := i.pack()
+			if e != nil {
+				err = e
+				return false
+			}
+			s.Entries = append(s.Entries, entry{Key: key, Type: typ, TTL: ttl, Scope: i.scope, Msg: buf})
+			return true
+		})
+		return err
+	}
+	if err := walk(c.pcache, Success); err != nil {
+		return err
+	}
+	if err := walk(c.ncache, Denial); err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.persist), filepath.Base(c.persist)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.persist)
+}
+
+// load adds the items in the persist file that have not expired since it was written to the cache. A missing file
+// is not an error.
+func (c *Cache) load() error {
+	buf, err := os.ReadFile(c.persist)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s := snapshot{}
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return err
+	}
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("unsupported version %d", s.Version)
+	}
+
+	// Check the whole file before anything is added to the cache.
+	elapsed := int(c.now().Sub(s.Time).Seconds())
+	items := make([]*item, len(s.Entries))
+	for j, e := range s.Entries {
+		if e.Type != Success && e.Type != Denial {
+			return fmt.Errorf("unknown type %q", e.Type)
+		}
+		m := new(dns.Msg)
+		if err := m.Unpack(e.Msg); err != nil {
+			return err
+		}
+		items[j] = newItem(m, s.Time, time.Duration(e.TTL)*time.Second)
+		items[j].scope = e.Scope
+	}
+
+	for _, x := range s.ScopeV4 {
+		c.scopes.add(1, uint8(x))
+	}
+	for _, x := range s.ScopeV6 {
+		c.scopes.add(2, uint8(x))
+	}
+	for j, e := range s.Entries {
+		if e.TTL-elapsed <= 0 {
+			continue
+		}
+		if e.Type == Success {
+			c.pcache.Add(e.Key, items[j])
+			continue
+		}
+		c.ncache.Add(e.Key, items[j])
+	}
+	return nil
+}
+
+// pack returns the question, rcode, flags and records of i as a message in wire format.
+func (i *item) pack() ([]byte, error) {
+	m := new(dns.Msg)
+	if i.name != "" {
+		m.Question = []dns.Question{{Name: i.name, Qtype: i.qtype, Qclass: dns.ClassINET}}
+	}
+	m.Rcode = i.Rcode
+	m.AuthenticatedData = i.AuthenticatedData
+	m.RecursionAvailable = i.RecursionAvailable
+	m.Answer = i.Answer
+	m.Ns = i.Ns
+	m.Extra = i.Extra
+	return m.Pack()
+}
+
+// persistLoop saves the cache every interval, until stop is closed.
+func (c *Cache) persistLoop(stop <-chan struct{}) {
+	tick := time.NewTicker(c.persistInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick.C:
+			if err := c.save(); err != nil {
+				log.Warningf("Failed to persist cache to %s: %s", c.persist, err)
+			}
+		}
+	}
+}
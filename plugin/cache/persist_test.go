@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	queries := 0
+	next := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		queries++
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch r.Question[0].Name {
+		case "short.example.org.":
+			m.Answer = []dns.RR{test.A("short.example.org. 10 IN A 127.0.0.1")}
+		case "nx.example.org.":
+			m.Rcode = dns.RcodeNameError
+			m.Ns = []dns.RR{test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300")}
+		default:
+			m.Answer = []dns.RR{test.A(r.Question[0].Name + " 3600 IN A 127.0.0.1")}
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	c := New()
+	c.Next = next
+	c.now = func() time.Time { return now }
+	c.persist = filepath.Join(dir, "cache.json")
+	for _, name := range []string{"example.org.", "short.example.org.", "nx.example.org."} {
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeA)
+		c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+	}
+	if err := c.save(); err != nil {
+		t.Fatalf("Failed to save the cache: %s", err)
+	}
+
+	// A minute later, short.example.org. has expired.
+	c = New()
+	c.Next = next
+	c.now = func() time.Time { return now.Add(time.Minute) }
+	c.persist = filepath.Join(dir, "cache.json")
+	if err := c.load(); err != nil {
+		t.Fatalf("Failed to load the cache: %s", err)
+	}
+	queries = 0
+
+	tests := []struct {
+		qname         string
+		expectQueries int
+		expectTTL     uint32
+	}{
+		{"example.org.", 0, 3540},
+		{"nx.example.org.", 0, 1740},
+		{"short.example.org.", 1, 10},
+	}
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(context.TODO(), rec, m)
+
+		if queries != tc.expectQueries {
+			t.Errorf("Test %d: expected %d queries upstream, got %d", i, tc.expectQueries, queries)
+		}
+		rrs := append(rec.Msg.Answer, rec.Msg.Ns...)
+		if len(rrs) != 1 || rrs[0].Header().Ttl != tc.expectTTL {
+			t.Errorf("Test %d: expected a record with TTL %d, got %v", i, tc.expectTTL, rrs)
+		}
+	}
+}
+
+func TestPersistBadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, content := range []string{
+		"not json",
+		`{"version": 2, "entries": [{"key": 1, "type": "success", "ttl": 100, "msg": ""}]}`,
+		`{"version": 1, "time": "2099-01-01T00:00:00Z", "entries": [{"key": 1, "type": "success", "ttl": 100, "msg": "AAAA"}]}`,
+	} {
+		file := filepath.Join(dir, "cache.json")
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		c := New()
+		c.persist = file
+		if err := c.load(); err == nil {
+			t.Errorf("Test %d: expected an error", i)
+		}
+		if c.pcache.Len() != 0 {
+			t.Errorf("Test %d: expected an empty cache, got %d items", i, c.pcache.Len())
+		}
+	}
+
+	// A missing file is fine.
+	c := New()
+	c.persist = filepath.Join(dir, "missing.json")
+	if err := c.load(); err != nil {
+		t.Errorf("Expected no error for a missing file, got %s", err)
+	}
+}
@@ -3,6 +3,8 @@ package cache
 import (
 	"errors"
 	"fmt"
+	"net"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -27,6 +29,39 @@ func setup(c *caddy.Controller) error {
 		return ca
 	})
 
+	c.OnStartup(func() error { caches.add(ca); return nil })
+	c.OnShutdown(func() error { caches.remove(ca); return nil })
+
+	if a := ca.admin; a != nil {
+		adminAddr.Set(a.Addr, a.onStartup)
+		c.OnStartup(func() error { adminAddr.Set(a.Addr, a.onStartup); return nil })
+		c.OnRestartFailed(func() error { adminAddr.Set(a.Addr, a.onStartup); return nil })
+
+		c.OnStartup(func() error { return adminAddr.ForEach() })
+		c.OnRestartFailed(func() error { return adminAddr.ForEach() })
+
+		c.OnRestart(a.onFinalShutdown)
+		c.OnFinalShutdown(a.onFinalShutdown)
+	}
+
+	if ca.persist != "" {
+		stop := make(chan struct{})
+		c.OnStartup(func() error {
+			if err := ca.load(); err != nil {
+				log.Warningf("Ignoring persisted cache %s: %s", ca.persist, err)
+			}
+			go ca.persistLoop(stop)
+			return nil
+		})
+		c.OnShutdown(func() error {
+			close(stop)
+			if err := ca.save(); err != nil {
+				log.Warningf("Failed to persist cache to %s: %s", ca.persist, err)
+			}
+			return nil
+		})
+	}
+
 	return nil
 }
 
@@ -164,30 +199,181 @@ func cacheParse(c *caddy.Controller) (*Cache, error) {
 				}
 
 			case "serve_stale":
+				if err := staleParse(c, ca); err != nil {
+					return nil, err
+				}
+			case "aggressive_nsec":
 				args := c.RemainingArgs()
 				if len(args) > 1 {
 					return nil, c.ArgErr()
 				}
-				ca.staleUpTo = 1 * time.Hour
+				capacity := defaultCap
 				if len(args) == 1 {
-					d, err := time.ParseDuration(args[0])
+					n, err := strconv.Atoi(args[0])
 					if err != nil {
 						return nil, err
 					}
-					if d < 0 {
-						return nil, errors.New("invalid negative duration for serve_stale")
+					if n <= 0 {
+						return nil, fmt.Errorf("aggressive_nsec capacity should be positive: %d", n)
 					}
-					ca.staleUpTo = d
+					capacity = n
+				}
+				ca.nsec = newNSEC(capacity)
+			case "persist":
+				args := c.RemainingArgs()
+				if len(args) == 0 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				ca.persist = args[0]
+				if root := dnsserver.GetConfig(c).Root; !filepath.IsAbs(ca.persist) && root != "" {
+					ca.persist = filepath.Join(root, ca.persist)
+				}
+				// Every zone of a server block has a cache of its own, they would overwrite each other's file.
+				key := persistKey(filepath.Clean(ca.persist))
+				if c.Get(key) != nil {
+					return nil, c.Errf("persist file %s is used by another cache", ca.persist)
+				}
+				c.Set(key, true)
+				ca.persistInterval = defaultPersistInterval
+				if len(args) == 2 {
+					d, err := time.ParseDuration(args[1])
+					if err != nil {
+						return nil, err
+					}
+					if d <= 0 {
+						return nil, fmt.Errorf("persist interval should be positive: %s", d)
+					}
+					ca.persistInterval = d
+				}
+			case "eviction":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				p, err := cache.ParsePolicy(args[0])
+				if err != nil {
+					return nil, err
+				}
+				ca.policy = p
+			case "admin":
+				a, err := adminParse(c)
+				if err != nil {
+					return nil, err
 				}
+				ca.admin = a
 			default:
 				return nil, c.ArgErr()
 			}
 		}
 
 		ca.Zones = origins
-		ca.pcache = cache.New(ca.pcap)
-		ca.ncache = cache.New(ca.ncap)
+		ca.pcache = cache.NewWithPolicy(ca.pcap, ca.policy)
+		ca.ncache = cache.NewWithPolicy(ca.ncap, ca.policy)
 	}
 
 	return ca, nil
 }
+
+// adminParse parses the arguments of the admin option: ADDRESS [token TOKEN] [allow CIDR...].
+func adminParse(c *caddy.Controller) (*admin, error) {
+	args := c.RemainingArgs()
+	if len(args) == 0 {
+		return nil, c.ArgErr()
+	}
+	a := &admin{Addr: args[0]}
+	if _, _, err := net.SplitHostPort(a.Addr); err != nil {
+		return nil, err
+	}
+	var allow []string
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "token":
+			if i+1 == len(args) {
+				return nil, c.ArgErr()
+			}
+			i++
+			a.token = args[i]
+		case "allow":
+			if i+1 == len(args) {
+				return nil, c.ArgErr()
+			}
+			allow = append(allow, args[i+1:]...)
+			i = len(args)
+		default:
+			return nil, c.Errf("unknown admin property '%s'", args[i])
+		}
+	}
+	if a.token == "" && len(allow) == 0 {
+		allow = defaultAdminAllow
+	}
+	for _, cidr := range allow {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		a.allow = append(a.allow, n)
+	}
+	return a, nil
+}
+
+// staleParse parses the arguments of the serve_stale option:
+// [DURATION] [immediate|verify] [timeout TIMEOUT] [ttl TTL] [backoff BACKOFF].
+func staleParse(c *caddy.Controller, ca *Cache) error {
+	args := c.RemainingArgs()
+	ca.staleUpTo = 1 * time.Hour
+	positive := func(i int) (time.Duration, error) {
+		if i+1 == len(args) {
+			return 0, c.ArgErr()
+		}
+		d, err := time.ParseDuration(args[i+1])
+		if err != nil {
+			return 0, err
+		}
+		if d <= 0 {
+			return 0, fmt.Errorf("serve_stale %s should be positive: %s", args[i], d)
+		}
+		return d, nil
+	}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "immediate":
+			ca.staleImmediate = true
+		case "verify":
+			ca.staleImmediate = false
+		case "timeout":
+			d, err := positive(i)
+			if err != nil {
+				return err
+			}
+			ca.staleTimeout = d
+			i++
+		case "ttl":
+			d, err := positive(i)
+			if err != nil {
+				return err
+			}
+			ca.staleTTL = uint32(d.Seconds())
+			i++
+		case "backoff":
+			d, err := positive(i)
+			if err != nil {
+				return err
+			}
+			ca.staleBackoff = d
+			i++
+		default:
+			if i > 0 {
+				return c.Errf("unknown serve_stale property '%s'", args[i])
+			}
+			d, err := time.ParseDuration(args[i])
+			if err != nil {
+				return err
+			}
+			if d < 0 {
+				return errors.New("invalid negative duration for serve_stale")
+			}
+			ca.staleUpTo = d
+		}
+	}
+	return nil
+}
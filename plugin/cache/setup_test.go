@@ -2,10 +2,13 @@ package cache
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/coredns/caddy"
+	"github.com/coredns/caddy/caddyfile"
+	"github.com/coredns/coredns/plugin/pkg/cache"
 )
 
 func TestSetup(t *testing.T) {
@@ -150,3 +153,204 @@ func TestServeStale(t *testing.T) {
 		}
 	}
 }
+
+func TestServeStaleOptions(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		immediate bool
+		timeout   time.Duration
+		ttl       uint32
+		backoff   time.Duration
+	}{
+		{"serve_stale", false, false, defaultStaleTimeout, defaultStaleTTL, defaultStaleBackoff},
+		{"serve_stale 1h immediate", false, true, defaultStaleTimeout, defaultStaleTTL, defaultStaleBackoff},
+		{"serve_stale 1h verify timeout 500ms", false, false, 500 * time.Millisecond, defaultStaleTTL, defaultStaleBackoff},
+		{"serve_stale ttl 10s backoff 1m", false, false, defaultStaleTimeout, 10, time.Minute},
+		// fails
+		{"serve_stale 1h timeout", true, false, 0, 0, 0},
+		{"serve_stale 1h timeout 0s", true, false, 0, 0, 0},
+		{"serve_stale 1h ttl aa", true, false, 0, 0, 0},
+		{"serve_stale 1h backoff -1s", true, false, 0, 0, 0},
+		{"serve_stale immediate 1h", true, false, 0, 0, 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", fmt.Sprintf("cache {\n%s\n}", test.input))
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr && err != nil {
+			continue
+		}
+		if ca.staleImmediate != test.immediate || ca.staleTimeout != test.timeout || ca.staleTTL != test.ttl || ca.staleBackoff != test.backoff {
+			t.Errorf("Test %v: Expected immediate %t, timeout %s, ttl %d and backoff %s but found: %t, %s, %d and %s",
+				i, test.immediate, test.timeout, test.ttl, test.backoff, ca.staleImmediate, ca.staleTimeout, ca.staleTTL, ca.staleBackoff)
+		}
+	}
+}
+
+func TestEviction(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		policy    cache.Policy
+	}{
+		{"", false, cache.Random},
+		{"eviction random", false, cache.Random},
+		{"eviction lru", false, cache.LRU},
+		{"eviction lfu", false, cache.LFU},
+		{"eviction arc", false, cache.ARC},
+		// fails
+		{"eviction", true, cache.Random},
+		{"eviction fifo", true, cache.Random},
+		{"eviction lru lfu", true, cache.Random},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", fmt.Sprintf("cache {\n%s\n}", test.input))
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr && err != nil {
+			continue
+		}
+		if ca.policy != test.policy {
+			t.Errorf("Test %v: Expected policy %s but found: %s", i, test.policy, ca.policy)
+		}
+	}
+}
+
+func TestAggressiveNSEC(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		capacity  int
+	}{
+		{"", false, 0},
+		{"aggressive_nsec", false, defaultCap},
+		{"aggressive_nsec 100", false, 100},
+		// fails
+		{"aggressive_nsec 0", true, 0},
+		{"aggressive_nsec aa", true, 0},
+		{"aggressive_nsec 100 200", true, 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", fmt.Sprintf("cache {\n%s\n}", test.input))
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr && err != nil {
+			continue
+		}
+		capacity := 0
+		if ca.nsec != nil {
+			capacity = ca.nsec.cap
+		}
+		if capacity != test.capacity {
+			t.Errorf("Test %v: Expected capacity %d but found: %d", i, test.capacity, capacity)
+		}
+	}
+}
+
+func TestPersistSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		persist   string
+		interval  time.Duration
+	}{
+		{"", false, "", 0},
+		{"persist /tmp/cache.json", false, "/tmp/cache.json", 5 * time.Minute},
+		{"persist /tmp/cache.json 30s", false, "/tmp/cache.json", 30 * time.Second},
+		// fails
+		{"persist", true, "", 0},
+		{"persist /tmp/cache.json 0s", true, "", 0},
+		{"persist /tmp/cache.json aa", true, "", 0},
+		{"persist /tmp/cache.json 30s 1m", true, "", 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", fmt.Sprintf("cache {\n%s\n}", test.input))
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr && err != nil {
+			continue
+		}
+		if ca.persist != test.persist || ca.persistInterval != test.interval {
+			t.Errorf("Test %v: Expected persist %q every %s but found: %q every %s", i, test.persist, test.interval, ca.persist, ca.persistInterval)
+		}
+	}
+}
+
+func TestPersistSetupDuplicate(t *testing.T) {
+	c := caddy.NewTestController("dns", "cache {\npersist /tmp/cache.json\n}")
+	if _, err := cacheParse(c); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// Another cache block of the same Corefile.
+	c.Dispenser = caddyfile.NewDispenser("Testfile", strings.NewReader("cache {\npersist /tmp/other.json\n}"))
+	if _, err := cacheParse(c); err != nil {
+		t.Fatalf("Expected no error for another file, got %v", err)
+	}
+	c.Dispenser = caddyfile.NewDispenser("Testfile", strings.NewReader("cache {\npersist /tmp/../tmp/cache.json\n}"))
+	if _, err := cacheParse(c); err == nil {
+		t.Error("Expected an error for a file used by another cache")
+	}
+}
+
+func TestAdminSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		token     string
+		allow     int
+	}{
+		{"admin localhost:8182", false, "", 2},
+		{"admin localhost:8182 token secret", false, "secret", 0},
+		{"admin :8182 allow 10.0.0.0/8", false, "", 1},
+		{"admin :8182 token secret allow 10.0.0.0/8 192.168.0.0/16", false, "secret", 2},
+		// fails
+		{"admin", true, "", 0},
+		{"admin localhost", true, "", 0},
+		{"admin :8182 token", true, "", 0},
+		{"admin :8182 allow", true, "", 0},
+		{"admin :8182 allow 10.0.0.1", true, "", 0},
+		{"admin :8182 secret", true, "", 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", fmt.Sprintf("cache {\n%s\n}", test.input))
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr && err != nil {
+			continue
+		}
+		if ca.admin.token != test.token || len(ca.admin.allow) != test.allow {
+			t.Errorf("Test %v: Expected token %q and %d networks but found: %q and %d", i, test.token, test.allow, ca.admin.token, len(ca.admin.allow))
+		}
+	}
+}
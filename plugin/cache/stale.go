@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// Defaults from RFC 8767, section 5.
+	defaultStaleTimeout = 1800 * time.Millisecond
+	defaultStaleTTL     = 30
+	defaultStaleBackoff = 30 * time.Second
+)
+
+// verifyStale queries the upstream for the stale item i and relays the answer to the client, when it arrives within
+// the client response timeout, see RFC 8767. It returns false when the upstream fails or doesn't answer in time, the
+// caller then answers with i. An answer that arrives later still refreshes the cache.
+func (c *Cache) verifyStale(ctx context.Context, state request.Request, i *item, server string) bool {
+	sw := &staleWriter{ResponseWriter: state.W}
+	cw := &ResponseWriter{ResponseWriter: sw, Cache: c, state: state, server: server, do: state.Do(), stale: i}
+
+	answered := make(chan bool, 1)
+	go func() {
+		c.doRefresh(ctx, state, cw)
+		answered <- sw.answered()
+	}()
+
+	timer := time.NewTimer(c.staleTimeout)
+	defer timer.Stop()
+	select {
+	case ok := <-answered:
+		if ok {
+			return true
+		}
+	case <-timer.C:
+	}
+	return !sw.claim()
+}
+
+// staleWriter relays the upstream's answer to the client, unless the stale item has been sent instead.
+type staleWriter struct {
+	dns.ResponseWriter
+
+	sync.Mutex
+	ok   bool // the upstream answered.
+	sent bool // an answer has been sent to the client.
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (s *staleWriter) WriteMsg(m *dns.Msg) error {
+	s.Lock()
+	defer s.Unlock()
+	s.ok = true
+	if s.sent {
+		return nil
+	}
+	s.sent = true
+	return s.ResponseWriter.WriteMsg(m)
+}
+
+func (s *staleWriter) answered() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.ok
+}
+
+// claim returns true when no answer has been sent to the client, and makes sure none will be.
+func (s *staleWriter) claim() bool {
+	s.Lock()
+	defer s.Unlock()
+	if s.sent {
+		return false
+	}
+	s.sent = true
+	return true
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestServeStaleVerify(t *testing.T) {
+	var (
+		queries int32
+		mode    atomic.Value // "ok", "servfail", "error" or "slow".
+	)
+	mode.Store("ok")
+	c := New()
+	c.staleUpTo = time.Hour
+	c.staleTimeout = 100 * time.Millisecond
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		atomic.AddInt32(&queries, 1)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch mode.Load().(string) {
+		case "servfail":
+			m.Rcode = dns.RcodeServerFailure
+		case "error":
+			return dns.RcodeServerFailure, nil
+		case "slow":
+			time.Sleep(300 * time.Millisecond)
+			fallthrough
+		default:
+			m.Answer = []dns.RR{test.A("example.org. 60 IN A 127.0.0.2")}
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	query := func() *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		req.SetEdns0(4096, false)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(context.TODO(), rec, req)
+		return rec.Msg
+	}
+	query()
+
+	tests := []struct {
+		mode          string
+		after         time.Duration // since the first query.
+		expectQueries int32
+		expectStale   bool
+	}{
+		{"ok", 2 * time.Minute, 1, false},                     // the upstream is healthy, its answer is served and cached
+		{"servfail", 4 * time.Minute, 1, true},                // the upstream fails
+		{"servfail", 4*time.Minute + 10*time.Second, 0, true}, // within the backoff, the upstream isn't queried
+		{"error", 5 * time.Minute, 1, true},                   // the backoff has passed
+		{"slow", 6 * time.Minute, 1, true},                    // the upstream doesn't answer in time
+	}
+	for i, tc := range tests {
+		mode.Store(tc.mode)
+		now = time.Now().Add(tc.after)
+		atomic.StoreInt32(&queries, 0)
+
+		m := query()
+		if q := atomic.LoadInt32(&queries); q != tc.expectQueries {
+			t.Errorf("Test %d: expected %d upstream queries, got %d", i, tc.expectQueries, q)
+		}
+		if m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 {
+			t.Fatalf("Test %d: expected an answer, got %v", i, m)
+		}
+		e := edns.ExtendedError(m)
+		if tc.expectStale {
+			if e == nil || e.InfoCode != dns.ExtendedErrorCodeStaleAnswer {
+				t.Errorf("Test %d: expected a stale answer extended error, got %v", i, e)
+			}
+			if ttl := m.Answer[0].Header().Ttl; ttl != defaultStaleTTL {
+				t.Errorf("Test %d: expected TTL %d, got %d", i, defaultStaleTTL, ttl)
+			}
+			continue
+		}
+		if e != nil {
+			t.Errorf("Test %d: expected no extended error, got %v", i, e)
+		}
+	}
+
+	// The slow answer still refreshes the cache.
+	time.Sleep(400 * time.Millisecond)
+	mode.Store("servfail")
+	atomic.StoreInt32(&queries, 0)
+	m := query()
+	if q := atomic.LoadInt32(&queries); q != 0 {
+		t.Errorf("Expected no upstream queries, got %d", q)
+	}
+	if e := edns.ExtendedError(m); e != nil {
+		t.Errorf("Expected the refreshed answer without extended error, got %v", e)
+	}
+}
+
+func TestServeStaleImmediate(t *testing.T) {
+	queries := make(chan struct{}, 1)
+	c := New()
+	c.staleUpTo = time.Hour
+	c.staleImmediate = true
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		queries <- struct{}{}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{test.A("example.org. 60 IN A 127.0.0.2")}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+	req := new(dns.Msg)
+	req.SetQuestion("example.org.", dns.TypeA)
+	c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), req)
+	<-queries
+
+	c.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, req)
+	if ttl := rec.Msg.Answer[0].Header().Ttl; ttl != defaultStaleTTL {
+		t.Errorf("Expected the stale answer with TTL %d, got %d", defaultStaleTTL, ttl)
+	}
+	// The stale entry is refreshed in the background.
+	select {
+	case <-queries:
+	case <-time.After(time.Second):
+		t.Error("Expected the stale entry to be refreshed")
+	}
+}
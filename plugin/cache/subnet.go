@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"hash/fnv"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// scopes records the scope prefix lengths of the responses with an EDNS Client Subnet option, see RFC 7871, that
+// are stored under a subnet key, per address family and longest first.
+type scopes struct {
+	sync.RWMutex
+	v4, v6 []uint8
+}
+
+func (s *scopes) add(family uint16, scope uint8) {
+	s.Lock()
+	defer s.Unlock()
+	l := &s.v4
+	if family == 2 {
+		l = &s.v6
+	}
+	for _, x := range *l {
+		if x == scope {
+			return
+		}
+	}
+	// Readers may hold the current list, so a new one is made.
+	n := append(append(make([]uint8, 0, len(*l)+1), *l...), scope)
+	sort.Slice(n, func(i, j int) bool { return n[i] > n[j] })
+	*l = n
+}
+
+func (s *scopes) list(family uint16) []uint8 {
+	s.RLock()
+	defer s.RUnlock()
+	if family == 2 {
+		return s.v6
+	}
+	return s.v4
+}
+
+// subnetHash returns the key for qname and qtype of the answers for the subnet of ip with prefix length scope. Like
+// hash, the DO and CD bits of the request are part of it.
+func subnetHash(qname string, qtype uint16, do, cd bool, ip net.IP, scope uint8) uint64 {
+	bits := net.IPv6len * 8
+	if len(ip) == net.IPv4len {
+		bits = net.IPv4len * 8
+	}
+	h := fnv.New64()
+	h.Write([]byte{byte(qtype >> 8)})
+	h.Write([]byte{byte(qtype)})
+	h.Write([]byte{flags(do, cd)})
+	h.Write([]byte(qname))
+	h.Write(ip.Mask(net.CIDRMask(int(scope), bits)))
+	h.Write([]byte{scope})
+	return h.Sum64()
+}
+
+// subnetKey returns the key of the response m to the request in state when m has an EDNS Client Subnet option with a
+// non zero scope. The key is for the client's subnet, as lookups use it, and not for the address in the option of m.
+// The family and the scope of the key are returned too.
+func subnetKey(state request.Request, m *dns.Msg, do, cd bool) (bool, uint64, uint16, uint8) {
+	e := edns.Subnet(m)
+	if e == nil || e.SourceScope == 0 || e.SourceNetmask == 0 {
+		return false, 0, 0, 0
+	}
+	family, ip, source := clientSubnet(state)
+	if ip == nil || family != e.Family {
+		return false, 0, 0, 0
+	}
+	// A scope longer than the source prefix means the answer is only good for the source prefix.
+	scope := e.SourceScope
+	if scope > e.SourceNetmask {
+		scope = e.SourceNetmask
+	}
+	if scope > source {
+		scope = source
+	}
+	return true, subnetHash(state.Name(), m.Question[0].Qtype, do, cd, ip, scope), family, scope
+}
+
+// clientSubnet returns the address family, the address and the source prefix length of the client's subnet: from the
+// EDNS Client Subnet option in the request, or else the client's address.
+func clientSubnet(state request.Request) (uint16, net.IP, uint8) {
+	if e := edns.Subnet(state.Req); e != nil {
+		if e.Family == 1 {
+			return 1, e.Address.To4(), e.SourceNetmask
+		}
+		return e.Family, e.Address.To16(), e.SourceNetmask
+	}
+	ip := net.ParseIP(state.IP())
+	if ip4 := ip.To4(); ip4 != nil {
+		return 1, ip4, net.IPv4len * 8
+	}
+	return 2, ip, net.IPv6len * 8
+}
+
+// keys returns the keys under which an answer for state may be stored: the subnet keys for the scopes that are in
+// use, longest first, and the key of the answers that are good for any client.
+func (c *Cache) keys(state request.Request) []uint64 {
+	do, cd := state.Do(), state.Req.CheckingDisabled
+	k := hash(state.Name(), state.QType(), do, cd)
+	family, ip, source := clientSubnet(state)
+	if ip == nil {
+		return []uint64{k}
+	}
+	scopes := c.scopes.list(family)
+	keys := make([]uint64, 0, len(scopes)+1)
+	for _, scope := range scopes {
+		if scope > source {
+			continue
+		}
+		keys = append(keys, subnetHash(state.Name(), state.QType(), do, cd, ip, scope))
+	}
+	return append(keys, k)
+}
+
+// setSubnet adds the EDNS Client Subnet option to m, a reply from the cache, when the client sent one in r.
+func setSubnet(r, m *dns.Msg, scope uint8) {
+	e := edns.Subnet(r)
+	if e == nil {
+		return
+	}
+	edns.SetSubnet(m, &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        e.Family,
+		SourceNetmask: e.SourceNetmask,
+		SourceScope:   scope,
+		Address:       e.Address,
+	})
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestSubnet(t *testing.T) {
+	c := New()
+	queries := 0
+	c.Next = subnetHandler(&queries)
+
+	tests := []struct {
+		remote        string
+		subnet        *dns.EDNS0_SUBNET // sent by the client
+		qname         string
+		expectA       string
+		expectQueries int
+		expectScope   int // -1 when no client subnet is expected in the reply
+	}{
+		{remote: "10.0.1.5", qname: "example.org.", expectA: "10.0.1.1", expectQueries: 1, expectScope: -1},
+		{remote: "10.0.1.9", qname: "example.org.", expectA: "10.0.1.1", expectQueries: 1, expectScope: -1},
+		{remote: "10.0.2.5", qname: "example.org.", expectA: "10.0.2.1", expectQueries: 2, expectScope: -1},
+		{
+			remote:        "10.0.9.9",
+			subnet:        &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("10.0.1.0")},
+			qname:         "example.org.",
+			expectA:       "10.0.1.1",
+			expectQueries: 2,
+			expectScope:   24,
+		},
+		// The client's subnet is too short for the answers stored per /24.
+		{
+			remote:        "10.0.9.9",
+			subnet:        &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 16, Address: net.ParseIP("10.0.0.0")},
+			qname:         "example.org.",
+			expectA:       "10.0.0.1",
+			expectQueries: 3,
+			expectScope:   24,
+		},
+		// An answer with a zero scope is good for everybody.
+		{remote: "10.0.1.5", qname: "global.example.org.", expectA: "10.0.1.1", expectQueries: 4, expectScope: -1},
+		{remote: "10.0.2.5", qname: "global.example.org.", expectA: "10.0.1.1", expectQueries: 4, expectScope: -1},
+		// The answer is stored for the client's subnet, not for the one in the upstream's option.
+		{remote: "10.0.3.5", qname: "other.example.org.", expectA: "10.0.3.1", expectQueries: 5, expectScope: -1},
+		{remote: "10.0.3.9", qname: "other.example.org.", expectA: "10.0.3.1", expectQueries: 5, expectScope: -1},
+		{remote: "10.0.7.5", qname: "other.example.org.", expectA: "10.0.7.1", expectQueries: 6, expectScope: -1},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		if tc.subnet != nil {
+			m.SetEdns0(4096, false)
+			edns.SetSubnet(m, tc.subnet)
+		}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: tc.remote})
+		c.ServeDNS(context.TODO(), rec, m)
+
+		if len(rec.Msg.Answer) != 1 || rec.Msg.Answer[0].(*dns.A).A.String() != tc.expectA {
+			t.Errorf("Test %d: expected answer %s, got %v", i, tc.expectA, rec.Msg.Answer)
+		}
+		if queries != tc.expectQueries {
+			t.Errorf("Test %d: expected %d queries upstream, got %d", i, tc.expectQueries, queries)
+		}
+		if tc.expectScope < 0 {
+			continue
+		}
+		if e := edns.Subnet(rec.Msg); e == nil || int(e.SourceScope) != tc.expectScope || !e.Address.Equal(tc.subnet.Address) {
+			t.Errorf("Test %d: expected the client's subnet with scope %d, got %v", i, tc.expectScope, e)
+		}
+	}
+}
+
+// subnetHandler answers with the first address of the client's /24, or with the source prefix when it is shorter,
+// and echoes the client subnet with a scope of 24. For global.example.org. the scope is 0, and for other.example.org.
+// another subnet is returned.
+func subnetHandler(queries *int) plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		*queries++
+		e := edns.Subnet(r)
+		if e == nil {
+			ip, _, _ := net.SplitHostPort(w.RemoteAddr().String())
+			e = &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP(ip).To4().Mask(net.CIDRMask(24, 32))}
+		}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		a := e.Address.To4().Mask(net.CIDRMask(int(e.SourceNetmask), 32))
+		a[3] = 1
+		m.Answer = []dns.RR{test.A(r.Question[0].Name + " 3600 IN A " + a.String())}
+		scope := uint8(24)
+		if r.Question[0].Name == "global.example.org." {
+			scope = 0
+		}
+		addr := e.Address
+		if r.Question[0].Name == "other.example.org." {
+			addr = net.ParseIP("10.0.7.0")
+		}
+		edns.SetSubnet(m, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: e.SourceNetmask, SourceScope: scope, Address: addr})
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
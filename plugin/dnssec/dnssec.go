@@ -7,6 +7,7 @@ import (
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/plugin/pkg/singleflight"
 	"github.com/coredns/coredns/request"
@@ -45,6 +46,7 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 	req := state.Req
 
 	incep, expir := incepExpir(now)
+	var failed error // the last signing error, it is reported to the client.
 
 	mt, _ := response.Typify(req, time.Now().UTC()) // TODO(miek): need opt record here?
 	if mt == response.Delegation {
@@ -60,13 +62,18 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 
 		if sigs, err := d.sign(req.Ns, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			failed = err
 		}
 		if sigs, err := d.nsec(state, mt, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			failed = err
 		}
 		if len(req.Ns) > 1 { // actually added nsec and sigs, reset the rcode
 			req.Rcode = dns.RcodeSuccess
 		}
+		signError(req, failed)
 		return req
 	}
 
@@ -74,23 +81,39 @@ func (d Dnssec) Sign(state request.Request, now time.Time, server string) *dns.M
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Answer = append(req.Answer, sigs...)
+		} else {
+			failed = err
 		}
 	}
 	for _, r := range rrSets(req.Ns) {
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Ns = append(req.Ns, sigs...)
+		} else {
+			failed = err
 		}
 	}
 	for _, r := range rrSets(req.Extra) {
 		ttl := r[0].Header().Ttl
 		if sigs, err := d.sign(r, state.Zone, ttl, incep, expir, server); err == nil {
 			req.Extra = append(req.Extra, sigs...)
+		} else {
+			failed = err
 		}
 	}
+	signError(req, failed)
 	return req
 }
 
+// signError adds an Extended DNS Error to m when signing failed, as the client gets an unsigned reply.
+func signError(m *dns.Msg, err error) {
+	if err == nil {
+		return
+	}
+	edns.SetExtendedError(m, dns.ExtendedErrorCodeOther, "failed to sign")
+	log.Errorf("Failed to sign the reply: %s", err)
+}
+
 func (d Dnssec) sign(rrs []dns.RR, signerName string, ttl, incep, expir uint32, server string) ([]dns.RR, error) {
 	k := hash(rrs)
 	sgs, ok := d.get(k, server)
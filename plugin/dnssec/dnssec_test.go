@@ -1,10 +1,14 @@
 package dnssec
 
 import (
+	"crypto"
+	"errors"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/coredns/coredns/request"
 
@@ -123,6 +127,31 @@ func TestSigningEmpty(t *testing.T) {
 	}
 }
 
+func TestSigningFailure(t *testing.T) {
+	k, rm1, rm2 := newKey(t)
+	defer rm1()
+	defer rm2()
+	k.s = failSigner{k.s}
+	d := New([]string{"miek.nl."}, []*DNSKEY{k}, false, nil, cache.New(defaultCap))
+
+	m := testMsg()
+	state := request.Request{Req: m, Zone: "miek.nl."}
+	m = d.Sign(state, time.Now().UTC(), server)
+	if !section(m.Answer, 0) {
+		t.Errorf("Answer section should have no RRSIGs")
+	}
+	if e := edns.ExtendedError(m); e == nil || e.InfoCode != dns.ExtendedErrorCodeOther {
+		t.Errorf("Expected an extended error, got %v", e)
+	}
+}
+
+// failSigner is a crypto.Signer that fails to sign.
+type failSigner struct{ crypto.Signer }
+
+func (failSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("no signing today")
+}
+
 func section(rss []dns.RR, nrSigs int) bool {
 	i := 0
 	for _, r := range rss {
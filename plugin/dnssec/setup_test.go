@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/pkg/cache"
 )
 
 func TestSetupDnssec(t *testing.T) {
@@ -34,55 +35,66 @@ func TestSetupDnssec(t *testing.T) {
 		expectedKeys       []string
 		expectedSplitkeys  bool
 		expectedCapacity   int
+		expectedPolicy     cache.Policy
 		expectedErrContent string
 	}{
-		{`dnssec`, false, nil, nil, false, defaultCap, ""},
-		{`dnssec example.org`, false, []string{"example.org."}, nil, false, defaultCap, ""},
-		{`dnssec 10.0.0.0/8`, false, []string{"10.in-addr.arpa."}, nil, false, defaultCap, ""},
+		{`dnssec`, false, nil, nil, false, defaultCap, cache.Random, ""},
+		{`dnssec example.org`, false, []string{"example.org."}, nil, false, defaultCap, cache.Random, ""},
+		{`dnssec 10.0.0.0/8`, false, []string{"10.in-addr.arpa."}, nil, false, defaultCap, cache.Random, ""},
 		{
 			`dnssec example.org {
 				cache_capacity 100
-			}`, false, []string{"example.org."}, nil, false, 100, "",
+			}`, false, []string{"example.org."}, nil, false, 100, cache.Random, "",
+		},
+		{
+			`dnssec example.org {
+				cache_eviction lfu
+			}`, false, []string{"example.org."}, nil, false, defaultCap, cache.LFU, "",
 		},
 		{
 			`dnssec cluster.local {
 				key file Kcluster.local
-			}`, false, []string{"cluster.local."}, nil, false, defaultCap, "",
+			}`, false, []string{"cluster.local."}, nil, false, defaultCap, cache.Random, "",
 		},
 		{
 			`dnssec example.org cluster.local {
 				key file Kcluster.local
-			}`, false, []string{"example.org.", "cluster.local."}, nil, false, defaultCap, "",
+			}`, false, []string{"example.org.", "cluster.local."}, nil, false, defaultCap, cache.Random, "",
 		},
 		// fails
 		{
 			`dnssec example.org {
 				key file Kcluster.local
-			}`, true, []string{"example.org."}, nil, false, defaultCap, "can not sign any",
+			}`, true, []string{"example.org."}, nil, false, defaultCap, cache.Random, "can not sign any",
 		},
 		{
 			`dnssec example.org {
 				key
-			}`, true, []string{"example.org."}, nil, false, defaultCap, "argument count",
+			}`, true, []string{"example.org."}, nil, false, defaultCap, cache.Random, "argument count",
 		},
 		{
 			`dnssec example.org {
 				key file
-			}`, true, []string{"example.org."}, nil, false, defaultCap, "argument count",
+			}`, true, []string{"example.org."}, nil, false, defaultCap, cache.Random, "argument count",
+		},
+		{
+			`dnssec example.org {
+				cache_eviction fifo
+			}`, true, []string{"example.org."}, nil, false, defaultCap, cache.Random, "unknown eviction policy",
 		},
 		{`dnssec
-		  dnssec`, true, nil, nil, false, defaultCap, ""},
+		  dnssec`, true, nil, nil, false, defaultCap, cache.Random, ""},
 		{
 			`dnssec cluster.local {
 				key file Kcluster.local
 				key file ksk_Kcluster.local
-			}`, false, []string{"cluster.local."}, nil, true, defaultCap, "",
+			}`, false, []string{"cluster.local."}, nil, true, defaultCap, cache.Random, "",
 		},
 	}
 
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.input)
-		zones, keys, capacity, splitkeys, err := dnssecParse(c)
+		zones, keys, capacity, policy, splitkeys, err := dnssecParse(c)
 
 		if test.shouldErr && err == nil {
 			t.Errorf("Test %d: Expected error but found %s for input %s", i, err, test.input)
@@ -114,6 +126,9 @@ func TestSetupDnssec(t *testing.T) {
 			if capacity != test.expectedCapacity {
 				t.Errorf("Dnssec not correctly set capacity for input '%s' Expected: '%d', actual: '%d'", test.input, capacity, test.expectedCapacity)
 			}
+			if policy != test.expectedPolicy {
+				t.Errorf("Dnssec not correctly set eviction policy for input '%s' Expected: '%s', actual: '%s'", test.input, test.expectedPolicy, policy)
+			}
 		}
 	}
 }
@@ -38,6 +38,8 @@ type Etcd struct {
 	Client     *etcdcv3.Client
 
 	endpoints []string // Stored here as well, to aid in testing.
+	index     *index   // If not nil, records are served from the index once it has been loaded.
+	generic   []string // Zones with records in the generic format.
 }
 
 // Services implements the ServiceBackend interface.
@@ -72,15 +74,21 @@ func (e *Etcd) Records(ctx context.Context, state request.Request, exact bool) (
 	name := state.Name()
 
 	path, star := msg.PathWithWildcard(name, e.PathPrefix)
-	r, err := e.get(ctx, path, !exact)
+	kvs, err := e.get(ctx, path, !exact)
 	if err != nil {
 		return nil, err
 	}
 	segments := strings.Split(msg.Path(name, e.PathPrefix), "/")
-	return e.loopNodes(r.Kvs, segments, star, state.QType())
+	return e.loopNodes(kvs, segments, star, state.QType())
 }
 
-func (e *Etcd) get(ctx context.Context, path string, recursive bool) (*etcdcv3.GetResponse, error) {
+func (e *Etcd) get(ctx context.Context, path string, recursive bool) ([]*mvccpb.KeyValue, error) {
+	if e.index != nil {
+		if _, ok := e.index.revision(); ok {
+			return e.index.get(path, recursive)
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
 	defer cancel()
 	if recursive {
@@ -101,7 +109,7 @@ func (e *Etcd) get(ctx context.Context, path string, recursive bool) (*etcdcv3.G
 				return nil, errKeyNotFound
 			}
 		}
-		return r, nil
+		return r.Kvs, nil
 	}
 
 	r, err := e.Client.Get(ctx, path)
@@ -111,7 +119,7 @@ func (e *Etcd) get(ctx context.Context, path string, recursive bool) (*etcdcv3.G
 	if r.Count == 0 {
 		return nil, errKeyNotFound
 	}
-	return r, nil
+	return r.Kvs, nil
 }
 
 func (e *Etcd) loopNodes(kv []*mvccpb.KeyValue, nameParts []string, star bool, qType uint16) (sx []msg.Service, err error) {
@@ -0,0 +1,116 @@
+package etcd
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// isGeneric returns true if the keys of name hold records in the generic format, see msg.Records.
+func (e *Etcd) isGeneric(name string) bool {
+	return plugin.Zones(e.generic).Matches(name) != ""
+}
+
+// serveGeneric answers the query for a name with records in the generic format.
+func (e *Etcd) serveGeneric(ctx context.Context, state request.Request, zone string, opt plugin.Options) (int, error) {
+	rrs, err := e.genericRecords(ctx, state.Name(), zone)
+	if err != nil && e.IsNameError(err) {
+		if e.Fall.Through(state.Name()) {
+			return plugin.NextOrFailure(e.Name(), e.Next, ctx, state.W, state.Req)
+		}
+		return plugin.BackendError(ctx, e, zone, dns.RcodeNameError, state, nil /* err */, opt)
+	}
+	if err != nil {
+		return plugin.BackendError(ctx, e, zone, dns.RcodeServerFailure, state, err, opt)
+	}
+
+	var records []dns.RR
+	qtype := state.QType()
+	for _, rr := range rrs {
+		if qtype == dns.TypeANY || rr.Header().Rrtype == qtype {
+			records = append(records, rr)
+		}
+	}
+	if len(records) == 0 {
+		for _, rr := range rrs {
+			if rr.Header().Rrtype == dns.TypeCNAME {
+				records = append(records, rr)
+			}
+		}
+	}
+	// The apex always has a SOA record.
+	if len(records) == 0 && qtype == dns.TypeSOA && state.Name() == zone {
+		records, _ = plugin.SOA(ctx, e, zone, state, opt)
+	}
+
+	if len(records) == 0 {
+		return plugin.BackendError(ctx, e, zone, dns.RcodeSuccess, state, nil, opt)
+	}
+
+	for _, rr := range records {
+		rr.Header().Name = state.QName()
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Authoritative = true
+	m.Answer = records
+
+	state.W.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// genericRecords returns the records of name. If name does not exist, the records of the wildcard name of its
+// closest encloser are returned.
+func (e *Etcd) genericRecords(ctx context.Context, name, zone string) ([]dns.RR, error) {
+	rrs, err := e.genericName(ctx, name, name, zone)
+	if err != errKeyNotFound {
+		return rrs, err
+	}
+	// The apex always exists.
+	if name == zone {
+		return nil, nil
+	}
+
+	for n := name; n != zone; {
+		off, end := dns.NextLabel(n, 0)
+		if end {
+			break
+		}
+		n = n[off:]
+		if n != zone {
+			// n exists when there are keys below it.
+			_, err := e.get(ctx, msg.Path(n, e.PathPrefix), true)
+			if err == errKeyNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		// n is the closest encloser.
+		return e.genericName(ctx, "*."+n, name, zone)
+	}
+	return nil, errKeyNotFound
+}
+
+// genericName returns the records of the key of name, with owner as their owner name. A name without a key,
+// but with keys below it, exists and has no records.
+func (e *Etcd) genericName(ctx context.Context, name, owner, zone string) ([]dns.RR, error) {
+	path := msg.Path(name, e.PathPrefix)
+	kvs, err := e.get(ctx, path, false)
+	if err == errKeyNotFound {
+		if _, err := e.get(ctx, path, true); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return msg.ParseRecords(kvs[0].Value, owner, zone, ttl)
+}
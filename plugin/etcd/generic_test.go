@@ -0,0 +1,124 @@
+package etcd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+var genericTestCases = []test.Case{
+	{
+		Qname: "www.generic.test.", Qtype: dns.TypeNAPTR,
+		Answer: []dns.RR{test.NAPTR(`www.generic.test. 300 IN NAPTR 100 10 "U" "E2U+sip" "!^.*$!sip:info@generic.test!" .`)},
+	},
+	{
+		Qname: "WWW.generic.test.", Qtype: dns.TypeMX,
+		Answer: []dns.RR{test.MX("WWW.generic.test. 60 IN MX 10 mail.generic.test.")},
+	},
+	// NODATA
+	{
+		Qname: "www.generic.test.", Qtype: dns.TypeAAAA,
+		Ns: []dns.RR{test.SOA("generic.test. 30 SOA ns.dns.generic.test. hostmaster.generic.test. 0 0 0 0 0")},
+	},
+	// CNAME
+	{
+		Qname: "alias.generic.test.", Qtype: dns.TypeA,
+		Answer: []dns.RR{test.CNAME("alias.generic.test. 300 IN CNAME www.generic.test.")},
+	},
+	// Empty non-terminal
+	{
+		Qname: "sub.generic.test.", Qtype: dns.TypeA,
+		Ns: []dns.RR{test.SOA("generic.test. 30 SOA ns.dns.generic.test. hostmaster.generic.test. 0 0 0 0 0")},
+	},
+	// Wildcard
+	{
+		Qname: "a.b.sub.generic.test.", Qtype: dns.TypeTXT,
+		Answer: []dns.RR{test.TXT(`a.b.sub.generic.test. 300 IN TXT "wildcard"`)},
+	},
+	// The wildcard does not cover the names of the closest encloser of www.
+	{
+		Qname: "a.www.generic.test.", Qtype: dns.TypeTXT, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{test.SOA("generic.test. 30 SOA ns.dns.generic.test. hostmaster.generic.test. 0 0 0 0 0")},
+	},
+	// The wildcard of the apex
+	{
+		Qname: "other.generic.test.", Qtype: dns.TypeTXT,
+		Answer: []dns.RR{test.TXT(`other.generic.test. 300 IN TXT "apex"`)},
+	},
+	{
+		Qname: "other.generic.test.", Qtype: dns.TypeA,
+		Ns: []dns.RR{test.SOA("generic.test. 30 SOA ns.dns.generic.test. hostmaster.generic.test. 0 0 0 0 0")},
+	},
+	{
+		Qname: "generic.test.", Qtype: dns.TypeSOA,
+		Answer: []dns.RR{test.SOA("generic.test. 30 SOA ns.dns.generic.test. hostmaster.generic.test. 0 0 0 0 0")},
+	},
+	// Invalid records
+	{
+		Qname: "bad.generic.test.", Qtype: dns.TypeA, Rcode: dns.RcodeServerFailure,
+		Ns: []dns.RR{test.SOA("generic.test. 30 SOA ns.dns.generic.test. hostmaster.generic.test. 0 0 0 0 0")},
+	},
+}
+
+func TestGeneric(t *testing.T) {
+	e := &Etcd{PathPrefix: "skydns", Zones: []string{"generic.test."}, generic: []string{"generic.test."}, index: newIndex()}
+	e.index.reset([]*mvccpb.KeyValue{
+		{Key: []byte("/skydns/test/generic/www"), Value: []byte(`{"records": [
+			"NAPTR 100 10 \"U\" \"E2U+sip\" \"!^.*$!sip:info@generic.test!\" .",
+			{"type": "MX", "ttl": 60, "rdata": {"preference": 10, "mx": "mail.generic.test."}}
+		]}`)},
+		{Key: []byte("/skydns/test/generic/alias"), Value: []byte(`{"records": ["CNAME www"]}`)},
+		{Key: []byte("/skydns/test/generic/sub/*"), Value: []byte(`{"records": ["TXT wildcard"]}`)},
+		{Key: []byte("/skydns/test/generic/*"), Value: []byte(`{"records": ["TXT apex"]}`)},
+		{Key: []byte("/skydns/test/generic/bad"), Value: []byte(`{"records": ["A bad"]}`)},
+	}, 1)
+	// Make the SOA serial predictable, the index revision is used.
+	e.index.rev = 0
+
+	ctx := context.TODO()
+	for i, tc := range genericTestCases {
+		m := tc.Msg()
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		e.ServeDNS(ctx, rec, m)
+
+		if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+			t.Errorf("Test %d, %v", i, err)
+		}
+	}
+}
+
+func TestGenericTransfer(t *testing.T) {
+	e := &Etcd{PathPrefix: "skydns", Zones: []string{"generic.test."}, generic: []string{"generic.test."}, index: newIndex()}
+	e.index.reset([]*mvccpb.KeyValue{
+		{Key: []byte("/skydns/test/generic/www"), Value: []byte(`{"records": ["A 10.0.0.1", "TXT www"]}`)},
+		{Key: []byte("/skydns/test/generic/*"), Value: []byte(`{"records": ["TXT apex"]}`)},
+		{Key: []byte("/skydns/test/generic/bad"), Value: []byte(`{"records": ["A bad"]}`)},
+	}, 1)
+
+	ch, err := e.Transfer("generic.test.", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rrs []string
+	for x := range ch {
+		for _, rr := range x {
+			rrs = append(rrs, rr.Header().Name+" "+dns.TypeToString[rr.Header().Rrtype])
+		}
+	}
+	expect := []string{"generic.test. SOA", "*.generic.test. TXT", "www.generic.test. A", "www.generic.test. TXT", "generic.test. SOA"}
+	if len(rrs) != len(expect) {
+		t.Fatalf("Expected %v, got %v", expect, rrs)
+	}
+	for i := range rrs {
+		if rrs[i] != expect[i] {
+			t.Errorf("Expected %v, got %v", expect, rrs)
+			break
+		}
+	}
+}
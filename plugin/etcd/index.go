@@ -0,0 +1,220 @@
+package etcd
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdcv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	resyncInterval   = 5 * time.Second  // wait before loading the index again after a failure
+	progressInterval = 10 * time.Second // how often to ask etcd if the watch is current
+)
+
+// index is an in-memory copy of the keys under the path of the plugin. It is kept current with an etcd watch
+// and serves the same lookups as a range read on etcd.
+type index struct {
+	sync.RWMutex
+	kvs    map[string]*mvccpb.KeyValue
+	keys   []string // sorted keys of kvs
+	rev    int64    // the etcd revision the index is current with
+	serial uint32   // the SOA serial, see nextSerial
+	synced bool
+}
+
+func newIndex() *index { return &index{kvs: make(map[string]*mvccpb.KeyValue)} }
+
+// reset replaces the contents of the index with kvs, which are current as of revision rev.
+func (i *index) reset(kvs []*mvccpb.KeyValue, rev int64) {
+	i.Lock()
+	defer i.Unlock()
+	i.kvs = make(map[string]*mvccpb.KeyValue, len(kvs))
+	i.keys = make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		k := string(kv.Key)
+		i.kvs[k] = kv
+		i.keys = append(i.keys, k)
+	}
+	sort.Strings(i.keys)
+	if !i.synced || rev != i.rev {
+		i.serial = nextSerial(i.serial)
+	}
+	i.rev = rev
+	i.synced = true
+}
+
+// apply applies the watch events to the index, which is then current as of revision rev.
+func (i *index) apply(events []*etcdcv3.Event, rev int64) {
+	i.Lock()
+	defer i.Unlock()
+	for _, ev := range events {
+		k := string(ev.Kv.Key)
+		switch ev.Type {
+		case mvccpb.PUT:
+			if _, ok := i.kvs[k]; !ok {
+				j := sort.SearchStrings(i.keys, k)
+				i.keys = append(i.keys, "")
+				copy(i.keys[j+1:], i.keys[j:])
+				i.keys[j] = k
+			}
+			i.kvs[k] = ev.Kv
+		case mvccpb.DELETE:
+			if _, ok := i.kvs[k]; !ok {
+				continue
+			}
+			delete(i.kvs, k)
+			j := sort.SearchStrings(i.keys, k)
+			i.keys = append(i.keys[:j], i.keys[j+1:]...)
+		}
+	}
+	if len(events) > 0 {
+		i.serial = nextSerial(i.serial)
+	}
+	if rev > i.rev {
+		i.rev = rev
+	}
+}
+
+// nextSerial returns the serial to use after the index changed, when prev was used before. It is the current
+// Unix time, or prev + 1 if that is not newer than prev (RFC 1982), e.g. after several changes in the same second.
+// Because the serial is derived from the time, it moves forward across restarts as well, unlike the etcd revision
+// which starts at 1 on a new cluster.
+func nextSerial(prev uint32) uint32 {
+	s := uint32(time.Now().Unix())
+	if int32(s-prev) <= 0 {
+		return prev + 1
+	}
+	return s
+}
+
+// get returns the key values for path, it works the same as Etcd.get.
+func (i *index) get(path string, recursive bool) ([]*mvccpb.KeyValue, error) {
+	i.RLock()
+	defer i.RUnlock()
+	if recursive {
+		if !strings.HasSuffix(path, "/") {
+			path = path + "/"
+		}
+		if kvs := i.prefix(path); len(kvs) > 0 {
+			return kvs, nil
+		}
+		path = strings.TrimSuffix(path, "/")
+	}
+	if kv, ok := i.kvs[path]; ok {
+		return []*mvccpb.KeyValue{kv}, nil
+	}
+	return nil, errKeyNotFound
+}
+
+// prefix returns the key values of the keys that start with p, in key order. The caller must hold the lock.
+func (i *index) prefix(p string) []*mvccpb.KeyValue {
+	var kvs []*mvccpb.KeyValue
+	for j := sort.SearchStrings(i.keys, p); j < len(i.keys) && strings.HasPrefix(i.keys[j], p); j++ {
+		kvs = append(kvs, i.kvs[i.keys[j]])
+	}
+	return kvs
+}
+
+// revision returns the revision the index is current with, and false if the index has not been loaded yet.
+func (i *index) revision() (int64, bool) {
+	i.RLock()
+	defer i.RUnlock()
+	return i.rev, i.synced
+}
+
+// soaSerial returns the serial of the SOA record for the contents of the index, and false if the index has not
+// been loaded yet.
+func (i *index) soaSerial() (uint32, bool) {
+	i.RLock()
+	defer i.RUnlock()
+	return i.serial, i.synced
+}
+
+// len returns the number of keys in the index.
+func (i *index) len() int {
+	i.RLock()
+	defer i.RUnlock()
+	return len(i.kvs)
+}
+
+// indexPath returns the etcd path the index holds.
+func (e *Etcd) indexPath() string { return msg.Path(".", e.PathPrefix) + "/" }
+
+// watch loads the index and keeps it current with an etcd watch, until ctx is canceled. When the watch
+// fails, or the revision it is at has been compacted, the index is loaded again.
+func (e *Etcd) watch(ctx context.Context) {
+	path := e.indexPath()
+	for {
+		rev, err := e.load(ctx, path)
+		if err == nil {
+			e.watchFrom(ctx, path, rev+1)
+		} else {
+			log.Warningf("Failed to load the index of %q: %s", path, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resyncInterval):
+		}
+	}
+}
+
+// load loads all keys under path into the index, and returns the revision it is current with.
+func (e *Etcd) load(ctx context.Context, path string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+	r, err := e.Client.Get(ctx, path, etcdcv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+	e.index.reset(r.Kvs, r.Header.Revision)
+	indexSize.WithLabelValues(path).Set(float64(e.index.len()))
+	watchLag.WithLabelValues(path).Set(0)
+	return r.Header.Revision, nil
+}
+
+// watchFrom applies the changes under path to the index, starting at revision rev. It returns when the watch ends.
+func (e *Etcd) watchFrom(ctx context.Context, path string, rev int64) {
+	ctx, cancel := context.WithCancel(etcdcv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	wch := e.Client.Watch(ctx, path, etcdcv3.WithPrefix(), etcdcv3.WithRev(rev))
+	tick := time.NewTicker(progressInterval)
+	defer tick.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			// The lag is the time since etcd last told us the index was current.
+			watchLag.WithLabelValues(path).Set(time.Since(last).Seconds())
+			e.Client.RequestProgress(ctx)
+		case wr, ok := <-wch:
+			if !ok {
+				return
+			}
+			if wr.CompactRevision != 0 {
+				log.Infof("The watch of %q is compacted at revision %d, loading the index again", path, wr.CompactRevision)
+				return
+			}
+			if err := wr.Err(); err != nil {
+				log.Warningf("Failed to watch %q: %s", path, err)
+				return
+			}
+			e.index.apply(wr.Events, wr.Header.Revision)
+			last = time.Now()
+			indexSize.WithLabelValues(path).Set(float64(e.index.len()))
+			watchLag.WithLabelValues(path).Set(0)
+		}
+	}
+}
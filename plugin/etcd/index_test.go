@@ -0,0 +1,184 @@
+package etcd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+
+	"github.com/miekg/dns"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdcv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestIndexGet(t *testing.T) {
+	i := newIndex()
+	i.reset([]*mvccpb.KeyValue{
+		indexKV(t, "/skydns/test/skydns/a", &msg.Service{Host: "10.0.0.1"}),
+		indexKV(t, "/skydns/test/skydns/mx/a", &msg.Service{Host: "10.0.0.2"}),
+		indexKV(t, "/skydns/test/skydns/mx/b", &msg.Service{Host: "10.0.0.3"}),
+		indexKV(t, "/skydns/test/skydns/mx1", &msg.Service{Host: "10.0.0.4"}),
+	}, 10)
+
+	i.apply([]*etcdcv3.Event{
+		{Type: mvccpb.PUT, Kv: indexKV(t, "/skydns/test/skydns/mx/c", &msg.Service{Host: "10.0.0.5"})},
+		{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte("/skydns/test/skydns/mx/a")}},
+		{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte("/skydns/test/skydns/nope")}},
+		{Type: mvccpb.PUT, Kv: indexKV(t, "/skydns/test/skydns/a", &msg.Service{Host: "10.0.0.6"})},
+	}, 12)
+
+	tests := []struct {
+		path      string
+		recursive bool
+		keys      []string
+	}{
+		{"/skydns/test/skydns/mx", true, []string{"/skydns/test/skydns/mx/b", "/skydns/test/skydns/mx/c"}},
+		{"/skydns/test/skydns/mx1", true, []string{"/skydns/test/skydns/mx1"}},
+		{"/skydns/test/skydns/mx", false, nil},
+		{"/skydns/test/skydns/a", false, []string{"/skydns/test/skydns/a"}},
+		{"/skydns/test/skydns/nope", true, nil},
+		{"/skydns/test", true, []string{"/skydns/test/skydns/a", "/skydns/test/skydns/mx/b", "/skydns/test/skydns/mx/c", "/skydns/test/skydns/mx1"}},
+	}
+	for j, tc := range tests {
+		kvs, err := i.get(tc.path, tc.recursive)
+		if tc.keys == nil {
+			if err != errKeyNotFound {
+				t.Errorf("Test %d, expected %s, got %v", j, errKeyNotFound, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d, expected no error, got %s", j, err)
+			continue
+		}
+		if len(kvs) != len(tc.keys) {
+			t.Errorf("Test %d, expected %d keys, got %d", j, len(tc.keys), len(kvs))
+			continue
+		}
+		for k, kv := range kvs {
+			if string(kv.Key) != tc.keys[k] {
+				t.Errorf("Test %d, expected key %s, got %s", j, tc.keys[k], kv.Key)
+			}
+		}
+	}
+
+	kvs, _ := i.get("/skydns/test/skydns/a", false)
+	if s := string(kvs[0].Value); s != `{"host":"10.0.0.6"}` {
+		t.Errorf("Expected the updated value, got %s", s)
+	}
+	if rev, ok := i.revision(); !ok || rev != 12 {
+		t.Errorf("Expected revision 12, got %d", rev)
+	}
+	if l := i.len(); l != 4 {
+		t.Errorf("Expected 4 keys, got %d", l)
+	}
+}
+
+func TestIndexTransfer(t *testing.T) {
+	e := &Etcd{PathPrefix: "skydns", Zones: []string{"skydns.test."}, index: newIndex()}
+
+	if _, err := e.Transfer("skydns.test.", 0); err == nil {
+		t.Fatal("Expected an error before the index is loaded")
+	}
+
+	e.index.reset([]*mvccpb.KeyValue{
+		indexKV(t, "/skydns/test/skydns/a", &msg.Service{Host: "10.0.0.1"}),
+		indexKV(t, "/skydns/test/skydns/b", &msg.Service{Host: "::1"}),
+		indexKV(t, "/skydns/test/skydns/c", &msg.Service{Host: "a.skydns.test"}),
+		indexKV(t, "/skydns/test/skydns/d", &msg.Service{Text: "text"}),
+		indexKV(t, "/skydns/test/skydns/e", &msg.Service{Host: "10.0.0.3", Port: 25, Mail: true}),
+		indexKV(t, "/skydns/test/other/a", &msg.Service{Host: "10.0.0.2"}),
+	}, 42)
+
+	ch, err := e.Transfer("skydns.test.", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rrs []dns.RR
+	for x := range ch {
+		rrs = append(rrs, x...)
+	}
+	expect := []uint16{dns.TypeSOA, dns.TypeA, dns.TypeSRV, dns.TypeAAAA, dns.TypeSRV, dns.TypeCNAME, dns.TypeTXT,
+		dns.TypeA, dns.TypeSRV, dns.TypeMX, dns.TypeSOA}
+	if len(rrs) != len(expect) {
+		t.Fatalf("Expected %d records, got %d: %v", len(expect), len(rrs), rrs)
+	}
+	for i, rr := range rrs {
+		if rr.Header().Rrtype != expect[i] {
+			t.Errorf("Expected record %d to be %s, got %s", i, dns.TypeToString[expect[i]], rr)
+		}
+	}
+	if srv := rrs[8].(*dns.SRV); srv.Hdr.Name != "e.skydns.test." || srv.Target != "e.skydns.test." || srv.Port != 25 {
+		t.Errorf("Expected an SRV record for e.skydns.test. pointing at itself, got %s", srv)
+	}
+	if mx := rrs[9].(*dns.MX); mx.Mx != "e.skydns.test." {
+		t.Errorf("Expected an MX record pointing at e.skydns.test., got %s", mx)
+	}
+	serial, _ := e.index.soaSerial()
+	if s := rrs[0].(*dns.SOA).Serial; s != serial {
+		t.Errorf("Expected serial %d, got %d", serial, s)
+	}
+
+	// ixfr fallback
+	ch, _ = e.Transfer("skydns.test.", serial)
+	rrs = rrs[:0]
+	for x := range ch {
+		rrs = append(rrs, x...)
+	}
+	if len(rrs) != 1 {
+		t.Errorf("Expected only the SOA record, got %v", rrs)
+	}
+
+	if _, err := e.Transfer("example.org.", 0); err == nil {
+		t.Error("Expected an error for a zone that is not ours")
+	}
+}
+
+func TestIndexSerial(t *testing.T) {
+	i := newIndex()
+	if _, ok := i.soaSerial(); ok {
+		t.Fatal("Expected no serial before the index is loaded")
+	}
+
+	now := uint32(time.Now().Unix())
+	i.reset(nil, 10)
+	first, ok := i.soaSerial()
+	if !ok || first < now {
+		t.Fatalf("Expected a serial of at least %d, got %d", now, first)
+	}
+
+	// A progress notification doesn't change the index.
+	i.apply(nil, 11)
+	if s, _ := i.soaSerial(); s != first {
+		t.Errorf("Expected serial %d, got %d", first, s)
+	}
+
+	// Changes within the same second still move the serial forward.
+	prev := first
+	for j := 0; j < 3; j++ {
+		i.apply([]*etcdcv3.Event{{Type: mvccpb.PUT, Kv: indexKV(t, "/skydns/test/skydns/a", &msg.Service{Host: "10.0.0.1"})}}, int64(12+j))
+		s, _ := i.soaSerial()
+		if !less(prev, s) {
+			t.Errorf("Expected serial %d to be newer than %d", s, prev)
+		}
+		prev = s
+	}
+
+	// Loading etcd again at a lower revision, e.g. a new cluster, keeps moving the serial forward.
+	i.reset(nil, 1)
+	if s, _ := i.soaSerial(); !less(prev, s) {
+		t.Errorf("Expected serial %d to be newer than %d", s, prev)
+	}
+}
+
+// less returns true if a is older than b, RFC 1982.
+func less(a, b uint32) bool { return int32(a-b) < 0 }
+
+func indexKV(t *testing.T, key string, s *msg.Service) *mvccpb.KeyValue {
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &mvccpb.KeyValue{Key: []byte(key), Value: b}
+}
@@ -0,0 +1,25 @@
+package etcd
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// indexSize is the number of keys in the index.
+	indexSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "etcd",
+		Name:      "index_keys",
+		Help:      "The number of keys in the index.",
+	}, []string{"path"})
+	// watchLag is the time since etcd last reported the index as current.
+	watchLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "etcd",
+		Name:      "index_watch_lag_seconds",
+		Help:      "The time since etcd last reported the index as current.",
+	}, []string{"path"})
+)
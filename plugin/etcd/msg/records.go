@@ -0,0 +1,107 @@
+package msg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Records is the generic value format of a key: a list of resource records of the name of the key. A record is
+// either a string with the record in presentation format, without the owner name:
+//
+//	"300 IN CAA 0 issue \"letsencrypt.org\""
+//
+// or an object with the type, the (optional) TTL and the rdata, with the names of the rdata fields of the
+// record in github.com/miekg/dns:
+//
+//	{"type": "MX", "ttl": 300, "rdata": {"preference": 10, "mx": "mail.example.org."}}
+type Records struct {
+	Records []json.RawMessage `json:"records"`
+}
+
+// record is a resource record in structured JSON.
+type record struct {
+	Type  string          `json:"type"`
+	TTL   *uint32         `json:"ttl,omitempty"`
+	Rdata json.RawMessage `json:"rdata"`
+}
+
+// ParseRecords parses the generic value format b of the key of name. Relative names in records in presentation
+// format are relative to origin, and records without a TTL get ttl.
+func ParseRecords(b []byte, name, origin string, ttl uint32) ([]dns.RR, error) {
+	rs := Records{}
+	if err := json.Unmarshal(b, &rs); err != nil {
+		return nil, err
+	}
+
+	rrs := make([]dns.RR, 0, len(rs.Records))
+	for _, raw := range rs.Records {
+		var (
+			rr  dns.RR
+			err error
+		)
+		if len(raw) > 0 && raw[0] == '"' {
+			rr, err = parsePresentation(raw, name, origin, ttl)
+		} else {
+			rr, err = parseStructured(raw, name, ttl)
+		}
+		if err != nil {
+			return nil, err
+		}
+		rrs = append(rrs, rr)
+	}
+	return rrs, nil
+}
+
+func parsePresentation(raw json.RawMessage, name, origin string, ttl uint32) (dns.RR, error) {
+	s := ""
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	if strings.ContainsAny(s, "\n\r") {
+		return nil, fmt.Errorf("record %q: must be a single line", s)
+	}
+	rr, err := dns.NewRR("$ORIGIN " + dns.Fqdn(origin) + "\n$TTL " + strconv.FormatUint(uint64(ttl), 10) + "\n" + name + " " + s)
+	if err != nil {
+		return nil, fmt.Errorf("record %q: %s", s, err)
+	}
+	if rr == nil {
+		return nil, fmt.Errorf("record %q: empty", s)
+	}
+	return rr, nil
+}
+
+func parseStructured(raw json.RawMessage, name string, ttl uint32) (dns.RR, error) {
+	r := record{}
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return nil, err
+	}
+	typ, ok := dns.StringToType[strings.ToUpper(r.Type)]
+	if !ok {
+		return nil, fmt.Errorf("record of type %q: unknown type", r.Type)
+	}
+	newRR, ok := dns.TypeToRR[typ]
+	if !ok {
+		return nil, fmt.Errorf("record of type %q: unsupported type", r.Type)
+	}
+	rr := newRR()
+	if len(r.Rdata) > 0 {
+		if err := json.Unmarshal(r.Rdata, rr); err != nil {
+			return nil, fmt.Errorf("record of type %q: %s", r.Type, err)
+		}
+	}
+	if r.TTL != nil {
+		ttl = *r.TTL
+	}
+	*rr.Header() = dns.RR_Header{Name: name, Rrtype: typ, Class: dns.ClassINET, Ttl: ttl}
+
+	// Parse the record again, to validate the rdata.
+	rr1, err := dns.NewRR(rr.String())
+	if err != nil || rr1 == nil {
+		return nil, fmt.Errorf("record of type %q: invalid rdata: %s", r.Type, rr)
+	}
+	return rr1, nil
+}
@@ -0,0 +1,54 @@
+package msg
+
+import "testing"
+
+func TestParseRecords(t *testing.T) {
+	tests := []struct {
+		value     string
+		shouldErr bool
+		expected  []string
+	}{
+		{`{"records": ["300 IN CAA 0 issue \"letsencrypt.org\""]}`, false,
+			[]string{"www.example.org.\t300\tIN\tCAA\t0 issue \"letsencrypt.org\""}},
+		{`{"records": ["MX 10 mail", "HTTPS 1 . alpn=h2"]}`, false,
+			[]string{"www.example.org.\t30\tIN\tMX\t10 mail.example.org.", "www.example.org.\t30\tIN\tHTTPS\t1 . alpn=\"h2\""}},
+		{`{"records": [{"type": "MX", "ttl": 300, "rdata": {"preference": 10, "mx": "mail.example.org."}}]}`, false,
+			[]string{"www.example.org.\t300\tIN\tMX\t10 mail.example.org."}},
+		{`{"records": [{"type": "sshfp", "rdata": {"algorithm": 1, "type": 1, "fingerprint": "dd465c09cfa51fb45020cc83316fff21b9ec74ac"}}]}`, false,
+			[]string{"www.example.org.\t30\tIN\tSSHFP\t1 1 DD465C09CFA51FB45020CC83316FFF21B9EC74AC"}},
+		{`{"records": [{"type": "A", "rdata": {"a": "10.0.0.1"}}, "AAAA ::1"]}`, false,
+			[]string{"www.example.org.\t30\tIN\tA\t10.0.0.1", "www.example.org.\t30\tIN\tAAAA\t::1"}},
+		{`{"records": []}`, false, nil},
+		// negative
+		{`{"records": ["A 10.0.0.1\nother A 10.0.0.2"]}`, true, nil},
+		{`{"records": ["A not-an-ip"]}`, true, nil},
+		{`{"records": [{"type": "NOPE"}]}`, true, nil},
+		{`{"records": [{"type": "A"}]}`, true, nil},
+		{`{"records": [{"type": "MX", "rdata": {"preference": "10"}}]}`, true, nil},
+		{`{"records": [10]}`, true, nil},
+		{`not json`, true, nil},
+	}
+
+	for i, tc := range tests {
+		rrs, err := ParseRecords([]byte(tc.value), "www.example.org.", "example.org.", 30)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got %v", i, rrs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if len(rrs) != len(tc.expected) {
+			t.Errorf("Test %d: expected %d records, got %d", i, len(tc.expected), len(rrs))
+			continue
+		}
+		for j, rr := range rrs {
+			if rr.String() != tc.expected[j] {
+				t.Errorf("Test %d: expected %q, got %q", i, tc.expected[j], rr.String())
+			}
+		}
+	}
+}
@@ -1,17 +1,21 @@
 package etcd
 
 import (
+	"context"
 	"crypto/tls"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 	mwtls "github.com/coredns/coredns/plugin/pkg/tls"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 
 	etcdcv3 "go.etcd.io/etcd/client/v3"
 )
 
+var log = clog.NewWithPlugin("etcd")
+
 func init() { plugin.Register("etcd", setup) }
 
 func setup(c *caddy.Controller) error {
@@ -20,6 +24,18 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("etcd", err)
 	}
 
+	if e.index != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.OnStartup(func() error {
+			go e.watch(ctx)
+			return nil
+		})
+		c.OnShutdown(func() error {
+			cancel()
+			return nil
+		})
+	}
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		e.Next = next
 		return e
@@ -79,6 +95,18 @@ func etcdParse(c *caddy.Controller) (*Etcd, error) {
 					return &Etcd{}, c.Errf("credentials requires 2 arguments, username and password")
 				}
 				username, password = args[0], args[1]
+			case "watch":
+				if len(c.RemainingArgs()) != 0 {
+					return &Etcd{}, c.ArgErr()
+				}
+				etc.index = newIndex()
+			case "generic":
+				for _, z := range plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), etc.Zones) {
+					if plugin.Zones(etc.Zones).Matches(z) == "" {
+						return &Etcd{}, c.Errf("generic zone '%s' is not a zone of the plugin", z)
+					}
+					etc.generic = append(etc.generic, z)
+				}
 			default:
 				if c.Val() != "}" {
 					return &Etcd{}, c.Errf("unknown property '%s'", c.Val())
@@ -58,6 +58,32 @@ func TestSetupEtcd(t *testing.T) {
 		}
 			`, true, "skydns", []string{"http://localhost:2379"}, "credentials requires 2 arguments", "username", "",
 		},
+		// with watch
+		{
+			`etcd {
+			watch
+		}
+			`, false, "skydns", []string{"http://localhost:2379"}, "", "", "",
+		},
+		{
+			`etcd {
+			watch 10
+		}
+			`, true, "skydns", []string{"http://localhost:2379"}, "Wrong argument count", "", "",
+		},
+		// with generic
+		{
+			`etcd skydns.local {
+			generic sub.skydns.local
+		}
+			`, false, "skydns", []string{"http://localhost:2379"}, "", "", "",
+		},
+		{
+			`etcd skydns.local {
+			generic example.org
+		}
+			`, true, "skydns", []string{"http://localhost:2379"}, "is not a zone of the plugin", "", "",
+		},
 		// with credentials, missing username and  password
 		{
 			`etcd {
@@ -0,0 +1,88 @@
+// +build etcd
+
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestWatch(t *testing.T) {
+	etc := newEtcdPlugin()
+	etc.index = newIndex()
+
+	set(t, etc, "a.watch.skydns.test.", 0, &msg.Service{Host: "10.0.0.1", Key: "a.watch.skydns.test."})
+	defer del(t, etc, "a.watch.skydns.test.")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go etc.watch(ctx)
+
+	waitFor(t, etc, "a.watch.skydns.test.", 1)
+
+	set(t, etc, "b.watch.skydns.test.", 0, &msg.Service{Host: "10.0.0.2", Key: "b.watch.skydns.test."})
+	defer del(t, etc, "b.watch.skydns.test.")
+	waitFor(t, etc, "watch.skydns.test.", 2)
+
+	del(t, etc, "b.watch.skydns.test.")
+	waitFor(t, etc, "watch.skydns.test.", 1)
+}
+
+func TestWatchCompacted(t *testing.T) {
+	etc := newEtcdPlugin()
+	etc.index = newIndex()
+	path := etc.indexPath()
+
+	rev, err := etc.load(ctxt, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set(t, etc, "c.watch.skydns.test.", 0, &msg.Service{Host: "10.0.0.3", Key: "c.watch.skydns.test."})
+	defer del(t, etc, "c.watch.skydns.test.")
+	r, err := etc.Client.Get(ctxt, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := etc.Client.Compact(ctxt, r.Header.Revision); err != nil {
+		t.Fatal(err)
+	}
+
+	// The watch can't start at a compacted revision, and must return, so the index is loaded again.
+	done := make(chan struct{})
+	go func() {
+		etc.watchFrom(ctxt, path, rev+1)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch of a compacted revision did not return")
+	}
+}
+
+// waitFor waits until the A query for name returns n records.
+func waitFor(t *testing.T, e *Etcd, name string, n int) {
+	var answer []dns.RR
+	for i := 0; i < 50; i++ {
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		e.ServeDNS(ctxt, rec, m)
+		if rec.Msg != nil {
+			answer = rec.Msg.Answer
+			if len(answer) == n {
+				return
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d records for %s, got %v", n, name, answer)
+}
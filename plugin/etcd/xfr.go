@@ -1,13 +1,26 @@
 package etcd
 
 import (
+	"context"
 	"time"
 
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/transfer"
 	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 )
 
-// Serial returns the serial number to use.
+// Serial returns the serial number to use. When the records are served from the index, this is the time the
+// index last changed, so secondaries notice the changes.
 func (e *Etcd) Serial(state request.Request) uint32 {
+	if e.index != nil {
+		if serial, ok := e.index.soaSerial(); ok {
+			return serial
+		}
+	}
 	return uint32(time.Now().Unix())
 }
 
@@ -15,3 +28,94 @@ func (e *Etcd) Serial(state request.Request) uint32 {
 func (e *Etcd) MinTTL(state request.Request) uint32 {
 	return 30
 }
+
+// Transfer implements the transfer.Transferer interface. Zones can only be transferred when the records are
+// served from the index.
+func (e *Etcd) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	if e.index == nil || plugin.Zones(e.Zones).Matches(zone) != zone {
+		return nil, transfer.ErrNotAuthoritative
+	}
+	if _, ok := e.index.revision(); !ok {
+		return nil, transfer.ErrNotAuthoritative
+	}
+	// state is not used here, hence the empty request.Request{}
+	soa, err := plugin.SOA(context.TODO(), e, zone, request.Request{}, plugin.Options{})
+	if err != nil {
+		return nil, transfer.ErrNotAuthoritative
+	}
+
+	var (
+		services []msg.Service
+		generic  []dns.RR
+	)
+	kvs, err := e.index.get(msg.Path(zone, e.PathPrefix), true)
+	if err == nil {
+		var legacy []*mvccpb.KeyValue
+		for _, kv := range kvs {
+			name := msg.Domain(string(kv.Key))
+			if !e.isGeneric(name) {
+				legacy = append(legacy, kv)
+				continue
+			}
+			rrs, err := msg.ParseRecords(kv.Value, name, zone, ttl)
+			if err != nil {
+				log.Warningf("Skipping the records of %q in the transfer of %q: %s", kv.Key, zone, err)
+				continue
+			}
+			generic = append(generic, rrs...)
+		}
+		// TXT includes the services without a host.
+		services, err = e.loopNodes(legacy, nil, false, dns.TypeTXT)
+	}
+	if err != nil && err != errKeyNotFound {
+		return nil, err
+	}
+
+	ch := make(chan []dns.RR)
+	go func() {
+		// ixfr fallback
+		if serial != 0 && soa[0].(*dns.SOA).Serial == serial {
+			ch <- soa
+			close(ch)
+			return
+		}
+		ch <- soa
+
+		for _, s := range services {
+			name := msg.Domain(s.Key)
+			what, ip := s.HostType()
+			var rrs []dns.RR
+			switch what {
+			case dns.TypeA:
+				rrs = append(rrs, s.NewA(name, ip))
+			case dns.TypeAAAA:
+				rrs = append(rrs, s.NewAAAA(name, ip))
+			case dns.TypeCNAME:
+				// No other records can share the name of a CNAME, so its SRV and MX records are left out.
+				ch <- []dns.RR{s.NewCNAME(name, s.Host)}
+				continue
+			case dns.TypeTXT:
+				ch <- []dns.RR{s.NewTXT(name)}
+				continue
+			}
+			// The SRV and MX records that a query for the name synthesises: they point at the name itself, and
+			// as the only service there, the SRV record has a weight of 100.
+			target := s
+			target.Host = name
+			if s.Port != -1 {
+				rrs = append(rrs, target.NewSRV(name, 100))
+			}
+			if s.Mail {
+				rrs = append(rrs, target.NewMX(name))
+			}
+			ch <- rrs
+		}
+		for _, rr := range generic {
+			ch <- []dns.RR{rr}
+		}
+
+		ch <- soa
+		close(ch)
+	}()
+	return ch, nil
+}
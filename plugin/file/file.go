@@ -5,7 +5,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/transfer"
@@ -55,9 +57,20 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 	// This is only for when we are a secondary zones.
 	if r.Opcode == dns.OpcodeNotify {
 		if z.isNotify(state) {
+			if !z.notifyAuthenticated(ctx, state) {
+				log.Warningf("Refusing notify from %s for %s: not signed with the key of the primary", state.IP(), zone)
+				m := new(dns.Msg)
+				m.SetRcode(r, dns.RcodeNotAuth)
+				w.WriteMsg(m)
+				return dns.RcodeSuccess, nil
+			}
+
 			m := new(dns.Msg)
 			m.SetReply(r)
 			m.Authoritative = true
+			if t := r.IsTsig(); t != nil && dnsserver.TsigVerified(ctx) && w.TsigStatus() == nil {
+				m.SetTsig(t.Hdr.Name, t.Algorithm, t.Fudge, time.Now().Unix())
+			}
 			w.WriteMsg(m)
 
 			log.Infof("Notify from %s for %s: checking transfer", state.IP(), zone)
@@ -76,6 +89,10 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 		return dns.RcodeSuccess, nil
 	}
 
+	if r.Opcode == dns.OpcodeUpdate {
+		return z.serveUpdate(ctx, w, r)
+	}
+
 	z.RLock()
 	exp := z.Expired
 	z.RUnlock()
@@ -0,0 +1,193 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultIXFRHistory is the default number of changes a zone keeps to answer incremental zone transfers with.
+const DefaultIXFRHistory = 10
+
+// change is a single change to a zone: the records deleted from the zone with SOA from, and the records added
+// to get to the zone with SOA to.
+type change struct {
+	from, to *dns.SOA
+	del, add []dns.RR
+}
+
+// len returns the number of records in c when sent in an incremental zone transfer.
+func (c change) len() int { return 2 + len(c.del) + len(c.add) }
+
+// replace sets the apex and tree of z to those of nz. The changes that got z to nz are added to its history;
+// if there are none the history is cleared, as it can't be used to get to the new zone.
+func (z *Zone) replace(nz *Zone, changes []change) {
+	z.Lock()
+	defer z.Unlock()
+
+	z.Apex = nz.Apex
+	z.Tree = nz.Tree
+	if len(changes) == 0 || z.IXFRHistory <= 0 {
+		z.changes = nil
+		z.since = nil
+		return
+	}
+	z.changes = append(z.changes, changes...)
+	if n := len(z.changes) - z.IXFRHistory; n > 0 {
+		z.changes = append([]change{}, z.changes[n:]...)
+	}
+	z.since = make(map[uint32]int, len(z.changes))
+	for i := range z.changes {
+		z.since[z.changes[i].from.Serial] = i
+	}
+}
+
+// replaceWithDiff sets the apex and tree of z, which were ap and tr, to those of nz. If the SOA serial went up the
+// difference is added to the history of z.
+func (z *Zone) replaceWithDiff(ap Apex, tr *tree.Tree, nz *Zone) {
+	if z.IXFRHistory <= 0 || ap.SOA == nil || nz.Apex.SOA == nil || !less(ap.SOA.Serial, nz.Apex.SOA.Serial) {
+		z.replace(nz, nil)
+		return
+	}
+	del, add := zoneDiff(ap, tr, nz.Apex, nz.Tree)
+	z.replace(nz, []change{{from: ap.SOA, to: nz.Apex.SOA, del: del, add: add}})
+}
+
+// changesSince returns the changes that bring a zone with SOA serial up to date with z. If the history doesn't reach
+// back to serial, or the changes hold more records than a full zone transfer, nil is returned; in that case a full
+// zone transfer should be done.
+func (z *Zone) changesSince(serial uint32) []change {
+	z.RLock()
+	ap := z.Apex
+	tr := z.Tree
+	var changes []change
+	if i, ok := z.since[serial]; ok {
+		changes = append([]change{}, z.changes[i:]...)
+	}
+	z.RUnlock()
+
+	if ap.SOA == nil || len(changes) == 0 || changes[len(changes)-1].to.Serial != ap.SOA.Serial {
+		return nil
+	}
+
+	l := 0
+	for _, c := range changes {
+		l += c.len()
+	}
+	// A full zone transfer holds the apex, all records and the closing SOA.
+	n := 2 + len(ap.SIGSOA) + len(ap.NS) + len(ap.SIGNS)
+	tr.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		n += len(e.All())
+		return nil
+	})
+	if l > n {
+		return nil
+	}
+	return changes
+}
+
+// zoneDiff returns the records that are deleted from, and added to, the zone with apex ap and tree tr to get to the
+// zone with apex nap and tree ntr. The SOA records are not included.
+func zoneDiff(ap Apex, tr *tree.Tree, nap Apex, ntr *tree.Tree) (del []dns.RR, add []dns.RR) {
+	apex := func(ap Apex) []dns.RR {
+		rrs := append([]dns.RR{}, ap.SIGSOA...)
+		rrs = append(rrs, ap.NS...)
+		return append(rrs, ap.SIGNS...)
+	}
+	del, add = diff(apex(ap), apex(nap))
+
+	a, b := tr.All(), ntr.All()
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch c := tree.Less(a[i], b[j].Name()); {
+		case c == 0:
+			d, n := diff(a[i].All(), b[j].All())
+			del = append(del, d...)
+			add = append(add, n...)
+			i++
+			j++
+		case c < 0:
+			add = append(add, b[j].All()...)
+			j++
+		default:
+			del = append(del, a[i].All()...)
+			i++
+		}
+	}
+	for ; i < len(a); i++ {
+		del = append(del, a[i].All()...)
+	}
+	for ; j < len(b); j++ {
+		add = append(add, b[j].All()...)
+	}
+	return del, add
+}
+
+// parseChanges parses the changes in rrs, which hold one or more sequences of an old SOA, the deleted records, the new
+// SOA and the added records, as used in an incremental zone transfer (RFC 1995) and the journal. A trailing incomplete
+// change is returned as an error when strict is true, otherwise it's ignored.
+func parseChanges(rrs []dns.RR, strict bool) ([]change, error) {
+	var changes []change
+	for _, rr := range rrs {
+		if soa, ok := rr.(*dns.SOA); ok {
+			if len(changes) == 0 || changes[len(changes)-1].to != nil {
+				changes = append(changes, change{from: soa})
+			} else {
+				changes[len(changes)-1].to = soa
+			}
+			continue
+		}
+		if len(changes) == 0 {
+			return nil, fmt.Errorf("changes do not start with a SOA record")
+		}
+		c := &changes[len(changes)-1]
+		if c.to == nil {
+			c.del = append(c.del, rr)
+		} else {
+			c.add = append(c.add, rr)
+		}
+	}
+	if len(changes) > 0 && changes[len(changes)-1].to == nil {
+		if strict {
+			return nil, fmt.Errorf("incomplete change from SOA serial %d", changes[len(changes)-1].from.Serial)
+		}
+		changes = changes[:len(changes)-1]
+	}
+	return changes, nil
+}
+
+// applyChanges returns a new zone that is the zone with apex ap and tree tr with changes applied, in order. It
+// returns an error if a change doesn't follow on from the previous one.
+func applyChanges(origin string, ap Apex, tr *tree.Tree, changes []change) (*Zone, error) {
+	if ap.SOA == nil {
+		return nil, fmt.Errorf("no SOA")
+	}
+	serial := ap.SOA.Serial
+
+	u := newUpdater(origin, ap, tr)
+	for _, c := range changes {
+		if c.from.Serial != serial {
+			return nil, fmt.Errorf("change from SOA serial %d does not apply to SOA serial %d", c.from.Serial, serial)
+		}
+		for _, rr := range c.del {
+			rr := rr
+			u.remove(strings.ToLower(rr.Header().Name), func(r dns.RR) bool { return dns.IsDuplicate(r, rr) })
+		}
+		for _, rr := range c.add {
+			rr.Header().Name = strings.ToLower(rr.Header().Name)
+			u.add(rr)
+		}
+		u.remove(origin, isType(dns.TypeSOA))
+		u.add(c.to)
+		serial = c.to.Serial
+	}
+
+	nz, _, _ := u.commit()
+	if nz == nil {
+		return nil, fmt.Errorf("no changes")
+	}
+	return nz, nil
+}
@@ -0,0 +1,214 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func ixfrZone(t *testing.T, serial uint32, extra string) *Zone {
+	db := fmt.Sprintf(`$ORIGIN example.org.
+@	3600 IN	SOA sns.dns.icann.org. noc.dns.icann.org. %d 7200 3600 1209600 3600
+	3600 IN NS  a.iana-servers.net.
+	3600 IN NS  b.iana-servers.net.
+www	3600 IN A   127.0.0.1
+a	3600 IN A   127.0.0.2
+%s
+`, serial, extra)
+	z, err := Parse(strings.NewReader(db), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	return z
+}
+
+func transferAll(t *testing.T, z *Zone, serial uint32) []dns.RR {
+	ch, err := z.Transfer(serial)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	var rrs []dns.RR
+	for r := range ch {
+		rrs = append(rrs, r...)
+	}
+	return rrs
+}
+
+func TestZoneDiff(t *testing.T) {
+	z := ixfrZone(t, 1, "b 3600 IN A 127.0.0.3")
+	z1 := ixfrZone(t, 2, "c 3600 IN A 127.0.0.4\n@ 3600 IN NS c.iana-servers.net.")
+
+	del, add := zoneDiff(z.Apex, z.Tree, z1.Apex, z1.Tree)
+	expectDel := []string{
+		"b.example.org.\t3600\tIN\tA\t127.0.0.3",
+	}
+	expectAdd := []string{
+		"example.org.\t3600\tIN\tNS\tc.iana-servers.net.",
+		"c.example.org.\t3600\tIN\tA\t127.0.0.4",
+	}
+	if x := rrStrings(del); strings.Join(x, "\n") != strings.Join(expectDel, "\n") {
+		t.Errorf("Expected deleted records %v, got %v", expectDel, x)
+	}
+	if x := rrStrings(add); strings.Join(x, "\n") != strings.Join(expectAdd, "\n") {
+		t.Errorf("Expected added records %v, got %v", expectAdd, x)
+	}
+}
+
+func TestTransferIncremental(t *testing.T) {
+	z := ixfrZone(t, 1, "")
+	z.reload(ixfrZone(t, 2, "b 3600 IN A 127.0.0.3"))
+	z.reload(ixfrZone(t, 3, "c 3600 IN A 127.0.0.4"))
+
+	rrs := transferAll(t, z, 1)
+	expect := []string{
+		"example.org.\t3600\tIN\tSOA\tsns.dns.icann.org. noc.dns.icann.org. 3 7200 3600 1209600 3600",
+		"example.org.\t3600\tIN\tSOA\tsns.dns.icann.org. noc.dns.icann.org. 1 7200 3600 1209600 3600",
+		"example.org.\t3600\tIN\tSOA\tsns.dns.icann.org. noc.dns.icann.org. 2 7200 3600 1209600 3600",
+		"b.example.org.\t3600\tIN\tA\t127.0.0.3",
+		"example.org.\t3600\tIN\tSOA\tsns.dns.icann.org. noc.dns.icann.org. 2 7200 3600 1209600 3600",
+		"b.example.org.\t3600\tIN\tA\t127.0.0.3",
+		"example.org.\t3600\tIN\tSOA\tsns.dns.icann.org. noc.dns.icann.org. 3 7200 3600 1209600 3600",
+		"c.example.org.\t3600\tIN\tA\t127.0.0.4",
+		"example.org.\t3600\tIN\tSOA\tsns.dns.icann.org. noc.dns.icann.org. 3 7200 3600 1209600 3600",
+	}
+	if x := rrStrings(rrs); strings.Join(x, "\n") != strings.Join(expect, "\n") {
+		t.Errorf("Expected incremental transfer:\n%s\ngot:\n%s", strings.Join(expect, "\n"), strings.Join(x, "\n"))
+	}
+
+	// Up to date, only the SOA is sent.
+	if rrs := transferAll(t, z, 3); len(rrs) != 1 {
+		t.Errorf("Expected 1 record, got %d", len(rrs))
+	}
+	// Unknown serial, the full zone is sent.
+	if rrs := transferAll(t, z, 100); len(rrs) != 7 {
+		t.Errorf("Expected full transfer of 7 records, got %d", len(rrs))
+	}
+}
+
+func TestTransferIncrementalHistory(t *testing.T) {
+	z := ixfrZone(t, 1, "")
+	z.IXFRHistory = 1
+	z.reload(ixfrZone(t, 2, "b 3600 IN A 127.0.0.3"))
+	z.reload(ixfrZone(t, 3, "b 3600 IN A 127.0.0.4"))
+
+	// The change from serial 1 is gone.
+	if rrs := transferAll(t, z, 1); len(rrs) != 7 {
+		t.Errorf("Expected full transfer of 7 records, got %d", len(rrs))
+	}
+	if rrs := transferAll(t, z, 2); len(rrs) != 6 {
+		t.Errorf("Expected incremental transfer of 6 records, got %d", len(rrs))
+	}
+
+	// A reload that doesn't increase the serial clears the history.
+	z.reload(ixfrZone(t, 3, "b 3600 IN A 127.0.0.5"))
+	if rrs := transferAll(t, z, 2); len(rrs) != 7 {
+		t.Errorf("Expected full transfer of 7 records, got %d", len(rrs))
+	}
+}
+
+func TestApplyChanges(t *testing.T) {
+	z := ixfrZone(t, 1, "b 3600 IN A 127.0.0.3")
+	z1 := ixfrZone(t, 2, "c 3600 IN A 127.0.0.4")
+	del, add := zoneDiff(z.Apex, z.Tree, z1.Apex, z1.Tree)
+
+	nz, err := applyChanges(z.origin, z.Apex, z.Tree, []change{{from: z.Apex.SOA, to: z1.Apex.SOA, del: del, add: add}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if del, add := zoneDiff(nz.Apex, nz.Tree, z1.Apex, z1.Tree); len(del)+len(add) != 0 {
+		t.Errorf("Expected no differences with the new zone, got deleted %v and added %v", del, add)
+	}
+	if nz.Apex.SOA.Serial != 2 {
+		t.Errorf("Expected serial 2, got %d", nz.Apex.SOA.Serial)
+	}
+
+	// A change that doesn't follow on from the zone's serial.
+	if _, err := applyChanges(z.origin, z1.Apex, z1.Tree, []change{{from: z.Apex.SOA, to: z1.Apex.SOA}}); err == nil {
+		t.Errorf("Expected error applying change from serial 1 to zone with serial 2")
+	}
+}
+
+type ixfrPrimary struct{}
+
+func (ixfrPrimary) Handler(w dns.ResponseWriter, req *dns.Msg) {
+	soa := func(serial int) dns.RR {
+		return test.SOA(fmt.Sprintf("%s IN SOA bla. bla. %d 0 0 0 0", testZone, serial))
+	}
+	m := new(dns.Msg)
+	m.SetReply(req)
+	switch req.Question[0].Qtype {
+	case dns.TypeIXFR:
+		m.Answer = []dns.RR{
+			soa(252),
+			soa(250), test.A(fmt.Sprintf("%s IN A 127.0.0.1", testZone)), soa(252), test.A(fmt.Sprintf("%s IN A 127.0.0.2", testZone)),
+			soa(252),
+		}
+	case dns.TypeAXFR:
+		m.Answer = []dns.RR{soa(252), test.A(fmt.Sprintf("%s IN A 127.0.0.3", testZone)), soa(252)}
+	}
+	w.WriteMsg(m)
+}
+
+func TestTransferInIncremental(t *testing.T) {
+	s := dnstest.NewServer(ixfrPrimary{}.Handler)
+	defer s.Close()
+
+	z := NewZone(testZone, "stdin")
+	z.Insert(test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 250 0 0 0 0", testZone)))
+	z.Insert(test.A(fmt.Sprintf("%s IN A 127.0.0.1", testZone)))
+	z.TransferFrom = []string{s.Addr}
+
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Unable to run TransferIn: %v", err)
+	}
+	if serial := z.SOASerialIfDefined(); serial != 252 {
+		t.Errorf("Expected serial 252, got %d", serial)
+	}
+	// The record from the IXFR, not the one from the AXFR.
+	if rrs := lookup(z, testZone, dns.TypeA); len(rrs) != 1 || rrs[0].(*dns.A).A.String() != "127.0.0.2" {
+		t.Errorf("Expected A record 127.0.0.2, got %v", rrs)
+	}
+	// The change is kept, so it can be sent on.
+	if len(z.changes) != 1 || z.changes[0].from.Serial != 250 {
+		t.Errorf("Expected the change from serial 250 in the history, got %d changes", len(z.changes))
+	}
+}
+
+func TestLoadTruncated(t *testing.T) {
+	soa := func(serial int) dns.RR {
+		return test.SOA(fmt.Sprintf("%s IN SOA bla. bla. %d 0 0 0 0", testZone, serial))
+	}
+	tests := [][]dns.RR{
+		// An incremental transfer without the final SOA.
+		{soa(252), soa(250), test.A(fmt.Sprintf("%s IN A 127.0.0.1", testZone)), soa(251), test.A(fmt.Sprintf("%s IN A 127.0.0.2", testZone))},
+		// An incremental transfer cut short after a change.
+		{soa(252), soa(250), test.A(fmt.Sprintf("%s IN A 127.0.0.1", testZone)), soa(251)},
+		// A full transfer without the final SOA.
+		{soa(252), test.A(fmt.Sprintf("%s IN A 127.0.0.3", testZone))},
+	}
+	for i, rrs := range tests {
+		z := NewZone(testZone, "stdin")
+		z.Insert(soa(250))
+		z.Insert(test.A(fmt.Sprintf("%s IN A 127.0.0.1", testZone)))
+
+		if err := z.load(rrs); err == nil {
+			t.Errorf("Test %d: expected an error loading a truncated transfer", i)
+		}
+		if serial := z.SOASerialIfDefined(); serial != 250 {
+			t.Errorf("Test %d: expected serial 250, got %d", i, serial)
+		}
+	}
+}
+
+func rrStrings(rrs []dns.RR) []string {
+	s := make([]string, len(rrs))
+	for i, rr := range rrs {
+		s[i] = rr.String()
+	}
+	return s
+}
@@ -0,0 +1,178 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// writeJournal appends a change to the journal file of z. A change is written in the same sequence IXFR (RFC 1995)
+// uses: the old SOA, the deleted records, the new SOA and the added records.
+func (z *Zone) writeJournal(c change) error {
+	f, err := os.OpenFile(z.Journal, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	writeChange(w, c)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeChange(w io.Writer, c change) {
+	fmt.Fprintf(w, "; serial %d -> %d\n", c.from.Serial, c.to.Serial)
+	fmt.Fprintln(w, c.from.String())
+	for _, rr := range c.del {
+		fmt.Fprintln(w, rr.String())
+	}
+	fmt.Fprintln(w, c.to.String())
+	for _, rr := range c.add {
+		fmt.Fprintln(w, rr.String())
+	}
+}
+
+// compactJournal replaces the journal of z with changes, dropping the changes that are no longer needed because
+// the zone file holds them, or because they don't follow on from it. With no changes the journal is truncated.
+func (z *Zone) compactJournal(changes []change) error {
+	if len(changes) == 0 {
+		if err := os.Truncate(z.Journal, 0); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(z.Journal), filepath.Base(z.Journal)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails once the file is renamed, which is fine.
+
+	w := bufio.NewWriter(tmp)
+	for _, c := range changes {
+		writeChange(w, c)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), z.Journal)
+}
+
+// readJournal reads the changes from the journal in r. A trailing incomplete change is ignored.
+func readJournal(r io.Reader, origin, fileName string) ([]change, error) {
+	zp := dns.NewZoneParser(r, origin, fileName)
+	var rrs []dns.RR
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	changes, err := parseChanges(rrs, false)
+	if err != nil {
+		return nil, fmt.Errorf("journal %q: %s", fileName, err)
+	}
+	return changes, nil
+}
+
+// ReplayJournal applies the changes from the journal of z that follow on from the zone's current SOA serial. This
+// brings a zone loaded from disk up to date with the dynamic updates it received. The older changes are removed
+// from the journal. It returns the number of changes applied.
+func (z *Zone) ReplayJournal() (int, error) {
+	f, err := os.Open(z.Journal)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	changes, err := readJournal(f, z.origin, z.Journal)
+	f.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	z.RLock()
+	ap := z.Apex
+	tr := z.Tree
+	z.RUnlock()
+	if ap.SOA == nil {
+		return 0, nil
+	}
+	// Skip the changes that are older than the zone.
+	skipped := 0
+	for len(changes) > 0 && changes[0].from.Serial != ap.SOA.Serial {
+		changes = changes[1:]
+		skipped++
+	}
+	if skipped > 0 {
+		if err := z.compactJournal(changes); err != nil {
+			log.Warningf("Failed to compact journal %q for zone %q: %s", z.Journal, z.origin, err)
+		}
+	}
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	nz, err := applyChanges(z.origin, ap, tr, changes)
+	if err != nil {
+		return 0, err
+	}
+	z.replace(nz, changes)
+	return len(changes), nil
+}
+
+// writeFile writes the zone to its file. The zone is first written to a temporary file in the same directory,
+// which is then renamed, so a concurrent reload never sees a partially written file.
+func (z *Zone) writeFile() error {
+	apex, err := z.ApexIfDefined()
+	if err != nil {
+		return err
+	}
+	z.RLock()
+	tr := z.Tree
+	file := z.file
+	z.RUnlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails once the file is renamed, which is fine.
+
+	if fi, err := os.Stat(file); err == nil {
+		tmp.Chmod(fi.Mode())
+	}
+
+	w := bufio.NewWriter(tmp)
+	fmt.Fprintf(w, "; zone %s, written after a dynamic update\n", z.origin)
+	for _, rr := range apex {
+		fmt.Fprintln(w, rr.String())
+	}
+	tr.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		for _, rr := range e.All() {
+			fmt.Fprintln(w, rr.String())
+		}
+		return nil
+	})
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
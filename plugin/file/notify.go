@@ -1,8 +1,10 @@
 package file
 
 import (
+	"context"
 	"net"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -31,3 +33,25 @@ func (z *Zone) isNotify(state request.Request) bool {
 	}
 	return false
 }
+
+// notifyAuthenticated checks the TSIG signature of the notify in state. If the primary it's from has a TSIG key in
+// z.TransferKeys, the notify must be signed with that key.
+func (z *Zone) notifyAuthenticated(ctx context.Context, state request.Request) bool {
+	remote := state.IP()
+	for _, f := range z.TransferFrom {
+		from, _, err := net.SplitHostPort(f)
+		if err != nil || from != remote {
+			continue
+		}
+		k, ok := z.TransferKeys[f]
+		if !ok {
+			return true
+		}
+		t := state.Req.IsTsig()
+		if t == nil || !dnsserver.TsigVerified(ctx) || state.W.TsigStatus() != nil {
+			return false
+		}
+		return dns.CanonicalName(t.Hdr.Name) == k.Name
+	}
+	return true
+}
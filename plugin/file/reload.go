@@ -15,6 +15,7 @@ func (z *Zone) Reload(t *transfer.Transfer) error {
 	tick := time.NewTicker(z.ReloadInterval)
 
 	go func() {
+		skipped := int64(-1) // the serial of the file that was last not reloaded, to only warn once.
 		for {
 			select {
 			case <-tick.C:
@@ -35,11 +36,14 @@ func (z *Zone) Reload(t *transfer.Transfer) error {
 					continue
 				}
 
-				// copy elements we need
-				z.Lock()
-				z.Apex = zone.Apex
-				z.Tree = zone.Tree
-				z.Unlock()
+				if !z.reload(zone) {
+					if s := int64(zone.Apex.SOA.Serial); s != skipped {
+						log.Warningf("Not reloading zone %q in %q: SOA serial %d is older than the updated zone's", z.origin, zFile, s)
+						skipped = s
+					}
+					continue
+				}
+				skipped = -1
 
 				log.Infof("Successfully reloaded zone %q in %q with %d SOA serial", z.origin, zFile, z.Apex.SOA.Serial)
 				if t != nil {
@@ -57,6 +61,31 @@ func (z *Zone) Reload(t *transfer.Transfer) error {
 	return nil
 }
 
+// reload replaces the contents of z with those of zone. A zone that accepts dynamic updates is only replaced when
+// the serial of zone is newer, otherwise the updates it received would be lost. It returns true if z was replaced.
+func (z *Zone) reload(zone *Zone) bool {
+	z.updateMu.Lock()
+	defer z.updateMu.Unlock()
+
+	z.RLock()
+	ap := z.Apex
+	tr := z.Tree
+	z.RUnlock()
+
+	if len(z.UpdateKeys) > 0 && ap.SOA != nil && zone.Apex.SOA != nil && less(zone.Apex.SOA.Serial, ap.SOA.Serial) {
+		return false
+	}
+
+	z.replaceWithDiff(ap, tr, zone)
+	if z.Journal != "" {
+		// The changes in the journal were made to the zone that was replaced.
+		if err := z.compactJournal(nil); err != nil {
+			log.Errorf("Failed to truncate journal for zone %q in %q: %s", z.origin, z.Journal, err)
+		}
+	}
+	return true
+}
+
 // SOASerialIfDefined returns the SOA's serial if the zone has a SOA record in the Apex, or -1 otherwise.
 func (z *Zone) SOASerialIfDefined() int64 {
 	z.RLock()
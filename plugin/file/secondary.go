@@ -1,29 +1,56 @@
 package file
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 
 	"github.com/miekg/dns"
 )
 
-// TransferIn retrieves the zone from the masters, parses it and sets it live.
+// TransferIn retrieves the zone from the masters, parses it and sets it live. If the zone already has a SOA an
+// incremental zone transfer (RFC 1995) is requested, which falls back to a full zone transfer if that fails.
 func (z *Zone) TransferIn() error {
 	if len(z.TransferFrom) == 0 {
 		return nil
 	}
+
+	z.RLock()
+	soa := z.Apex.SOA
+	z.RUnlock()
+
+	if soa != nil && z.IXFRHistory > 0 {
+		m := new(dns.Msg)
+		m.SetIxfr(z.origin, soa.Serial, soa.Ns, soa.Mbox)
+		err := z.transferIn(m)
+		if err == nil {
+			return nil
+		}
+		log.Warningf("Failed incremental transfer of `%s', falling back to a full transfer: %v", z.origin, err)
+	}
+
 	m := new(dns.Msg)
 	m.SetAxfr(z.origin)
+	return z.transferIn(m)
+}
 
-	z1 := z.CopyWithoutApex()
+// transferIn sends m to the masters until one of them successfully transfers the zone.
+func (z *Zone) transferIn(m *dns.Msg) error {
 	var (
 		Err error
 		tr  string
+		rrs []dns.RR
 	)
 
 Transfer:
 	for _, tr = range z.TransferFrom {
+		rrs = nil
 		t := new(dns.Transfer)
+		m := m.Copy()
+		if k, ok := z.TransferKeys[tr]; ok {
+			t.TsigSecret = map[string]string{k.Name: k.Secret}
+			m.SetTsig(k.Name, k.Algorithm, 300, time.Now().Unix())
+		}
 		c, err := t.In(m, tr)
 		if err != nil {
 			log.Errorf("Failed to setup transfer `%s' with `%q': %v", z.origin, tr, err)
@@ -36,15 +63,12 @@ Transfer:
 				Err = env.Error
 				continue Transfer
 			}
-			for _, rr := range env.RR {
-				if err := z1.Insert(rr); err != nil {
-					log.Errorf("Failed to parse transfer `%s' from: %q: %v", z.origin, tr, err)
-					Err = err
-					continue Transfer
-				}
-			}
+			rrs = append(rrs, env.RR...)
+		}
+		if Err = z.load(rrs); Err != nil {
+			log.Errorf("Failed to parse transfer `%s' from: %q: %v", z.origin, tr, Err)
+			continue Transfer
 		}
-		Err = nil
 		break
 	}
 	if Err != nil {
@@ -52,14 +76,68 @@ Transfer:
 	}
 
 	z.Lock()
-	z.Tree = z1.Tree
-	z.Apex = z1.Apex
 	z.Expired = false
 	z.Unlock()
 	log.Infof("Transferred: %s from %s", z.origin, tr)
 	return nil
 }
 
+// load sets the zone to the records of a zone transfer. These hold either a single SOA, when the zone is up to date,
+// the changes since the current SOA (RFC 1995), or the full zone.
+func (z *Zone) load(rrs []dns.RR) error {
+	if len(rrs) == 0 {
+		return fmt.Errorf("empty transfer")
+	}
+	first, ok := rrs[0].(*dns.SOA)
+	if !ok {
+		return fmt.Errorf("transfer does not start with a SOA record")
+	}
+
+	z.updateMu.Lock()
+	defer z.updateMu.Unlock()
+
+	z.RLock()
+	ap := z.Apex
+	tr := z.Tree
+	z.RUnlock()
+
+	if len(rrs) == 1 {
+		if ap.SOA == nil || ap.SOA.Serial != first.Serial {
+			return fmt.Errorf("incomplete transfer")
+		}
+		return nil
+	}
+	// A transfer ends with the SOA record it started with, without it the transfer was cut short.
+	if last, ok := rrs[len(rrs)-1].(*dns.SOA); !ok || last.Serial != first.Serial {
+		return fmt.Errorf("transfer does not end with the SOA record with serial %d", first.Serial)
+	}
+
+	if soa, ok := rrs[1].(*dns.SOA); ok && soa.Serial != first.Serial {
+		changes, err := parseChanges(rrs[1:len(rrs)-1], true)
+		if err != nil {
+			return err
+		}
+		nz, err := applyChanges(z.origin, ap, tr, changes)
+		if err != nil {
+			return err
+		}
+		z.replace(nz, changes)
+		return nil
+	}
+
+	nz := z.CopyWithoutApex()
+	for _, rr := range rrs {
+		if err := nz.Insert(rr); err != nil {
+			return err
+		}
+	}
+	if nz.Apex.SOA == nil {
+		return fmt.Errorf("no SOA record in transfer")
+	}
+	z.replaceWithDiff(ap, tr, nz)
+	return nil
+}
+
 // shouldTransfer checks the primaries of zone, retrieves the SOA record, checks the current serial
 // and the remote serial and will return true if the remote one is higher than the locally configured one.
 func (z *Zone) shouldTransfer() (bool, error) {
@@ -74,6 +152,12 @@ func (z *Zone) shouldTransfer() (bool, error) {
 Transfer:
 	for _, tr := range z.TransferFrom {
 		Err = nil
+		m := m.Copy()
+		c.TsigSecret = nil
+		if k, ok := z.TransferKeys[tr]; ok {
+			c.TsigSecret = map[string]string{k.Name: k.Secret}
+			m.SetTsig(k.Name, k.Algorithm, 300, time.Now().Unix())
+		}
 		ret, _, err := c.Exchange(m, tr)
 		if err != nil || ret.Rcode != dns.RcodeSuccess {
 			Err = err
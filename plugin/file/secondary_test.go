@@ -1,10 +1,14 @@
 package file
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/test"
 	"github.com/coredns/coredns/request"
 
@@ -138,6 +142,36 @@ func TestIsNotify(t *testing.T) {
 	}
 }
 
+func TestNotifyAuthenticated(t *testing.T) {
+	z := new(Zone)
+	z.origin = testZone
+	z.TransferFrom = []string{"10.240.0.1:53"} // IP from testing/responseWriter
+
+	state := newRequest(testZone, dns.TypeSOA)
+	state.Req.Opcode = dns.OpcodeNotify
+	if !z.notifyAuthenticated(context.TODO(), state) {
+		t.Fatal("Notify should be authenticated when the primary has no key")
+	}
+
+	z.TransferKeys = map[string]tsig.Key{"10.240.0.1:53": {Name: "xfr.key.", Algorithm: dns.HmacSHA256}}
+	if z.notifyAuthenticated(context.TODO(), state) {
+		t.Fatal("Unsigned notify should not be authenticated")
+	}
+	state.Req.SetTsig("other.key.", dns.HmacSHA256, 300, time.Now().Unix())
+	if z.notifyAuthenticated(context.TODO(), state) {
+		t.Fatal("Notify signed with another key should not be authenticated")
+	}
+	state.Req.Extra = nil
+	state.Req.SetTsig("xfr.key.", dns.HmacSHA256, 300, time.Now().Unix())
+	if z.notifyAuthenticated(context.TODO(), state) {
+		t.Fatal("Notify should not be authenticated when the server didn't verify the signature")
+	}
+	ctx := context.WithValue(context.TODO(), dnsserver.TsigKey{}, true)
+	if !z.notifyAuthenticated(ctx, state) {
+		t.Fatal("Notify signed with the key of the primary should be authenticated")
+	}
+}
+
 func newRequest(zone string, qtype uint16) request.Request {
 	m := new(dns.Msg)
 	m.SetQuestion("example.com.", dns.TypeA)
@@ -3,13 +3,17 @@ package file
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/coredns/coredns/plugin/transfer"
+
+	"github.com/miekg/dns"
 )
 
 func init() { plugin.Register("file", setup) }
@@ -28,6 +32,9 @@ func setup(c *caddy.Controller) error {
 			return nil
 		}
 		f.transfer = t.(*transfer.Transfer) // if found this must be OK.
+		for _, n := range zones.Names {
+			zones.Z[n].transfer = f.transfer
+		}
 		go func() {
 			for _, n := range zones.Names {
 				f.transfer.Notify(n)
@@ -105,14 +112,54 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 			names = append(names, origins[i])
 		}
 
+		var (
+			keys      = map[string]tsig.Key{}
+			allowed   []string
+			journal   string
+			writeBack bool
+			ixfr      = DefaultIXFRHistory
+		)
 		for c.NextBlock() {
 			switch c.Val() {
+			case "key":
+				k, err := tsig.Parse(c.RemainingArgs())
+				if err != nil {
+					return Zones{}, c.Err(err.Error())
+				}
+				keys[k.Name] = k
+			case "update":
+				allowed = c.RemainingArgs()
+				if len(allowed) == 0 {
+					return Zones{}, c.ArgErr()
+				}
+			case "journal":
+				args := c.RemainingArgs()
+				switch len(args) {
+				case 0:
+					journal = fileName + ".jnl"
+				case 1:
+					journal = args[0]
+					if !filepath.IsAbs(journal) && config.Root != "" {
+						journal = filepath.Join(config.Root, journal)
+					}
+				default:
+					return Zones{}, c.ArgErr()
+				}
+			case "write":
+				if c.NextArg() {
+					return Zones{}, c.ArgErr()
+				}
+				writeBack = true
 			case "reload":
 				d, err := time.ParseDuration(c.RemainingArgs()[0])
 				if err != nil {
 					return Zones{}, plugin.Error("file", err)
 				}
 				reload = d
+			case "ixfr":
+				if ixfr, err = parseIXFR(c); err != nil {
+					return Zones{}, err
+				}
 			case "upstream":
 				// remove soon
 				c.RemainingArgs()
@@ -121,6 +168,49 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 				return Zones{}, c.Errf("unknown property '%s'", c.Val())
 			}
 		}
+
+		for _, origin := range origins {
+			z[origin].IXFRHistory = ixfr
+		}
+
+		if len(allowed) == 0 {
+			if journal != "" || writeBack {
+				return Zones{}, c.Errf("'journal' and 'write' need 'update'")
+			}
+			continue
+		}
+		if (journal != "" || writeBack) && len(origins) > 1 {
+			return Zones{}, c.Errf("'journal' and 'write' can only be used with a single zone")
+		}
+		updateKeys := make(map[string]string, len(allowed))
+		for _, name := range allowed {
+			k, ok := keys[dns.CanonicalName(name)]
+			if !ok {
+				return Zones{}, c.Errf("update key %q is not defined", name)
+			}
+			if config.TsigSecret == nil {
+				config.TsigSecret = make(map[string]string)
+			}
+			if err := tsig.Register(config.TsigSecret, k); err != nil {
+				return Zones{}, c.Err(err.Error())
+			}
+			updateKeys[k.Name] = k.Algorithm
+		}
+		for _, origin := range origins {
+			z[origin].UpdateKeys = updateKeys
+			z[origin].Journal = journal
+			z[origin].WriteBack = writeBack
+			if journal == "" {
+				continue
+			}
+			n, err := z[origin].ReplayJournal()
+			if err != nil {
+				return Zones{}, plugin.Error("file", err)
+			}
+			if n > 0 {
+				log.Infof("Replayed %d changes from journal %q for zone %q", n, journal, origin)
+			}
+		}
 	}
 
 	for origin := range z {
@@ -138,3 +228,16 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 	}
 	return Zones{Z: z, Names: names}, nil
 }
+
+// parseIXFR parses the argument of the ixfr property: the number of changes kept for incremental zone transfers.
+func parseIXFR(c *caddy.Controller) (int, error) {
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 {
+		return 0, c.Errf("invalid ixfr history size %q", args[0])
+	}
+	return n, nil
+}
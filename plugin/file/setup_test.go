@@ -6,6 +6,8 @@ import (
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
 )
 
 func TestFileParse(t *testing.T) {
@@ -122,3 +124,134 @@ func TestParseReload(t *testing.T) {
 		}
 	}
 }
+
+func TestParseIXFR(t *testing.T) {
+	name, rm, err := test.TempFile(".", dbMiekNL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		history   int
+	}{
+		{`file ` + name + ` example.org.`, false, DefaultIXFRHistory},
+		{`file ` + name + ` example.org. {
+			ixfr 50
+			}`, false, 50},
+		{`file ` + name + ` example.org. {
+			ixfr 0
+			}`, false, 0},
+		// errors.
+		{`file ` + name + ` example.org. {
+			ixfr
+			}`, true, 0},
+		{`file ` + name + ` example.org. {
+			ixfr -1
+			}`, true, 0},
+		{`file ` + name + ` example.org. {
+			ixfr many
+			}`, true, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		z, err := fileParse(c)
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if x := z.Z["example.org."].IXFRHistory; x != test.history {
+			t.Errorf("Test %d expected ixfr history to be %d, but got %d", i, test.history, x)
+		}
+	}
+}
+
+func TestParseUpdate(t *testing.T) {
+	name, rm, err := test.TempFile(".", dbMiekNL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		keys      map[string]string
+		journal   string
+		writeBack bool
+	}{
+		{`file ` + name + ` miek.nl.`, false, nil, "", false},
+		{`file ` + name + ` miek.nl. {
+			key update.miek.nl. hmac-sha256 c2VjcmV0
+			update update.miek.nl.
+		}`, false, map[string]string{"update.miek.nl.": dns.HmacSHA256}, "", false},
+		{`file ` + name + ` miek.nl. {
+			update Update.Miek.nl
+			key update.miek.nl. hmac-sha512 c2VjcmV0
+			journal
+			write
+		}`, false, map[string]string{"update.miek.nl.": dns.HmacSHA512}, name + ".jnl", true},
+		{`file ` + name + ` miek.nl. {
+			key update.miek.nl. hmac-sha256 c2VjcmV0
+			update update.miek.nl.
+			journal /tmp/miek.nl.jnl
+		}`, false, map[string]string{"update.miek.nl.": dns.HmacSHA256}, "/tmp/miek.nl.jnl", false},
+		// errors.
+		{`file ` + name + ` miek.nl. {
+			update update.miek.nl.
+		}`, true, nil, "", false},
+		{`file ` + name + ` miek.nl. {
+			key update.miek.nl. hmac-md5 c2VjcmV0
+			update update.miek.nl.
+		}`, true, nil, "", false},
+		{`file ` + name + ` miek.nl. {
+			key update.miek.nl. hmac-sha256 c2VjcmV0
+			update
+		}`, true, nil, "", false},
+		{`file ` + name + ` miek.nl. {
+			journal
+		}`, true, nil, "", false},
+		{`file ` + name + ` miek.nl. example.org. {
+			key update.miek.nl. hmac-sha256 c2VjcmV0
+			update update.miek.nl.
+			write
+		}`, true, nil, "", false},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		z, err := fileParse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		zone := z.Z["miek.nl."]
+		if len(zone.UpdateKeys) != len(tc.keys) {
+			t.Errorf("Test %d: expected update keys %v, got %v", i, tc.keys, zone.UpdateKeys)
+		}
+		for k, alg := range tc.keys {
+			if zone.UpdateKeys[k] != alg {
+				t.Errorf("Test %d: expected algorithm %s for key %s, got %s", i, alg, k, zone.UpdateKeys[k])
+			}
+		}
+		if zone.Journal != tc.journal {
+			t.Errorf("Test %d: expected journal %q, got %q", i, tc.journal, zone.Journal)
+		}
+		if zone.WriteBack != tc.writeBack {
+			t.Errorf("Test %d: expected write %t, got %t", i, tc.writeBack, zone.WriteBack)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package tree
+
+import "github.com/miekg/dns"
+
+// Set returns a new tree in which name holds the records rrs, or in which name is deleted when rrs is empty. The
+// nodes on the path to name are copied and the other nodes are shared, t itself is not modified. This makes a
+// change to a few names cheap, while readers of t are not disturbed.
+func (t *Tree) Set(name string, rrs []dns.RR) *Tree {
+	t1 := &Tree{Root: t.Root, Count: t.Count}
+	if len(rrs) == 0 {
+		if _, ok := t.Search(name); !ok {
+			return t1
+		}
+		var d int
+		t1.Root, d = t1.Root.cowDelete(name)
+		t1.Count += d
+		if t1.Root != nil {
+			t1.Root.Color = black
+		}
+		return t1
+	}
+
+	e := &Elem{m: make(map[uint16][]dns.RR), name: name}
+	for _, rr := range rrs {
+		e.Insert(rr)
+	}
+	var d int
+	t1.Root, d = t1.Root.cowInsert(e)
+	t1.Count += d
+	t1.Root.Color = black
+	return t1
+}
+
+// The cow methods work like their counterparts in tree.go, but they copy a node before changing it.
+
+func (n *Node) clone() *Node {
+	n1 := *n
+	return &n1
+}
+
+func (n *Node) cowRotateLeft() *Node {
+	root := n.Right.clone()
+	n.Right = root.Left
+	root.Left = n
+	root.Color = n.Color
+	n.Color = red
+	return root
+}
+
+func (n *Node) cowRotateRight() *Node {
+	root := n.Left.clone()
+	n.Left = root.Right
+	root.Right = n
+	root.Color = n.Color
+	n.Color = red
+	return root
+}
+
+func (n *Node) cowFlipColors() {
+	n.Left = n.Left.clone()
+	n.Right = n.Right.clone()
+	n.flipColors()
+}
+
+func (n *Node) cowFixUp() *Node {
+	if n.Right.color() == red {
+		if mode == td234 && n.Right.Left.color() == red {
+			n.Right = n.Right.clone().cowRotateRight()
+		}
+		n = n.cowRotateLeft()
+	}
+	if n.Left.color() == red && n.Left.Left.color() == red {
+		n = n.cowRotateRight()
+	}
+	if mode == bu23 && n.Left.color() == red && n.Right.color() == red {
+		n.cowFlipColors()
+	}
+	return n
+}
+
+func (n *Node) cowMoveRedLeft() *Node {
+	n.cowFlipColors()
+	if n.Right.Left.color() == red {
+		n.Right = n.Right.cowRotateRight()
+		n = n.cowRotateLeft()
+		n.cowFlipColors()
+		if mode == td234 && n.Right.Right.color() == red {
+			n.Right = n.Right.cowRotateLeft()
+		}
+	}
+	return n
+}
+
+func (n *Node) cowMoveRedRight() *Node {
+	n.cowFlipColors()
+	if n.Left.Left.color() == red {
+		n = n.cowRotateRight()
+		n.cowFlipColors()
+	}
+	return n
+}
+
+// cowInsert inserts e, or replaces the element with the same name.
+func (n *Node) cowInsert(e *Elem) (root *Node, d int) {
+	if n == nil {
+		return &Node{Elem: e}, 1
+	}
+	n = n.clone()
+	if n.Elem == nil {
+		n.Elem = e
+		return n, 1
+	}
+
+	if mode == td234 {
+		if n.Left.color() == red && n.Right.color() == red {
+			n.cowFlipColors()
+		}
+	}
+
+	switch c := Less(n.Elem, e.Name()); {
+	case c == 0:
+		n.Elem = e
+	case c < 0:
+		n.Left, d = n.Left.cowInsert(e)
+	default:
+		n.Right, d = n.Right.cowInsert(e)
+	}
+
+	if n.Right.color() == red && n.Left.color() == black {
+		n = n.cowRotateLeft()
+	}
+	if n.Left.color() == red && n.Left.Left.color() == red {
+		n = n.cowRotateRight()
+	}
+
+	if mode == bu23 {
+		if n.Left.color() == red && n.Right.color() == red {
+			n.cowFlipColors()
+		}
+	}
+	return n, d
+}
+
+func (n *Node) cowDeleteMin() (root *Node, d int) {
+	if n.Left == nil {
+		return nil, -1
+	}
+	n = n.clone()
+	if n.Left.color() == black && n.Left.Left.color() == black {
+		n = n.cowMoveRedLeft()
+	}
+	n.Left, d = n.Left.cowDeleteMin()
+	return n.cowFixUp(), d
+}
+
+// cowDelete deletes the element with name, which must be in the tree.
+func (n *Node) cowDelete(name string) (root *Node, d int) {
+	n = n.clone()
+	if Less(n.Elem, name) < 0 {
+		if n.Left != nil {
+			if n.Left.color() == black && n.Left.Left.color() == black {
+				n = n.cowMoveRedLeft()
+			}
+			n.Left, d = n.Left.cowDelete(name)
+		}
+	} else {
+		if n.Left.color() == red {
+			n = n.cowRotateRight()
+		}
+		if n.Right == nil && Less(n.Elem, name) == 0 {
+			return nil, -1
+		}
+		if n.Right != nil {
+			if n.Right.color() == black && n.Right.Left.color() == black {
+				n = n.cowMoveRedRight()
+			}
+			if Less(n.Elem, name) == 0 {
+				n.Elem = n.Right.min().Elem
+				n.Right, d = n.Right.cowDeleteMin()
+			} else {
+				n.Right, d = n.Right.cowDelete(name)
+			}
+		}
+	}
+	return n.cowFixUp(), d
+}
@@ -0,0 +1,84 @@
+package tree
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestSet(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tr := &Tree{}
+	want := map[string]bool{}
+
+	for i := 0; i < 2000; i++ {
+		name := fmt.Sprintf("n%d.example.org.", rnd.Intn(200))
+		var rrs []dns.RR
+		if rnd.Intn(3) > 0 {
+			rr, _ := dns.NewRR(name + " 3600 IN A 127.0.0.1")
+			rrs = []dns.RR{rr}
+		}
+
+		before := dump(tr.Root)
+		tr1 := tr.Set(name, rrs)
+		if after := dump(tr.Root); after != before {
+			t.Fatalf("Step %d: expected the old tree to be unchanged", i)
+		}
+
+		want[name] = len(rrs) > 0
+		for n, ok := range want {
+			if _, found := tr1.Search(n); found != ok {
+				t.Fatalf("Step %d: expected %s to be found %t, got %t", i, n, ok, found)
+			}
+		}
+		count := 0
+		for _, ok := range want {
+			if ok {
+				count++
+			}
+		}
+		if tr1.Count != count {
+			t.Fatalf("Step %d: expected %d elements, got %d", i, count, tr1.Count)
+		}
+		if _, err := tr1.Root.blackHeight(); err != nil {
+			t.Fatalf("Step %d: %s", i, err)
+		}
+		tr = tr1
+	}
+}
+
+// dump returns the structure of the tree below n.
+func dump(n *Node) string {
+	if n == nil {
+		return "-"
+	}
+	return fmt.Sprintf("(%s %s %t %p %s)", dump(n.Left), n.Elem.Name(), n.Color, n.Elem, dump(n.Right))
+}
+
+// blackHeight returns the number of black links from n to a leaf, and checks that it is the same for all leaves
+// and that red links lean left.
+func (n *Node) blackHeight() (int, error) {
+	if n == nil {
+		return 1, nil
+	}
+	if n.Right.color() == red {
+		return 0, fmt.Errorf("right leaning red link at %s", n.Elem.Name())
+	}
+	l, err := n.Left.blackHeight()
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.Right.blackHeight()
+	if err != nil {
+		return 0, err
+	}
+	if l != r {
+		return 0, fmt.Errorf("black height %d and %d at %s", l, r, n.Elem.Name())
+	}
+	if n.color() == black {
+		l++
+	}
+	return l, nil
+}
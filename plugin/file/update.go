@@ -0,0 +1,469 @@
+package file
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/file/tree"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// serveUpdate handles the dynamic update (RFC 2136) in r for zone z and writes the reply to w. Updates must be
+// signed with one of the TSIG keys in z.UpdateKeys.
+func (z *Zone) serveUpdate(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	if len(z.UpdateKeys) == 0 || len(z.TransferFrom) > 0 {
+		log.Infof("Refusing update from %s for %s: updates are not enabled", state.IP(), z.origin)
+		return updateReply(w, r, dns.RcodeRefused, nil)
+	}
+	if !dnsserver.TsigVerified(ctx) {
+		log.Infof("Refusing update from %s for %s: transport does not support TSIG", state.IP(), z.origin)
+		return updateReply(w, r, dns.RcodeRefused, nil)
+	}
+	t := r.IsTsig()
+	if t == nil {
+		log.Infof("Refusing unsigned update from %s for %s", state.IP(), z.origin)
+		return updateReply(w, r, dns.RcodeRefused, nil)
+	}
+	if err := w.TsigStatus(); err != nil {
+		log.Warningf("Refusing update from %s for %s: bad signature with key %s: %s", state.IP(), z.origin, t.Hdr.Name, err)
+		return updateReply(w, r, dns.RcodeNotAuth, nil)
+	}
+	if alg, ok := z.UpdateKeys[strings.ToLower(t.Hdr.Name)]; !ok || !strings.EqualFold(alg, t.Algorithm) {
+		log.Warningf("Refusing update from %s for %s: key %s is not allowed", state.IP(), z.origin, t.Hdr.Name)
+		return updateReply(w, r, dns.RcodeRefused, t)
+	}
+
+	// RFC 2136, section 3.1.1: the zone section must hold a single SOA record for the zone.
+	if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeSOA {
+		return updateReply(w, r, dns.RcodeFormatError, t)
+	}
+	if strings.ToLower(r.Question[0].Name) != z.origin {
+		return updateReply(w, r, dns.RcodeNotAuth, t)
+	}
+
+	z.updateMu.Lock()
+	defer z.updateMu.Unlock()
+
+	z.RLock()
+	ap := z.Apex
+	tr := z.Tree
+	z.RUnlock()
+	if ap.SOA == nil {
+		return updateReply(w, r, dns.RcodeServerFailure, t)
+	}
+
+	for _, rr := range r.Answer {
+		rr.Header().Name = strings.ToLower(rr.Header().Name)
+	}
+	for _, rr := range r.Ns {
+		rr.Header().Name = strings.ToLower(rr.Header().Name)
+	}
+
+	u := newUpdater(z.origin, ap, tr)
+	if rcode := u.prerequisites(r.Answer); rcode != dns.RcodeSuccess {
+		return updateReply(w, r, rcode, t)
+	}
+	if rcode := u.check(r.Ns); rcode != dns.RcodeSuccess {
+		return updateReply(w, r, rcode, t)
+	}
+	for _, rr := range r.Ns {
+		u.apply(rr)
+	}
+
+	nz, del, add := u.commit()
+	if nz == nil {
+		// Nothing changed, so the serial isn't incremented either (RFC 2136, section 3.7).
+		return updateReply(w, r, dns.RcodeSuccess, t)
+	}
+
+	z.replace(nz, []change{{from: ap.SOA, to: nz.Apex.SOA, del: del, add: add}})
+
+	log.Infof("Update from %s with key %s for %s: deleted %d and added %d records, SOA serial %d", state.IP(), t.Hdr.Name, z.origin, len(del), len(add), nz.Apex.SOA.Serial)
+
+	if z.Journal != "" {
+		if err := z.writeJournal(change{from: ap.SOA, to: nz.Apex.SOA, del: del, add: add}); err != nil {
+			log.Errorf("Failed to write journal for zone %q to %q: %s", z.origin, z.Journal, err)
+		}
+	}
+	if z.WriteBack {
+		if err := z.writeFile(); err != nil {
+			log.Errorf("Failed to write zone %q to %q: %s", z.origin, z.File(), err)
+		} else if z.Journal != "" {
+			// The zone file holds all changes now.
+			if err := z.compactJournal(nil); err != nil {
+				log.Errorf("Failed to truncate journal for zone %q in %q: %s", z.origin, z.Journal, err)
+			}
+		}
+	}
+	if z.transfer != nil {
+		go func() {
+			if err := z.transfer.Notify(z.origin); err != nil {
+				log.Warningf("Failed sending notifies: %s", err)
+			}
+		}()
+	}
+
+	return updateReply(w, r, dns.RcodeSuccess, t)
+}
+
+// updateReply writes a reply with rcode to r. If t is not nil the reply is signed with the same key.
+func updateReply(w dns.ResponseWriter, r *dns.Msg, rcode int, t *dns.TSIG) (int, error) {
+	m := new(dns.Msg)
+	m.SetRcode(r, rcode)
+	if t != nil {
+		m.SetTsig(t.Hdr.Name, t.Algorithm, 300, time.Now().Unix())
+	}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// updater applies changes to a zone. It keeps track of the records at every name it touches; when the update
+// is committed a new zone is created from the old one and these records, that shares the names that didn't change.
+type updater struct {
+	origin string
+	apex   Apex
+	tree   *tree.Tree
+
+	orig  map[string][]dns.RR // records per name before the update
+	names map[string][]dns.RR // records per name after the update
+}
+
+func newUpdater(origin string, ap Apex, tr *tree.Tree) *updater {
+	return &updater{
+		origin: origin,
+		apex:   ap,
+		tree:   tr,
+		orig:   make(map[string][]dns.RR),
+		names:  make(map[string][]dns.RR),
+	}
+}
+
+// get returns the current records for name.
+func (u *updater) get(name string) []dns.RR {
+	if rrs, ok := u.names[name]; ok {
+		return rrs
+	}
+
+	var rrs []dns.RR
+	if name == u.origin {
+		if u.apex.SOA != nil {
+			rrs = append(rrs, u.apex.SOA)
+		}
+		rrs = append(rrs, u.apex.SIGSOA...)
+		rrs = append(rrs, u.apex.NS...)
+		rrs = append(rrs, u.apex.SIGNS...)
+	}
+	if e, ok := u.tree.Search(name); ok {
+		rrs = append(rrs, e.All()...)
+	}
+
+	u.orig[name] = rrs
+	u.names[name] = append([]dns.RR{}, rrs...)
+	return u.names[name]
+}
+
+// add adds rr to the zone. If an identical record (ignoring the TTL) exists, it is replaced.
+func (u *updater) add(rr dns.RR) {
+	rrs := u.get(rr.Header().Name)
+	for i := range rrs {
+		if dns.IsDuplicate(rrs[i], rr) {
+			rrs[i] = rr
+			return
+		}
+	}
+	u.names[rr.Header().Name] = append(rrs, rr)
+}
+
+// remove removes the records for which del returns true from name.
+func (u *updater) remove(name string, del func(dns.RR) bool) {
+	rrs := u.get(name)
+	keep := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		if !del(rr) {
+			keep = append(keep, rr)
+		}
+	}
+	u.names[name] = keep
+}
+
+// prerequisites checks the prerequisites in rrs against the zone, see RFC 2136, section 3.2.
+func (u *updater) prerequisites(rrs []dns.RR) int {
+	// Records that must exist as an RRset, keyed by name and type.
+	type key struct {
+		name  string
+		qtype uint16
+	}
+	rrsets := map[key][]dns.RR{}
+
+	for _, rr := range rrs {
+		h := rr.Header()
+		if h.Ttl != 0 {
+			return dns.RcodeFormatError
+		}
+		if !dns.IsSubDomain(u.origin, h.Name) {
+			return dns.RcodeNotZone
+		}
+		cur := u.get(h.Name)
+
+		switch h.Class {
+		case dns.ClassANY:
+			if h.Rdlength != 0 {
+				return dns.RcodeFormatError
+			}
+			if h.Rrtype == dns.TypeANY {
+				if len(cur) == 0 {
+					return dns.RcodeNameError
+				}
+				continue
+			}
+			if len(rrsOfType(cur, h.Rrtype)) == 0 {
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if h.Rdlength != 0 {
+				return dns.RcodeFormatError
+			}
+			if h.Rrtype == dns.TypeANY {
+				if len(cur) > 0 {
+					return dns.RcodeYXDomain
+				}
+				continue
+			}
+			if len(rrsOfType(cur, h.Rrtype)) > 0 {
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			k := key{h.Name, h.Rrtype}
+			rrsets[k] = append(rrsets[k], rr)
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	for k, want := range rrsets {
+		if !equalRRset(rrsOfType(u.get(k.name), k.qtype), want) {
+			return dns.RcodeNXRrset
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// check does the prescan of the update section in rrs, see RFC 2136, section 3.4.1.
+func (u *updater) check(rrs []dns.RR) int {
+	for _, rr := range rrs {
+		h := rr.Header()
+		if !dns.IsSubDomain(u.origin, h.Name) {
+			return dns.RcodeNotZone
+		}
+		switch h.Class {
+		case dns.ClassINET:
+			if isMetaType(h.Rrtype) {
+				return dns.RcodeFormatError
+			}
+			if h.Rrtype == dns.TypeNSEC3 || h.Rrtype == dns.TypeNSEC3PARAM {
+				return dns.RcodeRefused
+			}
+		case dns.ClassANY:
+			if h.Ttl != 0 || h.Rdlength != 0 || (isMetaType(h.Rrtype) && h.Rrtype != dns.TypeANY) {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassNONE:
+			if h.Ttl != 0 || isMetaType(h.Rrtype) {
+				return dns.RcodeFormatError
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// apply applies a single record from the update section, see RFC 2136, section 3.4.2.
+func (u *updater) apply(rr dns.RR) {
+	h := rr.Header()
+	apex := h.Name == u.origin
+
+	switch h.Class {
+	case dns.ClassINET:
+		cur := u.get(h.Name)
+		switch h.Rrtype {
+		case dns.TypeSOA:
+			soa := rrsOfType(cur, dns.TypeSOA)
+			if !apex || len(soa) == 0 || !less(soa[0].(*dns.SOA).Serial, rr.(*dns.SOA).Serial) {
+				return
+			}
+			u.remove(h.Name, isType(dns.TypeSOA))
+		case dns.TypeCNAME:
+			for _, c := range cur {
+				if t := c.Header().Rrtype; t != dns.TypeCNAME && !isDNSSECType(t) {
+					return
+				}
+			}
+			u.remove(h.Name, isType(dns.TypeCNAME))
+		default:
+			if !isDNSSECType(h.Rrtype) && len(rrsOfType(cur, dns.TypeCNAME)) > 0 {
+				return
+			}
+		}
+		u.add(rr)
+
+	case dns.ClassANY:
+		if h.Rrtype == dns.TypeANY {
+			u.remove(h.Name, func(r dns.RR) bool {
+				t := r.Header().Rrtype
+				return !apex || (t != dns.TypeSOA && t != dns.TypeNS)
+			})
+			return
+		}
+		if apex && (h.Rrtype == dns.TypeSOA || h.Rrtype == dns.TypeNS) {
+			return
+		}
+		u.remove(h.Name, isType(h.Rrtype))
+
+	case dns.ClassNONE:
+		if h.Rrtype == dns.TypeSOA {
+			return
+		}
+		target := dns.Copy(rr)
+		target.Header().Class = dns.ClassINET
+		if apex && h.Rrtype == dns.TypeNS {
+			ns := rrsOfType(u.get(h.Name), dns.TypeNS)
+			if len(ns) == 1 && dns.IsDuplicate(ns[0], target) {
+				// Never delete the last NS record of the zone.
+				return
+			}
+		}
+		u.remove(h.Name, func(r dns.RR) bool { return dns.IsDuplicate(r, target) })
+	}
+}
+
+// commit returns a new zone with the changes applied and the deleted and added records, not including the
+// SOA records. If the update did not explicitly change the SOA record, its serial is incremented. If nothing
+// changed, commit returns a nil zone.
+func (u *updater) commit() (*Zone, []dns.RR, []dns.RR) {
+	var del, add []dns.RR
+	soaChanged := false
+	for name, rrs := range u.names {
+		d, a := diff(u.orig[name], rrs)
+		for _, rr := range d {
+			if rr.Header().Rrtype == dns.TypeSOA {
+				soaChanged = true
+				continue
+			}
+			del = append(del, rr)
+		}
+		for _, rr := range a {
+			if rr.Header().Rrtype != dns.TypeSOA {
+				add = append(add, rr)
+			}
+		}
+	}
+	if len(del) == 0 && len(add) == 0 && !soaChanged {
+		return nil, nil, nil
+	}
+
+	// Only the names that changed are replaced in the tree, which shares the rest with the old zone.
+	z := NewZone(u.origin, "")
+	z.Apex = u.apex
+	z.Tree = u.tree
+	for name, rrs := range u.names {
+		// Insert normalizes the records and takes out the ones of the apex, copy them as the old zone may
+		// still be in use.
+		nz := NewZone(u.origin, "")
+		for _, rr := range rrs {
+			nz.Insert(dns.Copy(rr))
+		}
+		if name == u.origin {
+			z.Apex = nz.Apex
+		}
+		var all []dns.RR
+		if e, ok := nz.Tree.Search(name); ok {
+			all = e.All()
+		}
+		z.Tree = z.Tree.Set(name, all)
+	}
+
+	if !soaChanged {
+		soa := dns.Copy(z.Apex.SOA).(*dns.SOA)
+		soa.Serial++
+		z.Apex.SOA = soa
+	}
+	return z, del, add
+}
+
+// diff returns the records that are in a but not in b, and those that are in b but not in a.
+func diff(a, b []dns.RR) (del []dns.RR, add []dns.RR) {
+	in := func(rr dns.RR, rrs []dns.RR) bool {
+		s := rr.String()
+		for _, r := range rrs {
+			if r.String() == s {
+				return true
+			}
+		}
+		return false
+	}
+	for _, rr := range a {
+		if !in(rr, b) {
+			del = append(del, rr)
+		}
+	}
+	for _, rr := range b {
+		if !in(rr, a) {
+			add = append(add, rr)
+		}
+	}
+	return del, add
+}
+
+// equalRRset returns true if a and b hold the same records, ignoring the TTL and the class.
+func equalRRset(a, b []dns.RR) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	contains := func(rrs []dns.RR, rr dns.RR) bool {
+		rr = dns.Copy(rr)
+		rr.Header().Class = dns.ClassINET
+		for _, r := range rrs {
+			if dns.IsDuplicate(r, rr) {
+				return true
+			}
+		}
+		return false
+	}
+	for i := range a {
+		if !contains(b, a[i]) || !contains(a, b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func rrsOfType(rrs []dns.RR, qtype uint16) []dns.RR {
+	var ret []dns.RR
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == qtype {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+func isType(qtype uint16) func(dns.RR) bool {
+	return func(rr dns.RR) bool { return rr.Header().Rrtype == qtype }
+}
+
+func isMetaType(t uint16) bool {
+	switch t {
+	case dns.TypeANY, dns.TypeAXFR, dns.TypeIXFR, dns.TypeMAILA, dns.TypeMAILB:
+		return true
+	}
+	return false
+}
+
+func isDNSSECType(t uint16) bool { return t == dns.TypeRRSIG || t == dns.TypeNSEC }
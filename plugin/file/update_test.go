@@ -0,0 +1,371 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const dbUpdateOrg = `$ORIGIN example.org.
+@	3600 IN	SOA sns.dns.icann.org. noc.dns.icann.org. 2017042745 7200 3600 1209600 3600
+	3600 IN NS a.iana-servers.net.
+www	3600 IN A 127.0.0.1
+	3600 IN A 127.0.0.2
+alias	3600 IN CNAME www.example.org.
+`
+
+const updateKey = "update.example.org."
+
+// tsigWriter is a test.ResponseWriter with a configurable TSIG status.
+type tsigWriter struct {
+	test.ResponseWriter
+	err error
+}
+
+func (w *tsigWriter) TsigStatus() error { return w.err }
+
+func newUpdateZone(t *testing.T) *Zone {
+	z, err := Parse(strings.NewReader(dbUpdateOrg), "example.org.", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	z.UpdateKeys = map[string]string{updateKey: dns.HmacSHA256}
+	return z
+}
+
+// newUpdate returns a signed update for example.org., that went through a pack and unpack, like a real update does.
+func newUpdate(t *testing.T, prereq, update []dns.RR) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Answer = prereq
+	m.Ns = update
+	m.SetTsig(updateKey, dns.HmacSHA256, 300, time.Now().Unix())
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func serveUpdate(z *Zone, w dns.ResponseWriter, m *dns.Msg) *dns.Msg {
+	rec := dnstest.NewRecorder(w)
+	ctx := context.WithValue(context.TODO(), dnsserver.TsigKey{}, true)
+	z.serveUpdate(ctx, rec, m)
+	return rec.Msg
+}
+
+func lookup(z *Zone, name string, qtype uint16) []dns.RR {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	state := request.Request{W: &test.ResponseWriter{}, Req: m}
+	answer, _, _, _ := z.Lookup(context.TODO(), state, name)
+	return answer
+}
+
+func TestUpdateAuth(t *testing.T) {
+	z := newUpdateZone(t)
+	add := []dns.RR{test.A("new.example.org. 3600 IN A 127.0.0.3")}
+
+	unsigned := newUpdate(t, nil, add)
+	unsigned.Extra = nil
+	if m := serveUpdate(z, &test.ResponseWriter{}, unsigned); m.Rcode != dns.RcodeRefused {
+		t.Errorf("Expected REFUSED for unsigned update, got %s", dns.RcodeToString[m.Rcode])
+	}
+
+	if m := serveUpdate(z, &tsigWriter{err: dns.ErrSig}, newUpdate(t, nil, add)); m.Rcode != dns.RcodeNotAuth {
+		t.Errorf("Expected NOTAUTH for badly signed update, got %s", dns.RcodeToString[m.Rcode])
+	}
+
+	other := newUpdate(t, nil, add)
+	other.Extra[0].Header().Name = "other.example.org."
+	if m := serveUpdate(z, &test.ResponseWriter{}, other); m.Rcode != dns.RcodeRefused {
+		t.Errorf("Expected REFUSED for update signed with other key, got %s", dns.RcodeToString[m.Rcode])
+	}
+
+	z.UpdateKeys = nil
+	if m := serveUpdate(z, &test.ResponseWriter{}, newUpdate(t, nil, add)); m.Rcode != dns.RcodeRefused {
+		t.Errorf("Expected REFUSED for zone without updates, got %s", dns.RcodeToString[m.Rcode])
+	}
+	if len(lookup(z, "new.example.org.", dns.TypeA)) != 0 {
+		t.Errorf("Expected no records to be added")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		prereq   []dns.RR
+		update   []dns.RR
+		rcode    int
+		qname    string
+		qtype    uint16
+		expected []string
+		changed  bool
+	}{
+		// add a record
+		{
+			nil, []dns.RR{test.A("new.example.org. 3600 IN A 127.0.0.3")},
+			dns.RcodeSuccess, "new.example.org.", dns.TypeA, []string{"127.0.0.3"}, true,
+		},
+		// add to an existing RRset and replace the TTL of an existing record
+		{
+			nil, []dns.RR{test.A("www.example.org. 3600 IN A 127.0.0.3"), test.A("www.example.org. 60 IN A 127.0.0.1")},
+			dns.RcodeSuccess, "www.example.org.", dns.TypeA, []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"}, true,
+		},
+		// delete a single record
+		{
+			nil, []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: "www.example.org.", Rrtype: dns.TypeA, Class: dns.ClassNONE}, A: []byte{127, 0, 0, 1}}},
+			dns.RcodeSuccess, "www.example.org.", dns.TypeA, []string{"127.0.0.2"}, true,
+		},
+		// delete an RRset
+		{
+			nil, []dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: "www.example.org.", Rrtype: dns.TypeA, Class: dns.ClassANY}}},
+			dns.RcodeSuccess, "www.example.org.", dns.TypeA, nil, true,
+		},
+		// delete a name
+		{
+			nil, []dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: "alias.example.org.", Rrtype: dns.TypeANY, Class: dns.ClassANY}}},
+			dns.RcodeSuccess, "alias.example.org.", dns.TypeCNAME, nil, true,
+		},
+		// adding an A record to a name with a CNAME is ignored
+		{
+			nil, []dns.RR{test.A("alias.example.org. 3600 IN A 127.0.0.3")},
+			dns.RcodeSuccess, "alias.example.org.", dns.TypeA, []string{"www.example.org.", "127.0.0.1", "127.0.0.2"}, false,
+		},
+		// the last NS record can't be deleted
+		{
+			nil, []dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeNS, Class: dns.ClassANY}}},
+			dns.RcodeSuccess, "example.org.", dns.TypeNS, []string{"a.iana-servers.net."}, false,
+		},
+		// prerequisite: name is in use
+		{
+			[]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: "www.example.org.", Rrtype: dns.TypeANY, Class: dns.ClassANY}}},
+			[]dns.RR{test.A("www.example.org. 3600 IN A 127.0.0.3")},
+			dns.RcodeSuccess, "www.example.org.", dns.TypeA, []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"}, true,
+		},
+		// prerequisite: name is in use, fails
+		{
+			[]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: "new.example.org.", Rrtype: dns.TypeANY, Class: dns.ClassANY}}},
+			[]dns.RR{test.A("new.example.org. 3600 IN A 127.0.0.3")},
+			dns.RcodeNameError, "new.example.org.", dns.TypeA, nil, false,
+		},
+		// prerequisite: RRset does not exist, fails
+		{
+			[]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: "www.example.org.", Rrtype: dns.TypeA, Class: dns.ClassNONE}}},
+			[]dns.RR{test.A("www.example.org. 3600 IN A 127.0.0.3")},
+			dns.RcodeYXRrset, "www.example.org.", dns.TypeA, []string{"127.0.0.1", "127.0.0.2"}, false,
+		},
+		// prerequisite: RRset exists (value dependent)
+		{
+			[]dns.RR{test.A("www.example.org. 0 IN A 127.0.0.2"), test.A("www.example.org. 0 IN A 127.0.0.1")},
+			[]dns.RR{test.A("www.example.org. 3600 IN A 127.0.0.3")},
+			dns.RcodeSuccess, "www.example.org.", dns.TypeA, []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"}, true,
+		},
+		// prerequisite: RRset exists (value dependent), fails
+		{
+			[]dns.RR{test.A("www.example.org. 0 IN A 127.0.0.2")},
+			[]dns.RR{test.A("www.example.org. 3600 IN A 127.0.0.3")},
+			dns.RcodeNXRrset, "www.example.org.", dns.TypeA, []string{"127.0.0.1", "127.0.0.2"}, false,
+		},
+		// prerequisite with a non-zero TTL
+		{
+			[]dns.RR{test.A("www.example.org. 3600 IN A 127.0.0.2")},
+			nil,
+			dns.RcodeFormatError, "www.example.org.", dns.TypeA, []string{"127.0.0.1", "127.0.0.2"}, false,
+		},
+		// update outside of the zone
+		{
+			nil, []dns.RR{test.A("www.example.net. 3600 IN A 127.0.0.3")},
+			dns.RcodeNotZone, "www.example.org.", dns.TypeA, []string{"127.0.0.1", "127.0.0.2"}, false,
+		},
+	}
+
+	for i, tc := range tests {
+		z := newUpdateZone(t)
+		serial := z.SOASerialIfDefined()
+
+		m := serveUpdate(z, &test.ResponseWriter{}, newUpdate(t, tc.prereq, tc.update))
+		if m.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[m.Rcode])
+		}
+		if m.IsTsig() == nil {
+			t.Errorf("Test %d: expected a signed reply", i)
+		}
+
+		var got []string
+		for _, rr := range lookup(z, tc.qname, tc.qtype) {
+			switch x := rr.(type) {
+			case *dns.A:
+				got = append(got, x.A.String())
+			case *dns.CNAME:
+				got = append(got, x.Target)
+			case *dns.NS:
+				got = append(got, x.Ns)
+			}
+		}
+		if strings.Join(got, " ") != strings.Join(tc.expected, " ") {
+			t.Errorf("Test %d: expected %v, got %v", i, tc.expected, got)
+		}
+
+		if tc.changed && z.SOASerialIfDefined() != serial+1 {
+			t.Errorf("Test %d: expected serial %d, got %d", i, serial+1, z.SOASerialIfDefined())
+		}
+		if !tc.changed && z.SOASerialIfDefined() != serial {
+			t.Errorf("Test %d: expected serial %d to be unchanged, got %d", i, serial, z.SOASerialIfDefined())
+		}
+	}
+}
+
+func TestUpdateSOA(t *testing.T) {
+	z := newUpdateZone(t)
+
+	soa := test.SOA("example.org. 3600 IN SOA sns.dns.icann.org. noc.dns.icann.org. 2017050000 7200 3600 1209600 3600")
+	if m := serveUpdate(z, &test.ResponseWriter{}, newUpdate(t, nil, []dns.RR{soa})); m.Rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected NOERROR, got %s", dns.RcodeToString[m.Rcode])
+	}
+	if s := z.SOASerialIfDefined(); s != 2017050000 {
+		t.Errorf("Expected serial %d, got %d", 2017050000, s)
+	}
+
+	// An older serial is ignored.
+	soa = test.SOA("example.org. 3600 IN SOA sns.dns.icann.org. noc.dns.icann.org. 2017040000 7200 3600 1209600 3600")
+	serveUpdate(z, &test.ResponseWriter{}, newUpdate(t, nil, []dns.RR{soa}))
+	if s := z.SOASerialIfDefined(); s != 2017050000 {
+		t.Errorf("Expected serial %d, got %d", 2017050000, s)
+	}
+}
+
+func TestUpdateJournal(t *testing.T) {
+	dir := t.TempDir()
+	z := newUpdateZone(t)
+	z.Journal = filepath.Join(dir, "db.example.org.jnl")
+
+	updates := [][]dns.RR{
+		{test.A("new.example.org. 3600 IN A 127.0.0.3")},
+		{&dns.ANY{Hdr: dns.RR_Header{Name: "www.example.org.", Rrtype: dns.TypeA, Class: dns.ClassANY}}},
+	}
+	for _, u := range updates {
+		if m := serveUpdate(z, &test.ResponseWriter{}, newUpdate(t, nil, u)); m.Rcode != dns.RcodeSuccess {
+			t.Fatalf("Expected NOERROR, got %s", dns.RcodeToString[m.Rcode])
+		}
+	}
+
+	// Load the zone again and replay the journal.
+	z1 := newUpdateZone(t)
+	z1.Journal = z.Journal
+	n, err := z1.ReplayJournal()
+	if err != nil {
+		t.Fatalf("Expected no error replaying the journal, got %s", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 changes to be replayed, got %d", n)
+	}
+	if z1.SOASerialIfDefined() != z.SOASerialIfDefined() {
+		t.Errorf("Expected serial %d, got %d", z.SOASerialIfDefined(), z1.SOASerialIfDefined())
+	}
+	if len(lookup(z1, "new.example.org.", dns.TypeA)) != 1 {
+		t.Errorf("Expected new.example.org. to be added")
+	}
+	if len(lookup(z1, "www.example.org.", dns.TypeA)) != 0 {
+		t.Errorf("Expected www.example.org. to be deleted")
+	}
+
+	// Replaying again does nothing, the serials don't match anymore, and the changes are removed.
+	if n, _ := z1.ReplayJournal(); n != 0 {
+		t.Errorf("Expected no changes to be replayed, got %d", n)
+	}
+	if fi, err := os.Stat(z.Journal); err != nil || fi.Size() != 0 {
+		t.Errorf("Expected an empty journal, got %v", fi)
+	}
+}
+
+func TestUpdateWriteBack(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "db.example.org")
+	if err := os.WriteFile(name, []byte(dbUpdateOrg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	z := newUpdateZone(t)
+	z.SetFile(name)
+	z.WriteBack = true
+	z.Journal = name + ".jnl"
+
+	if m := serveUpdate(z, &test.ResponseWriter{}, newUpdate(t, nil, []dns.RR{test.A("new.example.org. 3600 IN A 127.0.0.3")})); m.Rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected NOERROR, got %s", dns.RcodeToString[m.Rcode])
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	z1, err := Parse(f, "example.org.", name, 0)
+	if err != nil {
+		t.Fatalf("Expected no error parsing the written zone, got %s", err)
+	}
+	if z1.SOASerialIfDefined() != z.SOASerialIfDefined() {
+		t.Errorf("Expected serial %d, got %d", z.SOASerialIfDefined(), z1.SOASerialIfDefined())
+	}
+	if len(lookup(z1, "new.example.org.", dns.TypeA)) != 1 {
+		t.Errorf("Expected new.example.org. in the written zone")
+	}
+	if len(lookup(z1, "www.example.org.", dns.TypeA)) != 2 {
+		t.Errorf("Expected www.example.org. in the written zone")
+	}
+	// The written zone holds the change, so the journal is emptied.
+	if fi, err := os.Stat(z.Journal); err != nil || fi.Size() != 0 {
+		t.Errorf("Expected an empty journal, got %v", fi)
+	}
+}
+
+func TestUpdateReload(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "db.example.org")
+	if err := os.WriteFile(name, []byte(dbUpdateOrg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	z := newUpdateZone(t)
+	z.SetFile(name)
+	z.ReloadInterval = 10 * time.Millisecond
+	z.Reload(nil)
+	defer z.OnShutdown()
+
+	if m := serveUpdate(z, &test.ResponseWriter{}, newUpdate(t, nil, []dns.RR{test.A("new.example.org. 3600 IN A 127.0.0.3")})); m.Rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected NOERROR, got %s", dns.RcodeToString[m.Rcode])
+	}
+	// The file on disk has an older serial than the updated zone, reloading it would drop the update.
+	time.Sleep(30 * time.Millisecond)
+
+	if len(lookup(z, "new.example.org.", dns.TypeA)) != 1 {
+		t.Errorf("Expected new.example.org. to survive a reload")
+	}
+	if z.SOASerialIfDefined() != 2017042746 {
+		t.Errorf("Expected serial %d, got %d", 2017042746, z.SOASerialIfDefined())
+	}
+}
+
+func TestUpdateSharesTree(t *testing.T) {
+	z := newUpdateZone(t)
+	www, _ := z.Tree.Search("www.example.org.")
+
+	if m := serveUpdate(z, &test.ResponseWriter{}, newUpdate(t, nil, []dns.RR{test.A("new.example.org. 3600 IN A 127.0.0.3")})); m.Rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected NOERROR, got %s", dns.RcodeToString[m.Rcode])
+	}
+	// Only the changed name is replaced, the others are shared with the zone before the update.
+	if e, _ := z.Tree.Search("www.example.org."); e != www {
+		t.Errorf("Expected www.example.org. to be shared with the old zone")
+	}
+	if len(lookup(z, "new.example.org.", dns.TypeA)) != 1 {
+		t.Errorf("Expected new.example.org. to be added")
+	}
+}
@@ -16,8 +16,9 @@ func (f File) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
 	return z.Transfer(serial)
 }
 
-// Transfer transfers a zone with serial in the returned channel and implements IXFR fallback, by just
-// sending a single SOA record.
+// Transfer transfers a zone with serial in the returned channel. If serial is the current serial of the zone only
+// its SOA record is sent. If the zone's history holds the changes since serial, an incremental zone transfer
+// (RFC 1995) is sent, otherwise the full zone is.
 func (z *Zone) Transfer(serial uint32) (<-chan []dns.RR, error) {
 	// get soa and apex
 	apex, err := z.ApexIfDefined()
@@ -25,6 +26,14 @@ func (z *Zone) Transfer(serial uint32) (<-chan []dns.RR, error) {
 		return nil, err
 	}
 
+	var changes []change
+	if serial != 0 {
+		changes = z.changesSince(serial)
+		if len(changes) > 0 && changes[len(changes)-1].to.Serial != apex[0].(*dns.SOA).Serial {
+			changes = nil // zone changed in between
+		}
+	}
+
 	ch := make(chan []dns.RR)
 	go func() {
 		if serial != 0 && apex[0].(*dns.SOA).Serial == serial { // ixfr fallback, only send SOA
@@ -34,6 +43,18 @@ func (z *Zone) Transfer(serial uint32) (<-chan []dns.RR, error) {
 			return
 		}
 
+		if changes != nil {
+			ch <- []dns.RR{apex[0]}
+			for _, c := range changes {
+				ch <- append([]dns.RR{c.from}, c.del...)
+				ch <- append([]dns.RR{c.to}, c.add...)
+			}
+			ch <- []dns.RR{apex[0]}
+
+			close(ch)
+			return
+		}
+
 		ch <- apex
 		z.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error { ch <- e.All(); return nil })
 		ch <- []dns.RR{apex[0]}
@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/plugin/file/tree"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/transfer"
 
 	"github.com/miekg/dns"
 )
@@ -26,11 +28,24 @@ type Zone struct {
 
 	StartupOnce  sync.Once
 	TransferFrom []string
+	TransferKeys map[string]tsig.Key // TSIG keys to sign transfers from, and verify notifies of, the primaries in TransferFrom.
 
 	ReloadInterval time.Duration
 	reloadShutdown chan bool
 
 	Upstream *upstream.Upstream // Upstream for looking up external names during the resolution process.
+
+	// Dynamic updates, see update.go.
+	UpdateKeys map[string]string  // TSIG key names that are allowed to update the zone, mapped to their algorithm.
+	Journal    string             // If not empty, the file to which applied updates are appended.
+	WriteBack  bool               // Write the zone back to its file after each update.
+	updateMu   sync.Mutex         // Serializes updates.
+	transfer   *transfer.Transfer // Used to send notifies after an update.
+
+	// Incremental zone transfers, see ixfr.go.
+	IXFRHistory int            // Number of changes to keep; zero disables incremental transfers.
+	changes     []change       // Changes to the zone, oldest first.
+	since       map[uint32]int // Index in changes of the change from each SOA serial.
 }
 
 // Apex contains the apex records of a zone: SOA, NS and their potential signatures.
@@ -49,6 +64,7 @@ func NewZone(name, file string) *Zone {
 		file:           filepath.Clean(file),
 		Tree:           &tree.Tree{},
 		reloadShutdown: make(chan bool),
+		IXFRHistory:    DefaultIXFRHistory,
 	}
 }
 
@@ -56,6 +72,7 @@ func NewZone(name, file string) *Zone {
 func (z *Zone) Copy() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferFrom = z.TransferFrom
+	z1.TransferKeys = z.TransferKeys
 	z1.Expired = z.Expired
 
 	z1.Apex = z.Apex
@@ -66,6 +83,7 @@ func (z *Zone) Copy() *Zone {
 func (z *Zone) CopyWithoutApex() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferFrom = z.TransferFrom
+	z1.TransferKeys = z.TransferKeys
 	z1.Expired = z.Expired
 
 	return z1
@@ -75,6 +75,10 @@ func (t *Transport) Dial(proto string) (*persistConn, bool, error) {
 func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options) (*dns.Msg, error) {
 	start := time.Now()
 
+	if p.exchanger != nil {
+		return p.exchange(ctx, state, start)
+	}
+
 	proto := ""
 	switch {
 	case opts.forceTCP: // TCP flag has precedence over UDP flag
@@ -124,6 +128,26 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options
 
 	p.transport.Yield(pc)
 
+	p.report(ret, start)
+	return ret, nil
+}
+
+// exchange sends the request to a DNS-over-HTTPS or DNS-over-QUIC upstream.
+func (p *Proxy) exchange(ctx context.Context, state request.Request, start time.Time) (*dns.Msg, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	ret, err := p.exchanger.Exchange(ctx, state.Req)
+	if err != nil {
+		return nil, err
+	}
+
+	p.report(ret, start)
+	return ret, nil
+}
+
+// report updates the per upstream metrics for the reply ret.
+func (p *Proxy) report(ret *dns.Msg, start time.Time) {
 	rc, ok := dns.RcodeToString[ret.Rcode]
 	if !ok {
 		rc = strconv.Itoa(ret.Rcode)
@@ -132,8 +156,6 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts options
 	RequestCount.WithLabelValues(p.addr).Add(1)
 	RcodeCount.WithLabelValues(rc, p.addr).Add(1)
 	RequestDuration.WithLabelValues(p.addr, rc).Observe(time.Since(start).Seconds())
-
-	return ret, nil
 }
 
 const cumulativeAvgWeight = 4
@@ -0,0 +1,104 @@
+package forward
+
+import (
+	"context"
+
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/pkg/validator"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// setDNSSEC sets the DO and CD bits in m, so the upstream returns the DNSSEC records and leaves the validation to
+// us.
+func setDNSSEC(m *dns.Msg) {
+	m.CheckingDisabled = true
+	if o := m.IsEdns0(); o != nil {
+		o.SetDo()
+		if o.UDPSize() < defaultUDPBufSize {
+			o.SetUDPSize(defaultUDPBufSize)
+		}
+		return
+	}
+	m.SetEdns0(defaultUDPBufSize, true)
+}
+
+// validate validates ret, the upstream's response to the request in state. A bogus response is replaced by a
+// SERVFAIL with an Extended DNS Error. The AD bit is set on secure responses, and the DNSSEC records are removed
+// when the client didn't ask for them.
+func (f *Forward) validate(ctx context.Context, state request.Request, ret *dns.Msg) *dns.Msg {
+	secure := false
+	if !state.Req.CheckingDisabled {
+		res, err := f.validator.Validate(ctx, &resolver{f: f, w: state.W}, ret)
+		DNSSECResultCount.WithLabelValues(res.String()).Add(1)
+		switch res {
+		case validator.Bogus:
+			log.Debugf("Failed to validate %s %s: %s", state.Name(), state.Type(), err)
+			m := new(dns.Msg)
+			m.SetRcode(state.Req, dns.RcodeServerFailure)
+			if state.Req.IsEdns0() != nil {
+				code := validator.InfoCode(err)
+				edns.SetExtendedError(m, code, dns.ExtendedErrorCodeToString[code])
+				state.SizeAndDo(m)
+			}
+			return m
+		case validator.Secure:
+			secure = true
+		}
+	}
+
+	ret.CheckingDisabled = state.Req.CheckingDisabled
+	ret.AuthenticatedData = secure && (state.Do() || state.Req.AuthenticatedData)
+	if !state.Do() {
+		ret.Answer = validator.Filter(ret.Answer, state.QType())
+		ret.Ns = validator.Filter(ret.Ns, state.QType())
+		ret.Extra = validator.Filter(ret.Extra, state.QType())
+	}
+	if state.Req.IsEdns0() == nil {
+		// The OPT record was added by us.
+		ret.Extra = edns.RemoveOPT(ret.Extra)
+	}
+	state.SizeAndDo(ret)
+	return state.Scrub(ret)
+}
+
+// resolver looks up the records needed for validation with the upstreams of f.
+type resolver struct {
+	f *Forward
+	w dns.ResponseWriter
+}
+
+// Lookup implements the validator.Resolver interface.
+func (r *resolver) Lookup(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.CheckingDisabled = true
+	m.SetEdns0(defaultUDPBufSize, true)
+	state := request.Request{W: r.w, Req: m}
+
+	err := ErrNoHealthy
+	for _, proxy := range r.f.List() {
+		if proxy.Down(r.f.maxfails) {
+			continue
+		}
+		opts := r.f.opts
+		ret, e := proxy.Connect(ctx, state, opts)
+		if e == nil && ret.Truncated && !opts.forceTCP {
+			opts.forceTCP = true
+			ret, e = proxy.Connect(ctx, state, opts)
+		}
+		if e != nil {
+			err = e
+			continue
+		}
+		if !state.Match(ret) {
+			continue
+		}
+		return ret, nil
+	}
+	return nil, err
+}
+
+// defaultUDPBufSize is the buffer size used in the queries sent for validation.
+const defaultUDPBufSize = 2048
@@ -0,0 +1,196 @@
+package forward
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/validator"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// newSignedUpstream returns a server that answers for a signed root, org. and example.org. zone, and a bogus.org.
+// zone that is signed with a key that doesn't match its DS record. The trust anchor for the root is returned too.
+func newSignedUpstream(t *testing.T) (*dnstest.Server, dns.RR) {
+	kRoot, kOrg, kExample, kBogus := test.NewKey("."), test.NewKey("org."), test.NewKey("example.org."), test.NewKey("bogus.org.")
+
+	zones := map[string][]dns.RR{
+		".": test.Sign(".", []dns.RR{
+			test.SOA(". 3600 IN SOA a.root-servers.test. hostmaster.root-servers.test. 1 3600 600 86400 300"),
+			test.NS(". 3600 IN NS a.root-servers.test."),
+			test.NS("org. 3600 IN NS ns.org."),
+			test.A("ns.org. 3600 IN A 127.0.0.2"),
+			kOrg.DS(),
+		}, kRoot),
+		"org.": test.Sign("org.", []dns.RR{
+			test.SOA("org. 3600 IN SOA ns.org. hostmaster.org. 1 3600 600 86400 300"),
+			test.NS("org. 3600 IN NS ns.org."),
+			test.A("ns.org. 3600 IN A 127.0.0.2"),
+			test.NS("example.org. 3600 IN NS ns.example.org."),
+			test.A("ns.example.org. 3600 IN A 127.0.0.3"),
+			kExample.DS(),
+			test.NS("bogus.org. 3600 IN NS ns.bogus.org."),
+			test.A("ns.bogus.org. 3600 IN A 127.0.0.4"),
+			test.NewKey("bogus.org.").DS(),
+		}, kOrg),
+		"example.org.": test.Sign("example.org.", []dns.RR{
+			test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300"),
+			test.NS("example.org. 3600 IN NS ns.example.org."),
+			test.A("ns.example.org. 3600 IN A 127.0.0.3"),
+			test.A("www.example.org. 3600 IN A 192.0.2.1"),
+		}, kExample),
+		"bogus.org.": test.Sign("bogus.org.", []dns.RR{
+			test.SOA("bogus.org. 3600 IN SOA ns.bogus.org. hostmaster.bogus.org. 1 3600 600 86400 300"),
+			test.NS("bogus.org. 3600 IN NS ns.bogus.org."),
+			test.A("ns.bogus.org. 3600 IN A 127.0.0.4"),
+			test.A("www.bogus.org. 3600 IN A 192.0.2.2"),
+		}, kBogus),
+	}
+
+	parsed := map[string]*file.Zone{}
+	for origin, rrs := range zones {
+		var sb strings.Builder
+		for _, rr := range rrs {
+			sb.WriteString(rr.String() + "\n")
+		}
+		z, err := file.Parse(strings.NewReader(sb.String()), origin, "stdin", 0)
+		if err != nil {
+			t.Fatalf("Failed to parse zone %s: %s", origin, err)
+		}
+		parsed[origin] = z
+	}
+
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		// Act as a recursive resolver and answer from the zone that holds the name, or for DS from its parent.
+		name, qtype := strings.ToLower(r.Question[0].Name), r.Question[0].Qtype
+		zone := ""
+		for origin := range parsed {
+			if !dns.IsSubDomain(origin, name) || len(origin) <= len(zone) {
+				continue
+			}
+			if qtype == dns.TypeDS && origin == name && name != "." {
+				continue
+			}
+			zone = origin
+		}
+		f := file.File{Zones: file.Zones{Z: map[string]*file.Zone{zone: parsed[zone]}, Names: []string{zone}}}
+		f.ServeDNS(context.TODO(), w, r)
+	})
+	return s, kRoot.DS()
+}
+
+func TestForwardValidate(t *testing.T) {
+	s, anchor := newSignedUpstream(t)
+	defer s.Close()
+
+	c := caddy.NewTestController("dns", "forward . "+s.Addr)
+	f, err := parseForward(c)
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %s", err)
+	}
+	f.validator = validator.New([]dns.RR{anchor})
+	f.OnStartup()
+	defer f.OnShutdown()
+
+	tests := []struct {
+		qname     string
+		do, ad    bool
+		cd        bool
+		edns      bool
+		rcode     int
+		expectAD  bool
+		expectSig bool
+		expectEDE bool
+	}{
+		{qname: "www.example.org.", edns: true, do: true, expectAD: true, expectSig: true},
+		{qname: "www.example.org.", edns: true},
+		{qname: "www.example.org.", ad: true, expectAD: true},
+		{qname: "www.example.org."},
+		{qname: "www.bogus.org.", edns: true, rcode: dns.RcodeServerFailure, expectEDE: true},
+		{qname: "www.bogus.org.", rcode: dns.RcodeServerFailure},
+		{qname: "www.bogus.org.", edns: true, do: true, cd: true, expectSig: true},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		m.AuthenticatedData = tc.ad
+		m.CheckingDisabled = tc.cd
+		if tc.edns {
+			m.SetEdns0(4096, tc.do)
+		}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := f.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rec.Msg.Rcode])
+		}
+		if rec.Msg.AuthenticatedData != tc.expectAD {
+			t.Errorf("Test %d: expected AD bit %t, got %t", i, tc.expectAD, rec.Msg.AuthenticatedData)
+		}
+		if rec.Msg.CheckingDisabled != tc.cd {
+			t.Errorf("Test %d: expected CD bit %t, got %t", i, tc.cd, rec.Msg.CheckingDisabled)
+		}
+		sig := false
+		for _, rr := range rec.Msg.Answer {
+			if rr.Header().Rrtype == dns.TypeRRSIG {
+				sig = true
+			}
+		}
+		if sig != tc.expectSig {
+			t.Errorf("Test %d: expected signatures %t, got %t", i, tc.expectSig, sig)
+		}
+		o := rec.Msg.IsEdns0()
+		if (o != nil) != tc.edns {
+			t.Errorf("Test %d: expected OPT record %t, got %t", i, tc.edns, o != nil)
+			continue
+		}
+		if o != nil && o.Do() != tc.do {
+			t.Errorf("Test %d: expected DO bit %t, got %t", i, tc.do, o.Do())
+		}
+		ede := false
+		if o != nil {
+			for _, opt := range o.Option {
+				if e, ok := opt.(*dns.EDNS0_EDE); ok && e.InfoCode == dns.ExtendedErrorCodeDNSKEYMissing {
+					ede = true
+				}
+			}
+		}
+		if ede != tc.expectEDE {
+			t.Errorf("Test %d: expected extended error %t, got %t", i, tc.expectEDE, ede)
+		}
+	}
+}
+
+func TestForwardNegativeTrustAnchor(t *testing.T) {
+	s, anchor := newSignedUpstream(t)
+	defer s.Close()
+
+	c := caddy.NewTestController("dns", "forward . "+s.Addr)
+	f, err := parseForward(c)
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %s", err)
+	}
+	f.validator = validator.New([]dns.RR{anchor})
+	f.validator.SetNegativeAnchors("bogus.org.")
+	f.OnStartup()
+	defer f.OnShutdown()
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.bogus.org.", dns.TypeA)
+	m.SetEdns0(4096, true)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := f.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if rec.Msg.Rcode != dns.RcodeSuccess || rec.Msg.AuthenticatedData {
+		t.Errorf("Expected an insecure NOERROR response, got %s with AD bit %t", dns.RcodeToString[rec.Msg.Rcode], rec.Msg.AuthenticatedData)
+	}
+}
@@ -2,10 +2,12 @@ package forward
 
 import (
 	"net"
+	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/coredns/coredns/plugin/dnstap/msg"
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/request"
 
 	tap "github.com/dnstap/golang-dnstap"
@@ -13,10 +15,15 @@ import (
 )
 
 // toDnstap will send the forward and received message to the dnstap plugin.
-func toDnstap(f *Forward, host string, state request.Request, opts options, reply *dns.Msg, start time.Time) {
+func toDnstap(f *Forward, proxy *Proxy, state request.Request, opts options, reply *dns.Msg, start time.Time) {
 	// Query
 	q := new(tap.Message)
 	msg.SetQueryTime(q, start)
+	host := proxy.addr
+	if proxy.trans == transport.HTTPS {
+		u, _ := url.Parse(host) // this is preparsed and can't err here
+		host = u.Host
+	}
 	h, p, _ := net.SplitHostPort(host)      // this is preparsed and can't err here
 	port, _ := strconv.ParseUint(p, 10, 32) // same here
 	ip := net.ParseIP(h)
@@ -24,6 +31,10 @@ func toDnstap(f *Forward, host string, state request.Request, opts options, repl
 	var ta net.Addr = &net.UDPAddr{IP: ip, Port: int(port)}
 	t := state.Proto()
 	switch {
+	case proxy.trans == transport.HTTPS:
+		t = "tcp"
+	case proxy.trans == transport.QUIC:
+		t = "udp"
 	case opts.forceTCP:
 		t = "tcp"
 	case opts.preferUDP:
@@ -0,0 +1,74 @@
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/doh"
+
+	"github.com/miekg/dns"
+)
+
+// dohTransport sends queries to a DNS-over-HTTPS (RFC 8484) upstream. Connections are pooled
+// by the HTTP transport and multiplexed when the upstream speaks HTTP/2.
+type dohTransport struct {
+	url       string
+	transport *http.Transport
+	client    *http.Client
+}
+
+func newDoHTransport(url string) *dohTransport {
+	tr := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConnsPerHost: 8,
+		IdleConnTimeout:     defaultExpire,
+		TLSHandshakeTimeout: maxTimeout,
+		TLSClientConfig:     &tls.Config{NextProtos: []string{"h2", "http/1.1"}},
+	}
+	return &dohTransport{url: url, transport: tr, client: &http.Client{Transport: tr}}
+}
+
+// Exchange sends m as a POST request to the upstream and returns the reply. The message ID is set to 0
+// on the wire, as recommended by RFC 8484, section 4.1, and restored in the reply.
+func (t *dohTransport) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	q := m.Copy()
+	q.Id = 0
+
+	req, err := doh.NewRequestURL(http.MethodPost, t.url, q)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected HTTP status from %s: %d", t.url, resp.StatusCode)
+	}
+
+	ret, err := doh.ResponseToMsg(resp)
+	if err != nil {
+		return nil, err
+	}
+	ret.Id = m.Id
+	return ret, nil
+}
+
+// SetTLSConfig sets the TLS config used for the HTTPS connections.
+func (t *dohTransport) SetTLSConfig(cfg *tls.Config) {
+	cfg = cfg.Clone()
+	cfg.NextProtos = []string{"h2", "http/1.1"}
+	t.transport.TLSClientConfig = cfg
+}
+
+// SetExpire sets the time after which idle connections are closed.
+func (t *dohTransport) SetExpire(expire time.Duration) { t.transport.IdleConnTimeout = expire }
+
+// Stop closes all idle connections.
+func (t *dohTransport) Stop() { t.transport.CloseIdleConnections() }
@@ -0,0 +1,106 @@
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/doh"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// newDoHServer returns a HTTP/2 capable DoH server that answers every query with an A record.
+func newDoHServer(h2 *uint32) *httptest.Server {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != doh.Path {
+			http.Error(w, "", http.StatusNotFound)
+			return
+		}
+		if r.ProtoMajor == 2 {
+			atomic.AddUint32(h2, 1)
+		}
+		m, err := doh.RequestToMsg(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if m.Id != 0 {
+			http.Error(w, "non zero ID", http.StatusBadRequest)
+			return
+		}
+		ret := new(dns.Msg)
+		ret.SetReply(m)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		buf, _ := ret.Pack()
+		w.Header().Set("Content-Type", doh.MimeType)
+		w.Write(buf)
+	}))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	return s
+}
+
+func TestDoHProxy(t *testing.T) {
+	var h2 uint32
+	s := newDoHServer(&h2)
+	defer s.Close()
+
+	u, err := parseDoHURL(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewProxy(u, transport.HTTPS)
+	p.SetTLSConfig(&tls.Config{RootCAs: s.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs})
+	f := New()
+	f.SetProxy(p)
+	defer f.OnShutdown()
+
+	for i := 0; i < 3; i++ {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		if _, err := f.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Expected to receive reply, but got: %s", err)
+		}
+		if rec.Msg.Id != m.Id {
+			t.Errorf("Expected ID %d, got %d", m.Id, rec.Msg.Id)
+		}
+		if x := rec.Msg.Answer[0].Header().Name; x != "example.org." {
+			t.Errorf("Expected %s, got %s", "example.org.", x)
+		}
+	}
+	if x := atomic.LoadUint32(&h2); x != 3 {
+		t.Errorf("Expected 3 HTTP/2 requests, got %d", x)
+	}
+}
+
+func TestDoHProxyHealthcheck(t *testing.T) {
+	var h2 uint32
+	s := newDoHServer(&h2)
+	u, _ := parseDoHURL(s.URL)
+
+	p := NewProxy(u, transport.HTTPS)
+	p.SetTLSConfig(&tls.Config{RootCAs: s.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs})
+
+	if err := p.health.Check(p); err != nil {
+		t.Errorf("Expected healthy upstream, got: %s", err)
+	}
+
+	s.Close()
+	p.exchanger.Stop()
+	if err := p.health.Check(p); err == nil {
+		t.Errorf("Expected unhealthy upstream")
+	}
+	if x := atomic.LoadUint32(&p.fails); x != 1 {
+		t.Errorf("Expected 1 failure, got %d", x)
+	}
+}
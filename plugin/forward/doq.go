@@ -0,0 +1,179 @@
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// doqTransport sends queries to a DNS-over-QUIC (RFC 9250) upstream. A single QUIC connection is kept
+// open and every query is sent on its own stream.
+type doqTransport struct {
+	addr       string
+	tlsConfig  *tls.Config
+	quicConfig *quic.Config
+
+	mu   sync.Mutex
+	conn quic.Connection
+}
+
+func newDoQTransport(addr string) *doqTransport {
+	return &doqTransport{
+		addr:       addr,
+		tlsConfig:  &tls.Config{NextProtos: []string{"doq"}},
+		quicConfig: &quic.Config{MaxIdleTimeout: defaultExpire},
+	}
+}
+
+// cached returns the cached connection if it is still usable, or nil.
+func (t *doqTransport) cached() quic.Connection {
+	if t.conn != nil && t.conn.Context().Err() == nil {
+		return t.conn
+	}
+	return nil
+}
+
+// dial returns the cached connection if it is still usable, otherwise it creates a new one. The
+// returned bool is true when the connection came from the cache. The lock isn't held while dialing;
+// if another query cached a connection in the meantime, that one is used and the new one closed.
+func (t *doqTransport) dial(ctx context.Context) (quic.Connection, bool, error) {
+	t.mu.Lock()
+	conn := t.cached()
+	t.mu.Unlock()
+	if conn != nil {
+		ConnCacheHitsCount.WithLabelValues(t.addr, "quic").Add(1)
+		return conn, true, nil
+	}
+	ConnCacheMissesCount.WithLabelValues(t.addr, "quic").Add(1)
+
+	conn, err := quic.DialAddr(ctx, t.addr, t.tlsConfig, t.quicConfig)
+	if err != nil {
+		return nil, false, err
+	}
+
+	t.mu.Lock()
+	if other := t.cached(); other != nil {
+		t.mu.Unlock()
+		conn.CloseWithError(0, "")
+		return other, true, nil
+	}
+	t.conn = conn
+	t.mu.Unlock()
+	return conn, false, nil
+}
+
+// drop removes conn from the cache and closes it.
+func (t *doqTransport) drop(conn quic.Connection) {
+	t.mu.Lock()
+	if t.conn == conn {
+		t.conn = nil
+	}
+	t.mu.Unlock()
+	conn.CloseWithError(0, "")
+}
+
+// connError returns true if err, returned by a stream of conn, means conn itself is no longer usable.
+func connError(conn quic.Connection, err error) bool {
+	if conn.Context().Err() != nil {
+		return true
+	}
+	var (
+		te *quic.TransportError
+		ae *quic.ApplicationError
+		ie *quic.IdleTimeoutError
+		se *quic.StatelessResetError
+	)
+	return errors.As(err, &te) || errors.As(err, &ae) || errors.As(err, &ie) || errors.As(err, &se)
+}
+
+// Exchange sends m on a new stream and returns the reply. The message ID is set to 0 on the wire, as
+// required by RFC 9250, section 4.2.1, and restored in the reply.
+func (t *doqTransport) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	conn, cached, err := t.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		t.drop(conn)
+		if cached {
+			return nil, ErrCachedClosed
+		}
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetDeadline(deadline)
+	}
+
+	q := m.Copy()
+	q.Id = 0
+	buf, err := q.Pack()
+	if err != nil {
+		stream.CancelRead(0)
+		stream.Close()
+		return nil, err
+	}
+
+	b := make([]byte, 2+len(buf))
+	binary.BigEndian.PutUint16(b, uint16(len(buf)))
+	copy(b[2:], buf)
+	if _, err := stream.Write(b); err != nil {
+		stream.CancelRead(0)
+		if connError(conn, err) {
+			t.drop(conn)
+		}
+		return nil, err
+	}
+	// Signal there are no more queries on this stream.
+	stream.Close()
+
+	l := make([]byte, 2)
+	if _, err := io.ReadFull(stream, l); err != nil {
+		if connError(conn, err) {
+			t.drop(conn)
+		}
+		return nil, err
+	}
+	buf = make([]byte, binary.BigEndian.Uint16(l))
+	if _, err := io.ReadFull(stream, buf); err != nil {
+		if connError(conn, err) {
+			t.drop(conn)
+		}
+		return nil, err
+	}
+
+	ret := new(dns.Msg)
+	if err := ret.Unpack(buf); err != nil {
+		return nil, err
+	}
+	ret.Id = m.Id
+	return ret, nil
+}
+
+// SetTLSConfig sets the TLS config used for the QUIC connection.
+func (t *doqTransport) SetTLSConfig(cfg *tls.Config) {
+	cfg = cfg.Clone()
+	cfg.NextProtos = []string{"doq"}
+	t.tlsConfig = cfg
+}
+
+// SetExpire sets the time after which an idle connection is closed.
+func (t *doqTransport) SetExpire(expire time.Duration) { t.quicConfig.MaxIdleTimeout = expire }
+
+// Stop closes the cached connection.
+func (t *doqTransport) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn != nil {
+		t.conn.CloseWithError(0, "")
+		t.conn = nil
+	}
+}
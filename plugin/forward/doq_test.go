@@ -0,0 +1,166 @@
+package forward
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// newDoQServer returns a DoQ listener that answers every query with an A record, it borrows
+// the certificate of a httptest TLS server. The returned tls.Config can be used by clients.
+func newDoQServer(t *testing.T) (*quic.Listener, *tls.Config) {
+	hs := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(hs.Close)
+
+	serverConfig := &tls.Config{Certificates: hs.TLS.Certificates, NextProtos: []string{"doq"}}
+	ln, err := quic.ListenAddr("127.0.0.1:0", serverConfig, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					stream, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go serveDoQStream(stream)
+				}
+			}()
+		}
+	}()
+
+	clientConfig := &tls.Config{RootCAs: hs.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+	return ln, clientConfig
+}
+
+func serveDoQStream(stream quic.Stream) {
+	defer stream.Close()
+	l := make([]byte, 2)
+	if _, err := io.ReadFull(stream, l); err != nil {
+		return
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(l))
+	if _, err := io.ReadFull(stream, buf); err != nil {
+		return
+	}
+	m := new(dns.Msg)
+	if err := m.Unpack(buf); err != nil || m.Id != 0 {
+		return
+	}
+	ret := new(dns.Msg)
+	ret.SetReply(m)
+	ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+	buf, _ = ret.Pack()
+	b := make([]byte, 2+len(buf))
+	binary.BigEndian.PutUint16(b, uint16(len(buf)))
+	copy(b[2:], buf)
+	stream.Write(b)
+}
+
+func TestDoQProxy(t *testing.T) {
+	ln, cfg := newDoQServer(t)
+
+	p := NewProxy(ln.Addr().String(), transport.QUIC)
+	p.SetTLSConfig(cfg)
+	f := New()
+	f.SetProxy(p)
+	defer f.OnShutdown()
+
+	for i := 0; i < 3; i++ {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		if _, err := f.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Expected to receive reply, but got: %s", err)
+		}
+		if rec.Msg.Id != m.Id {
+			t.Errorf("Expected ID %d, got %d", m.Id, rec.Msg.Id)
+		}
+		if x := rec.Msg.Answer[0].Header().Name; x != "example.org." {
+			t.Errorf("Expected %s, got %s", "example.org.", x)
+		}
+	}
+
+	if err := p.health.Check(p); err != nil {
+		t.Errorf("Expected healthy upstream, got: %s", err)
+	}
+}
+
+func TestDoQProxyConnectionReuse(t *testing.T) {
+	ln, cfg := newDoQServer(t)
+
+	tr := newDoQTransport(ln.Addr().String())
+	tr.SetTLSConfig(cfg)
+	defer tr.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if _, err := tr.Exchange(context.TODO(), m); err != nil {
+		t.Fatal(err)
+	}
+	first := tr.conn
+	if _, err := tr.Exchange(context.TODO(), m); err != nil {
+		t.Fatal(err)
+	}
+	if tr.conn != first {
+		t.Errorf("Expected the QUIC connection to be reused")
+	}
+}
+
+func TestDoQProxyConnectionClosed(t *testing.T) {
+	hs := httptest.NewTLSServer(http.NotFoundHandler())
+	defer hs.Close()
+
+	// This server closes the connection as soon as a query arrives.
+	ln, err := quic.ListenAddr("127.0.0.1:0", &tls.Config{Certificates: hs.TLS.Certificates, NextProtos: []string{"doq"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			if _, err := conn.AcceptStream(context.Background()); err == nil {
+				conn.CloseWithError(1, "")
+			}
+		}
+	}()
+
+	tr := newDoQTransport(ln.Addr().String())
+	tr.SetTLSConfig(&tls.Config{RootCAs: hs.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs})
+	defer tr.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if _, err := tr.Exchange(context.TODO(), m); err == nil {
+		t.Fatal("Expected an error from a closed connection")
+	}
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if tr.conn != nil {
+		t.Errorf("Expected the closed QUIC connection to be dropped from the cache")
+	}
+}
@@ -0,0 +1,105 @@
+package forward
+
+import (
+	"net"
+
+	"github.com/coredns/coredns/plugin/pkg/edns"
+
+	"github.com/miekg/dns"
+)
+
+// subnet holds the source prefix lengths of the EDNS Client Subnet option, see RFC 7871, that is added to the
+// queries sent upstream.
+type subnet struct {
+	v4, v6 uint8
+}
+
+// set adds an EDNS Client Subnet option for the client's address ip to m. When m already has one, its source
+// prefix is shortened to the configured length.
+func (s *subnet) set(m *dns.Msg, ip net.IP) {
+	if e := edns.Subnet(m); e != nil {
+		if e.SourceNetmask == 0 {
+			// The client doesn't want its subnet to be used.
+			return
+		}
+		switch e.Family {
+		case 1:
+			if e.SourceNetmask > s.v4 {
+				e.SourceNetmask = s.v4
+			}
+			e.Address = e.Address.Mask(net.CIDRMask(int(e.SourceNetmask), net.IPv4len*8))
+		case 2:
+			if e.SourceNetmask > s.v6 {
+				e.SourceNetmask = s.v6
+			}
+			e.Address = e.Address.Mask(net.CIDRMask(int(e.SourceNetmask), net.IPv6len*8))
+		}
+		e.SourceScope = 0
+		return
+	}
+	if ip == nil {
+		return
+	}
+
+	e := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: s.v4}
+	if ip4 := ip.To4(); ip4 != nil {
+		e.Address = ip4.Mask(net.CIDRMask(int(s.v4), net.IPv4len*8))
+	} else {
+		e.Family = 2
+		e.SourceNetmask = s.v6
+		e.Address = ip.Mask(net.CIDRMask(int(s.v6), net.IPv6len*8))
+	}
+	if m.IsEdns0() == nil {
+		m.SetEdns0(defaultUDPBufSize, false)
+	}
+	edns.SetSubnet(m, e)
+}
+
+// match returns true when the EDNS Client Subnet option in ret, the reply to m, has the same family, source prefix
+// length and address as the one in m, or when ret has none.
+func (s *subnet) match(m, ret *dns.Msg) bool {
+	upstream := edns.Subnet(ret)
+	if upstream == nil {
+		return true
+	}
+	e := edns.Subnet(m)
+	if e == nil {
+		return false
+	}
+	if upstream.Family != e.Family || upstream.SourceNetmask != e.SourceNetmask {
+		return false
+	}
+	bits := net.IPv4len * 8
+	if e.Family == 2 {
+		bits = net.IPv6len * 8
+	}
+	mask := net.CIDRMask(int(e.SourceNetmask), bits)
+	return upstream.Address.Mask(mask).Equal(e.Address.Mask(mask))
+}
+
+// reply sets the EDNS Client Subnet option in ret, the reply to a query with the option upstream, which is the
+// option returned by the upstream. When the client in r sent the option itself, it gets its own option back with
+// the upstream's scope. Otherwise the upstream's option is kept, so the cache plugin can use the scope; it's
+// removed before the reply is written to the client.
+func (s *subnet) reply(r, ret *dns.Msg, upstream *dns.EDNS0_SUBNET) {
+	if upstream == nil {
+		return
+	}
+	e := edns.Subnet(r)
+	if e == nil {
+		edns.SetSubnet(ret, upstream)
+		return
+	}
+	edns.SetSubnet(ret, &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        e.Family,
+		SourceNetmask: e.SourceNetmask,
+		SourceScope:   upstream.SourceScope,
+		Address:       e.Address,
+	})
+}
+
+const (
+	defaultSubnetV4 = 24
+	defaultSubnetV6 = 56
+)
@@ -0,0 +1,142 @@
+package forward
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestForwardECS(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		// Return the client subnet that was sent as a TXT record, and echo it with a scope of 16.
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		if e := edns.Subnet(r); e != nil {
+			ret.Answer = append(ret.Answer, test.TXT("example.org. 3600 IN TXT \""+e.String()+"\""))
+			ret.SetEdns0(4096, false)
+			edns.SetSubnet(ret, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: e.Family, SourceNetmask: e.SourceNetmask, SourceScope: 16, Address: e.Address})
+		}
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	c := caddy.NewTestController("dns", "forward . "+s.Addr+" {\necs\n}\n")
+	f, err := parseForward(c)
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %s", err)
+	}
+	f.OnStartup()
+	defer f.OnShutdown()
+
+	tests := []struct {
+		w            dns.ResponseWriter
+		subnet       *dns.EDNS0_SUBNET // sent by the client
+		expectSent   string
+		expectSubnet string
+	}{
+		{w: &test.ResponseWriter{}, expectSent: "10.240.0.0/24/0", expectSubnet: "10.240.0.0/24/16"},
+		{w: &test.ResponseWriter6{}, expectSent: "[fe80::]/56/0", expectSubnet: "[fe80::]/56/16"},
+		{
+			w:            &test.ResponseWriter{},
+			subnet:       &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 32, Address: net.ParseIP("192.0.2.77")},
+			expectSent:   "192.0.2.0/24/0",
+			expectSubnet: "192.0.2.77/32/16",
+		},
+		{
+			w:            &test.ResponseWriter{},
+			subnet:       &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 20, Address: net.ParseIP("192.0.0.0")},
+			expectSent:   "192.0.0.0/20/0",
+			expectSubnet: "192.0.0.0/20/16",
+		},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeTXT)
+		if tc.subnet != nil {
+			m.SetEdns0(4096, false)
+			edns.SetSubnet(m, tc.subnet)
+		}
+		rec := dnstest.NewRecorder(tc.w)
+		if _, err := f.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+
+		if len(rec.Msg.Answer) != 1 {
+			t.Fatalf("Test %d: expected 1 answer, got %d", i, len(rec.Msg.Answer))
+		}
+		if sent := rec.Msg.Answer[0].(*dns.TXT).Txt[0]; sent != tc.expectSent {
+			t.Errorf("Test %d: expected client subnet %s to be sent, got %s", i, tc.expectSent, sent)
+		}
+		if e := edns.Subnet(rec.Msg); e == nil || e.String() != tc.expectSubnet {
+			t.Errorf("Test %d: expected client subnet %s in the reply, got %v", i, tc.expectSubnet, e)
+		}
+	}
+}
+
+func TestForwardECSMismatch(t *testing.T) {
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		// Reply with a client subnet for another address.
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, test.A("example.org. 3600 IN A 127.0.0.1"))
+		ret.SetEdns0(4096, false)
+		edns.SetSubnet(ret, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 24, Address: net.ParseIP("192.0.2.0")})
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	c := caddy.NewTestController("dns", "forward . "+s.Addr+" {\necs\n}\n")
+	f, err := parseForward(c)
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %s", err)
+	}
+	f.OnStartup()
+	defer f.OnShutdown()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	rcode, err := f.ServeDNS(context.TODO(), rec, m)
+	if rcode != dns.RcodeServerFailure || err == nil {
+		t.Errorf("Expected SERVFAIL and an error for a mismatched client subnet, got %s and %v", dns.RcodeToString[rcode], err)
+	}
+}
+
+func TestSubnetMatch(t *testing.T) {
+	query := func(e *dns.EDNS0_SUBNET) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		if e != nil {
+			m.SetEdns0(4096, false)
+			edns.SetSubnet(m, e)
+		}
+		return m
+	}
+	sent := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("10.240.0.0").To4()}
+
+	tests := []struct {
+		sent, reply *dns.EDNS0_SUBNET
+		match       bool
+	}{
+		{sent: sent, reply: nil, match: true},
+		{sent: sent, reply: &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 16, Address: net.ParseIP("10.240.0.0")}, match: true},
+		{sent: sent, reply: &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 16, Address: net.ParseIP("10.241.0.0")}, match: false},
+		{sent: sent, reply: &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 16, SourceScope: 16, Address: net.ParseIP("10.240.0.0")}, match: false},
+		{sent: sent, reply: &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 2, SourceNetmask: 24, SourceScope: 16, Address: net.ParseIP("::")}, match: false},
+		{sent: nil, reply: &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 16, Address: net.ParseIP("10.240.0.0")}, match: false},
+	}
+	s := &subnet{v4: defaultSubnetV4, v6: defaultSubnetV6}
+	for i, tc := range tests {
+		if match := s.match(query(tc.sent), query(tc.reply)); match != tc.match {
+			t.Errorf("Test %d: expected match %t, got %t", i, tc.match, match)
+		}
+	}
+}
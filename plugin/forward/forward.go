@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"net"
 	"sync/atomic"
 	"time"
 
@@ -15,7 +16,9 @@ import (
 	"github.com/coredns/coredns/plugin/debug"
 	"github.com/coredns/coredns/plugin/dnstap"
 	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/validator"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -42,6 +45,11 @@ type Forward struct {
 	expire        time.Duration
 	maxConcurrent int64
 
+	validator *validator.Validator // nil when DNSSEC validation is disabled.
+	negative  []string             // negative trust anchors.
+
+	ecs *subnet // nil when no EDNS Client Subnet option is added.
+
 	opts options // also here for testing
 
 	// ErrLimitExceeded indicates that a query was rejected because the number of concurrent queries has exceeded
@@ -88,6 +96,20 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		}
 	}
 
+	// With validation the upstreams are asked for the DNSSEC records, and with ecs the client's subnet is added to
+	// the query; client is the request as it was received.
+	client := state
+	if f.validator != nil || f.ecs != nil {
+		m := r.Copy()
+		if f.validator != nil {
+			setDNSSEC(m)
+		}
+		if f.ecs != nil {
+			f.ecs.set(m, net.ParseIP(state.IP()))
+		}
+		state = request.Request{W: w, Req: m}
+	}
+
 	fails := 0
 	var span, child ot.Span
 	var upstreamErr error
@@ -137,8 +159,8 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 			if err == ErrCachedClosed { // Remote side closed conn, can only happen with TCP.
 				continue
 			}
-			// Retry with TCP if truncated and prefer_udp configured.
-			if ret != nil && ret.Truncated && !opts.forceTCP && opts.preferUDP {
+			// Retry with TCP if truncated and prefer_udp configured, or when the response needs to be validated.
+			if ret != nil && ret.Truncated && !opts.forceTCP && (opts.preferUDP || f.validator != nil) {
 				opts.forceTCP = true
 				continue
 			}
@@ -150,7 +172,7 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 		}
 
 		if f.tapPlugin != nil {
-			toDnstap(f, proxy.addr, state, opts, ret, start)
+			toDnstap(f, proxy, state, opts, ret, start)
 		}
 
 		upstreamErr = err
@@ -177,15 +199,32 @@ func (f *Forward) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg
 			return 0, nil
 		}
 
+		// A reply with a client subnet that doesn't match the one sent is dropped, RFC 7871, section 7.3. The
+		// other upstreams are tried once.
+		if f.ecs != nil && !f.ecs.match(state.Req, ret) {
+			upstreamErr = ErrSubnetMismatch
+			if i < len(list) {
+				continue
+			}
+			break
+		}
+
+		upstream := edns.Subnet(ret)
+		if f.validator != nil {
+			ret = f.validate(ctx, client, ret)
+		}
+		if f.ecs != nil {
+			f.ecs.reply(r, ret, upstream)
+		}
 		w.WriteMsg(ret)
 		return 0, nil
 	}
 
 	if upstreamErr != nil {
-		return dns.RcodeServerFailure, upstreamErr
+		return dns.RcodeServerFailure, edns.NewError(dns.ExtendedErrorCodeNoReachableAuthority, upstreamErr)
 	}
 
-	return dns.RcodeServerFailure, ErrNoHealthy
+	return dns.RcodeServerFailure, edns.NewError(dns.ExtendedErrorCodeNoReachableAuthority, ErrNoHealthy)
 }
 
 func (f *Forward) match(state request.Request) bool {
@@ -225,6 +264,8 @@ var (
 	ErrNoForward = errors.New("no forwarder defined")
 	// ErrCachedClosed means cached connection was closed by peer.
 	ErrCachedClosed = errors.New("cached connection was closed by peer")
+	// ErrSubnetMismatch means the client subnet in the reply doesn't match the one in the query.
+	ErrSubnetMismatch = errors.New("client subnet in reply does not match query")
 )
 
 // options holds various options that can be set.
@@ -1,6 +1,7 @@
 package forward
 
 import (
+	"context"
 	"crypto/tls"
 	"sync/atomic"
 	"time"
@@ -39,6 +40,9 @@ func NewHealthChecker(trans string, recursionDesired bool) HealthChecker {
 		c.WriteTimeout = hcWriteTimeout
 
 		return &dnsHc{c: c, recursionDesired: recursionDesired}
+
+	case transport.HTTPS, transport.QUIC:
+		return &exchangeHc{recursionDesired: recursionDesired}
 	}
 
 	log.Warningf("No healthchecker for transport %q", trans)
@@ -89,3 +93,38 @@ func (h *dnsHc) send(addr string) error {
 
 	return err
 }
+
+// exchangeHc is a health checker for DNS-over-HTTPS and DNS-over-QUIC endpoints. It sends the health
+// check query through the proxy's own exchanger, so it shares the connection with the real queries.
+type exchangeHc struct {
+	recursionDesired bool
+}
+
+// SetTLSConfig is a noop, the TLS config is set on the proxy's exchanger.
+func (h *exchangeHc) SetTLSConfig(cfg *tls.Config) {}
+
+func (h *exchangeHc) SetRecursionDesired(recursionDesired bool) {
+	h.recursionDesired = recursionDesired
+}
+func (h *exchangeHc) GetRecursionDesired() bool {
+	return h.recursionDesired
+}
+
+// Check is used as the up.Func in the up.Probe.
+func (h *exchangeHc) Check(p *Proxy) error {
+	ping := new(dns.Msg)
+	ping.SetQuestion(".", dns.TypeNS)
+	ping.MsgHdr.RecursionDesired = h.recursionDesired
+
+	ctx, cancel := context.WithTimeout(context.Background(), hcReadTimeout+hcWriteTimeout)
+	defer cancel()
+
+	if _, err := p.exchanger.Exchange(ctx, ping); err != nil {
+		HealthcheckFailureCount.WithLabelValues(p.addr).Add(1)
+		atomic.AddUint32(&p.fails, 1)
+		return err
+	}
+
+	atomic.StoreUint32(&p.fails, 0)
+	return nil
+}
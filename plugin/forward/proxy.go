@@ -1,20 +1,28 @@
 package forward
 
 import (
+	"context"
 	"crypto/tls"
 	"runtime"
 	"sync/atomic"
 	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/plugin/pkg/up"
+
+	"github.com/miekg/dns"
 )
 
 // Proxy defines an upstream host.
 type Proxy struct {
 	fails uint32
 	addr  string
+	trans string
 
+	// transport is the persistent connection cache for DNS and DNS-over-TLS upstreams, for
+	// DNS-over-HTTPS and DNS-over-QUIC upstreams exchanger is used instead.
 	transport *Transport
+	exchanger exchanger
 
 	// health checking
 	probe  *up.Probe
@@ -24,10 +32,18 @@ type Proxy struct {
 // NewProxy returns a new proxy.
 func NewProxy(addr, trans string) *Proxy {
 	p := &Proxy{
-		addr:      addr,
-		fails:     0,
-		probe:     up.New(),
-		transport: newTransport(addr),
+		addr:  addr,
+		trans: trans,
+		fails: 0,
+		probe: up.New(),
+	}
+	switch trans {
+	case transport.HTTPS:
+		p.exchanger = newDoHTransport(addr)
+	case transport.QUIC:
+		p.exchanger = newDoQTransport(addr)
+	default:
+		p.transport = newTransport(addr)
 	}
 	p.health = NewHealthChecker(trans, true)
 	runtime.SetFinalizer(p, (*Proxy).finalizer)
@@ -36,12 +52,22 @@ func NewProxy(addr, trans string) *Proxy {
 
 // SetTLSConfig sets the TLS config in the lower p.transport and in the healthchecking client.
 func (p *Proxy) SetTLSConfig(cfg *tls.Config) {
-	p.transport.SetTLSConfig(cfg)
+	if p.exchanger != nil {
+		p.exchanger.SetTLSConfig(cfg)
+	} else {
+		p.transport.SetTLSConfig(cfg)
+	}
 	p.health.SetTLSConfig(cfg)
 }
 
 // SetExpire sets the expire duration in the lower p.transport.
-func (p *Proxy) SetExpire(expire time.Duration) { p.transport.SetExpire(expire) }
+func (p *Proxy) SetExpire(expire time.Duration) {
+	if p.exchanger != nil {
+		p.exchanger.SetExpire(expire)
+		return
+	}
+	p.transport.SetExpire(expire)
+}
 
 // Healthcheck kicks of a round of health checks for this proxy.
 func (p *Proxy) Healthcheck() {
@@ -66,13 +92,31 @@ func (p *Proxy) Down(maxfails uint32) bool {
 }
 
 // close stops the health checking goroutine.
-func (p *Proxy) stop()      { p.probe.Stop() }
-func (p *Proxy) finalizer() { p.transport.Stop() }
+func (p *Proxy) stop() { p.probe.Stop() }
+
+func (p *Proxy) finalizer() {
+	if p.exchanger != nil {
+		p.exchanger.Stop()
+		return
+	}
+	p.transport.Stop()
+}
 
 // start starts the proxy's healthchecking.
 func (p *Proxy) start(duration time.Duration) {
 	p.probe.Start(duration)
-	p.transport.Start()
+	if p.exchanger == nil {
+		p.transport.Start()
+	}
+}
+
+// exchanger sends a single query to an upstream that does not use the persistent connection
+// cache in Transport, i.e. DNS-over-HTTPS and DNS-over-QUIC.
+type exchanger interface {
+	Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error)
+	SetTLSConfig(*tls.Config)
+	SetExpire(time.Duration)
+	Stop()
 }
 
 const (
@@ -4,6 +4,10 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -11,9 +15,13 @@ import (
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/dnstap"
+	"github.com/coredns/coredns/plugin/pkg/doh"
 	"github.com/coredns/coredns/plugin/pkg/parse"
 	pkgtls "github.com/coredns/coredns/plugin/pkg/tls"
 	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/pkg/validator"
+
+	"github.com/miekg/dns"
 )
 
 func init() { plugin.Register("forward", setup) }
@@ -104,19 +112,22 @@ func parseStanza(c *caddy.Controller) (*Forward, error) {
 		return f, c.ArgErr()
 	}
 
-	toHosts, err := parse.HostPortOrFile(to...)
+	toHosts, err := parseTo(to)
 	if err != nil {
 		return f, err
 	}
 
 	transports := make([]string, len(toHosts))
-	allowedTrans := map[string]bool{"dns": true, "tls": true}
+	allowedTrans := map[string]bool{"dns": true, "tls": true, "https": true, "quic": true}
 	for i, host := range toHosts {
 		trans, h := parse.Transport(host)
 
 		if !allowedTrans[trans] {
 			return f, fmt.Errorf("'%s' is not supported as a destination protocol in forward: %s", trans, host)
 		}
+		if trans == transport.HTTPS {
+			h = host // DoH upstreams are addressed by their full URL.
+		}
 		p := NewProxy(h, trans)
 		f.proxies = append(f.proxies, p)
 		transports[i] = trans
@@ -132,13 +143,21 @@ func parseStanza(c *caddy.Controller) (*Forward, error) {
 		f.tlsConfig.ServerName = f.tlsServerName
 	}
 
+	if len(f.negative) > 0 {
+		if f.validator == nil {
+			return f, c.Err("negative_trust_anchor can only be used with validate")
+		}
+		f.validator.SetNegativeAnchors(f.negative...)
+	}
+
 	// Initialize ClientSessionCache in tls.Config. This may speed up a TLS handshake
 	// in upcoming connections to the same TLS server.
 	f.tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(len(f.proxies))
 
 	for i := range f.proxies {
 		// Only set this for proxies that need it.
-		if transports[i] == transport.TLS {
+		switch transports[i] {
+		case transport.TLS, transport.HTTPS, transport.QUIC:
 			f.proxies[i].SetTLSConfig(f.tlsConfig)
 		}
 		f.proxies[i].SetExpire(f.expire)
@@ -257,6 +276,66 @@ func parseBlock(c *caddy.Controller, f *Forward) error {
 		}
 		f.ErrLimitExceeded = errors.New("concurrent queries exceeded maximum " + c.Val())
 		f.maxConcurrent = int64(n)
+	case "validate":
+		if f.validator != nil {
+			return c.Err("validate can only be given once")
+		}
+		args := c.RemainingArgs()
+		if len(args) == 0 {
+			f.validator = validator.New(validator.RootAnchors())
+			break
+		}
+		var anchors []dns.RR
+		for _, fileName := range args {
+			if root := dnsserver.GetConfig(c).Root; !filepath.IsAbs(fileName) && root != "" {
+				fileName = filepath.Join(root, fileName)
+			}
+			reader, err := os.Open(fileName)
+			if err != nil {
+				return err
+			}
+			rrs, err := validator.ParseAnchors(reader, fileName)
+			reader.Close()
+			if err != nil {
+				return c.Err(err.Error())
+			}
+			anchors = append(anchors, rrs...)
+		}
+		f.validator = validator.New(anchors)
+	case "negative_trust_anchor":
+		names := c.RemainingArgs()
+		if len(names) == 0 {
+			return c.ArgErr()
+		}
+		for i := 0; i < len(names); i++ {
+			f.negative = append(f.negative, plugin.Host(names[i]).NormalizeExact()...)
+		}
+	case "ecs":
+		args := c.RemainingArgs()
+		if len(args) > 2 {
+			return c.ArgErr()
+		}
+		f.ecs = &subnet{v4: defaultSubnetV4, v6: defaultSubnetV6}
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			if n < 0 || n > net.IPv4len*8 {
+				return fmt.Errorf("ecs IPv4 prefix length must be between 0 and 32: %d", n)
+			}
+			f.ecs.v4 = uint8(n)
+		}
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil {
+				return err
+			}
+			if n < 0 || n > net.IPv6len*8 {
+				return fmt.Errorf("ecs IPv6 prefix length must be between 0 and 128: %d", n)
+			}
+			f.ecs.v6 = uint8(n)
+		}
 
 	default:
 		return c.Errf("unknown property '%s'", c.Val())
@@ -265,4 +344,49 @@ func parseBlock(c *caddy.Controller, f *Forward) error {
 	return nil
 }
 
+// parseTo parses the TO... arguments. DNS-over-HTTPS upstreams are URLs that may use a hostname, these
+// are handled by parseDoHURL, everything else goes through parse.HostPortOrFile.
+func parseTo(to []string) ([]string, error) {
+	var hosts []string
+	for _, t := range to {
+		if trans, _ := parse.Transport(t); trans == transport.HTTPS {
+			u, err := parseDoHURL(t)
+			if err != nil {
+				return nil, err
+			}
+			hosts = append(hosts, u)
+			continue
+		}
+		h, err := parse.HostPortOrFile(t)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, h...)
+	}
+	return hosts, nil
+}
+
+// parseDoHURL normalizes a DoH upstream like https://dns.example.net to https://dns.example.net:443/dns-query.
+func parseDoHURL(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("no host in DNS-over-HTTPS upstream: %q", s)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("query or fragment not allowed in DNS-over-HTTPS upstream: %q", s)
+	}
+	port := u.Port()
+	if port == "" {
+		port = transport.HTTPSPort
+	}
+	path := u.EscapedPath()
+	if path == "" || path == "/" {
+		path = doh.Path
+	}
+	return transport.HTTPS + "://" + net.JoinHostPort(u.Hostname(), port) + path, nil
+}
+
 const max = 15 // Maximum number of upstreams.
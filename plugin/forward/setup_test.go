@@ -33,12 +33,15 @@ func TestSetup(t *testing.T) {
 		{"forward . [2003::1]:53", false, ".", nil, 2, options{hcRecursionDesired: true}, ""},
 		{"forward . 127.0.0.1 \n", false, ".", nil, 2, options{hcRecursionDesired: true}, ""},
 		{"forward 10.9.3.0/18 127.0.0.1", false, "0.9.10.in-addr.arpa.", nil, 2, options{hcRecursionDesired: true}, ""},
+		{"forward . https://127.0.0.1 quic://127.0.0.1 \n", false, ".", nil, 2, options{hcRecursionDesired: true}, ""},
+		{"forward . https://dns.example.org/dns-query 127.0.0.1\n", false, ".", nil, 2, options{hcRecursionDesired: true}, ""},
 		// negative
 		{"forward . a27.0.0.1", true, "", nil, 0, options{hcRecursionDesired: true}, "not an IP"},
 		{"forward . 127.0.0.1 {\nblaatl\n}\n", true, "", nil, 0, options{hcRecursionDesired: true}, "unknown property"},
 		{`forward . ::1
 		forward com ::2`, true, "", nil, 0, options{hcRecursionDesired: true}, "plugin"},
-		{"forward . https://127.0.0.1 \n", true, ".", nil, 2, options{hcRecursionDesired: true}, "'https' is not supported as a destination protocol in forward: https://127.0.0.1"},
+		{"forward . grpc://127.0.0.1 \n", true, ".", nil, 2, options{hcRecursionDesired: true}, "'grpc' is not supported as a destination protocol in forward: grpc://127.0.0.1"},
+		{"forward . https://dns.example.org/dns-query?dns=1 \n", true, ".", nil, 2, options{hcRecursionDesired: true}, "query or fragment not allowed"},
 	}
 
 	for i, test := range tests {
@@ -124,6 +127,38 @@ func TestSetupTLS(t *testing.T) {
 	}
 }
 
+func TestSetupDoHDoQ(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedAddrs []string
+		expectedTrans []string
+	}{
+		{"forward . https://dns.example.org", []string{"https://dns.example.org:443/dns-query"}, []string{"https"}},
+		{"forward . https://10.0.0.1:8443/resolve", []string{"https://10.0.0.1:8443/resolve"}, []string{"https"}},
+		{"forward . quic://10.0.0.1 quic://[::1]:8853", []string{"10.0.0.1:853", "[::1]:8853"}, []string{"quic", "quic"}},
+		{"forward . https://[2003::1]/ tls://10.0.0.1", []string{"https://[2003::1]:443/dns-query", "10.0.0.1:853"}, []string{"https", "tls"}},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+		}
+		for j, p := range f.proxies {
+			if p.addr != test.expectedAddrs[j] {
+				t.Errorf("Test %d: expected address %q, got %q", i, test.expectedAddrs[j], p.addr)
+			}
+			if p.trans != test.expectedTrans[j] {
+				t.Errorf("Test %d: expected transport %q, got %q", i, test.expectedTrans[j], p.trans)
+			}
+			if (p.trans == "https" || p.trans == "quic") && p.exchanger == nil {
+				t.Errorf("Test %d: expected an exchanger for %s", i, p.addr)
+			}
+		}
+	}
+}
+
 func TestSetupResolvconf(t *testing.T) {
 	const resolv = "resolv.conf"
 	if err := ioutil.WriteFile(resolv,
@@ -257,3 +292,101 @@ func TestSetupHealthCheck(t *testing.T) {
 		}
 	}
 }
+
+func TestSetupValidate(t *testing.T) {
+	anchor, err := ioutil.TempFile("", "anchor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(anchor.Name())
+	anchor.WriteString("example.org. 3600 IN DS 60485 13 2 D4B7D520E7BB5F0F67674A0CCEB1E3E0614B93C4F9E99B8383F6A1E4469DA50A\n")
+	anchor.Close()
+
+	tests := []struct {
+		input         string
+		shouldErr     bool
+		expectedValid bool
+		expectedErr   string
+	}{
+		// positive
+		{"forward . 127.0.0.1", false, false, ""},
+		{"forward . 127.0.0.1 {\nvalidate\n}\n", false, true, ""},
+		{"forward . 127.0.0.1 {\nvalidate " + anchor.Name() + "\n}\n", false, true, ""},
+		{"forward . 127.0.0.1 {\nvalidate\nnegative_trust_anchor example.org example.net\n}\n", false, true, ""},
+		{"forward . 127.0.0.1 {\nnegative_trust_anchor example.org\nvalidate\n}\n", false, true, ""},
+		// negative
+		{"forward . 127.0.0.1 {\nvalidate /does/not/exist\n}\n", true, false, "no such file"},
+		{"forward . 127.0.0.1 {\nvalidate\nvalidate\n}\n", true, false, "only be given once"},
+		{"forward . 127.0.0.1 {\nnegative_trust_anchor example.org\n}\n", true, false, "only be used with validate"},
+		{"forward . 127.0.0.1 {\nvalidate\nnegative_trust_anchor\n}\n", true, false, "Wrong argument count"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found %s for input %s", i, err, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+
+			if !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("Test %d: expected error to contain: %v, found error: %v, input: %s", i, test.expectedErr, err, test.input)
+			}
+		}
+
+		if !test.shouldErr && (f.validator != nil) != test.expectedValid {
+			t.Errorf("Test %d: expected validation %t, got %t", i, test.expectedValid, f.validator != nil)
+		}
+	}
+}
+
+func TestSetupECS(t *testing.T) {
+	tests := []struct {
+		input       string
+		shouldErr   bool
+		expectedECS *subnet
+		expectedErr string
+	}{
+		// positive
+		{"forward . 127.0.0.1", false, nil, ""},
+		{"forward . 127.0.0.1 {\necs\n}\n", false, &subnet{v4: 24, v6: 56}, ""},
+		{"forward . 127.0.0.1 {\necs 16\n}\n", false, &subnet{v4: 16, v6: 56}, ""},
+		{"forward . 127.0.0.1 {\necs 20 48\n}\n", false, &subnet{v4: 20, v6: 48}, ""},
+		// negative
+		{"forward . 127.0.0.1 {\necs 33\n}\n", true, nil, "between 0 and 32"},
+		{"forward . 127.0.0.1 {\necs 24 129\n}\n", true, nil, "between 0 and 128"},
+		{"forward . 127.0.0.1 {\necs x\n}\n", true, nil, "invalid syntax"},
+		{"forward . 127.0.0.1 {\necs 24 56 64\n}\n", true, nil, "Wrong argument count"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		f, err := parseForward(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found %s for input %s", i, err, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+
+			if !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("Test %d: expected error to contain: %v, found error: %v, input: %s", i, test.expectedErr, err, test.input)
+			}
+		}
+
+		if test.shouldErr {
+			continue
+		}
+		if (f.ecs == nil) != (test.expectedECS == nil) || (f.ecs != nil && *f.ecs != *test.expectedECS) {
+			t.Errorf("Test %d: expected ecs %v, got %v", i, test.expectedECS, f.ecs)
+		}
+	}
+}
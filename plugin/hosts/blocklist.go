@@ -0,0 +1,228 @@
+package hosts
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+// The kinds of entries of a blocklist, the first byte of their key in the nameSet.
+const (
+	blockName  = 'n' // the name is blocked.
+	blockBelow = 'b' // the subdomains of the name are blocked.
+	allowName  = 'N' // the name is allowed.
+	allowBelow = 'B' // the subdomains of the name are allowed.
+)
+
+// blocklist holds the entries of a blocklist file.
+type blocklist struct {
+	set nameSet
+}
+
+// Len returns the number of entries in the blocklist.
+func (b *blocklist) Len() int { return b.set.Len() }
+
+// match returns whether the blocklist blocks or allows name.
+func (b *blocklist) match(name string) (blocked, allowed bool) {
+	if b.set.Len() == 0 {
+		return false, false
+	}
+	blocked = b.set.has(blockName, name)
+	allowed = b.set.has(allowName, name)
+	for off, end := dns.NextLabel(name, 0); !end; off, end = dns.NextLabel(name, off) {
+		parent := name[off:]
+		blocked = blocked || b.set.has(blockBelow, parent)
+		allowed = allowed || b.set.has(allowBelow, parent)
+	}
+	return blocked, allowed
+}
+
+// nameSet is a sorted set of keys, packed in a single buffer. Compared to a map it uses a fraction of the memory,
+// which matters for blocklists with millions of names.
+type nameSet struct {
+	data    []byte   // the keys, each prefixed with its length.
+	offsets []uint32 // the offsets of the keys in data, sorted by key.
+}
+
+// add adds a key of kind for name, keys are only found after sort has been called.
+func (s *nameSet) add(kind byte, name string) {
+	if len(name) > 254 {
+		return
+	}
+	s.offsets = append(s.offsets, uint32(len(s.data)))
+	s.data = append(s.data, byte(len(name)+1), kind)
+	s.data = append(s.data, name...)
+}
+
+func (s *nameSet) key(i int) []byte {
+	off := s.offsets[i]
+	return s.data[off+1 : off+1+uint32(s.data[off])]
+}
+
+// sort sorts the keys and removes the duplicates. The remaining keys are copied to a buffer of their exact size,
+// as data grew while adding them and holds the duplicates.
+func (s *nameSet) sort() {
+	sort.Slice(s.offsets, func(i, j int) bool { return bytes.Compare(s.key(i), s.key(j)) < 0 })
+	n, size := 0, 0
+	for i := range s.offsets {
+		if i > 0 && bytes.Equal(s.key(i), s.key(n-1)) {
+			continue
+		}
+		s.offsets[n] = s.offsets[i]
+		size += 1 + len(s.key(n))
+		n++
+	}
+	s.offsets = s.offsets[:n:n]
+
+	data := make([]byte, 0, size)
+	for i, off := range s.offsets {
+		s.offsets[i] = uint32(len(data))
+		data = append(data, s.data[off:off+1+uint32(s.data[off])]...)
+	}
+	s.data = data
+}
+
+// compare compares the key at index i with the key of kind for name, without building the latter.
+func (s *nameSet) compare(i int, kind byte, name string) int {
+	key := s.key(i)
+	switch {
+	case key[0] < kind:
+		return -1
+	case key[0] > kind:
+		return 1
+	case string(key[1:]) < name:
+		return -1
+	case string(key[1:]) > name:
+		return 1
+	}
+	return 0
+}
+
+func (s *nameSet) has(kind byte, name string) bool {
+	i := sort.Search(len(s.offsets), func(i int) bool { return s.compare(i, kind, name) >= 0 })
+	return i < len(s.offsets) && s.compare(i, kind, name) == 0
+}
+
+// Len returns the number of keys in the set.
+func (s *nameSet) Len() int { return len(s.offsets) }
+
+// localNames are the names that blocklists in hosts file format commonly define for the host itself, they are not
+// blocked.
+var localNames = map[string]bool{
+	"localhost": true, "localhost.localdomain": true, "local": true, "broadcasthost": true, "0.0.0.0": true,
+	"ip6-localhost": true, "ip6-loopback": true, "ip6-localnet": true, "ip6-mcastprefix": true,
+	"ip6-allnodes": true, "ip6-allrouters": true, "ip6-allhosts": true,
+}
+
+// parseBlocklist parses a blocklist. Lines can be in any of these formats:
+//
+//   - a domain name, that is blocked;
+//   - a wildcard, *.example.org, that blocks the subdomains of example.org;
+//   - a hosts file entry, an address followed by names, that are blocked;
+//   - an adblock rule, ||example.org^, that blocks example.org and its subdomains, or an exception, @@||example.org^,
+//     that allows them. Rules with modifiers other than $important, or with a path, are ignored.
+//
+// Comments start with a # or, in adblock lists, a !. Names outside the Origins are left out.
+func (h *Hostsfile) parseBlocklist(r io.Reader) *blocklist {
+	b := &blocklist{}
+
+	add := func(kind byte, name string) {
+		name = plugin.Name(name).Normalize()
+		if _, ok := dns.IsDomainName(name); !ok || plugin.Zones(h.Origins).Matches(name) == "" {
+			return
+		}
+		b.set.add(kind, name)
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '!' || line[0] == '[' {
+			continue
+		}
+
+		if rule, allow := strings.CutPrefix(line, "@@"); strings.HasPrefix(rule, "||") {
+			name, ok := adblockRule(rule[2:])
+			if !ok {
+				continue
+			}
+			if allow {
+				add(allowName, name)
+				add(allowBelow, name)
+			} else {
+				add(blockName, name)
+				add(blockBelow, name)
+			}
+			continue
+		}
+
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		f := strings.Fields(line)
+		switch {
+		case len(f) == 0:
+		case len(f) == 1 && strings.HasPrefix(f[0], "*."):
+			add(blockBelow, f[0][2:])
+		case len(f) == 1:
+			add(blockName, f[0])
+		case parseIP(f[0]) != nil:
+			for _, name := range f[1:] {
+				if !localNames[strings.ToLower(name)] {
+					add(blockName, name)
+				}
+			}
+		}
+	}
+	b.set.sort()
+	return b
+}
+
+// adblockRule returns the name of the adblock rule, with the leading || removed.
+func adblockRule(rule string) (string, bool) {
+	name, rest, ok := strings.Cut(rule, "^")
+	if !ok || (rest != "" && rest != "$important") {
+		return "", false
+	}
+	if strings.ContainsAny(name, "/*:") {
+		return "", false
+	}
+	return name, true
+}
+
+// Blocked returns true when name is blocked by a blocklist, and not allowed by any of them.
+func (h *Hostsfile) Blocked(name string) bool {
+	name = strings.ToLower(name)
+
+	h.RLock()
+	defer h.RUnlock()
+
+	blocked := false
+	for _, s := range h.blocklists {
+		for _, f := range s.files {
+			b, a := f.blocked.match(name)
+			if a {
+				return false
+			}
+			blocked = blocked || b
+		}
+	}
+	return blocked
+}
+
+// blocklistLen returns the number of entries in all blocklists. It must be called with the lock held.
+func (h *Hostsfile) blocklistLen() int {
+	l := 0
+	for _, s := range h.blocklists {
+		for _, f := range s.files {
+			l += f.blocked.Len()
+		}
+	}
+	return l
+}
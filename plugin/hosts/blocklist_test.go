@@ -0,0 +1,171 @@
+package hosts
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const blocklistExample = `
+# domain list
+ads.example.org
+*.tracker.example.org
+
+# hosts file format
+0.0.0.0 0.0.0.0
+127.0.0.1 localhost
+0.0.0.0 banner.example.net popup.example.net # comment
+
+! adblock
+[Adblock Plus 2.0]
+||adserver.example.com^
+||cdn.example.com^$important
+||example.com^$third-party
+||example.com^/path
+@@||good.adserver.example.com^
+`
+
+func TestParseBlocklist(t *testing.T) {
+	h := testHostsfile("")
+	h.blocklists = []*source{{files: []*sourceFile{{blocked: h.parseBlocklist(strings.NewReader(blocklistExample))}}}}
+
+	tests := []struct {
+		name    string
+		blocked bool
+	}{
+		{"ads.example.org.", true},
+		{"sub.ads.example.org.", false},
+		{"tracker.example.org.", false},
+		{"a.tracker.example.org.", true},
+		{"a.b.tracker.example.org.", true},
+		{"banner.example.net.", true},
+		{"Popup.Example.Net.", true},
+		{"localhost.", false},
+		{"0.0.0.0.", false},
+		{"adserver.example.com.", true},
+		{"x.adserver.example.com.", true},
+		{"good.adserver.example.com.", false},
+		{"x.good.adserver.example.com.", false},
+		{"cdn.example.com.", true},
+		{"example.com.", false},
+		{"example.org.", false},
+	}
+	for _, tc := range tests {
+		if blocked := h.Blocked(tc.name); blocked != tc.blocked {
+			t.Errorf("Expected %s blocked to be %t, got %t", tc.name, tc.blocked, blocked)
+		}
+	}
+}
+
+func TestNameSet(t *testing.T) {
+	s := nameSet{}
+	for _, name := range []string{"c.", "a.", "b.", "a.", strings.Repeat("x", 255)} {
+		s.add(blockName, name)
+	}
+	s.add(allowName, "a.")
+	s.sort()
+	if s.Len() != 4 {
+		t.Errorf("Expected 4 keys, got %d", s.Len())
+	}
+	for _, name := range []string{"a.", "b.", "c."} {
+		if !s.has(blockName, name) {
+			t.Errorf("Expected %s in the set", name)
+		}
+	}
+	if s.has(blockName, "d.") || s.has(allowName, "b.") {
+		t.Error("Found keys that were not added")
+	}
+	// Four keys of a kind byte and a two byte name, each prefixed with its length.
+	if len(s.data) != 16 || cap(s.data) != 16 {
+		t.Errorf("Expected the keys to take 16 bytes, got %d with a capacity of %d", len(s.data), cap(s.data))
+	}
+	if n := testing.AllocsPerRun(10, func() { s.has(blockName, "b.") }); n != 0 {
+		t.Errorf("Expected no allocations per lookup, got %.0f", n)
+	}
+}
+
+func TestSources(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("20-second", "10.0.0.2 www.example.org\n")
+	write("10-first", "10.0.0.1 www.example.org\n10.0.0.3 *.example.org\n")
+	write(".hidden", "10.0.0.9 www.example.org\n")
+
+	h := testHostsfile("10.0.0.0 www.example.org\n")
+	h.sources = []*source{{path: dir}}
+	h.readHosts()
+
+	ips := h.LookupStaticHostV4("www.example.org.")
+	if got := strings.Join(toStrings(ips), " "); got != "10.0.0.0 10.0.0.1 10.0.0.2" {
+		t.Errorf("Expected the addresses of the hosts file and the sources in order, got %s", got)
+	}
+	if ips := h.LookupStaticHostV4("other.example.org."); len(ips) != 1 || ips[0].String() != "10.0.0.3" {
+		t.Errorf("Expected the wildcard address from the source, got %v", ips)
+	}
+
+	// Removed files are dropped.
+	os.Remove(filepath.Join(dir, "20-second"))
+	h.readHosts()
+	if ips := h.LookupStaticHostV4("www.example.org."); len(ips) != 2 {
+		t.Errorf("Expected 2 addresses after removing a file, got %v", ips)
+	}
+}
+
+func toStrings(ips []net.IP) []string {
+	s := make([]string, len(ips))
+	for i, ip := range ips {
+		s[i] = ip.String()
+	}
+	return s
+}
+
+func TestBlocked(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blocklist")
+	if err := os.WriteFile(path, []byte("ads.example.org\nwww.example.org\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, nxdomain := range []bool{false, true} {
+		h := Hosts{
+			Next:      test.NextHandler(dns.RcodeNameError, nil),
+			Hostsfile: testHostsfile("10.0.0.1 www.example.org\n"),
+		}
+		h.options.blockNXDomain = nxdomain
+		h.blocklists = []*source{{path: path, block: true}}
+		h.readHosts()
+
+		tests := []test.Case{
+			{Qname: "ads.example.org.", Qtype: dns.TypeA, Answer: []dns.RR{test.A("ads.example.org. 3600 IN A 0.0.0.0")}},
+			{Qname: "ads.example.org.", Qtype: dns.TypeAAAA, Answer: []dns.RR{test.AAAA("ads.example.org. 3600 IN AAAA ::")}},
+			{Qname: "ads.example.org.", Qtype: dns.TypeMX},
+			// Names with entries are not blocked.
+			{Qname: "www.example.org.", Qtype: dns.TypeA, Answer: []dns.RR{test.A("www.example.org. 3600 IN A 10.0.0.1")}},
+		}
+		for i, tc := range tests {
+			if nxdomain && tc.Qname == "ads.example.org." {
+				tc.Answer = nil
+				tc.Rcode = dns.RcodeNameError
+			}
+			rec := dnstest.NewRecorder(&test.ResponseWriter{})
+			if _, err := h.ServeDNS(context.Background(), rec, tc.Msg()); err != nil {
+				t.Fatalf("Test %d: expected no error, got %v", i, err)
+			}
+			if err := test.SortAndCheck(rec.Msg, tc); err != nil {
+				t.Errorf("Test %d (nxdomain %t): %s", i, nxdomain, err)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/request"
@@ -52,6 +53,21 @@ func (h Hosts) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 		answers = aaaa(qname, h.options.ttl, ips)
 	}
 
+	// Blocked names are only blocked when there are no entries for them.
+	if len(answers) == 0 && state.QType() != dns.TypePTR && !h.otherRecordsExist(qname) && h.Blocked(qname) {
+		blockedCount.WithLabelValues(metrics.WithServer(ctx)).Inc()
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+		if h.options.blockNXDomain {
+			m.Rcode = dns.RcodeNameError
+		} else {
+			m.Answer = blocked(qname, h.options.ttl, state.QType())
+		}
+		w.WriteMsg(m)
+		return m.Rcode, nil
+	}
+
 	// Only on NXDOMAIN we will fallthrough.
 	if len(answers) == 0 && !h.otherRecordsExist(qname) {
 		if h.Fall.Through(qname) {
@@ -109,6 +125,17 @@ func aaaa(zone string, ttl uint32, ips []net.IP) []dns.RR {
 	return answers
 }
 
+// blocked returns the answer for a blocked name: the unspecified address for A and AAAA queries, nothing otherwise.
+func blocked(zone string, ttl uint32, qtype uint16) []dns.RR {
+	switch qtype {
+	case dns.TypeA:
+		return a(zone, ttl, []net.IP{net.IPv4zero})
+	case dns.TypeAAAA:
+		return aaaa(zone, ttl, []net.IP{net.IPv6unspecified})
+	}
+	return nil
+}
+
 // ptr takes a slice of host names and filters out the ones that aren't in Origins, if specified, and returns a slice of PTR RRs.
 func (h *Hosts) ptr(zone string, ttl uint32, names []string) []dns.RR {
 	answers := make([]dns.RR, len(names))
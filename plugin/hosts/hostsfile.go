@@ -17,6 +17,8 @@ import (
 	"time"
 
 	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
 )
 
 // parseIP calls discards any v6 zone info, before calling net.ParseIP.
@@ -39,6 +41,9 @@ type options struct {
 
 	// The time between two reload of the configuration
 	reload time.Duration
+
+	// Respond to queries for blocked names with NXDOMAIN, instead of with the unspecified address
+	blockNXDomain bool
 }
 
 func newOptions() *options {
@@ -55,6 +60,11 @@ type Map struct {
 	name4 map[string][]net.IP
 	name6 map[string][]net.IP
 
+	// Key for the list of literal IP addresses of wildcard entries is the name they match the subdomains of: the
+	// key of *.example.org is example.org.
+	wild4 map[string][]net.IP
+	wild6 map[string][]net.IP
+
 	// Key for the list of host names must be a literal IP address
 	// including IPv6 address without zone identifier.
 	// We don't support old-classful IP address notation.
@@ -65,6 +75,8 @@ func newMap() *Map {
 	return &Map{
 		name4: make(map[string][]net.IP),
 		name6: make(map[string][]net.IP),
+		wild4: make(map[string][]net.IP),
+		wild6: make(map[string][]net.IP),
 		addr:  make(map[string][]string),
 	}
 }
@@ -78,12 +90,26 @@ func (h *Map) Len() int {
 	for _, v6 := range h.name6 {
 		l += len(v6)
 	}
+	for _, v4 := range h.wild4 {
+		l += len(v4)
+	}
+	for _, v6 := range h.wild6 {
+		l += len(v6)
+	}
 	for _, a := range h.addr {
 		l += len(a)
 	}
 	return l
 }
 
+// family returns the maps of the IPv4 or IPv6 entries, and of the wildcard entries.
+func (h *Map) family(v6 bool) (map[string][]net.IP, map[string][]net.IP) {
+	if v6 {
+		return h.name6, h.wild6
+	}
+	return h.name4, h.wild4
+}
+
 // Hostsfile contains known host entries.
 type Hostsfile struct {
 	sync.RWMutex
@@ -97,6 +123,12 @@ type Hostsfile struct {
 	// inline saves the hosts file that is inlined in a Corefile.
 	inline *Map
 
+	// sources are the additional hosts files and directories, in the order they are looked up in.
+	sources []*source
+
+	// blocklists are the files and directories with the names that are blocked.
+	blocklists []*source
+
 	// path to the hosts file
 	path string
 
@@ -107,8 +139,24 @@ type Hostsfile struct {
 	options *options
 }
 
-// readHosts determines if the cached data needs to be updated based on the size and modification time of the hostsfile.
+// readHosts determines if the cached data needs to be updated based on the size and modification time of the hostsfile,
+// and of the files of the sources and blocklists.
 func (h *Hostsfile) readHosts() {
+	h.readHostsfile()
+	for _, s := range h.sources {
+		h.readSource(s)
+	}
+	for _, s := range h.blocklists {
+		h.readSource(s)
+	}
+
+	h.Lock()
+	hostsEntries.WithLabelValues().Set(float64(h.entries()))
+	blocklistEntries.Set(float64(h.blocklistLen()))
+	h.Unlock()
+}
+
+func (h *Hostsfile) readHostsfile() {
 	file, err := os.Open(h.path)
 	if err != nil {
 		// We already log a warning if the file doesn't exist or can't be opened on setup. No need to return the error here.
@@ -138,7 +186,6 @@ func (h *Hostsfile) readHosts() {
 	h.mtime = stat.ModTime()
 	h.size = stat.Size()
 
-	hostsEntries.WithLabelValues().Set(float64(h.inline.Len() + h.hmap.Len()))
 	hostsReloadTime.Set(float64(stat.ModTime().UnixNano()) / 1e9)
 	h.Unlock()
 }
@@ -180,6 +227,20 @@ func (h *Hostsfile) parse(r io.Reader) *Map {
 
 		for i := 1; i < len(f); i++ {
 			name := plugin.Name(string(f[i])).Normalize()
+			if strings.HasPrefix(name, "*.") {
+				// Wildcard entries match the subdomains of the name, they don't get reverse entries.
+				name = name[2:]
+				if plugin.Zones(h.Origins).Matches(name) == "" {
+					continue
+				}
+				switch family {
+				case 1:
+					hmap.wild4[name] = append(hmap.wild4[name], addr)
+				case 2:
+					hmap.wild6[name] = append(hmap.wild6[name], addr)
+				}
+				continue
+			}
 			if plugin.Zones(h.Origins).Matches(name) == "" {
 				// name is not in Origins
 				continue
@@ -202,38 +263,66 @@ func (h *Hostsfile) parse(r io.Reader) *Map {
 	return hmap
 }
 
-// lookupStaticHost looks up the IP addresses for the given host from the hosts file.
-func (h *Hostsfile) lookupStaticHost(m map[string][]net.IP, host string) []net.IP {
+// maps returns the maps in the order they are looked up in: the hosts file, the sources and the inline entries. It
+// must be called with the lock held.
+func (h *Hostsfile) maps() []*Map {
+	maps := []*Map{h.hmap}
+	for _, s := range h.sources {
+		for _, f := range s.files {
+			maps = append(maps, f.hmap)
+		}
+	}
+	return append(maps, h.inline)
+}
+
+// entries returns the number of entries in all maps. It must be called with the lock held.
+func (h *Hostsfile) entries() int {
+	l := 0
+	for _, m := range h.maps() {
+		l += m.Len()
+	}
+	return l
+}
+
+// lookupStaticHost looks up the IP addresses for the given host in the maps. When host has none, the addresses of
+// the most specific wildcard entry matching it are returned.
+func (h *Hostsfile) lookupStaticHost(v6 bool, host string) []net.IP {
 	h.RLock()
 	defer h.RUnlock()
 
-	if len(m) == 0 {
-		return nil
+	maps := h.maps()
+	var ips []net.IP
+	for _, m := range maps {
+		names, _ := m.family(v6)
+		ips = append(ips, names[host]...)
+	}
+	if len(ips) > 0 {
+		return ips
 	}
 
-	ips, ok := m[host]
-	if !ok {
-		return nil
+	for off, end := dns.NextLabel(host, 0); !end; off, end = dns.NextLabel(host, off) {
+		parent := host[off:]
+		for _, m := range maps {
+			_, wild := m.family(v6)
+			ips = append(ips, wild[parent]...)
+		}
+		if len(ips) > 0 {
+			return ips
+		}
 	}
-	ipsCp := make([]net.IP, len(ips))
-	copy(ipsCp, ips)
-	return ipsCp
+	return nil
 }
 
 // LookupStaticHostV4 looks up the IPv4 addresses for the given host from the hosts file.
 func (h *Hostsfile) LookupStaticHostV4(host string) []net.IP {
 	host = strings.ToLower(host)
-	ip1 := h.lookupStaticHost(h.hmap.name4, host)
-	ip2 := h.lookupStaticHost(h.inline.name4, host)
-	return append(ip1, ip2...)
+	return h.lookupStaticHost(false, host)
 }
 
 // LookupStaticHostV6 looks up the IPv6 addresses for the given host from the hosts file.
 func (h *Hostsfile) LookupStaticHostV6(host string) []net.IP {
 	host = strings.ToLower(host)
-	ip1 := h.lookupStaticHost(h.hmap.name6, host)
-	ip2 := h.lookupStaticHost(h.inline.name6, host)
-	return append(ip1, ip2...)
+	return h.lookupStaticHost(true, host)
 }
 
 // LookupStaticAddr looks up the hosts for the given address from the hosts file.
@@ -245,15 +334,10 @@ func (h *Hostsfile) LookupStaticAddr(addr string) []string {
 
 	h.RLock()
 	defer h.RUnlock()
-	hosts1 := h.hmap.addr[addr]
-	hosts2 := h.inline.addr[addr]
 
-	if len(hosts1) == 0 && len(hosts2) == 0 {
-		return nil
+	var hosts []string
+	for _, m := range h.maps() {
+		hosts = append(hosts, m.addr[addr]...)
 	}
-
-	hostsCp := make([]string, len(hosts1)+len(hosts2))
-	copy(hostsCp, hosts1)
-	copy(hostsCp[len(hosts1):], hosts2)
-	return hostsCp
+	return hosts
 }
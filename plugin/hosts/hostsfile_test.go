@@ -242,3 +242,42 @@ func TestHostCacheModification(t *testing.T) {
 	}
 	testStaticAddr(t, entip, h)
 }
+
+func TestLookupWildcard(t *testing.T) {
+	h := testHostsfile(`10.0.0.1 *.example.org
+		10.0.0.2 *.sub.example.org
+		10.0.0.3 www.sub.example.org
+		::1 *.example.org`)
+
+	tests := []struct {
+		name string
+		v4   []string
+	}{
+		{"example.org.", nil},
+		{"a.example.org.", []string{"10.0.0.1"}},
+		{"a.b.example.org.", []string{"10.0.0.1"}},
+		{"sub.example.org.", []string{"10.0.0.1"}},
+		{"a.sub.example.org.", []string{"10.0.0.2"}},
+		{"www.sub.example.org.", []string{"10.0.0.3"}},
+		{"WWW.Sub.Example.Org.", []string{"10.0.0.3"}},
+	}
+	for _, tc := range tests {
+		ips := h.LookupStaticHostV4(tc.name)
+		if len(ips) != len(tc.v4) {
+			t.Errorf("LookupStaticHostV4(%s) = %v; want %v", tc.name, ips, tc.v4)
+			continue
+		}
+		for i := range ips {
+			if ips[i].String() != tc.v4[i] {
+				t.Errorf("LookupStaticHostV4(%s) = %v; want %v", tc.name, ips, tc.v4)
+			}
+		}
+	}
+	if ips := h.LookupStaticHostV6("a.example.org."); len(ips) != 1 {
+		t.Errorf("Expected wildcard IPv6 address, got %v", ips)
+	}
+	// Wildcards don't get reverse entries.
+	if names := h.LookupStaticAddr("10.0.0.1"); len(names) != 0 {
+		t.Errorf("Expected no reverse entries for wildcards, got %v", names)
+	}
+}
@@ -15,6 +15,20 @@ var (
 		Name:      "entries",
 		Help:      "The combined number of entries in hosts and Corefile.",
 	}, []string{})
+	// blocklistEntries is the number of entries in the blocklists.
+	blocklistEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "hosts",
+		Name:      "blocklist_entries",
+		Help:      "The number of entries in the blocklists.",
+	})
+	// blockedCount is the number of requests for blocked names.
+	blockedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "hosts",
+		Name:      "blocked_requests_total",
+		Help:      "Counter of requests for blocked names.",
+	}, []string{"server"})
 	// hostsReloadTime is the timestamp of the last reload of hosts file.
 	hostsReloadTime = promauto.NewGauge(prometheus.GaugeOpts{
 		Namespace: plugin.Namespace,
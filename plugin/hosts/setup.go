@@ -140,6 +140,42 @@ func hostsParse(c *caddy.Controller) (Hosts, error) {
 					return h, c.Errf("invalid negative duration for reload '%s'", remaining[0])
 				}
 				h.options.reload = reload
+			case "source", "blocklist":
+				block := c.Val() == "blocklist"
+				remaining := c.RemainingArgs()
+				if len(remaining) == 0 {
+					return h, c.Errf("%s needs at least one file or directory", c.Val())
+				}
+				for _, path := range remaining {
+					if !filepath.IsAbs(path) && config.Root != "" {
+						path = filepath.Join(config.Root, path)
+					}
+					if _, err := os.Stat(path); err != nil {
+						if !os.IsNotExist(err) {
+							return h, c.Errf("unable to access %s '%s': %v", c.Val(), path, err)
+						}
+						log.Warningf("File does not exist: %s", path)
+					}
+					src := &source{path: path, block: block}
+					if block {
+						h.blocklists = append(h.blocklists, src)
+					} else {
+						h.sources = append(h.sources, src)
+					}
+				}
+			case "block_response":
+				remaining := c.RemainingArgs()
+				if len(remaining) != 1 {
+					return h, c.ArgErr()
+				}
+				switch remaining[0] {
+				case "zero":
+					h.options.blockNXDomain = false
+				case "nxdomain":
+					h.options.blockNXDomain = true
+				default:
+					return h, c.Errf("block_response must be zero or nxdomain, got '%s'", remaining[0])
+				}
 			default:
 				if len(h.Fall.Zones) == 0 {
 					line := strings.Join(append([]string{c.Val()}, c.RemainingArgs()...), " ")
@@ -167,3 +167,54 @@ func TestHostsInlineParse(t *testing.T) {
 		}
 	}
 }
+
+func TestHostsSourcesParse(t *testing.T) {
+	tests := []struct {
+		input         string
+		shouldErr     bool
+		sources       int
+		blocklists    int
+		blockNXDomain bool
+	}{
+		{`hosts {
+			source /etc/hosts.d /etc/hosts.extra
+			blocklist /etc/blocklists
+		}`, false, 2, 1, false},
+		{`hosts {
+			blocklist ads.txt trackers.txt
+			block_response nxdomain
+		}`, false, 0, 2, true},
+		{`hosts {
+			block_response zero
+		}`, false, 0, 0, false},
+		// fails
+		{`hosts {
+			source
+		}`, true, 0, 0, false},
+		{`hosts {
+			block_response servfail
+		}`, true, 0, 0, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		h, err := hostsParse(c)
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if err != nil {
+			continue
+		}
+		if len(h.sources) != test.sources {
+			t.Errorf("Test %d expected %d sources, got %d", i, test.sources, len(h.sources))
+		}
+		if len(h.blocklists) != test.blocklists {
+			t.Errorf("Test %d expected %d blocklists, got %d", i, test.blocklists, len(h.blocklists))
+		}
+		if h.options.blockNXDomain != test.blockNXDomain {
+			t.Errorf("Test %d expected block_response nxdomain %t, got %t", i, test.blockNXDomain, h.options.blockNXDomain)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package hosts
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// source is a file, or a directory of files, with hosts entries or with blocked names. The files of a directory are
+// read in lexical order, and every file is reread on its own when it changed. The data of a file is replaced before
+// the next one is read, so a reload never holds more than one extra file in memory.
+type source struct {
+	path  string
+	block bool // the files are blocklists.
+
+	// files is modified with the lock of the Hostsfile held, by a single goroutine.
+	files []*sourceFile
+}
+
+// sourceFile is a file of a source.
+type sourceFile struct {
+	path  string
+	mtime time.Time
+	size  int64
+
+	hmap    *Map       // for hosts files.
+	blocked *blocklist // for blocklists.
+}
+
+// paths returns the files of the source: the source itself if it is a file, otherwise the regular files in it, that
+// are not hidden, in lexical order.
+func (s *source) paths() []string {
+	stat, err := os.Stat(s.path)
+	if err != nil {
+		return nil
+	}
+	if !stat.IsDir() {
+		return []string{s.path}
+	}
+
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		log.Warningf("Failed to read directory %q: %s", s.path, err)
+		return nil
+	}
+	paths := []string{}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		paths = append(paths, filepath.Join(s.path, e.Name()))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// readSource rereads the files of s that changed, adds the ones that appeared and drops the ones that are gone.
+func (h *Hostsfile) readSource(s *source) {
+	old := map[string]*sourceFile{}
+	for _, f := range s.files {
+		old[f.path] = f
+	}
+
+	paths := s.paths()
+	files := make([]*sourceFile, 0, len(paths))
+	for _, path := range paths {
+		f, ok := old[path]
+		if !ok {
+			f = &sourceFile{path: path, hmap: newMap(), blocked: &blocklist{}}
+		}
+		files = append(files, f)
+	}
+	h.Lock()
+	s.files = files
+	h.Unlock()
+
+	for _, f := range files {
+		h.readSourceFile(s, f)
+	}
+}
+
+func (h *Hostsfile) readSourceFile(s *source, f *sourceFile) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return
+	}
+	if f.mtime.Equal(stat.ModTime()) && f.size == stat.Size() {
+		return
+	}
+
+	if s.block {
+		blocked := h.parseBlocklist(file)
+		log.Debugf("Parsed blocklist %s into %d entries", f.path, blocked.Len())
+		h.Lock()
+		f.blocked = blocked
+		h.Unlock()
+	} else {
+		hmap := h.parse(file)
+		log.Debugf("Parsed hosts file %s into %d entries", f.path, hmap.Len())
+		h.Lock()
+		f.hmap = hmap
+		h.Unlock()
+	}
+	f.mtime = stat.ModTime()
+	f.size = stat.Size()
+	hostsReloadTime.Set(float64(stat.ModTime().UnixNano()) / 1e9)
+}
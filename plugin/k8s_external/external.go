@@ -1,12 +1,13 @@
 /*
 Package external implements external names for kubernetes clusters.
 
-This plugin only handles three qtypes (except the apex queries, because those are handled
-differently). We support A, AAAA and SRV request, for all other types we return NODATA or
+This plugin only handles five qtypes (except the apex queries, because those are handled
+differently). We support A, AAAA, SRV, CNAME and TXT request, for all other types we return NODATA or
 NXDOMAIN depending on the state of the cluster.
 
 A plugin willing to provide these services must implement the Externaler interface, although it
-likely only makes sense for the *kubernetes* plugin.
+likely only makes sense for the *kubernetes* plugin. A plugin that serves the names of other
+sources than services, must implement the ExternalSourcer interface as well.
 
 */
 package external
@@ -31,6 +32,13 @@ type Externaler interface {
 	ExternalAddress(state request.Request) []dns.RR
 }
 
+// ExternalSourcer defines the interface that a plugin should implement to serve the names of other sources
+// than services.
+type ExternalSourcer interface {
+	// ExternalSources enables the sources, it is called before the plugin starts up.
+	ExternalSources(sources []string) error
+}
+
 // External resolves Ingress and Loadbalance IPs from kubernetes clusters.
 type External struct {
 	Next  plugin.Handler
@@ -39,6 +47,7 @@ type External struct {
 	hostmaster string
 	apex       string
 	ttl        uint32
+	sources    []string
 
 	upstream *upstream.Upstream
 
@@ -98,6 +107,10 @@ func (e *External) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		m.Answer = e.aaaa(ctx, svc, state)
 	case dns.TypeSRV:
 		m.Answer, m.Extra = e.srv(svc, state)
+	case dns.TypeCNAME:
+		m.Answer = e.cname(svc, state)
+	case dns.TypeTXT:
+		m.Answer = e.txt(svc, state)
 	default:
 		m.Ns = []dns.RR{e.soa(state)}
 	}
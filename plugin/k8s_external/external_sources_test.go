@@ -0,0 +1,145 @@
+package external
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/kubernetes"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestExternalSources(t *testing.T) {
+	k := kubernetes.New([]string{"cluster.local."})
+	k.APIConn = &externalSources{}
+	if err := k.ExternalSources([]string{"dnsendpoint"}); err != nil {
+		t.Fatal(err)
+	}
+
+	e := New()
+	e.Zones = []string{"example.com."}
+	e.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	e.externalFunc = k.External
+	e.externalAddrFunc = externalAddress // internal test function
+
+	ctx := context.TODO()
+	for i, tc := range testsSources {
+		r := tc.Msg()
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		_, err := e.ServeDNS(ctx, w, r)
+		if err != tc.Error {
+			t.Errorf("Test %d expected no error, got %v", i, err)
+			return
+		}
+
+		resp := w.Msg
+		if resp == nil {
+			t.Fatalf("Test %d, got nil message and no error for %q", i, r.Question[0].Name)
+		}
+		if err = test.SortAndCheck(resp, tc); err != nil {
+			t.Errorf("Test %d, %v", i, err)
+		}
+	}
+}
+
+var testsSources = []test.Case{
+	{
+		Qname: "www.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("www.example.com.	5	IN	A	1.2.3.4"),
+			test.A("www.example.com.	5	IN	A	1.2.3.5"),
+		},
+	},
+	{
+		Qname: "www.example.com.", Qtype: dns.TypeTXT, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.TXT(`www.example.com.	5	IN	TXT	"owner=team-a"`),
+		},
+	},
+	// NODATA
+	{
+		Qname: "www.example.com.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	{
+		Qname: "alias.example.com.", Qtype: dns.TypeCNAME, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.CNAME("alias.example.com.	5	IN	CNAME	www.example.com."),
+		},
+	},
+	{
+		Qname: "_http._tcp.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.SRV("_http._tcp.example.com.	5	IN	SRV	10 20 8080 www.example.com."),
+		},
+	},
+	// Wildcard
+	{
+		Qname: "a.b.wild.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("a.b.wild.example.com.	5	IN	A	1.2.3.6"),
+		},
+	},
+	// Not in a source, and not a service
+	{
+		Qname: "svc.nope.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+}
+
+type externalSources struct{ external }
+
+func (externalSources) DNSEndpointIndex(host string) []*object.DNSEndpoint {
+	return dnsEndpointIndexExternal[host]
+}
+
+var dnsEndpointIndexExternal = map[string][]*object.DNSEndpoint{
+	"www.example.com.": {
+		{
+			Name:      "www",
+			Namespace: "testns",
+			Endpoints: []object.DNSEndpointRecord{
+				{DNSName: "www.example.com", RecordType: "A", Targets: []string{"1.2.3.4", "1.2.3.5"}},
+				{DNSName: "www.example.com", RecordType: "TXT", Targets: []string{"owner=team-a"}},
+				{DNSName: "alias.example.com", RecordType: "CNAME", Targets: []string{"www.example.com"}},
+			},
+		},
+	},
+	"alias.example.com.": {
+		{
+			Name:      "www",
+			Namespace: "testns",
+			Endpoints: []object.DNSEndpointRecord{
+				{DNSName: "www.example.com", RecordType: "A", Targets: []string{"1.2.3.4", "1.2.3.5"}},
+				{DNSName: "www.example.com", RecordType: "TXT", Targets: []string{"owner=team-a"}},
+				{DNSName: "alias.example.com", RecordType: "CNAME", Targets: []string{"www.example.com"}},
+			},
+		},
+	},
+	"_http._tcp.example.com.": {
+		{
+			Name:      "srv",
+			Namespace: "testns",
+			Endpoints: []object.DNSEndpointRecord{
+				{DNSName: "_http._tcp.example.com", RecordType: "SRV", Targets: []string{"10 20 8080 www.example.com"}},
+			},
+		},
+	},
+	"*.wild.example.com.": {
+		{
+			Name:      "wild",
+			Namespace: "testns",
+			Endpoints: []object.DNSEndpointRecord{
+				{DNSName: "*.wild.example.com", RecordType: "A", Targets: []string{"1.2.3.6"}},
+			},
+		},
+	},
+}
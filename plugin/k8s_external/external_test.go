@@ -169,6 +169,17 @@ func (external) Run()
 func (external) Stop() error                                                       { return nil }
 func (external) EpIndexReverse(string) []*object.Endpoints                         { return nil }
 func (external) SvcIndexReverse(string) []*object.Service                          { return nil }
+func (external) ExternalNameIndex(string) []*object.Service                        { return nil }
+func (external) ServiceImportList() []*object.ServiceImport                        { return nil }
+func (external) MultiClusterEndpointsList() []*object.MultiClusterEndpoints        { return nil }
+func (external) SvcImportIndex(string) []*object.ServiceImport                     { return nil }
+func (external) MCEpIndex(string) []*object.MultiClusterEndpoints                  { return nil }
+func (external) NodeZone(string) string                                            { return "" }
+func (external) DNSEndpointIndex(string) []*object.DNSEndpoint                     { return nil }
+func (external) IngressIndex(string) []*object.Ingress                             { return nil }
+func (external) GatewayIndex(string) []*object.Gateway                             { return nil }
+func (external) GatewayHostIndex(string) []*object.Gateway                         { return nil }
+func (external) HTTPRouteIndex(string) []*object.HTTPRoute                         { return nil }
 func (external) Modified() int64                                                   { return 0 }
 func (external) EpIndex(s string) []*object.Endpoints                              { return nil }
 func (external) EndpointsList() []*object.Endpoints                                { return nil }
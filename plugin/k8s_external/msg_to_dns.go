@@ -112,7 +112,13 @@ func (e *External) srv(services []msg.Service, state request.Request) (records,
 
 		switch what {
 		case dns.TypeCNAME:
-			// can't happen
+			// A host name is the target of an SRV record of an external source, it is returned as is.
+			srv := &dns.SRV{Hdr: dns.RR_Header{Name: state.QName(), Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: e.ttl},
+				Priority: uint16(s.Priority), Weight: uint16(s.Weight), Port: uint16(s.Port), Target: dns.Fqdn(s.Host)}
+
+			if ok := isDuplicate(dup, srv.Target, "", srv.Port); !ok {
+				records = append(records, srv)
+			}
 
 		case dns.TypeA, dns.TypeAAAA:
 			addr := s.Host
@@ -138,6 +144,28 @@ func (e *External) srv(services []msg.Service, state request.Request) (records,
 	return records, extra
 }
 
+func (e *External) cname(services []msg.Service, state request.Request) (records []dns.RR) {
+	for _, s := range services {
+		if what, _ := s.HostType(); what != dns.TypeCNAME {
+			continue
+		}
+		records = append(records, s.NewCNAME(state.QName(), s.Host))
+	}
+	return records
+}
+
+func (e *External) txt(services []msg.Service, state request.Request) (records []dns.RR) {
+	for _, s := range services {
+		if what, _ := s.HostType(); what != dns.TypeTXT {
+			continue
+		}
+		rr := s.NewTXT(state.QName())
+		rr.Hdr.Ttl = e.ttl
+		records = append(records, rr)
+	}
+	return records
+}
+
 // not sure if this is even needed.
 
 // item holds records.
@@ -1,6 +1,7 @@
 package external
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/coredns/caddy"
@@ -27,6 +28,16 @@ func setup(c *caddy.Controller) error {
 			e.externalFunc = x.External
 			e.externalAddrFunc = x.ExternalAddress
 		}
+		if len(e.sources) == 0 {
+			return nil
+		}
+		x, ok := m.(ExternalSourcer)
+		if !ok {
+			return plugin.Error("k8s_external", errors.New("sources are not supported by the kubernetes plugin"))
+		}
+		if err := x.ExternalSources(e.sources); err != nil {
+			return plugin.Error("k8s_external", err)
+		}
 		return nil
 	})
 
@@ -66,6 +77,19 @@ func parse(c *caddy.Controller) (*External, error) {
 					return nil, c.ArgErr()
 				}
 				e.apex = args[0]
+			case "sources":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, a := range args {
+					switch a {
+					case "dnsendpoint", "gateway", "ingress":
+						e.sources = append(e.sources, a)
+					default:
+						return nil, c.Errf("unknown source '%s', must be one of: dnsendpoint, gateway, ingress", a)
+					}
+				}
 			default:
 				return nil, c.Errf("unknown property '%s'", c.Val())
 			}
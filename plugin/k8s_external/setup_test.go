@@ -1,6 +1,7 @@
 package external
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/coredns/caddy"
@@ -8,16 +9,26 @@ import (
 
 func TestSetup(t *testing.T) {
 	tests := []struct {
-		input        string
-		shouldErr    bool
-		expectedZone string
-		expectedApex string
+		input           string
+		shouldErr       bool
+		expectedZone    string
+		expectedApex    string
+		expectedSources []string
 	}{
-		{`k8s_external`, false, "", "dns"},
-		{`k8s_external example.org`, false, "example.org.", "dns"},
+		{`k8s_external`, false, "", "dns", nil},
+		{`k8s_external example.org`, false, "example.org.", "dns", nil},
 		{`k8s_external example.org {
 			apex testdns
-}`, false, "example.org.", "testdns"},
+}`, false, "example.org.", "testdns", nil},
+		{`k8s_external example.org {
+			sources dnsendpoint gateway
+}`, false, "example.org.", "dns", []string{"dnsendpoint", "gateway"}},
+		{`k8s_external example.org {
+			sources
+}`, true, "", "", nil},
+		{`k8s_external example.org {
+			sources service
+}`, true, "", "", nil},
 	}
 
 	for i, test := range tests {
@@ -43,6 +54,9 @@ func TestSetup(t *testing.T) {
 			if test.expectedApex != e.apex {
 				t.Errorf("Test %d, expected apex %q for input %s, got: %q", i, test.expectedApex, test.input, e.apex)
 			}
+			if !reflect.DeepEqual(test.expectedSources, e.sources) {
+				t.Errorf("Test %d, expected sources %v for input %s, got: %v", i, test.expectedSources, test.input, e.sources)
+			}
 		}
 	}
 }
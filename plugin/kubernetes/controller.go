@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,10 +14,15 @@ import (
 	api "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1"
 	discoveryV1beta1 "k8s.io/api/discovery/v1beta1"
+	networking "k8s.io/api/networking/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
@@ -28,6 +34,7 @@ const (
 	epNameNamespaceIndex  = "EndpointNameNamespace"
 	epIPIndex             = "EndpointsIP"
 	externalNameIndex     = "externalName"
+	externalHostIndex     = "externalHost"
 )
 
 type dnsController interface {
@@ -40,7 +47,19 @@ type dnsController interface {
 	EpIndex(string) []*object.Endpoints
 	EpIndexReverse(string) []*object.Endpoints
 
+	ServiceImportList() []*object.ServiceImport
+	MultiClusterEndpointsList() []*object.MultiClusterEndpoints
+	SvcImportIndex(string) []*object.ServiceImport
+	MCEpIndex(string) []*object.MultiClusterEndpoints
+
+	DNSEndpointIndex(string) []*object.DNSEndpoint
+	IngressIndex(string) []*object.Ingress
+	GatewayIndex(string) []*object.Gateway
+	GatewayHostIndex(string) []*object.Gateway
+	HTTPRouteIndex(string) []*object.HTTPRoute
+
 	GetNodeByName(context.Context, string) (*api.Node, error)
+	NodeZone(string) string
 	GetNamespaceByName(string) (*api.Namespace, error)
 
 	Run()
@@ -66,15 +85,37 @@ type dnsControl struct {
 	// with the api.Endpoints Lister/Controller on k8s systems that don't use discovery.EndpointSlices
 	epLock sync.RWMutex
 
-	svcController cache.Controller
-	podController cache.Controller
-	epController  cache.Controller
-	nsController  cache.Controller
-
-	svcLister cache.Indexer
-	podLister cache.Indexer
-	epLister  cache.Indexer
-	nsLister  cache.Store
+	svcController  cache.Controller
+	podController  cache.Controller
+	epController   cache.Controller
+	nsController   cache.Controller
+	nodeController cache.Controller
+
+	svcLister  cache.Indexer
+	podLister  cache.Indexer
+	epLister   cache.Indexer
+	nsLister   cache.Store
+	nodeLister cache.Indexer
+
+	// svcImportController and mcEpController watch the ServiceImports and the EndpointSlices of the imported
+	// services, they are only set when multicluster zones are configured.
+	svcImportController cache.Controller
+	mcEpController      cache.Controller
+
+	svcImportLister cache.Indexer
+	mcEpLister      cache.Indexer
+
+	// The controllers of the objects that are served by the k8s_external plugin, they are only set when these
+	// sources are enabled.
+	dnsEndpointController cache.Controller
+	ingressController     cache.Controller
+	gatewayController     cache.Controller
+	httpRouteController   cache.Controller
+
+	dnsEndpointLister cache.Indexer
+	ingressLister     cache.Indexer
+	gatewayLister     cache.Indexer
+	httpRouteLister   cache.Indexer
 
 	// stopLock is used to enforce only a single call to Stop is active.
 	// Needed because we allow stopping through an http endpoint and
@@ -90,6 +131,7 @@ type dnsControl struct {
 type dnsControlOpts struct {
 	initPodCache       bool
 	initEndpointsCache bool
+	initNodeCache      bool
 	ignoreEmptyService bool
 
 	// Label handling.
@@ -98,8 +140,9 @@ type dnsControlOpts struct {
 	namespaceLabelSelector *meta.LabelSelector
 	namespaceSelector      labels.Selector
 
-	zones            []string
-	endpointNameMode bool
+	zones             []string
+	endpointNameMode  bool
+	multiclusterZones []string
 }
 
 // newDNSController creates a controller for CoreDNS.
@@ -152,6 +195,19 @@ func newdnsController(ctx context.Context, kubeClient kubernetes.Interface, opts
 		dns.epLock.Unlock()
 	}
 
+	if opts.initNodeCache {
+		dns.nodeLister, dns.nodeController = object.NewIndexerInformer(
+			&cache.ListWatch{
+				ListFunc:  nodeListFunc(ctx, dns.client),
+				WatchFunc: nodeWatchFunc(ctx, dns.client),
+			},
+			&api.Node{},
+			cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
+			cache.Indexers{},
+			object.DefaultProcessor(object.ToNode, nil),
+		)
+	}
+
 	dns.nsLister, dns.nsController = cache.NewInformer(
 		&cache.ListWatch{
 			ListFunc:  namespaceListFunc(ctx, dns.client, dns.namespaceSelector),
@@ -200,6 +256,92 @@ func (dns *dnsControl) WatchEndpointSliceV1beta1(ctx context.Context) {
 	dns.epLock.Unlock()
 }
 
+// WatchMultiCluster sets up the watches of the ServiceImports, with the dynamic client mcsClient, and of the
+// EndpointSlices of the imported services, of the Multi-Cluster Services API.
+func (dns *dnsControl) WatchMultiCluster(ctx context.Context, mcsClient dynamic.Interface) {
+	dns.svcImportLister, dns.svcImportController = object.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc:  dynamicListFunc(ctx, mcsClient, object.ServiceImportResource, api.NamespaceAll, dns.selector),
+			WatchFunc: dynamicWatchFunc(ctx, mcsClient, object.ServiceImportResource, api.NamespaceAll, dns.selector),
+		},
+		&unstructured.Unstructured{},
+		cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
+		cache.Indexers{svcNameNamespaceIndex: svcImportNameNamespaceIndexFunc},
+		object.DefaultProcessor(object.ToServiceImport, nil),
+	)
+
+	// Only the EndpointSlices that are labeled with the name of a ServiceImport belong to an imported service.
+	imported, _ := labels.NewRequirement(object.LabelServiceImportName, selection.Exists, nil)
+	selector := labels.NewSelector()
+	if dns.selector != nil {
+		selector = dns.selector
+	}
+	selector = selector.Add(*imported)
+
+	dns.mcEpLister, dns.mcEpController = object.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc:  endpointSliceListFunc(ctx, dns.client, api.NamespaceAll, selector),
+			WatchFunc: endpointSliceWatchFunc(ctx, dns.client, api.NamespaceAll, selector),
+		},
+		&discovery.EndpointSlice{},
+		cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
+		cache.Indexers{epNameNamespaceIndex: mcEpNameNamespaceIndexFunc},
+		object.DefaultProcessor(object.EndpointSliceToMultiClusterEndpoints, nil),
+	)
+}
+
+// WatchDNSEndpoints sets up the watch of the DNSEndpoints of external-dns, with the dynamic client c.
+func (dns *dnsControl) WatchDNSEndpoints(ctx context.Context, c dynamic.Interface) {
+	dns.dnsEndpointLister, dns.dnsEndpointController = object.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc:  dynamicListFunc(ctx, c, object.DNSEndpointResource, api.NamespaceAll, dns.selector),
+			WatchFunc: dynamicWatchFunc(ctx, c, object.DNSEndpointResource, api.NamespaceAll, dns.selector),
+		},
+		&unstructured.Unstructured{},
+		cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
+		cache.Indexers{externalHostIndex: dnsEndpointHostIndexFunc},
+		object.DefaultProcessor(object.ToDNSEndpoint, nil),
+	)
+}
+
+// WatchGateways sets up the watches of the Gateways and HTTPRoutes of the Gateway API, with the dynamic client c.
+func (dns *dnsControl) WatchGateways(ctx context.Context, c dynamic.Interface) {
+	dns.gatewayLister, dns.gatewayController = object.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc:  dynamicListFunc(ctx, c, object.GatewayResource, api.NamespaceAll, dns.selector),
+			WatchFunc: dynamicWatchFunc(ctx, c, object.GatewayResource, api.NamespaceAll, dns.selector),
+		},
+		&unstructured.Unstructured{},
+		cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
+		cache.Indexers{svcNameNamespaceIndex: gatewayNameNamespaceIndexFunc, externalHostIndex: gatewayHostIndexFunc},
+		object.DefaultProcessor(object.ToGateway, nil),
+	)
+	dns.httpRouteLister, dns.httpRouteController = object.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc:  dynamicListFunc(ctx, c, object.HTTPRouteResource, api.NamespaceAll, dns.selector),
+			WatchFunc: dynamicWatchFunc(ctx, c, object.HTTPRouteResource, api.NamespaceAll, dns.selector),
+		},
+		&unstructured.Unstructured{},
+		cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
+		cache.Indexers{externalHostIndex: httpRouteHostIndexFunc},
+		object.DefaultProcessor(object.ToHTTPRoute, nil),
+	)
+}
+
+// WatchIngresses sets up the watch of the Ingresses.
+func (dns *dnsControl) WatchIngresses(ctx context.Context) {
+	dns.ingressLister, dns.ingressController = object.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc:  ingressListFunc(ctx, dns.client, api.NamespaceAll, dns.selector),
+			WatchFunc: ingressWatchFunc(ctx, dns.client, api.NamespaceAll, dns.selector),
+		},
+		&networking.Ingress{},
+		cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
+		cache.Indexers{externalHostIndex: ingressHostIndexFunc},
+		object.DefaultProcessor(object.ToIngress, nil),
+	)
+}
+
 func (dns *dnsControl) EndpointsLatencyRecorder() *object.EndpointLatencyRecorder {
 	return &object.EndpointLatencyRecorder{
 		ServiceFunc: func(o meta.Object) []*object.Service {
@@ -261,6 +403,81 @@ func epNameNamespaceIndexFunc(obj interface{}) ([]string, error) {
 	return []string{s.Index}, nil
 }
 
+func svcImportNameNamespaceIndexFunc(obj interface{}) ([]string, error) {
+	s, ok := obj.(*object.ServiceImport)
+	if !ok {
+		return nil, errObj
+	}
+	return []string{s.Index}, nil
+}
+
+func mcEpNameNamespaceIndexFunc(obj interface{}) ([]string, error) {
+	s, ok := obj.(*object.MultiClusterEndpoints)
+	if !ok {
+		return nil, errObj
+	}
+	return []string{s.Index}, nil
+}
+
+// hostKey returns the key of the host name h in the external host indexes.
+func hostKey(h string) string { return strings.ToLower(strings.TrimSuffix(h, ".")) + "." }
+
+func dnsEndpointHostIndexFunc(obj interface{}) ([]string, error) {
+	d, ok := obj.(*object.DNSEndpoint)
+	if !ok {
+		return nil, errObj
+	}
+	idx := make([]string, len(d.Endpoints))
+	for i, e := range d.Endpoints {
+		idx[i] = hostKey(e.DNSName)
+	}
+	return idx, nil
+}
+
+func ingressHostIndexFunc(obj interface{}) ([]string, error) {
+	ing, ok := obj.(*object.Ingress)
+	if !ok {
+		return nil, errObj
+	}
+	idx := make([]string, len(ing.Hosts))
+	for i, h := range ing.Hosts {
+		idx[i] = hostKey(h)
+	}
+	return idx, nil
+}
+
+func gatewayNameNamespaceIndexFunc(obj interface{}) ([]string, error) {
+	g, ok := obj.(*object.Gateway)
+	if !ok {
+		return nil, errObj
+	}
+	return []string{g.Index}, nil
+}
+
+func gatewayHostIndexFunc(obj interface{}) ([]string, error) {
+	g, ok := obj.(*object.Gateway)
+	if !ok {
+		return nil, errObj
+	}
+	idx := make([]string, len(g.Hostnames))
+	for i, h := range g.Hostnames {
+		idx[i] = hostKey(h)
+	}
+	return idx, nil
+}
+
+func httpRouteHostIndexFunc(obj interface{}) ([]string, error) {
+	r, ok := obj.(*object.HTTPRoute)
+	if !ok {
+		return nil, errObj
+	}
+	idx := make([]string, len(r.Hostnames))
+	for i, h := range r.Hostnames {
+		idx[i] = hostKey(h)
+	}
+	return idx, nil
+}
+
 func epIPIndexFunc(obj interface{}) ([]string, error) {
 	ep, ok := obj.(*object.Endpoints)
 	if !ok {
@@ -317,6 +534,24 @@ func endpointsListFunc(ctx context.Context, c kubernetes.Interface, ns string, s
 	}
 }
 
+func ingressListFunc(ctx context.Context, c kubernetes.Interface, ns string, s labels.Selector) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		if s != nil {
+			opts.LabelSelector = s.String()
+		}
+		return c.NetworkingV1().Ingresses(ns).List(ctx, opts)
+	}
+}
+
+func dynamicListFunc(ctx context.Context, c dynamic.Interface, r schema.GroupVersionResource, ns string, s labels.Selector) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		if s != nil {
+			opts.LabelSelector = s.String()
+		}
+		return c.Resource(r).Namespace(ns).List(ctx, opts)
+	}
+}
+
 func namespaceListFunc(ctx context.Context, c kubernetes.Interface, s labels.Selector) func(meta.ListOptions) (runtime.Object, error) {
 	return func(opts meta.ListOptions) (runtime.Object, error) {
 		if s != nil {
@@ -326,6 +561,12 @@ func namespaceListFunc(ctx context.Context, c kubernetes.Interface, s labels.Sel
 	}
 }
 
+func nodeListFunc(ctx context.Context, c kubernetes.Interface) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		return c.CoreV1().Nodes().List(ctx, opts)
+	}
+}
+
 func serviceWatchFunc(ctx context.Context, c kubernetes.Interface, ns string, s labels.Selector) func(options meta.ListOptions) (watch.Interface, error) {
 	return func(options meta.ListOptions) (watch.Interface, error) {
 		if s != nil {
@@ -375,6 +616,24 @@ func endpointsWatchFunc(ctx context.Context, c kubernetes.Interface, ns string,
 	}
 }
 
+func ingressWatchFunc(ctx context.Context, c kubernetes.Interface, ns string, s labels.Selector) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		if s != nil {
+			options.LabelSelector = s.String()
+		}
+		return c.NetworkingV1().Ingresses(ns).Watch(ctx, options)
+	}
+}
+
+func dynamicWatchFunc(ctx context.Context, c dynamic.Interface, r schema.GroupVersionResource, ns string, s labels.Selector) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		if s != nil {
+			options.LabelSelector = s.String()
+		}
+		return c.Resource(r).Namespace(ns).Watch(ctx, options)
+	}
+}
+
 func namespaceWatchFunc(ctx context.Context, c kubernetes.Interface, s labels.Selector) func(options meta.ListOptions) (watch.Interface, error) {
 	return func(options meta.ListOptions) (watch.Interface, error) {
 		if s != nil {
@@ -384,6 +643,12 @@ func namespaceWatchFunc(ctx context.Context, c kubernetes.Interface, s labels.Se
 	}
 }
 
+func nodeWatchFunc(ctx context.Context, c kubernetes.Interface) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		return c.CoreV1().Nodes().Watch(ctx, options)
+	}
+}
+
 // Stop stops the  controller.
 func (dns *dnsControl) Stop() error {
 	dns.stopLock.Lock()
@@ -414,6 +679,9 @@ func (dns *dnsControl) Run() {
 		go dns.podController.Run(dns.stopCh)
 	}
 	go dns.nsController.Run(dns.stopCh)
+	for _, c := range dns.optionalControllers() {
+		go c.Run(dns.stopCh)
+	}
 	<-dns.stopCh
 }
 
@@ -431,7 +699,26 @@ func (dns *dnsControl) HasSynced() bool {
 		c = dns.podController.HasSynced()
 	}
 	d := dns.nsController.HasSynced()
-	return a && b && c && d
+	e := true
+	for _, c := range dns.optionalControllers() {
+		e = e && c.HasSynced()
+	}
+	return a && b && c && d && e
+}
+
+// optionalControllers returns the controllers that are only set up in some configurations.
+func (dns *dnsControl) optionalControllers() []cache.Controller {
+	var cs []cache.Controller
+	for _, c := range []cache.Controller{
+		dns.nodeController,
+		dns.svcImportController, dns.mcEpController,
+		dns.dnsEndpointController, dns.ingressController, dns.gatewayController, dns.httpRouteController,
+	} {
+		if c != nil {
+			cs = append(cs, c)
+		}
+	}
+	return cs
 }
 
 func (dns *dnsControl) ServiceList() (svcs []*object.Service) {
@@ -555,6 +842,72 @@ func (dns *dnsControl) EpIndexReverse(ip string) (ep []*object.Endpoints) {
 	return ep
 }
 
+func (dns *dnsControl) ServiceImportList() (svcs []*object.ServiceImport) {
+	if dns.svcImportLister == nil {
+		return nil
+	}
+	os := dns.svcImportLister.List()
+	for _, o := range os {
+		s, ok := o.(*object.ServiceImport)
+		if !ok {
+			continue
+		}
+		svcs = append(svcs, s)
+	}
+	return svcs
+}
+
+func (dns *dnsControl) MultiClusterEndpointsList() (eps []*object.MultiClusterEndpoints) {
+	if dns.mcEpLister == nil {
+		return nil
+	}
+	os := dns.mcEpLister.List()
+	for _, o := range os {
+		ep, ok := o.(*object.MultiClusterEndpoints)
+		if !ok {
+			continue
+		}
+		eps = append(eps, ep)
+	}
+	return eps
+}
+
+func (dns *dnsControl) SvcImportIndex(idx string) (svcs []*object.ServiceImport) {
+	if dns.svcImportLister == nil {
+		return nil
+	}
+	os, err := dns.svcImportLister.ByIndex(svcNameNamespaceIndex, idx)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		s, ok := o.(*object.ServiceImport)
+		if !ok {
+			continue
+		}
+		svcs = append(svcs, s)
+	}
+	return svcs
+}
+
+func (dns *dnsControl) MCEpIndex(idx string) (ep []*object.MultiClusterEndpoints) {
+	if dns.mcEpLister == nil {
+		return nil
+	}
+	os, err := dns.mcEpLister.ByIndex(epNameNamespaceIndex, idx)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		e, ok := o.(*object.MultiClusterEndpoints)
+		if !ok {
+			continue
+		}
+		ep = append(ep, e)
+	}
+	return ep
+}
+
 // GetNodeByName return the node by name. If nothing is found an error is
 // returned. This query causes a roundtrip to the k8s API server, so use
 // sparingly. Currently this is only used for Federation.
@@ -563,6 +916,112 @@ func (dns *dnsControl) GetNodeByName(ctx context.Context, name string) (*api.Nod
 	return v1node, err
 }
 
+func (dns *dnsControl) DNSEndpointIndex(idx string) (ds []*object.DNSEndpoint) {
+	if dns.dnsEndpointLister == nil {
+		return nil
+	}
+	os, err := dns.dnsEndpointLister.ByIndex(externalHostIndex, idx)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		x, ok := o.(*object.DNSEndpoint)
+		if !ok {
+			continue
+		}
+		ds = append(ds, x)
+	}
+	return ds
+}
+
+func (dns *dnsControl) IngressIndex(idx string) (ings []*object.Ingress) {
+	if dns.ingressLister == nil {
+		return nil
+	}
+	os, err := dns.ingressLister.ByIndex(externalHostIndex, idx)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		x, ok := o.(*object.Ingress)
+		if !ok {
+			continue
+		}
+		ings = append(ings, x)
+	}
+	return ings
+}
+
+func (dns *dnsControl) GatewayIndex(idx string) (gws []*object.Gateway) {
+	if dns.gatewayLister == nil {
+		return nil
+	}
+	os, err := dns.gatewayLister.ByIndex(svcNameNamespaceIndex, idx)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		x, ok := o.(*object.Gateway)
+		if !ok {
+			continue
+		}
+		gws = append(gws, x)
+	}
+	return gws
+}
+
+func (dns *dnsControl) GatewayHostIndex(idx string) (gws []*object.Gateway) {
+	if dns.gatewayLister == nil {
+		return nil
+	}
+	os, err := dns.gatewayLister.ByIndex(externalHostIndex, idx)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		x, ok := o.(*object.Gateway)
+		if !ok {
+			continue
+		}
+		gws = append(gws, x)
+	}
+	return gws
+}
+
+func (dns *dnsControl) HTTPRouteIndex(idx string) (routes []*object.HTTPRoute) {
+	if dns.httpRouteLister == nil {
+		return nil
+	}
+	os, err := dns.httpRouteLister.ByIndex(externalHostIndex, idx)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		x, ok := o.(*object.HTTPRoute)
+		if !ok {
+			continue
+		}
+		routes = append(routes, x)
+	}
+	return routes
+}
+
+// NodeZone returns the topology zone of the node name, or the empty string when it is not known.
+func (dns *dnsControl) NodeZone(name string) string {
+	if dns.nodeLister == nil || name == "" {
+		return ""
+	}
+	o, exists, err := dns.nodeLister.GetByKey(name)
+	if err != nil || !exists {
+		return ""
+	}
+	n, ok := o.(*object.Node)
+	if !ok {
+		return ""
+	}
+	return n.Zone
+}
+
 // GetNamespaceByName returns the namespace by name. If nothing is found an error is returned.
 func (dns *dnsControl) GetNamespaceByName(name string) (*api.Namespace, error) {
 	os := dns.nsLister.List()
@@ -601,6 +1060,17 @@ func (dns *dnsControl) detectChanges(oldObj, newObj interface{}) {
 		if !endpointsEquivalent(oldObj.(*object.Endpoints), newObj.(*object.Endpoints)) {
 			dns.updateModifed()
 		}
+	case *object.Node:
+		if oldObj.(*object.Node).Zone != newObj.(*object.Node).Zone {
+			dns.updateModifed()
+		}
+	case *object.ServiceImport, *object.DNSEndpoint, *object.Ingress, *object.Gateway, *object.HTTPRoute:
+		dns.updateModifed()
+	case *object.MultiClusterEndpoints:
+		a, b := oldObj.(*object.MultiClusterEndpoints), newObj.(*object.MultiClusterEndpoints)
+		if a.ClusterID != b.ClusterID || !endpointsEquivalent(&a.Endpoints, &b.Endpoints) {
+			dns.updateModifed()
+		}
 	default:
 		log.Warningf("Updates for %T not supported.", ob)
 	}
@@ -628,6 +1098,9 @@ func subsetsEquivalent(sa, sb object.EndpointSubset) bool {
 		if aaddr.Hostname != baddr.Hostname {
 			return false
 		}
+		if aaddr.Zone != baddr.Zone || strings.Join(aaddr.ZoneHints, ",") != strings.Join(baddr.ZoneHints, ",") {
+			return false
+		}
 	}
 
 	for port, aport := range sa.Ports {
@@ -1,6 +1,10 @@
 package kubernetes
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
 	"strings"
 
 	"github.com/coredns/coredns/plugin/etcd/msg"
@@ -9,11 +13,68 @@ import (
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
 )
 
+// The sources of the records of the external plugin, besides services.
+const (
+	ExternalSourceDNSEndpoint = "dnsendpoint"
+	ExternalSourceGateway     = "gateway"
+	ExternalSourceIngress     = "ingress"
+)
+
+// ExternalSources implements the ExternalSources call from the external plugin. It enables the watches of the
+// sources, and must be called before the plugin starts up.
+func (k *Kubernetes) ExternalSources(sources []string) error {
+	for _, s := range sources {
+		switch s {
+		case ExternalSourceDNSEndpoint, ExternalSourceGateway, ExternalSourceIngress:
+		default:
+			return fmt.Errorf("unknown external source: %q", s)
+		}
+		if k.externalSources == nil {
+			k.externalSources = map[string]bool{}
+		}
+		k.externalSources[s] = true
+	}
+	return nil
+}
+
+// watchExternalSources sets up the watches of the enabled external sources.
+func (k *Kubernetes) watchExternalSources(ctx context.Context, dns *dnsControl, config *rest.Config) error {
+	if len(k.externalSources) == 0 {
+		return nil
+	}
+	if k.externalSources[ExternalSourceIngress] {
+		dns.WatchIngresses(ctx)
+	}
+	if !k.externalSources[ExternalSourceDNSEndpoint] && !k.externalSources[ExternalSourceGateway] {
+		return nil
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes external sources controller: %q", err)
+	}
+	if k.externalSources[ExternalSourceDNSEndpoint] {
+		dns.WatchDNSEndpoints(ctx, client)
+	}
+	if k.externalSources[ExternalSourceGateway] {
+		dns.WatchGateways(ctx, client)
+	}
+	return nil
+}
+
 // External implements the ExternalFunc call from the external plugin.
-// It returns any services matching in the services' ExternalIPs.
+// It returns any services matching in the services' ExternalIPs, or the records of the host names
+// declared in the external sources.
 func (k *Kubernetes) External(state request.Request) ([]msg.Service, int) {
+	if len(k.externalSources) > 0 {
+		if services, found := k.externalHost(state); found {
+			return services, dns.RcodeSuccess
+		}
+	}
+
 	base, _ := dnsutil.TrimZone(state.Name(), state.Zone)
 
 	segs := dns.SplitDomainName(base)
@@ -92,3 +153,130 @@ func (k *Kubernetes) ExternalAddress(state request.Request) []dns.RR {
 	// plugin to bind to a different IP address.
 	return k.nsAddrs(true, state.Zone)
 }
+
+// externalHost returns the records for the query name from the external sources, that match the query type. If
+// the name is not declared in any source, the records of the closest wildcard name covering it are returned. The
+// boolean is false if neither exists.
+func (k *Kubernetes) externalHost(state request.Request) ([]msg.Service, bool) {
+	name := state.Name()
+	if services, found := k.externalHostRecords(name, state); found {
+		return services, true
+	}
+	for name != state.Zone {
+		off, end := dns.NextLabel(name, 0)
+		if end {
+			break
+		}
+		name = name[off:]
+		if services, found := k.externalHostRecords("*."+name, state); found {
+			return services, true
+		}
+	}
+	return nil, false
+}
+
+// externalHostRecords returns the records for name from the external sources, and whether name exists.
+func (k *Kubernetes) externalHostRecords(name string, state request.Request) (services []msg.Service, found bool) {
+	key := hostKey(name)
+	qtype := state.QType()
+	zonePath := msg.Path(state.QName(), coredns)
+
+	if k.externalSources[ExternalSourceDNSEndpoint] {
+		for _, d := range k.APIConn.DNSEndpointIndex(key) {
+			if !k.namespaceExposed(d.Namespace) {
+				continue
+			}
+			for _, e := range d.Endpoints {
+				if hostKey(e.DNSName) != key {
+					continue
+				}
+				found = true
+				services = append(services, k.externalRecords(e.RecordType, e.Targets, qtype, zonePath)...)
+			}
+		}
+	}
+
+	if k.externalSources[ExternalSourceIngress] {
+		for _, ing := range k.APIConn.IngressIndex(key) {
+			if !k.namespaceExposed(ing.Namespace) {
+				continue
+			}
+			found = true
+			services = append(services, k.externalAddresses(ing.Addresses, qtype, zonePath)...)
+		}
+	}
+
+	if k.externalSources[ExternalSourceGateway] {
+		for _, g := range k.APIConn.GatewayHostIndex(key) {
+			if !k.namespaceExposed(g.Namespace) {
+				continue
+			}
+			found = true
+			services = append(services, k.externalAddresses(g.Addresses, qtype, zonePath)...)
+		}
+		for _, r := range k.APIConn.HTTPRouteIndex(key) {
+			if !k.namespaceExposed(r.Namespace) || len(r.Gateways) == 0 {
+				continue
+			}
+			found = true
+			for _, idx := range r.Gateways {
+				for _, g := range k.APIConn.GatewayIndex(idx) {
+					services = append(services, k.externalAddresses(g.Addresses, qtype, zonePath)...)
+				}
+			}
+		}
+	}
+	return services, found
+}
+
+// externalAddresses returns the records for the IP addresses or host names addrs that match qtype.
+func (k *Kubernetes) externalAddresses(addrs []string, qtype uint16, zonePath string) (services []msg.Service) {
+	for _, a := range addrs {
+		typ := "CNAME"
+		if ip := net.ParseIP(a); ip != nil {
+			typ = "A"
+			if ip.To4() == nil {
+				typ = "AAAA"
+			}
+		}
+		services = append(services, k.externalRecords(typ, []string{a}, qtype, zonePath)...)
+	}
+	return services
+}
+
+// externalRecords returns the records of type typ with targets that match qtype. CNAME records match A and AAAA
+// queries too.
+func (k *Kubernetes) externalRecords(typ string, targets []string, qtype uint16, zonePath string) (services []msg.Service) {
+	for _, t := range targets {
+		s := msg.Service{TTL: k.ttl, Key: zonePath}
+		switch {
+		case typ == "A" && qtype == dns.TypeA, typ == "AAAA" && qtype == dns.TypeAAAA:
+			ip := net.ParseIP(t)
+			if ip == nil || (ip.To4() != nil) != (typ == "A") {
+				continue
+			}
+			s.Host = t
+		case typ == "CNAME" && (qtype == dns.TypeCNAME || qtype == dns.TypeA || qtype == dns.TypeAAAA):
+			s.Host = t
+		case typ == "TXT" && qtype == dns.TypeTXT:
+			s.Text = t
+		case typ == "SRV" && qtype == dns.TypeSRV:
+			// priority weight port target
+			f := strings.Fields(t)
+			if len(f) != 4 {
+				continue
+			}
+			priority, err1 := strconv.Atoi(f[0])
+			weight, err2 := strconv.Atoi(f[1])
+			port, err3 := strconv.Atoi(f[2])
+			if err1 != nil || err2 != nil || err3 != nil {
+				continue
+			}
+			s.Priority, s.Weight, s.Port, s.Host = priority, weight, port, f[3]
+		default:
+			continue
+		}
+		services = append(services, s)
+	}
+	return services
+}
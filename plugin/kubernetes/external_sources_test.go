@@ -0,0 +1,170 @@
+package kubernetes
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	api "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var extSourcesCases = []struct {
+	Qname string
+	Qtype uint16
+	Rcode int
+	Hosts []string // hosts or texts of the services
+}{
+	// DNSEndpoint
+	{Qname: "www.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess, Hosts: []string{"1.2.3.4"}},
+	{Qname: "www.example.org.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess},
+	{Qname: "www.example.org.", Qtype: dns.TypeTXT, Rcode: dns.RcodeSuccess, Hosts: []string{"hello"}},
+	{Qname: "_http._tcp.www.example.org.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess, Hosts: []string{"www.example.org"}},
+	// Ingress
+	{Qname: "shop.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess, Hosts: []string{"10.1.1.1", "lb.cloud.example.net"}},
+	{Qname: "shop.example.org.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess, Hosts: []string{"lb.cloud.example.net"}},
+	// Gateway listener, with a wildcard hostname
+	{Qname: "foo.gw.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess, Hosts: []string{"10.2.2.2"}},
+	// HTTPRoute attached to the gateway
+	{Qname: "api.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess, Hosts: []string{"10.2.2.2"}},
+	// HTTPRoute not accepted by the gateway
+	{Qname: "rejected.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError},
+	// Not declared anywhere, falls through to the services
+	{Qname: "svc1.testns.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError},
+}
+
+func TestExternalSources(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		&api.Namespace{ObjectMeta: meta.ObjectMeta{Name: "testns"}},
+		&networking.Ingress{
+			ObjectMeta: meta.ObjectMeta{Name: "shop", Namespace: "testns"},
+			Spec:       networking.IngressSpec{Rules: []networking.IngressRule{{Host: "Shop.example.org"}}},
+			Status: networking.IngressStatus{LoadBalancer: api.LoadBalancerStatus{
+				Ingress: []api.LoadBalancerIngress{{IP: "10.1.1.1"}, {Hostname: "lb.cloud.example.net"}},
+			}},
+		},
+	)
+	dynClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			object.DNSEndpointResource: "DNSEndpointList",
+			object.GatewayResource:     "GatewayList",
+			object.HTTPRouteResource:   "HTTPRouteList",
+		},
+		extUnstructured("externaldns.k8s.io/v1alpha1", "DNSEndpoint", "www", map[string]interface{}{
+			"endpoints": []interface{}{
+				map[string]interface{}{"dnsName": "www.example.org", "recordType": "A", "targets": []interface{}{"1.2.3.4"}},
+				map[string]interface{}{"dnsName": "www.example.org", "recordType": "TXT", "targets": []interface{}{"hello"}},
+				map[string]interface{}{"dnsName": "_http._tcp.www.example.org", "recordType": "SRV", "targets": []interface{}{"0 50 80 www.example.org"}},
+			},
+		}, nil),
+		extUnstructured("gateway.networking.k8s.io/v1beta1", "HTTPRoute", "api", map[string]interface{}{
+			"hostnames":  []interface{}{"api.example.org"},
+			"parentRefs": []interface{}{map[string]interface{}{"name": "gw"}},
+		}, routeStatus("gw", "True")),
+		extUnstructured("gateway.networking.k8s.io/v1beta1", "HTTPRoute", "rejected", map[string]interface{}{
+			"hostnames":  []interface{}{"rejected.example.org"},
+			"parentRefs": []interface{}{map[string]interface{}{"name": "gw"}},
+		}, routeStatus("gw", "False")),
+	)
+
+	// The fake client guesses the wrong resource for a Gateway, so create it explicitly.
+	gw := extUnstructured("gateway.networking.k8s.io/v1beta1", "Gateway", "gw", map[string]interface{}{
+		"listeners": []interface{}{map[string]interface{}{"name": "http", "hostname": "*.gw.example.org"}},
+	}, map[string]interface{}{
+		"addresses": []interface{}{map[string]interface{}{"type": "IPAddress", "value": "10.2.2.2"}},
+	})
+	if _, err := dynClient.Resource(object.GatewayResource).Namespace("testns").Create(ctx, gw, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	k := New([]string{"cluster.local."})
+	if err := k.ExternalSources([]string{ExternalSourceDNSEndpoint, ExternalSourceGateway, ExternalSourceIngress}); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := newdnsController(ctx, client, dnsControlOpts{initEndpointsCache: true, zones: k.Zones})
+	ctrl.WatchDNSEndpoints(ctx, dynClient)
+	ctrl.WatchGateways(ctx, dynClient)
+	ctrl.WatchIngresses(ctx)
+	k.APIConn = ctrl
+	go ctrl.Run()
+	defer ctrl.Stop()
+
+	for i := 0; !ctrl.HasSynced(); i++ {
+		if i > 50 {
+			t.Fatal("Controller failed to sync")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	for i, tc := range extSourcesCases {
+		m := new(dns.Msg).SetQuestion(tc.Qname, tc.Qtype)
+		state := request.Request{W: &test.ResponseWriter{}, Req: m, Zone: "example.org."}
+
+		svc, rcode := k.External(state)
+
+		if x := tc.Rcode; x != rcode {
+			t.Errorf("Test %d, expected rcode %d, got %d", i, x, rcode)
+		}
+
+		hosts := []string{}
+		for _, s := range svc {
+			if s.Text != "" {
+				hosts = append(hosts, s.Text)
+				continue
+			}
+			hosts = append(hosts, s.Host)
+		}
+		sort.Strings(hosts)
+		if len(hosts) != len(tc.Hosts) {
+			t.Errorf("Test %d, expected %v, got %v", i, tc.Hosts, hosts)
+			continue
+		}
+		for j := range hosts {
+			if hosts[j] != tc.Hosts[j] {
+				t.Errorf("Test %d, expected %v, got %v", i, tc.Hosts, hosts)
+				break
+			}
+		}
+	}
+
+	if err := k.ExternalSources([]string{"service"}); err == nil {
+		t.Error("Expected error for unknown source")
+	}
+}
+
+func extUnstructured(apiVersion, kind, name string, spec, status map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": "testns"},
+		"spec":       spec,
+	}}
+	if status != nil {
+		u.Object["status"] = status
+	}
+	return u
+}
+
+// routeStatus returns the status of a route with the Accepted condition for the gateway.
+func routeStatus(gateway, accepted string) map[string]interface{} {
+	return map[string]interface{}{
+		"parents": []interface{}{map[string]interface{}{
+			"parentRef":  map[string]interface{}{"name": gateway},
+			"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": accepted}},
+		}},
+	}
+}
@@ -550,12 +550,23 @@ type APIConnServeTest struct {
 	notSynced bool
 }
 
-func (a APIConnServeTest) HasSynced() bool                         { return !a.notSynced }
-func (APIConnServeTest) Run()                                      {}
-func (APIConnServeTest) Stop() error                               { return nil }
-func (APIConnServeTest) EpIndexReverse(string) []*object.Endpoints { return nil }
-func (APIConnServeTest) SvcIndexReverse(string) []*object.Service  { return nil }
-func (APIConnServeTest) Modified() int64                           { return int64(3) }
+func (a APIConnServeTest) HasSynced() bool                                          { return !a.notSynced }
+func (APIConnServeTest) Run()                                                       {}
+func (APIConnServeTest) Stop() error                                                { return nil }
+func (APIConnServeTest) EpIndexReverse(string) []*object.Endpoints                  { return nil }
+func (APIConnServeTest) SvcIndexReverse(string) []*object.Service                   { return nil }
+func (APIConnServeTest) ExternalNameIndex(string) []*object.Service                 { return nil }
+func (APIConnServeTest) ServiceImportList() []*object.ServiceImport                 { return nil }
+func (APIConnServeTest) MultiClusterEndpointsList() []*object.MultiClusterEndpoints { return nil }
+func (APIConnServeTest) SvcImportIndex(string) []*object.ServiceImport              { return nil }
+func (APIConnServeTest) MCEpIndex(string) []*object.MultiClusterEndpoints           { return nil }
+func (APIConnServeTest) NodeZone(string) string                                     { return "" }
+func (APIConnServeTest) DNSEndpointIndex(string) []*object.DNSEndpoint              { return nil }
+func (APIConnServeTest) IngressIndex(string) []*object.Ingress                      { return nil }
+func (APIConnServeTest) GatewayIndex(string) []*object.Gateway                      { return nil }
+func (APIConnServeTest) GatewayHostIndex(string) []*object.Gateway                  { return nil }
+func (APIConnServeTest) HTTPRouteIndex(string) []*object.HTTPRoute                  { return nil }
+func (APIConnServeTest) Modified() int64                                            { return int64(3) }
 
 func (APIConnServeTest) PodIndex(ip string) []*object.Pod {
 	if ip != "10.240.0.1" {
@@ -25,6 +25,7 @@ import (
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -45,6 +46,12 @@ type Kubernetes struct {
 	Namespaces       map[string]struct{}
 	podMode          string
 	endpointNameMode bool
+	// topologyAware answers headless services with the endpoints in the zone of the client, when there are at
+	// least topologyMinEndpoints of them.
+	topologyAware        bool
+	topologyMinEndpoints int
+	// externalSources are the kinds of objects, besides services, that are served by the k8s_external plugin.
+	externalSources  map[string]bool
 	Fall             fall.F
 	ttl              uint32
 	opts             dnsControlOpts
@@ -245,16 +252,30 @@ func (k *Kubernetes) InitKubeCache(ctx context.Context) (onStart func() error, o
 		k.opts.namespaceSelector = selector
 	}
 
-	k.opts.initPodCache = k.podMode == podModeVerified
+	k.opts.initPodCache = k.podMode == podModeVerified || k.topologyAware
+	k.opts.initNodeCache = k.topologyAware
 
 	k.opts.zones = k.Zones
 	k.opts.endpointNameMode = k.endpointNameMode
 
-	k.APIConn = newdnsController(ctx, kubeClient, k.opts)
+	dns := newdnsController(ctx, kubeClient, k.opts)
+	if len(k.opts.multiclusterZones) > 0 {
+		mcsClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create kubernetes multicluster notification controller: %q", err)
+		}
+		dns.WatchMultiCluster(ctx, mcsClient)
+	}
+	k.APIConn = dns
 
 	initEndpointWatch := k.opts.initEndpointsCache
 
 	onStart = func() error {
+		// The k8s_external plugin enables its sources when it starts up, which is before we do.
+		if err := k.watchExternalSources(ctx, dns, config); err != nil {
+			return err
+		}
+
 		go func() {
 			if initEndpointWatch {
 				// Revert to watching Endpoints for incompatible K8s.
@@ -347,13 +368,14 @@ func (k *Kubernetes) endpointSliceSupported(kubeClient *kubernetes.Clientset) (b
 
 // Records looks up services in kubernetes.
 func (k *Kubernetes) Records(ctx context.Context, state request.Request, exact bool) ([]msg.Service, error) {
-	r, e := parseRequest(state.Name(), state.Zone)
+	multicluster := k.isMultiCluster(state.Zone)
+	r, e := parseRequest(state.Name(), state.Zone, multicluster)
 	if e != nil {
 		return nil, e
 	}
 	if r.podOrSvc == "" {
 		if r.name != "" {
-			services, err := k.findServices(r, state.Zone)
+			services, err := k.findServices(r, state.Zone, "")
 			return services, err
 		}
 		return nil, nil
@@ -367,15 +389,39 @@ func (k *Kubernetes) Records(ctx context.Context, state request.Request, exact b
 		return nil, errNsNotExposed
 	}
 
+	if multicluster {
+		// There are no pod records in a multicluster zone.
+		if r.podOrSvc == Pod {
+			return nil, errNoItems
+		}
+		services, err := k.findMultiClusterServices(r, state.Zone)
+		return services, err
+	}
+
 	if r.podOrSvc == Pod {
 		pods, err := k.findPods(r, state.Zone)
 		return pods, err
 	}
 
-	services, err := k.findServices(r, state.Zone)
+	client := ""
+	if k.topologyAware {
+		client = state.IP()
+	}
+	services, err := k.findServices(r, state.Zone, client)
 	return services, err
 }
 
+// isMultiCluster returns true if zone is a multicluster zone, in which the services imported from the cluster set
+// are served.
+func (k *Kubernetes) isMultiCluster(zone string) bool {
+	for _, z := range k.opts.multiclusterZones {
+		if strings.EqualFold(z, zone) {
+			return true
+		}
+	}
+	return false
+}
+
 func endpointHostname(addr object.EndpointAddress, endpointNameMode bool) string {
 	if addr.Hostname != "" {
 		return addr.Hostname
@@ -462,11 +508,13 @@ func (k *Kubernetes) findPods(r recordRequest, zone string) (pods []msg.Service,
 }
 
 // findServices returns the services matching r from the cache.
-func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.Service, err error) {
+// The client IP is used to select the endpoints of headless services in the topology_aware mode.
+func (k *Kubernetes) findServices(r recordRequest, zone, client string) (services []msg.Service, err error) {
 	var (
 		endpointsListFunc func() []*object.Endpoints
 		endpointsList     []*object.Endpoints
 		serviceList       []*object.Service
+		clientZone        *string
 	)
 	if r.name != "" {
 		serviceList = k.APIConn.ExternalNameIndex(r.name)
@@ -485,26 +533,9 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 			return services, err
 		}
 	}
-	if !wildcard(r.namespace) && !k.namespaceExposed(r.namespace) {
-		return nil, errNoItems
-	}
-
-	// handle empty service name
-	if r.service == "" {
-		if k.namespaceExposed(r.namespace) || wildcard(r.namespace) {
-			// NODATA
-			return nil, nil
-		}
-		// NXDOMAIN
-		return nil, errNoItems
-	}
-
-	err = errNoItems
-	if wildcard(r.service) && !wildcard(r.namespace) {
-		// If namespace exists, err should be nil, so that we return NODATA instead of NXDOMAIN
-		if k.namespaceExposed(r.namespace) {
-			err = nil
-		}
+	done, err := k.checkServiceRequest(r)
+	if done {
+		return nil, err
 	}
 
 	if wildcard(r.service) || wildcard(r.namespace) {
@@ -518,27 +549,14 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 
 	zonePath := msg.Path(zone, coredns)
 	for _, svc := range serviceList {
-		if !(match(r.namespace, svc.Namespace) && match(r.service, svc.Name)) {
-			continue
-		}
-
-		// If request namespace is a wildcard, filter results against Corefile namespace list.
-		// (Namespaces without a wildcard were filtered before the call to this function.)
-		if wildcard(r.namespace) && !k.namespaceExposed(svc.Namespace) {
+		if !k.matchService(r, svc.Name, svc.Namespace) {
 			continue
 		}
 
 		// If "ignore empty_service" option is set and no endpoints exist, return NXDOMAIN unless
 		// it's a headless or externalName service (covered below).
 		if k.opts.ignoreEmptyService && svc.Type != api.ServiceTypeExternalName && !svc.Headless() { // serve NXDOMAIN if no endpoint is able to answer
-			podsCount := 0
-			for _, ep := range endpointsListFunc() {
-				for _, eps := range ep.Subsets {
-					podsCount = podsCount + len(eps.Addresses)
-				}
-			}
-
-			if podsCount == 0 {
+			if !hasAddresses(endpointsListFunc()) {
 				continue
 			}
 		}
@@ -561,51 +579,268 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 				endpointsList = endpointsListFunc()
 			}
 
+			var inZone func(object.EndpointAddress) bool
+			if k.topologyAware && svc.Headless() && r.endpoint == "" {
+				if clientZone == nil {
+					z := k.clientZone(client)
+					clientZone = &z
+				}
+				inZone = k.topologyFilter(endpointsList, object.EndpointsKey(svc.Name, svc.Namespace), *clientZone)
+			}
+
 			for _, ep := range endpointsList {
 				if object.EndpointsKey(svc.Name, svc.Namespace) != ep.Index {
 					continue
 				}
+				if s := k.endpointServices(r, ep, inZone, zonePath, Svc, svc.Namespace, svc.Name); len(s) > 0 {
+					services = append(services, s...)
+					err = nil
+				}
+			}
+			continue
+		}
+
+		// ClusterIP service
+		if s, ok := k.portServices(r, svc.Ports, svc.ClusterIPs, zonePath, Svc, svc.Namespace, svc.Name); ok {
+			services = append(services, s...)
+			err = nil
+		}
+	}
+	return services, err
+}
+
+// checkServiceRequest checks the namespace and service of r before the services are searched. If the
+// reply doesn't depend on the services it returns true, with the error to return (nil for NODATA).
+// Otherwise it returns false, and an error if the reply is NXDOMAIN when no service matches.
+func (k *Kubernetes) checkServiceRequest(r recordRequest) (bool, error) {
+	if !wildcard(r.namespace) && !k.namespaceExposed(r.namespace) {
+		return true, errNoItems
+	}
 
-				for _, eps := range ep.Subsets {
-					for _, addr := range eps.Addresses {
+	// handle empty service name
+	if r.service == "" {
+		if k.namespaceExposed(r.namespace) || wildcard(r.namespace) {
+			// NODATA
+			return true, nil
+		}
+		// NXDOMAIN
+		return true, errNoItems
+	}
 
-						// See comments in parse.go parseRequest about the endpoint handling.
-						if r.endpoint != "" {
-							if !match(r.endpoint, endpointHostname(addr, k.endpointNameMode)) {
-								continue
-							}
-						}
+	// If namespace exists, err should be nil, so that we return NODATA instead of NXDOMAIN
+	if wildcard(r.service) && !wildcard(r.namespace) && k.namespaceExposed(r.namespace) {
+		return false, nil
+	}
+	return false, errNoItems
+}
 
-						for _, p := range eps.Ports {
-							if !(match(r.port, p.Name) && match(r.protocol, string(p.Protocol))) {
-								continue
-							}
-							s := msg.Service{Host: addr.IP, Port: int(p.Port), TTL: k.ttl}
-							s.Key = strings.Join([]string{zonePath, Svc, svc.Namespace, svc.Name, endpointHostname(addr, k.endpointNameMode)}, "/")
+// matchService returns true if the service with name and namespace matches r.
+func (k *Kubernetes) matchService(r recordRequest, name, namespace string) bool {
+	if !(match(r.namespace, namespace) && match(r.service, name)) {
+		return false
+	}
+	// If request namespace is a wildcard, filter results against Corefile namespace list.
+	// (Namespaces without a wildcard were filtered by checkServiceRequest.)
+	return !wildcard(r.namespace) || k.namespaceExposed(namespace)
+}
 
-							err = nil
+// hasAddresses returns true if any of endpoints has an address.
+func hasAddresses(endpoints []*object.Endpoints) bool {
+	for _, ep := range endpoints {
+		for _, eps := range ep.Subsets {
+			if len(eps.Addresses) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
 
-							services = append(services, s)
-						}
+// endpointServices returns a service for each address and port of ep that match r, and that inZone, when not nil,
+// returns true for. The key of a service is made of key and the hostname of the address.
+func (k *Kubernetes) endpointServices(r recordRequest, ep *object.Endpoints, inZone func(object.EndpointAddress) bool, key ...string) []msg.Service {
+	var services []msg.Service
+	for _, eps := range ep.Subsets {
+		for _, addr := range eps.Addresses {
+			// See comments in parse.go parseRequest about the endpoint handling.
+			if r.endpoint != "" {
+				if !match(r.endpoint, endpointHostname(addr, k.endpointNameMode)) {
+					continue
+				}
+			}
+			if inZone != nil && !inZone(addr) {
+				continue
+			}
+
+			for _, p := range eps.Ports {
+				if !(match(r.port, p.Name) && match(r.protocol, string(p.Protocol))) {
+					continue
+				}
+				s := msg.Service{Host: addr.IP, Port: int(p.Port), TTL: k.ttl}
+				s.Key = strings.Join(key, "/") + "/" + endpointHostname(addr, k.endpointNameMode)
+				services = append(services, s)
+			}
+		}
+	}
+	return services
+}
+
+// portServices returns a service for each of ips and each of ports that match r, with key as the key. It returns
+// true if a port matched.
+func (k *Kubernetes) portServices(r recordRequest, ports []api.ServicePort, ips []string, key ...string) ([]msg.Service, bool) {
+	var services []msg.Service
+	matched := false
+	for _, p := range ports {
+		if !(match(r.port, p.Name) && match(r.protocol, string(p.Protocol))) {
+			continue
+		}
+		matched = true
+
+		for _, ip := range ips {
+			s := msg.Service{Host: ip, Port: int(p.Port), TTL: k.ttl}
+			s.Key = strings.Join(key, "/")
+			services = append(services, s)
+		}
+	}
+	return services, matched
+}
+
+// clientZone returns the zone of the node of the pod with the IP client, or the empty string if it is not known.
+func (k *Kubernetes) clientZone(client string) string {
+	for _, p := range k.APIConn.PodIndex(client) {
+		if p.PodIP == client {
+			return k.APIConn.NodeZone(p.NodeName)
+		}
+	}
+	return ""
+}
+
+// endpointZone returns the zone of addr, from its EndpointSlice or else from its node.
+func (k *Kubernetes) endpointZone(addr object.EndpointAddress) string {
+	if addr.Zone != "" {
+		return addr.Zone
+	}
+	return k.APIConn.NodeZone(addr.NodeName)
+}
+
+// topologyFilter returns the function that selects the addresses of the endpoints with index idx that are used to
+// answer a client in zone. When every address has zone hints, the addresses hinted for zone are selected.
+// Otherwise the addresses in zone are, if there are at least k.topologyMinEndpoints of them. If all addresses are
+// to be used, nil is returned.
+func (k *Kubernetes) topologyFilter(endpoints []*object.Endpoints, idx, zone string) func(object.EndpointAddress) bool {
+	if zone == "" {
+		return nil
+	}
+	all, hinted, hintedZone, inZone := 0, 0, 0, 0
+	for _, ep := range endpoints {
+		if ep.Index != idx {
+			continue
+		}
+		for _, eps := range ep.Subsets {
+			for _, addr := range eps.Addresses {
+				all++
+				if len(addr.ZoneHints) > 0 {
+					hinted++
+					if hintsZone(addr, zone) {
+						hintedZone++
 					}
 				}
+				if k.endpointZone(addr) == zone {
+					inZone++
+				}
 			}
+		}
+	}
+
+	switch {
+	case hinted == all && hintedZone > 0 && hintedZone < all:
+		return func(addr object.EndpointAddress) bool { return hintsZone(addr, zone) }
+	case inZone >= k.topologyMinEndpoints && inZone > 0 && inZone < all:
+		return func(addr object.EndpointAddress) bool { return k.endpointZone(addr) == zone }
+	}
+	return nil
+}
+
+// hintsZone returns true if addr has a hint for zone.
+func hintsZone(addr object.EndpointAddress, zone string) bool {
+	for _, z := range addr.ZoneHints {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// findMultiClusterServices returns the imported services matching r from the cache.
+func (k *Kubernetes) findMultiClusterServices(r recordRequest, zone string) (services []msg.Service, err error) {
+	done, err := k.checkServiceRequest(r)
+	if done {
+		return nil, err
+	}
+
+	var (
+		endpointsListFunc func() []*object.MultiClusterEndpoints
+		endpointsList     []*object.MultiClusterEndpoints
+		serviceList       []*object.ServiceImport
+	)
+	if wildcard(r.service) || wildcard(r.namespace) {
+		serviceList = k.APIConn.ServiceImportList()
+		endpointsListFunc = func() []*object.MultiClusterEndpoints { return k.APIConn.MultiClusterEndpointsList() }
+	} else {
+		idx := object.ServiceKey(r.service, r.namespace)
+		serviceList = k.APIConn.SvcImportIndex(idx)
+		endpointsListFunc = func() []*object.MultiClusterEndpoints { return k.APIConn.MCEpIndex(idx) }
+	}
+
+	zonePath := msg.Path(zone, coredns)
+	for _, svc := range serviceList {
+		if !k.matchService(r, svc.Name, svc.Namespace) {
 			continue
 		}
 
-		// ClusterIP service
-		for _, p := range svc.Ports {
-			if !(match(r.port, p.Name) && match(r.protocol, string(p.Protocol))) {
+		// Only headless services have names for their clusters and endpoints.
+		if !svc.Headless() && (r.cluster != "" || r.endpoint != "") {
+			continue
+		}
+
+		// If "ignore empty_service" option is set and no endpoints exist, return NXDOMAIN unless
+		// it's a headless service (covered below).
+		if k.opts.ignoreEmptyService && !svc.Headless() {
+			mces := endpointsListFunc()
+			endpoints := make([]*object.Endpoints, len(mces))
+			for i := range mces {
+				endpoints[i] = &mces[i].Endpoints
+			}
+			if !hasAddresses(endpoints) {
 				continue
 			}
+		}
 
-			err = nil
+		// Headless service
+		if svc.Headless() {
+			if endpointsList == nil {
+				endpointsList = endpointsListFunc()
+			}
 
-			for _, ip := range svc.ClusterIPs {
-				s := msg.Service{Host: ip, Port: int(p.Port), TTL: k.ttl}
-				s.Key = strings.Join([]string{zonePath, Svc, svc.Namespace, svc.Name}, "/")
-				services = append(services, s)
+			for _, ep := range endpointsList {
+				if object.EndpointsKey(svc.Name, svc.Namespace) != ep.Index {
+					continue
+				}
+				if r.cluster != "" && !match(r.cluster, ep.ClusterID) {
+					continue
+				}
+				if s := k.endpointServices(r, &ep.Endpoints, nil, zonePath, Svc, svc.Namespace, svc.Name, ep.ClusterID); len(s) > 0 {
+					services = append(services, s...)
+					err = nil
+				}
 			}
+			continue
+		}
+
+		// ClusterSetIP service
+		if s, ok := k.portServices(r, svc.Ports, svc.IPs, zonePath, Svc, svc.Namespace, svc.Name); ok {
+			services = append(services, s...)
+			err = nil
 		}
 	}
 	return services, err
@@ -60,13 +60,24 @@ func TestEndpointHostname(t *testing.T) {
 
 type APIConnServiceTest struct{}
 
-func (APIConnServiceTest) HasSynced() bool                           { return true }
-func (APIConnServiceTest) Run()                                      {}
-func (APIConnServiceTest) Stop() error                               { return nil }
-func (APIConnServiceTest) PodIndex(string) []*object.Pod             { return nil }
-func (APIConnServiceTest) SvcIndexReverse(string) []*object.Service  { return nil }
-func (APIConnServiceTest) EpIndexReverse(string) []*object.Endpoints { return nil }
-func (APIConnServiceTest) Modified() int64                           { return 0 }
+func (APIConnServiceTest) HasSynced() bool                                            { return true }
+func (APIConnServiceTest) Run()                                                       {}
+func (APIConnServiceTest) Stop() error                                                { return nil }
+func (APIConnServiceTest) PodIndex(string) []*object.Pod                              { return nil }
+func (APIConnServiceTest) SvcIndexReverse(string) []*object.Service                   { return nil }
+func (APIConnServiceTest) ExternalNameIndex(string) []*object.Service                 { return nil }
+func (APIConnServiceTest) ServiceImportList() []*object.ServiceImport                 { return nil }
+func (APIConnServiceTest) MultiClusterEndpointsList() []*object.MultiClusterEndpoints { return nil }
+func (APIConnServiceTest) SvcImportIndex(string) []*object.ServiceImport              { return nil }
+func (APIConnServiceTest) MCEpIndex(string) []*object.MultiClusterEndpoints           { return nil }
+func (APIConnServiceTest) NodeZone(string) string                                     { return "" }
+func (APIConnServiceTest) DNSEndpointIndex(string) []*object.DNSEndpoint              { return nil }
+func (APIConnServiceTest) IngressIndex(string) []*object.Ingress                      { return nil }
+func (APIConnServiceTest) GatewayIndex(string) []*object.Gateway                      { return nil }
+func (APIConnServiceTest) GatewayHostIndex(string) []*object.Gateway                  { return nil }
+func (APIConnServiceTest) HTTPRouteIndex(string) []*object.HTTPRoute                  { return nil }
+func (APIConnServiceTest) EpIndexReverse(string) []*object.Endpoints                  { return nil }
+func (APIConnServiceTest) Modified() int64                                            { return 0 }
 
 func (APIConnServiceTest) SvcIndex(string) []*object.Service {
 	svcs := []*object.Service{
@@ -0,0 +1,255 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	api "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var dnsMultiClusterTestCases = []test.Case{
+	// A ClusterSetIP Service
+	{
+		Qname: "svc1.testns.svc.clusterset.local.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("svc1.testns.svc.clusterset.local.	5	IN	A	10.0.0.1"),
+		},
+	},
+	// SRV ClusterSetIP Service
+	{
+		Qname: "_http._tcp.svc1.testns.svc.clusterset.local.", Qtype: dns.TypeSRV,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.SRV("_http._tcp.svc1.testns.svc.clusterset.local.	5	IN	SRV	0 100 80 svc1.testns.svc.clusterset.local."),
+		},
+		Extra: []dns.RR{
+			test.A("svc1.testns.svc.clusterset.local.	5	IN	A	10.0.0.1"),
+		},
+	},
+	// A ClusterSetIP Service has no cluster names
+	{
+		Qname: "cluster1.svc1.testns.svc.clusterset.local.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("clusterset.local.	5	IN	SOA	ns.dns.clusterset.local. hostmaster.clusterset.local. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// A Headless Service, across the cluster set
+	{
+		Qname: "hdls1.testns.svc.clusterset.local.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("hdls1.testns.svc.clusterset.local.	5	IN	A	172.0.0.2"),
+			test.A("hdls1.testns.svc.clusterset.local.	5	IN	A	172.0.0.3"),
+			test.A("hdls1.testns.svc.clusterset.local.	5	IN	A	172.1.0.2"),
+		},
+	},
+	// A Headless Service in one cluster
+	{
+		Qname: "cluster2.hdls1.testns.svc.clusterset.local.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("cluster2.hdls1.testns.svc.clusterset.local.	5	IN	A	172.1.0.2"),
+		},
+	},
+	// A Headless Service endpoint in one cluster
+	{
+		Qname: "dup-name.cluster1.hdls1.testns.svc.clusterset.local.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("dup-name.cluster1.hdls1.testns.svc.clusterset.local.	5	IN	A	172.0.0.2"),
+		},
+	},
+	{
+		Qname: "dup-name.cluster3.hdls1.testns.svc.clusterset.local.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("clusterset.local.	5	IN	SOA	ns.dns.clusterset.local. hostmaster.clusterset.local. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// SRV Headless Service
+	{
+		Qname: "_http._tcp.hdls1.testns.svc.clusterset.local.", Qtype: dns.TypeSRV,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.SRV("_http._tcp.hdls1.testns.svc.clusterset.local.	5	IN	SRV	0 33 80 172-0-0-3.cluster1.hdls1.testns.svc.clusterset.local."),
+			test.SRV("_http._tcp.hdls1.testns.svc.clusterset.local.	5	IN	SRV	0 33 80 dup-name.cluster1.hdls1.testns.svc.clusterset.local."),
+			test.SRV("_http._tcp.hdls1.testns.svc.clusterset.local.	5	IN	SRV	0 33 80 dup-name.cluster2.hdls1.testns.svc.clusterset.local."),
+		},
+		Extra: []dns.RR{
+			test.A("172-0-0-3.cluster1.hdls1.testns.svc.clusterset.local.	5	IN	A	172.0.0.3"),
+			test.A("dup-name.cluster1.hdls1.testns.svc.clusterset.local.	5	IN	A	172.0.0.2"),
+			test.A("dup-name.cluster2.hdls1.testns.svc.clusterset.local.	5	IN	A	172.1.0.2"),
+		},
+	},
+	// A Service that is not imported
+	{
+		Qname: "svc2.testns.svc.clusterset.local.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("clusterset.local.	5	IN	SOA	ns.dns.clusterset.local. hostmaster.clusterset.local. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// No pods in the cluster set
+	{
+		Qname: "10-0-0-1.testns.pod.clusterset.local.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("clusterset.local.	5	IN	SOA	ns.dns.clusterset.local. hostmaster.clusterset.local. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	// The local Service in the cluster zone
+	{
+		Qname: "svc1.testns.svc.cluster.local.", Qtype: dns.TypeA,
+		Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("svc1.testns.svc.cluster.local.	5	IN	A	10.1.0.1"),
+		},
+	},
+}
+
+func TestServeDNSMultiCluster(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		&api.Namespace{ObjectMeta: meta.ObjectMeta{Name: "testns"}},
+		&api.Service{
+			ObjectMeta: meta.ObjectMeta{Name: "svc1", Namespace: "testns"},
+			Spec: api.ServiceSpec{
+				Type:       api.ServiceTypeClusterIP,
+				ClusterIP:  "10.1.0.1",
+				ClusterIPs: []string{"10.1.0.1"},
+				Ports:      []api.ServicePort{{Name: "http", Protocol: "tcp", Port: 80}},
+			},
+		},
+		importedEndpointSlice("hdls1-cluster1", "hdls1", "cluster1", map[string]string{"172.0.0.2": "dup-name", "172.0.0.3": ""}),
+		importedEndpointSlice("hdls1-cluster2", "hdls1", "cluster2", map[string]string{"172.1.0.2": "dup-name"}),
+	)
+	mcsClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{object.ServiceImportResource: "ServiceImportList"},
+		serviceImport("svc1", object.ClusterSetIP, "10.0.0.1"),
+		serviceImport("hdls1", object.Headless),
+	)
+
+	k := New([]string{"cluster.local.", "clusterset.local."})
+	k.opts.multiclusterZones = []string{"clusterset.local."}
+	dns := newdnsController(ctx, client, dnsControlOpts{initEndpointsCache: true, zones: k.Zones})
+	dns.WatchMultiCluster(ctx, mcsClient)
+	k.APIConn = dns
+	go dns.Run()
+	defer dns.Stop()
+
+	for i := 0; !dns.HasSynced(); i++ {
+		if i > 50 {
+			t.Fatal("Controller failed to sync")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	for i, tc := range dnsMultiClusterTestCases {
+		r := tc.Msg()
+
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		_, err := k.ServeDNS(ctx, w, r)
+		if err != tc.Error {
+			t.Errorf("Test %d expected no error, got %v", i, err)
+			return
+		}
+
+		resp := w.Msg
+		if resp == nil {
+			t.Fatalf("Test %d, got nil message and no error for %q", i, r.Question[0].Name)
+		}
+
+		// Before sorting, make sure that CNAMES do not appear after their target records
+		if err := test.CNAMEOrder(resp); err != nil {
+			t.Errorf("Test %d, %v", i, err)
+		}
+
+		if err := test.SortAndCheck(resp, tc); err != nil {
+			t.Errorf("Test %d, %v", i, err)
+		}
+	}
+}
+
+func TestParseRequestMultiCluster(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string // output from r.String()
+	}{
+		{"svc1.testns.svc.clusterset.local.", "*.*..svc1.testns.svc"},
+		{"cluster1.hdls1.testns.svc.clusterset.local.", "*.*..cluster1.hdls1.testns.svc"},
+		{"name.cluster1.hdls1.testns.svc.clusterset.local.", "*.*.name.cluster1.hdls1.testns.svc"},
+		{"_http._tcp.hdls1.testns.svc.clusterset.local.", "http.tcp..hdls1.testns.svc"},
+	}
+	for i, tc := range tests {
+		r, e := parseRequest(tc.query, "clusterset.local.", true)
+		if e != nil {
+			t.Errorf("Test %d, expected no error, got '%v'.", i, e)
+		}
+		if rs := r.String(); rs != tc.expected {
+			t.Errorf("Test %d, expected (stringified) recordRequest: %s, got %s", i, tc.expected, rs)
+		}
+	}
+}
+
+func serviceImport(name, typ string, ips ...string) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"type":  typ,
+		"ports": []interface{}{map[string]interface{}{"name": "http", "protocol": "tcp", "port": int64(80)}},
+	}
+	if len(ips) > 0 {
+		i := make([]interface{}, len(ips))
+		for j := range ips {
+			i[j] = ips[j]
+		}
+		spec["ips"] = i
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "multicluster.x-k8s.io/v1alpha1",
+		"kind":       "ServiceImport",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "testns"},
+		"spec":       spec,
+	}}
+}
+
+func importedEndpointSlice(name, svc, cluster string, hosts map[string]string) *discovery.EndpointSlice {
+	port, portName, protocol := int32(80), "http", api.Protocol("tcp")
+	ready := true
+	ends := &discovery.EndpointSlice{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      name,
+			Namespace: "testns",
+			Labels: map[string]string{
+				object.LabelServiceImportName: svc,
+				object.LabelSourceCluster:     cluster,
+			},
+		},
+		AddressType: discovery.AddressTypeIPv4,
+		Ports:       []discovery.EndpointPort{{Name: &portName, Protocol: &protocol, Port: &port}},
+	}
+	for ip, host := range hosts {
+		e := discovery.Endpoint{Addresses: []string{ip}, Conditions: discovery.EndpointConditions{Ready: &ready}}
+		if host != "" {
+			h := host
+			e.Hostname = &h
+		}
+		ends.Endpoints = append(ends.Endpoints, e)
+	}
+	return ends
+}
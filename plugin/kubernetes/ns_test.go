@@ -13,14 +13,25 @@ import (
 
 type APIConnTest struct{}
 
-func (APIConnTest) HasSynced() bool                          { return true }
-func (APIConnTest) Run()                                     {}
-func (APIConnTest) Stop() error                              { return nil }
-func (APIConnTest) PodIndex(string) []*object.Pod            { return nil }
-func (APIConnTest) SvcIndexReverse(string) []*object.Service { return nil }
-func (APIConnTest) EpIndex(string) []*object.Endpoints       { return nil }
-func (APIConnTest) EndpointsList() []*object.Endpoints       { return nil }
-func (APIConnTest) Modified() int64                          { return 0 }
+func (APIConnTest) HasSynced() bool                                            { return true }
+func (APIConnTest) Run()                                                       {}
+func (APIConnTest) Stop() error                                                { return nil }
+func (APIConnTest) PodIndex(string) []*object.Pod                              { return nil }
+func (APIConnTest) SvcIndexReverse(string) []*object.Service                   { return nil }
+func (APIConnTest) ExternalNameIndex(string) []*object.Service                 { return nil }
+func (APIConnTest) ServiceImportList() []*object.ServiceImport                 { return nil }
+func (APIConnTest) MultiClusterEndpointsList() []*object.MultiClusterEndpoints { return nil }
+func (APIConnTest) SvcImportIndex(string) []*object.ServiceImport              { return nil }
+func (APIConnTest) MCEpIndex(string) []*object.MultiClusterEndpoints           { return nil }
+func (APIConnTest) NodeZone(string) string                                     { return "" }
+func (APIConnTest) DNSEndpointIndex(string) []*object.DNSEndpoint              { return nil }
+func (APIConnTest) IngressIndex(string) []*object.Ingress                      { return nil }
+func (APIConnTest) GatewayIndex(string) []*object.Gateway                      { return nil }
+func (APIConnTest) GatewayHostIndex(string) []*object.Gateway                  { return nil }
+func (APIConnTest) HTTPRouteIndex(string) []*object.HTTPRoute                  { return nil }
+func (APIConnTest) EpIndex(string) []*object.Endpoints                         { return nil }
+func (APIConnTest) EndpointsList() []*object.Endpoints                         { return nil }
+func (APIConnTest) Modified() int64                                            { return 0 }
 
 func (a APIConnTest) SvcIndex(s string) []*object.Service {
 	switch s {
@@ -0,0 +1,96 @@
+package object
+
+import (
+	"fmt"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DNSEndpointResource is the resource of the DNSEndpoints of external-dns.
+var DNSEndpointResource = schema.GroupVersionResource{Group: "externaldns.k8s.io", Version: "v1alpha1", Resource: "dnsendpoints"}
+
+// DNSEndpoint is a stripped down external-dns DNSEndpoint with only the items we need for CoreDNS.
+type DNSEndpoint struct {
+	// Don't add new fields to this struct without talking to the CoreDNS maintainers.
+	Version   string
+	Name      string
+	Namespace string
+	Endpoints []DNSEndpointRecord
+
+	*Empty
+}
+
+// DNSEndpointRecord is a set of records of a single name and type.
+type DNSEndpointRecord struct {
+	DNSName    string   `json:"dnsName"`
+	RecordType string   `json:"recordType"`
+	Targets    []string `json:"targets"`
+}
+
+// dnsEndpointSpec is the part of the spec of a DNSEndpoint we need.
+type dnsEndpointSpec struct {
+	Endpoints []DNSEndpointRecord `json:"endpoints"`
+}
+
+// ToDNSEndpoint converts an unstructured DNSEndpoint to a *DNSEndpoint.
+func ToDNSEndpoint(obj meta.Object) (meta.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	spec := dnsEndpointSpec{}
+	if m, ok := u.Object["spec"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &spec); err != nil {
+			return nil, fmt.Errorf("invalid DNSEndpoint %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+		}
+	}
+
+	d := &DNSEndpoint{
+		Version:   u.GetResourceVersion(),
+		Name:      u.GetName(),
+		Namespace: u.GetNamespace(),
+		Endpoints: spec.Endpoints,
+	}
+
+	*u = unstructured.Unstructured{}
+
+	return d, nil
+}
+
+var _ runtime.Object = &DNSEndpoint{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (d *DNSEndpoint) DeepCopyObject() runtime.Object {
+	d1 := &DNSEndpoint{
+		Version:   d.Version,
+		Name:      d.Name,
+		Namespace: d.Namespace,
+		Endpoints: make([]DNSEndpointRecord, len(d.Endpoints)),
+	}
+	for i, e := range d.Endpoints {
+		d1.Endpoints[i] = DNSEndpointRecord{DNSName: e.DNSName, RecordType: e.RecordType, Targets: make([]string, len(e.Targets))}
+		copy(d1.Endpoints[i].Targets, e.Targets)
+	}
+	return d1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (d *DNSEndpoint) GetNamespace() string { return d.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (d *DNSEndpoint) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (d *DNSEndpoint) GetName() string { return d.Name }
+
+// SetName implements the metav1.Object interface.
+func (d *DNSEndpoint) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (d *DNSEndpoint) GetResourceVersion() string { return d.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (d *DNSEndpoint) SetResourceVersion(version string) {}
@@ -36,6 +36,9 @@ type EndpointAddress struct {
 	Hostname      string
 	NodeName      string
 	TargetRefName string
+	// Zone is the zone of the endpoint and ZoneHints the zones it should serve, both are only set from EndpointSlices.
+	Zone      string
+	ZoneHints []string
 }
 
 // EndpointPort is a tuple that describes a single port.
@@ -142,6 +145,14 @@ func EndpointSliceToEndpoints(obj meta.Object) (meta.Object, error) {
 			if end.NodeName != nil {
 				ea.NodeName = *end.NodeName
 			}
+			if end.Zone != nil {
+				ea.Zone = *end.Zone
+			}
+			if end.Hints != nil {
+				for _, z := range end.Hints.ForZones {
+					ea.ZoneHints = append(ea.ZoneHints, z.Name)
+				}
+			}
 			e.Subsets[0].Addresses = append(e.Subsets[0].Addresses, ea)
 			e.IndexIP = append(e.IndexIP, a)
 		}
@@ -190,6 +201,12 @@ func EndpointSliceV1beta1ToEndpoints(obj meta.Object) (meta.Object, error) {
 				ea.TargetRefName = end.TargetRef.Name
 			}
 			// EndpointSlice does not contain NodeName, leave blank
+			ea.Zone = end.Topology[api.LabelTopologyZone]
+			if end.Hints != nil {
+				for _, z := range end.Hints.ForZones {
+					ea.ZoneHints = append(ea.ZoneHints, z.Name)
+				}
+			}
 			e.Subsets[0].Addresses = append(e.Subsets[0].Addresses, ea)
 			e.IndexIP = append(e.IndexIP, a)
 		}
@@ -242,7 +259,11 @@ func (e *Endpoints) DeepCopyObject() runtime.Object {
 			Ports:     make([]EndpointPort, len(eps.Ports)),
 		}
 		for j, a := range eps.Addresses {
-			ea := EndpointAddress{IP: a.IP, Hostname: a.Hostname, NodeName: a.NodeName, TargetRefName: a.TargetRefName}
+			ea := EndpointAddress{IP: a.IP, Hostname: a.Hostname, NodeName: a.NodeName, TargetRefName: a.TargetRefName, Zone: a.Zone}
+			if len(a.ZoneHints) > 0 {
+				ea.ZoneHints = make([]string, len(a.ZoneHints))
+				copy(ea.ZoneHints, a.ZoneHints)
+			}
 			sub.Addresses[j] = ea
 		}
 		for k, p := range eps.Ports {
@@ -0,0 +1,228 @@
+package object
+
+import (
+	"fmt"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The resources of the Gateway API.
+var (
+	GatewayResource   = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1beta1", Resource: "gateways"}
+	HTTPRouteResource = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1beta1", Resource: "httproutes"}
+)
+
+// Gateway is a stripped down Gateway with only the items we need for CoreDNS.
+type Gateway struct {
+	// Don't add new fields to this struct without talking to the CoreDNS maintainers.
+	Version   string
+	Name      string
+	Namespace string
+	Index     string
+	// Hostnames are the hostnames of the listeners of the gateway.
+	Hostnames []string
+	// Addresses are the IP addresses and hostnames the gateway is reachable on.
+	Addresses []string
+
+	*Empty
+}
+
+// HTTPRoute is a stripped down HTTPRoute with only the items we need for CoreDNS.
+type HTTPRoute struct {
+	// Don't add new fields to this struct without talking to the CoreDNS maintainers.
+	Version   string
+	Name      string
+	Namespace string
+	Hostnames []string
+	// Gateways are the indexes of the parent gateways that accepted the route.
+	Gateways []string
+
+	*Empty
+}
+
+// gateway is the part of a Gateway we need.
+type gateway struct {
+	Spec struct {
+		Listeners []struct {
+			Hostname string `json:"hostname"`
+		} `json:"listeners"`
+	} `json:"spec"`
+	Status struct {
+		Addresses []struct {
+			Value string `json:"value"`
+		} `json:"addresses"`
+	} `json:"status"`
+}
+
+// parentRef is a reference from a route to its parent.
+type parentRef struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// httpRoute is the part of an HTTPRoute we need.
+type httpRoute struct {
+	Spec struct {
+		Hostnames []string `json:"hostnames"`
+	} `json:"spec"`
+	Status struct {
+		Parents []struct {
+			ParentRef  parentRef `json:"parentRef"`
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+		} `json:"parents"`
+	} `json:"status"`
+}
+
+// ToGateway converts an unstructured Gateway to a *Gateway.
+func ToGateway(obj meta.Object) (meta.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	gw := gateway{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &gw); err != nil {
+		return nil, fmt.Errorf("invalid Gateway %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+	}
+
+	g := &Gateway{
+		Version:   u.GetResourceVersion(),
+		Name:      u.GetName(),
+		Namespace: u.GetNamespace(),
+		Index:     ServiceKey(u.GetName(), u.GetNamespace()),
+	}
+	for _, l := range gw.Spec.Listeners {
+		if l.Hostname != "" {
+			g.Hostnames = append(g.Hostnames, l.Hostname)
+		}
+	}
+	for _, a := range gw.Status.Addresses {
+		if a.Value != "" {
+			g.Addresses = append(g.Addresses, a.Value)
+		}
+	}
+
+	*u = unstructured.Unstructured{}
+
+	return g, nil
+}
+
+// ToHTTPRoute converts an unstructured HTTPRoute to a *HTTPRoute.
+func ToHTTPRoute(obj meta.Object) (meta.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	route := httpRoute{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &route); err != nil {
+		return nil, fmt.Errorf("invalid HTTPRoute %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+	}
+
+	r := &HTTPRoute{
+		Version:   u.GetResourceVersion(),
+		Name:      u.GetName(),
+		Namespace: u.GetNamespace(),
+		Hostnames: route.Spec.Hostnames,
+	}
+	// Only use the gateways that accepted the route, the gateway controller sets this after checking the
+	// allowed routes of the listeners.
+	for _, p := range route.Status.Parents {
+		if p.ParentRef.Kind != "" && p.ParentRef.Kind != "Gateway" {
+			continue
+		}
+		accepted := false
+		for _, c := range p.Conditions {
+			if c.Type == "Accepted" {
+				accepted = c.Status == "True"
+			}
+		}
+		if !accepted {
+			continue
+		}
+		ns := p.ParentRef.Namespace
+		if ns == "" {
+			ns = r.Namespace
+		}
+		r.Gateways = append(r.Gateways, ServiceKey(p.ParentRef.Name, ns))
+	}
+
+	*u = unstructured.Unstructured{}
+
+	return r, nil
+}
+
+var (
+	_ runtime.Object = &Gateway{}
+	_ runtime.Object = &HTTPRoute{}
+)
+
+// DeepCopyObject implements the ObjectKind interface.
+func (g *Gateway) DeepCopyObject() runtime.Object {
+	g1 := &Gateway{
+		Version:   g.Version,
+		Name:      g.Name,
+		Namespace: g.Namespace,
+		Index:     g.Index,
+		Hostnames: make([]string, len(g.Hostnames)),
+		Addresses: make([]string, len(g.Addresses)),
+	}
+	copy(g1.Hostnames, g.Hostnames)
+	copy(g1.Addresses, g.Addresses)
+	return g1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (g *Gateway) GetNamespace() string { return g.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (g *Gateway) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (g *Gateway) GetName() string { return g.Name }
+
+// SetName implements the metav1.Object interface.
+func (g *Gateway) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (g *Gateway) GetResourceVersion() string { return g.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (g *Gateway) SetResourceVersion(version string) {}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (r *HTTPRoute) DeepCopyObject() runtime.Object {
+	r1 := &HTTPRoute{
+		Version:   r.Version,
+		Name:      r.Name,
+		Namespace: r.Namespace,
+		Hostnames: make([]string, len(r.Hostnames)),
+		Gateways:  make([]string, len(r.Gateways)),
+	}
+	copy(r1.Hostnames, r.Hostnames)
+	copy(r1.Gateways, r.Gateways)
+	return r1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (r *HTTPRoute) GetNamespace() string { return r.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (r *HTTPRoute) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (r *HTTPRoute) GetName() string { return r.Name }
+
+// SetName implements the metav1.Object interface.
+func (r *HTTPRoute) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (r *HTTPRoute) GetResourceVersion() string { return r.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (r *HTTPRoute) SetResourceVersion(version string) {}
@@ -0,0 +1,87 @@
+package object
+
+import (
+	"fmt"
+
+	networking "k8s.io/api/networking/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Ingress is a stripped down networking.Ingress with only the items we need for CoreDNS.
+type Ingress struct {
+	// Don't add new fields to this struct without talking to the CoreDNS maintainers.
+	Version   string
+	Name      string
+	Namespace string
+	Hosts     []string
+	// Addresses are the IP addresses and hostnames of the load balancer of the ingress.
+	Addresses []string
+
+	*Empty
+}
+
+// ToIngress converts a *networking.Ingress to an *Ingress.
+func ToIngress(obj meta.Object) (meta.Object, error) {
+	ing, ok := obj.(*networking.Ingress)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	i := &Ingress{
+		Version:   ing.GetResourceVersion(),
+		Name:      ing.GetName(),
+		Namespace: ing.GetNamespace(),
+	}
+	for _, r := range ing.Spec.Rules {
+		if r.Host != "" {
+			i.Hosts = append(i.Hosts, r.Host)
+		}
+	}
+	for _, lb := range ing.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			i.Addresses = append(i.Addresses, lb.IP)
+			continue
+		}
+		if lb.Hostname != "" {
+			i.Addresses = append(i.Addresses, lb.Hostname)
+		}
+	}
+
+	*ing = networking.Ingress{}
+
+	return i, nil
+}
+
+var _ runtime.Object = &Ingress{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (i *Ingress) DeepCopyObject() runtime.Object {
+	i1 := &Ingress{
+		Version:   i.Version,
+		Name:      i.Name,
+		Namespace: i.Namespace,
+		Hosts:     make([]string, len(i.Hosts)),
+		Addresses: make([]string, len(i.Addresses)),
+	}
+	copy(i1.Hosts, i.Hosts)
+	copy(i1.Addresses, i.Addresses)
+	return i1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (i *Ingress) GetNamespace() string { return i.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (i *Ingress) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (i *Ingress) GetName() string { return i.Name }
+
+// SetName implements the metav1.Object interface.
+func (i *Ingress) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (i *Ingress) GetResourceVersion() string { return i.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (i *Ingress) SetResourceVersion(version string) {}
@@ -0,0 +1,49 @@
+package object
+
+import (
+	"fmt"
+
+	discovery "k8s.io/api/discovery/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The labels an MCS controller puts on the EndpointSlices of an imported service.
+const (
+	// LabelServiceImportName is the name of the ServiceImport the EndpointSlice belongs to.
+	LabelServiceImportName = "multicluster.kubernetes.io/service-name"
+	// LabelSourceCluster is the id of the cluster the endpoints are in.
+	LabelSourceCluster = "multicluster.kubernetes.io/source-cluster"
+)
+
+// MultiClusterEndpoints is the Endpoints of an imported service in one cluster of the cluster set.
+type MultiClusterEndpoints struct {
+	Endpoints
+	ClusterID string
+}
+
+// EndpointSliceToMultiClusterEndpoints converts a *discovery.EndpointSlice of an imported service to a
+// *MultiClusterEndpoints.
+func EndpointSliceToMultiClusterEndpoints(obj meta.Object) (meta.Object, error) {
+	ends, ok := obj.(*discovery.EndpointSlice)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	name := ends.Labels[LabelServiceImportName]
+	clusterID := ends.Labels[LabelSourceCluster]
+
+	e, err := EndpointSliceToEndpoints(ends)
+	if err != nil {
+		return nil, err
+	}
+	mce := &MultiClusterEndpoints{Endpoints: *e.(*Endpoints), ClusterID: clusterID}
+	mce.Index = EndpointsKey(name, mce.Namespace)
+	return mce, nil
+}
+
+var _ runtime.Object = &MultiClusterEndpoints{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (e *MultiClusterEndpoints) DeepCopyObject() runtime.Object {
+	return &MultiClusterEndpoints{Endpoints: *e.Endpoints.DeepCopyObject().(*Endpoints), ClusterID: e.ClusterID}
+}
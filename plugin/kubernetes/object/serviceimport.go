@@ -0,0 +1,113 @@
+package object
+
+import (
+	"fmt"
+
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ServiceImportResource is the resource of the ServiceImports of the Multi-Cluster Services API. They are watched
+// with the dynamic client, so CoreDNS doesn't depend on the MCS API types.
+var ServiceImportResource = schema.GroupVersionResource{Group: "multicluster.x-k8s.io", Version: "v1alpha1", Resource: "serviceimports"}
+
+// The types of a ServiceImport.
+const (
+	ClusterSetIP = "ClusterSetIP"
+	Headless     = "Headless"
+)
+
+// ServiceImport is a stripped down ServiceImport with only the items we need for CoreDNS.
+type ServiceImport struct {
+	// Don't add new fields to this struct without talking to the CoreDNS maintainers.
+	Version   string
+	Name      string
+	Namespace string
+	Index     string
+	IPs       []string
+	Type      string
+	Ports     []api.ServicePort
+
+	*Empty
+}
+
+// serviceImportSpec is the part of the spec of a ServiceImport we need.
+type serviceImportSpec struct {
+	IPs   []string          `json:"ips,omitempty"`
+	Type  string            `json:"type"`
+	Ports []api.ServicePort `json:"ports"`
+}
+
+// ToServiceImport converts an unstructured ServiceImport to a *ServiceImport.
+func ToServiceImport(obj meta.Object) (meta.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	spec := serviceImportSpec{}
+	if m, ok := u.Object["spec"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &spec); err != nil {
+			return nil, fmt.Errorf("invalid ServiceImport %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+		}
+	}
+
+	s := &ServiceImport{
+		Version:   u.GetResourceVersion(),
+		Name:      u.GetName(),
+		Namespace: u.GetNamespace(),
+		Index:     ServiceKey(u.GetName(), u.GetNamespace()),
+		IPs:       spec.IPs,
+		Type:      spec.Type,
+		Ports:     spec.Ports,
+	}
+	if len(s.Ports) == 0 {
+		// Add sentinel if there are no ports.
+		s.Ports = []api.ServicePort{{Port: -1}}
+	}
+
+	*u = unstructured.Unstructured{}
+
+	return s, nil
+}
+
+// Headless returns true if the imported service is headless.
+func (s *ServiceImport) Headless() bool { return s.Type == Headless }
+
+var _ runtime.Object = &ServiceImport{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (s *ServiceImport) DeepCopyObject() runtime.Object {
+	s1 := &ServiceImport{
+		Version:   s.Version,
+		Name:      s.Name,
+		Namespace: s.Namespace,
+		Index:     s.Index,
+		Type:      s.Type,
+		IPs:       make([]string, len(s.IPs)),
+		Ports:     make([]api.ServicePort, len(s.Ports)),
+	}
+	copy(s1.IPs, s.IPs)
+	copy(s1.Ports, s.Ports)
+	return s1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (s *ServiceImport) GetNamespace() string { return s.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (s *ServiceImport) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (s *ServiceImport) GetName() string { return s.Name }
+
+// SetName implements the metav1.Object interface.
+func (s *ServiceImport) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (s *ServiceImport) GetResourceVersion() string { return s.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (s *ServiceImport) SetResourceVersion(version string) {}
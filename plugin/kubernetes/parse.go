@@ -19,6 +19,8 @@ type recordRequest struct {
 	service string
 	// The namespace used in Kubernetes.
 	namespace string
+	// The cluster id of the cluster set member, only used in multicluster zones.
+	cluster string
 	// A each name can be for a pod or a service, here we track what we've seen, either "pod" or "service".
 	podOrSvc string
 }
@@ -26,11 +28,15 @@ type recordRequest struct {
 // parseRequest parses the qname to find all the elements we need for querying k8s. Anything
 // that is not parsed will have the wildcard "*" value (except r.endpoint).
 // Potential underscores are stripped from _port and _protocol.
-func parseRequest(name, zone string) (r recordRequest, err error) {
+// When multicluster is true, zone is a multicluster zone and the names of the cluster set members are parsed too.
+func parseRequest(name, zone string, multicluster bool) (r recordRequest, err error) {
 	// 3 Possible cases:
 	// 1. _port._protocol.service.namespace.pod|svc.zone
 	// 2. (endpoint): endpoint.service.namespace.pod|svc.zone
 	// 3. (service): service.namespace.pod|svc.zone
+	// In a multicluster zone there are 2 more cases:
+	// 4. (cluster): cluster.service.namespace.svc.zone
+	// 5. (endpoint in cluster): endpoint.cluster.service.namespace.svc.zone
 	base, _ := dnsutil.TrimZone(name, zone)
 	// return NODATA for apex queries
 	if base == "" || base == Svc || base == Pod {
@@ -77,11 +83,21 @@ func parseRequest(name, zone string) (r recordRequest, err error) {
 
 	// Because of ambiguity we check the labels left: 1: an endpoint. 2: port and protocol.
 	// Anything else is a query that is too long to answer and can safely be delegated to return an nxdomain.
+	// In a multicluster zone 1 label is a cluster, and 2 labels without underscores are an endpoint and a cluster.
 	switch last {
 
-	case 0: // endpoint only
+	case 0: // endpoint only, or cluster only
+		if multicluster {
+			r.cluster = segs[last]
+			break
+		}
 		r.endpoint = segs[last]
 	case 1: // service and port
+		if multicluster && segs[0][0] != '_' && segs[1][0] != '_' {
+			r.endpoint = segs[0]
+			r.cluster = segs[1]
+			break
+		}
 		host := segs[0]
 		r.protocol = stripUnderscore(segs[last])
 		r.port = stripUnderscore(segs[last-1])
@@ -120,6 +136,9 @@ func (r recordRequest) String() string {
 	s := r.port
 	s += "." + r.protocol
 	s += "." + r.endpoint
+	if r.cluster != "" {
+		s += "." + r.cluster
+	}
 	s += "." + r.service
 	s += "." + r.namespace
 	s += "." + r.podOrSvc
@@ -54,6 +54,17 @@ func (APIConnReverseTest) SvcIndexReverse(ip string) []*object.Service {
 	}
 	return svcs
 }
+func (APIConnReverseTest) ExternalNameIndex(string) []*object.Service                 { return nil }
+func (APIConnReverseTest) ServiceImportList() []*object.ServiceImport                 { return nil }
+func (APIConnReverseTest) MultiClusterEndpointsList() []*object.MultiClusterEndpoints { return nil }
+func (APIConnReverseTest) SvcImportIndex(string) []*object.ServiceImport              { return nil }
+func (APIConnReverseTest) MCEpIndex(string) []*object.MultiClusterEndpoints           { return nil }
+func (APIConnReverseTest) NodeZone(string) string                                     { return "" }
+func (APIConnReverseTest) DNSEndpointIndex(string) []*object.DNSEndpoint              { return nil }
+func (APIConnReverseTest) IngressIndex(string) []*object.Ingress                      { return nil }
+func (APIConnReverseTest) GatewayIndex(string) []*object.Gateway                      { return nil }
+func (APIConnReverseTest) GatewayHostIndex(string) []*object.Gateway                  { return nil }
+func (APIConnReverseTest) HTTPRouteIndex(string) []*object.HTTPRoute                  { return nil }
 
 func (APIConnReverseTest) EpIndexReverse(ip string) []*object.Endpoints {
 	ep1s1 := object.Endpoints{
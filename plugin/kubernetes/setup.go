@@ -231,6 +231,34 @@ func ParseStanza(c *caddy.Controller) (*Kubernetes, error) {
 				overrides,
 			)
 			k8s.ClientConfig = config
+		case "topology_aware":
+			args := c.RemainingArgs()
+			if len(args) > 1 {
+				return nil, c.ArgErr()
+			}
+			k8s.topologyAware = true
+			k8s.topologyMinEndpoints = 1
+			if len(args) == 1 {
+				m, err := strconv.Atoi(args[0])
+				if err != nil {
+					return nil, err
+				}
+				if m < 1 {
+					return nil, c.Errf("topology_aware minimum must be positive: %d", m)
+				}
+				k8s.topologyMinEndpoints = m
+			}
+		case "multicluster":
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return nil, c.ArgErr()
+			}
+			for _, z := range plugin.OriginsFromArgsOrServerBlock(args, nil) {
+				if plugin.Zones(k8s.Zones).Matches(z) != z {
+					return nil, c.Errf("multicluster zone '%s' is not a zone of the plugin", z)
+				}
+				k8s.opts.multiclusterZones = append(k8s.opts.multiclusterZones, z)
+			}
 		default:
 			return nil, c.Errf("unknown property '%s'", c.Val())
 		}
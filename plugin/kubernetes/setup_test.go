@@ -1,6 +1,7 @@
 package kubernetes
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -610,3 +611,92 @@ func TestKubernetesParseIgnoreEmptyService(t *testing.T) {
 		}
 	}
 }
+
+func TestKubernetesParseMultiCluster(t *testing.T) {
+	tests := []struct {
+		input             string // Corefile data as string
+		shouldErr         bool   // true if test case is expected to produce an error.
+		expectedMultiZone []string
+	}{
+		{`kubernetes cluster.local clusterset.local {
+	multicluster clusterset.local
+}`, false, []string{"clusterset.local."}},
+		{`kubernetes cluster.local clusterset.local {
+	multicluster CLUSTERSET.LOCAL.
+}`, false, []string{"clusterset.local."}},
+		{`kubernetes cluster.local {
+	multicluster clusterset.local
+}`, true, nil},
+		{`kubernetes cluster.local clusterset.local {
+	multicluster
+}`, true, nil},
+		{`kubernetes cluster.local {
+}`, false, nil},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		k8sController, err := kubernetesParse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error, but did not find error for input '%s'", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(k8sController.opts.multiclusterZones, test.expectedMultiZone) {
+			t.Errorf("Test %d: Expected multicluster zones %v, got %v", i, test.expectedMultiZone, k8sController.opts.multiclusterZones)
+		}
+	}
+}
+
+func TestKubernetesParseTopologyAware(t *testing.T) {
+	tests := []struct {
+		input       string // Corefile data as string
+		shouldErr   bool   // true if test case is expected to produce an error.
+		expectedMin int    // 0 if topology_aware is not set
+	}{
+		{`kubernetes cluster.local {
+	topology_aware
+}`, false, 1},
+		{`kubernetes cluster.local {
+	topology_aware 3
+}`, false, 3},
+		{`kubernetes cluster.local {
+	topology_aware 0
+}`, true, 0},
+		{`kubernetes cluster.local {
+	topology_aware 1 2
+}`, true, 0},
+		{`kubernetes cluster.local {
+}`, false, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		k8sController, err := kubernetesParse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error, but did not find error for input '%s'", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			continue
+		}
+
+		if k8sController.topologyAware != (test.expectedMin > 0) {
+			t.Errorf("Test %d: Expected topology_aware to be %v", i, test.expectedMin > 0)
+		}
+		if k8sController.topologyMinEndpoints != test.expectedMin {
+			t.Errorf("Test %d: Expected topology_aware minimum %d, got %d", i, test.expectedMin, k8sController.topologyMinEndpoints)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	api "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestServeDNSTopologyAware(t *testing.T) {
+	tests := []struct {
+		client string // IP of the client
+		min    int    // topology_aware minimum
+		test.Case
+	}{
+		// Client in zone-a
+		{"10.240.0.1", 1, test.Case{
+			Qname: "hdls1.testns.svc.cluster.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A("hdls1.testns.svc.cluster.local.	5	IN	A	172.0.0.1"),
+				test.A("hdls1.testns.svc.cluster.local.	5	IN	A	172.0.0.2"),
+			},
+		}},
+		// Client in zone-b
+		{"10.240.0.2", 1, test.Case{
+			Qname: "hdls1.testns.svc.cluster.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A("hdls1.testns.svc.cluster.local.	5	IN	A	172.0.0.3"),
+			},
+		}},
+		// Not enough endpoints in zone-b
+		{"10.240.0.2", 2, test.Case{
+			Qname: "hdls1.testns.svc.cluster.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A("hdls1.testns.svc.cluster.local.	5	IN	A	172.0.0.1"),
+				test.A("hdls1.testns.svc.cluster.local.	5	IN	A	172.0.0.2"),
+				test.A("hdls1.testns.svc.cluster.local.	5	IN	A	172.0.0.3"),
+			},
+		}},
+		// Client is not a pod
+		{"10.240.0.9", 1, test.Case{
+			Qname: "hdls1.testns.svc.cluster.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A("hdls1.testns.svc.cluster.local.	5	IN	A	172.0.0.1"),
+				test.A("hdls1.testns.svc.cluster.local.	5	IN	A	172.0.0.2"),
+				test.A("hdls1.testns.svc.cluster.local.	5	IN	A	172.0.0.3"),
+			},
+		}},
+		// Endpoint queries are not filtered
+		{"10.240.0.2", 1, test.Case{
+			Qname: "ep1.hdls1.testns.svc.cluster.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A("ep1.hdls1.testns.svc.cluster.local.	5	IN	A	172.0.0.1"),
+			},
+		}},
+		// Zone hints win over the zones of the endpoints
+		{"10.240.0.1", 1, test.Case{
+			Qname: "hdls2.testns.svc.cluster.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A("hdls2.testns.svc.cluster.local.	5	IN	A	172.1.0.1"),
+				test.A("hdls2.testns.svc.cluster.local.	5	IN	A	172.1.0.3"),
+			},
+		}},
+		{"10.240.0.2", 3, test.Case{
+			Qname: "hdls2.testns.svc.cluster.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A("hdls2.testns.svc.cluster.local.	5	IN	A	172.1.0.2"),
+			},
+		}},
+	}
+
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		&api.Namespace{ObjectMeta: meta.ObjectMeta{Name: "testns"}},
+		topologyNode("node1", "zone-a"),
+		topologyNode("node2", "zone-b"),
+		topologyPod("client-a", "node1", "10.240.0.1"),
+		topologyPod("client-b", "node2", "10.240.0.2"),
+		topologyHeadlessSvc("hdls1"),
+		topologyHeadlessSvc("hdls2"),
+		topologyEndpointSlice("hdls1", []discovery.Endpoint{
+			topologyEndpoint("172.0.0.1", "ep1", "node1", "zone-a"),
+			topologyEndpoint("172.0.0.2", "ep2", "node1", ""),
+			topologyEndpoint("172.0.0.3", "ep3", "node2", "zone-b"),
+		}),
+		topologyEndpointSlice("hdls2", []discovery.Endpoint{
+			topologyEndpoint("172.1.0.1", "ep1", "node1", "zone-a", "zone-a"),
+			topologyEndpoint("172.1.0.2", "ep2", "node1", "zone-a", "zone-b"),
+			topologyEndpoint("172.1.0.3", "ep3", "node2", "zone-b", "zone-a"),
+		}),
+	)
+
+	k := New([]string{"cluster.local."})
+	k.topologyAware = true
+	dns := newdnsController(ctx, client, dnsControlOpts{initEndpointsCache: true, initPodCache: true, initNodeCache: true, zones: k.Zones})
+	k.APIConn = dns
+	go dns.Run()
+	defer dns.Stop()
+
+	for i := 0; !dns.HasSynced(); i++ {
+		if i > 50 {
+			t.Fatal("Controller failed to sync")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	for i, tc := range tests {
+		k.topologyMinEndpoints = tc.min
+		r := tc.Msg()
+
+		w := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: tc.client})
+
+		_, err := k.ServeDNS(ctx, w, r)
+		if err != tc.Error {
+			t.Errorf("Test %d expected no error, got %v", i, err)
+			return
+		}
+
+		resp := w.Msg
+		if resp == nil {
+			t.Fatalf("Test %d, got nil message and no error for %q", i, r.Question[0].Name)
+		}
+
+		if err := test.SortAndCheck(resp, tc.Case); err != nil {
+			t.Errorf("Test %d, %v", i, err)
+		}
+	}
+}
+
+func topologyNode(name, zone string) *api.Node {
+	return &api.Node{ObjectMeta: meta.ObjectMeta{Name: name, Labels: map[string]string{api.LabelTopologyZone: zone}}}
+}
+
+func topologyPod(name, node, ip string) *api.Pod {
+	return &api.Pod{
+		ObjectMeta: meta.ObjectMeta{Name: name, Namespace: "testns"},
+		Spec:       api.PodSpec{NodeName: node},
+		Status:     api.PodStatus{PodIP: ip},
+	}
+}
+
+func topologyHeadlessSvc(name string) *api.Service {
+	return &api.Service{
+		ObjectMeta: meta.ObjectMeta{Name: name, Namespace: "testns"},
+		Spec: api.ServiceSpec{
+			ClusterIP:  api.ClusterIPNone,
+			ClusterIPs: []string{api.ClusterIPNone},
+		},
+	}
+}
+
+func topologyEndpointSlice(svc string, endpoints []discovery.Endpoint) *discovery.EndpointSlice {
+	port, portName, protocol := int32(80), "http", api.Protocol("tcp")
+	return &discovery.EndpointSlice{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      svc + "-abcde",
+			Namespace: "testns",
+			Labels:    map[string]string{discovery.LabelServiceName: svc},
+		},
+		AddressType: discovery.AddressTypeIPv4,
+		Ports:       []discovery.EndpointPort{{Name: &portName, Protocol: &protocol, Port: &port}},
+		Endpoints:   endpoints,
+	}
+}
+
+func topologyEndpoint(ip, hostname, node, zone string, hints ...string) discovery.Endpoint {
+	e := discovery.Endpoint{Addresses: []string{ip}, Hostname: &hostname, NodeName: &node}
+	if zone != "" {
+		e.Zone = &zone
+	}
+	if len(hints) > 0 {
+		e.Hints = &discovery.EndpointHints{}
+		for _, h := range hints {
+			e.Hints.ForZones = append(e.Hints.ForZones, discovery.ForZone{Name: h})
+		}
+	}
+	return e
+}
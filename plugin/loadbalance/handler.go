@@ -9,14 +9,22 @@ import (
 	"github.com/miekg/dns"
 )
 
-// RoundRobin is a plugin to rewrite responses for "load balancing".
+// RoundRobin is a plugin to rewrite responses for "load balancing". Despite its name it orders the records with
+// the configured policy, which is round robin when none is set.
 type RoundRobin struct {
 	Next plugin.Handler
+
+	shuffle    shuffleFunc // nil means round robin.
+	health     *health     // nil when addresses are not health checked.
+	maxRecords int         // 0 means no limit.
 }
 
+// shuffleFunc orders the records of the response res in place.
+type shuffleFunc func(ctx context.Context, w dns.ResponseWriter, res *dns.Msg)
+
 // ServeDNS implements the plugin.Handler interface.
 func (rr RoundRobin) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
-	wrr := &RoundRobinResponseWriter{w}
+	wrr := &RoundRobinResponseWriter{ResponseWriter: w, ctx: ctx, shuffle: rr.shuffle, health: rr.health, maxRecords: rr.maxRecords}
 	return plugin.NextOrFailure(rr.Name(), rr.Next, ctx, wrr, r)
 }
 
@@ -0,0 +1,197 @@
+package loadbalance
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultHealthInterval = 10 * time.Second
+	defaultHealthTimeout  = 2 * time.Second
+	// healthIdle is the number of intervals after which an address that is no longer returned isn't checked anymore.
+	healthIdle = 10
+	// maxHealthTargets is the maximum number of addresses that are checked, addresses seen after that are not.
+	maxHealthTargets = 1024
+	// healthWorkers is the maximum number of checks that run at the same time.
+	healthWorkers = 16
+)
+
+// health checks the addresses of the names in zones returned in responses, and drops the ones that fail their check.
+type health struct {
+	zones    []string
+	proto    string // "tcp" or "http"
+	port     string
+	path     string // URL path of the http check.
+	interval time.Duration
+	timeout  time.Duration
+
+	client *http.Client
+	check  func(addr string) bool
+
+	sync.RWMutex
+	targets map[string]*target // address -> target
+
+	stop chan struct{}
+}
+
+// target is an address that is health checked.
+type target struct {
+	seen    int64 // unix nano time it was last returned in a response.
+	checked bool
+	healthy bool
+}
+
+func newHealth(zones []string, proto, port, path string) *health {
+	h := &health{
+		zones:    zones,
+		proto:    proto,
+		port:     port,
+		path:     path,
+		interval: defaultHealthInterval,
+		timeout:  defaultHealthTimeout,
+		targets:  map[string]*target{},
+	}
+	h.check = h.dial
+	return h
+}
+
+// filter returns rrs without the A and AAAA records of addresses that failed their last check. New addresses are
+// healthy until they are checked, and addresses of names outside of h.zones are not checked. When all addresses
+// fail, rrs is returned as is: an unhealthy backend may still answer, no backend can't.
+func (h *health) filter(rrs []dns.RR) []dns.RR {
+	now := time.Now().UnixNano()
+	out := make([]dns.RR, 0, len(rrs))
+	addresses, healthy := 0, 0
+	for _, r := range rrs {
+		addr := addressOf(r)
+		if addr == "" || plugin.Zones(h.zones).Matches(r.Header().Name) == "" {
+			out = append(out, r)
+			continue
+		}
+		addresses++
+		if h.healthy(addr, now) {
+			healthy++
+			out = append(out, r)
+		}
+	}
+	if healthy == 0 && addresses > 0 {
+		return rrs
+	}
+	return out
+}
+
+// healthy returns true when addr didn't fail its last check, it registers addr to be checked if there is room.
+func (h *health) healthy(addr string, now int64) bool {
+	h.RLock()
+	t, ok := h.targets[addr]
+	if ok {
+		atomic.StoreInt64(&t.seen, now)
+		healthy := !t.checked || t.healthy
+		h.RUnlock()
+		return healthy
+	}
+	h.RUnlock()
+
+	h.Lock()
+	if _, ok := h.targets[addr]; !ok && len(h.targets) < maxHealthTargets {
+		h.targets[addr] = &target{seen: now}
+	}
+	h.Unlock()
+	return true
+}
+
+// start starts checking the targets, until close is called.
+func (h *health) start() {
+	h.client = &http.Client{
+		Timeout: h.timeout,
+		// Redirects are not followed, a redirect is healthy.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	h.stop = make(chan struct{})
+	go h.run()
+}
+
+func (h *health) close() { close(h.stop) }
+
+func (h *health) run() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			h.checkAll(time.Now())
+		}
+	}
+}
+
+// checkAll checks all targets, at most healthWorkers at a time, and forgets the ones that have not been returned
+// for a while.
+func (h *health) checkAll(now time.Time) {
+	idle := now.Add(-healthIdle * h.interval).UnixNano()
+	h.Lock()
+	addrs := make([]string, 0, len(h.targets))
+	for addr, t := range h.targets {
+		if atomic.LoadInt64(&t.seen) < idle {
+			delete(h.targets, addr)
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	h.Unlock()
+
+	results := make([]bool, len(addrs))
+	sem := make(chan struct{}, healthWorkers)
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, addr string) {
+			defer func() { <-sem; wg.Done() }()
+			results[i] = h.check(addr)
+		}(i, addr)
+	}
+	wg.Wait()
+
+	h.Lock()
+	for i, addr := range addrs {
+		if t, ok := h.targets[addr]; ok {
+			if t.healthy != results[i] || !t.checked {
+				log.Infof("Address %s is healthy: %t", addr, results[i])
+			}
+			t.checked = true
+			t.healthy = results[i]
+		}
+	}
+	h.Unlock()
+}
+
+// dial checks addr by connecting to it over TCP, or by getting the URL path from it over HTTP, which must return
+// a 2xx or 3xx status.
+func (h *health) dial(addr string) bool {
+	hostport := net.JoinHostPort(addr, h.port)
+	if h.proto == "tcp" {
+		conn, err := net.DialTimeout("tcp", hostport, h.timeout)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+
+	resp, err := h.client.Get("http://" + hostport + "/" + strings.TrimPrefix(h.path, "/"))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 400
+}
@@ -0,0 +1,104 @@
+package loadbalance
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestHealthFilter(t *testing.T) {
+	h := newHealth([]string{"."}, "tcp", "80", "")
+	down := map[string]bool{"10.0.0.2": true}
+	h.check = func(addr string) bool { return !down[addr] }
+
+	rrs := []dns.RR{
+		test.CNAME("www.example.org.	300	IN	CNAME	lb.example.org."),
+		test.A("lb.example.org.	300	IN	A	10.0.0.1"),
+		test.A("lb.example.org.	300	IN	A	10.0.0.2"),
+	}
+	// Before they're checked, all addresses are healthy.
+	if got := h.filter(rrs); len(got) != 3 {
+		t.Fatalf("Expected 3 records before the first check, got %d", len(got))
+	}
+
+	h.checkAll(time.Now())
+	got := h.filter(rrs)
+	if len(got) != 2 || addressOf(got[1]) != "10.0.0.1" {
+		t.Errorf("Expected the CNAME and 10.0.0.1, got %v", got)
+	}
+
+	// When all addresses fail, they are all returned.
+	down["10.0.0.1"] = true
+	h.checkAll(time.Now())
+	if got := h.filter(rrs); len(got) != 3 {
+		t.Errorf("Expected all 3 records when all addresses fail, got %d", len(got))
+	}
+
+	// Addresses that are no longer returned are forgotten.
+	h.checkAll(time.Now().Add(2 * healthIdle * h.interval))
+	if len(h.targets) != 0 {
+		t.Errorf("Expected idle addresses to be forgotten, got %d", len(h.targets))
+	}
+}
+
+func TestHealthLimits(t *testing.T) {
+	h := newHealth([]string{"example.org."}, "tcp", "80", "")
+	h.check = func(string) bool { return false }
+
+	rrs := []dns.RR{
+		test.A("lb.example.org.	300	IN	A	10.0.0.1"),
+		test.A("lb.example.net.	300	IN	A	10.0.0.2"),
+	}
+	h.filter(rrs)
+	if _, ok := h.targets["10.0.0.2"]; ok || len(h.targets) != 1 {
+		t.Errorf("Expected only the address in example.org. to be checked, got %v", h.targets)
+	}
+
+	for i := 0; i < maxHealthTargets+10; i++ {
+		h.filter([]dns.RR{&dns.A{Hdr: dns.RR_Header{Name: "lb.example.org.", Rrtype: dns.TypeA}, A: net.IPv4(10, 1, byte(i>>8), byte(i))}})
+	}
+	if len(h.targets) != maxHealthTargets {
+		t.Errorf("Expected %d addresses to be checked, got %d", maxHealthTargets, len(h.targets))
+	}
+}
+
+func TestHealthDial(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	tests := []struct {
+		proto, path string
+		healthy     bool
+	}{
+		{"tcp", "", true},
+		{"http", "/healthz", true},
+		{"http", "healthz", true},
+		{"http", "/", false},
+	}
+	for i, tc := range tests {
+		h := newHealth([]string{"."}, tc.proto, port, tc.path)
+		h.start()
+		if healthy := h.dial("127.0.0.1"); healthy != tc.healthy {
+			t.Errorf("Test %d: expected healthy %t, got %t", i, tc.healthy, healthy)
+		}
+		h.close()
+	}
+
+	srv.Close()
+	h := newHealth([]string{"."}, "tcp", port, "")
+	h.timeout = 100 * time.Millisecond
+	if h.dial("127.0.0.1") {
+		t.Error("Expected closed port to be unhealthy")
+	}
+}
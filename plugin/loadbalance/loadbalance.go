@@ -2,11 +2,22 @@
 package loadbalance
 
 import (
+	"context"
+
 	"github.com/miekg/dns"
 )
 
-// RoundRobinResponseWriter is a response writer that shuffles A, AAAA and MX records.
-type RoundRobinResponseWriter struct{ dns.ResponseWriter }
+// RoundRobinResponseWriter is a response writer that shuffles A, AAAA and MX records. When created by RoundRobin
+// it orders the records with the configured policy instead, drops the addresses that fail their health check and
+// limits the number of records.
+type RoundRobinResponseWriter struct {
+	dns.ResponseWriter
+
+	ctx        context.Context
+	shuffle    shuffleFunc // nil means round robin.
+	health     *health
+	maxRecords int
+}
 
 // WriteMsg implements the dns.ResponseWriter interface.
 func (r *RoundRobinResponseWriter) WriteMsg(res *dns.Msg) error {
@@ -18,11 +29,27 @@ func (r *RoundRobinResponseWriter) WriteMsg(res *dns.Msg) error {
 		return r.ResponseWriter.WriteMsg(res)
 	}
 
+	if r.health != nil {
+		res.Answer = r.health.filter(res.Answer)
+		res.Extra = r.health.filter(res.Extra)
+	}
+	if r.shuffle != nil {
+		r.shuffle(r.ctx, r.ResponseWriter, res)
+	} else {
+		shuffleRoundRobin(r.ctx, r.ResponseWriter, res)
+	}
+	if r.maxRecords > 0 {
+		res.Answer = limit(res.Answer, r.maxRecords)
+	}
+
+	return r.ResponseWriter.WriteMsg(res)
+}
+
+// shuffleRoundRobin is the shuffleFunc of the round_robin policy.
+func shuffleRoundRobin(_ context.Context, _ dns.ResponseWriter, res *dns.Msg) {
 	res.Answer = roundRobin(res.Answer)
 	res.Ns = roundRobin(res.Ns)
 	res.Extra = roundRobin(res.Extra)
-
-	return r.ResponseWriter.WriteMsg(res)
 }
 
 func roundRobin(in []dns.RR) []dns.RR {
@@ -72,6 +99,23 @@ func roundRobinShuffle(records []dns.RR) {
 	}
 }
 
+// limit returns rrs with at most max A, AAAA, MX and SRV records of each type, the first ones are kept.
+func limit(rrs []dns.RR, max int) []dns.RR {
+	count := map[uint16]int{}
+	out := rrs[:0]
+	for _, r := range rrs {
+		switch t := r.Header().Rrtype; t {
+		case dns.TypeA, dns.TypeAAAA, dns.TypeMX, dns.TypeSRV:
+			if count[t] >= max {
+				continue
+			}
+			count[t]++
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
 // Write implements the dns.ResponseWriter interface.
 func (r *RoundRobinResponseWriter) Write(buf []byte) (int, error) {
 	// Should we pack and unpack here to fiddle with the packet... Not likely.
@@ -202,3 +202,28 @@ func handler() plugin.Handler {
 		return dns.RcodeSuccess, nil
 	})
 }
+
+func TestLoadBalanceMaxRecords(t *testing.T) {
+	lb := RoundRobin{Next: handler(), shuffle: shuffleRoundRobin, maxRecords: 2}
+
+	req := new(dns.Msg)
+	req.SetQuestion("endpoint.region2.skydns.test.", dns.TypeA)
+	req.Answer = []dns.RR{
+		test.CNAME("cname.region2.skydns.test.	300	IN	CNAME		endpoint.region2.skydns.test."),
+		test.A("endpoint.region2.skydns.test.		300	IN	A			10.240.0.1"),
+		test.A("endpoint.region2.skydns.test.		300	IN	A			10.240.0.2"),
+		test.A("endpoint.region2.skydns.test.		300	IN	A			10.240.0.3"),
+		test.AAAA("endpoint.region2.skydns.test.	300	IN	AAAA		::1"),
+	}
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := lb.ServeDNS(context.TODO(), rec, req); err != nil {
+		t.Fatalf("Expected no error, but got %s", err)
+	}
+	cname, address, _, _ := countRecords(rec.Msg.Answer)
+	if cname != 1 {
+		t.Errorf("Expected 1 CNAME, got %d", cname)
+	}
+	if address != 3 {
+		t.Errorf("Expected 2 A and 1 AAAA, got %d addresses", address)
+	}
+}
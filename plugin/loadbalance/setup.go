@@ -2,6 +2,10 @@ package loadbalance
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
@@ -13,32 +17,181 @@ var log = clog.NewWithPlugin("loadbalance")
 
 func init() { plugin.Register("loadbalance", setup) }
 
+const defaultWeightsReload = 30 * time.Second
+
 func setup(c *caddy.Controller) error {
-	err := parse(c)
+	lb, wt, err := parse(c)
 	if err != nil {
 		return plugin.Error("loadbalance", err)
 	}
 
+	if wt != nil && wt.path != "" {
+		parseChan := wt.periodicWeightsUpdate()
+		c.OnShutdown(func() error {
+			close(parseChan)
+			return nil
+		})
+	}
+	if lb.health != nil {
+		c.OnStartup(func() error {
+			lb.health.start()
+			return nil
+		})
+		c.OnShutdown(func() error {
+			lb.health.close()
+			return nil
+		})
+	}
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
-		return RoundRobin{Next: next}
+		lb.Next = next
+		if wt != nil {
+			wt.next = next
+		}
+		return lb
 	})
 
 	return nil
 }
 
-func parse(c *caddy.Controller) error {
+// parse parses the loadbalance directive, it also returns the weighted policy when that is used.
+func parse(c *caddy.Controller) (RoundRobin, *weighted, error) {
+	lb := RoundRobin{shuffle: shuffleRoundRobin}
+	var wt *weighted
+
+	i := 0
 	for c.Next() {
+		if i > 0 {
+			return lb, nil, plugin.ErrOnce
+		}
+		i++
+
 		args := c.RemainingArgs()
-		switch len(args) {
-		case 0:
-			return nil
-		case 1:
-			if args[0] != "round_robin" {
-				return fmt.Errorf("unknown policy: %s", args[0])
+		if len(args) > 0 {
+			switch args[0] {
+			case "round_robin":
+				if len(args) > 1 {
+					return lb, nil, c.ArgErr()
+				}
+			case "srv":
+				if len(args) > 1 {
+					return lb, nil, c.ArgErr()
+				}
+				lb.shuffle = shuffleSRV
+			case "weighted":
+				if len(args) != 2 {
+					return lb, nil, c.ArgErr()
+				}
+				wt = &weighted{reload: defaultWeightsReload}
+				if args[1] != "txt" {
+					wt.path = args[1]
+					if config := dnsserver.GetConfig(c); !filepath.IsAbs(wt.path) && config.Root != "" {
+						wt.path = filepath.Join(config.Root, wt.path)
+					}
+				}
+				lb.shuffle = wt.shuffle
+			default:
+				if len(args) > 1 {
+					return lb, nil, c.ArgErr()
+				}
+				return lb, nil, fmt.Errorf("unknown policy: %s", args[0])
+			}
+		}
 
+		for c.NextBlock() {
+			switch c.Val() {
+			case "reload":
+				if wt == nil || wt.path == "" {
+					return lb, nil, c.Errf("reload is only valid for the weighted policy with a weights file")
+				}
+				d, err := durationArg(c)
+				if err != nil {
+					return lb, nil, err
+				}
+				wt.reload = d
+			case "max_records":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return lb, nil, c.ArgErr()
+				}
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					return lb, nil, c.Errf("invalid max_records %q", args[0])
+				}
+				lb.maxRecords = n
+			case "health_check":
+				args := c.RemainingArgs()
+				if len(args) < 2 {
+					return lb, nil, c.ArgErr()
+				}
+				if _, err := strconv.ParseUint(args[1], 10, 16); err != nil {
+					return lb, nil, c.Errf("invalid port %q", args[1])
+				}
+				zones := plugin.OriginsFromArgsOrServerBlock(nil, c.ServerBlockKeys)
+				switch {
+				case args[0] == "tcp" && len(args) == 2:
+					lb.health = newHealth(zones, "tcp", args[1], "")
+				case args[0] == "http" && len(args) <= 3:
+					path := "/"
+					if len(args) == 3 {
+						path = args[2]
+					}
+					lb.health = newHealth(zones, "http", args[1], path)
+				default:
+					return lb, nil, c.Errf("health_check expects tcp PORT or http PORT [PATH], got %v", args)
+				}
+			case "health_zones":
+				if lb.health == nil {
+					return lb, nil, c.Errf("%s needs a health_check before it", c.Val())
+				}
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return lb, nil, c.ArgErr()
+				}
+				lb.health.zones = plugin.OriginsFromArgsOrServerBlock(args, c.ServerBlockKeys)
+			case "health_interval", "health_timeout":
+				if lb.health == nil {
+					return lb, nil, c.Errf("%s needs a health_check before it", c.Val())
+				}
+				prop := c.Val()
+				d, err := durationArg(c)
+				if err != nil {
+					return lb, nil, err
+				}
+				if d == 0 {
+					return lb, nil, c.Errf("%s must be positive", prop)
+				}
+				if prop == "health_interval" {
+					lb.health.interval = d
+				} else {
+					lb.health.timeout = d
+				}
+			default:
+				return lb, nil, c.Errf("unknown property %q", c.Val())
 			}
-			return nil
 		}
 	}
-	return c.ArgErr()
+
+	if wt != nil && wt.path != "" {
+		if err := wt.readWeights(); os.IsNotExist(err) {
+			log.Warningf("File does not exist: %s", wt.path)
+		} else if err != nil {
+			return lb, nil, err
+		}
+	}
+	return lb, wt, nil
+}
+
+// durationArg parses the single argument of the current property as a duration that is not negative.
+func durationArg(c *caddy.Controller) (time.Duration, error) {
+	prop := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil || d < 0 {
+		return 0, c.Errf("invalid duration %q for %s", args[0], prop)
+	}
+	return d, nil
 }
@@ -17,14 +17,65 @@ func TestSetup(t *testing.T) {
 		// positive
 		{`loadbalance`, false, "round_robin", ""},
 		{`loadbalance round_robin`, false, "round_robin", ""},
+		{`loadbalance srv`, false, "srv", ""},
+		{`loadbalance weighted txt`, false, "weighted", ""},
+		{`loadbalance weighted testdata/missing`, false, "weighted", ""},
+		{`loadbalance weighted testdata/weights {
+			reload 10s
+		}`, false, "weighted", ""},
+		{`loadbalance {
+			max_records 2
+			health_check tcp 80
+			health_interval 5s
+			health_timeout 1s
+		}`, false, "round_robin", ""},
+		{`loadbalance srv {
+			health_check http 8080 /healthz
+		}`, false, "srv", ""},
+		{`loadbalance {
+			health_check tcp 80
+			health_zones example.org example.net
+		}`, false, "round_robin", ""},
 		// negative
 		{`loadbalance fleeb`, true, "", "unknown policy"},
 		{`loadbalance a b`, true, "", "argument count or unexpected line"},
+		{`loadbalance round_robin a`, true, "", "argument count or unexpected line"},
+		{`loadbalance weighted`, true, "", "argument count or unexpected line"},
+		{`loadbalance weighted testdata/weights.invalid`, true, "", "invalid weight"},
+		{`loadbalance weighted txt {
+			reload 10s
+		}`, true, "", "only valid for the weighted policy"},
+		{`loadbalance {
+			max_records 0
+		}`, true, "", "invalid max_records"},
+		{`loadbalance {
+			health_check udp 53
+		}`, true, "", "health_check expects"},
+		{`loadbalance {
+			health_check tcp port
+		}`, true, "", "invalid port"},
+		{`loadbalance {
+			health_interval 5s
+		}`, true, "", "needs a health_check"},
+		{`loadbalance {
+			health_check tcp 80
+			health_timeout 0s
+		}`, true, "", "must be positive"},
+		{`loadbalance {
+			health_zones example.org
+		}`, true, "", "needs a health_check"},
+		{`loadbalance {
+			health_check tcp 80
+			health_zones
+		}`, true, "", "argument count or unexpected line"},
+		{`loadbalance {
+			fleeb
+		}`, true, "", "unknown property"},
 	}
 
 	for i, test := range tests {
 		c := caddy.NewTestController("dns", test.input)
-		err := parse(c)
+		_, _, err := parse(c)
 
 		if test.shouldErr && err == nil {
 			t.Errorf("Test %d: Expected error but found %s for input %s", i, err, test.input)
@@ -0,0 +1,42 @@
+package loadbalance
+
+import (
+	"context"
+	"sort"
+
+	"github.com/miekg/dns"
+)
+
+// shuffleSRV is the shuffleFunc of the srv policy: the SRV records are ordered as a client would use them, by
+// priority and then by weighted random selection, as in RFC 2782. The other records are shuffled as with the
+// round_robin policy.
+func shuffleSRV(_ context.Context, _ dns.ResponseWriter, res *dns.Msg) {
+	res.Answer = orderSRV(roundRobin(res.Answer))
+	res.Ns = roundRobin(res.Ns)
+	res.Extra = roundRobin(res.Extra)
+}
+
+// orderSRV orders the SRV records in rrs, which follow the other records.
+func orderSRV(rrs []dns.RR) []dns.RR {
+	srv := []dns.RR{}
+	out := []dns.RR{}
+	for _, r := range rrs {
+		if r.Header().Rrtype == dns.TypeSRV {
+			srv = append(srv, r)
+			continue
+		}
+		out = append(out, r)
+	}
+
+	sort.SliceStable(srv, func(i, j int) bool { return srv[i].(*dns.SRV).Priority < srv[j].(*dns.SRV).Priority })
+	for i := 0; i < len(srv); {
+		j := i + 1
+		for j < len(srv) && srv[j].(*dns.SRV).Priority == srv[i].(*dns.SRV).Priority {
+			j++
+		}
+		weightedShuffle(srv[i:j], func(r dns.RR) int { return int(r.(*dns.SRV).Weight) })
+		i = j
+	}
+
+	return append(out, srv...)
+}
@@ -0,0 +1,257 @@
+package loadbalance
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+
+	"github.com/miekg/dns"
+)
+
+// weightPrefix is the label prepended to a name to look up the TXT records with the weights of its addresses.
+const weightPrefix = "_weight"
+
+// weighted is the weighted policy: the address records are ordered by weighted random selection. The weights are
+// read from a file, or looked up in TXT records when path is empty.
+type weighted struct {
+	next plugin.Handler
+
+	path   string
+	reload time.Duration
+
+	sync.RWMutex
+	weights map[string]map[string]int // owner name -> address -> weight
+	mtime   time.Time
+	size    int64
+}
+
+// shuffle is the shuffleFunc of the weighted policy.
+func (wt *weighted) shuffle(ctx context.Context, w dns.ResponseWriter, res *dns.Msg) {
+	cname := []dns.RR{}
+	address := []dns.RR{}
+	rest := []dns.RR{}
+	for _, r := range res.Answer {
+		switch r.Header().Rrtype {
+		case dns.TypeCNAME:
+			cname = append(cname, r)
+		case dns.TypeA, dns.TypeAAAA:
+			address = append(address, r)
+		default:
+			rest = append(rest, r)
+		}
+	}
+
+	if len(address) > 1 {
+		weights := map[string]map[string]int{}
+		for _, r := range address {
+			name := strings.ToLower(r.Header().Name)
+			if _, ok := weights[name]; !ok {
+				weights[name] = wt.weightsOf(ctx, w, name)
+			}
+		}
+		weightedShuffle(address, func(r dns.RR) int {
+			if weight, ok := weights[strings.ToLower(r.Header().Name)][addressOf(r)]; ok {
+				return weight
+			}
+			return 1
+		})
+	}
+
+	out := append(cname, rest...)
+	res.Answer = append(out, address...)
+	res.Ns = roundRobin(res.Ns)
+	res.Extra = roundRobin(res.Extra)
+}
+
+// weightsOf returns the weights of the addresses of name.
+func (wt *weighted) weightsOf(ctx context.Context, w dns.ResponseWriter, name string) map[string]int {
+	if wt.path != "" {
+		wt.RLock()
+		defer wt.RUnlock()
+		return wt.weights[name]
+	}
+
+	r := new(dns.Msg)
+	r.SetQuestion(dnsutil.Join(weightPrefix, name), dns.TypeTXT)
+	nw := nonwriter.New(w)
+	if _, err := plugin.NextOrFailure("loadbalance", wt.next, ctx, nw, r); err != nil || nw.Msg == nil {
+		return nil
+	}
+	weights := map[string]int{}
+	for _, rr := range nw.Msg.Answer {
+		txt, ok := rr.(*dns.TXT)
+		if !ok {
+			continue
+		}
+		for _, s := range txt.Txt {
+			addr, weight, err := parseWeight(strings.Fields(s))
+			if err != nil {
+				log.Warningf("Invalid weight in TXT record of %s: %s", r.Question[0].Name, err)
+				continue
+			}
+			weights[addr] = weight
+		}
+	}
+	return weights
+}
+
+// readWeights reads the weights file, when it changed since it was last read.
+func (wt *weighted) readWeights() error {
+	file, err := os.Open(wt.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	wt.RLock()
+	unchanged := wt.mtime.Equal(stat.ModTime()) && wt.size == stat.Size()
+	wt.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	weights, err := parseWeights(file)
+	if err != nil {
+		return fmt.Errorf("%s: %s", wt.path, err)
+	}
+	log.Debugf("Parsed weights file into %d names", len(weights))
+
+	wt.Lock()
+	wt.weights = weights
+	wt.mtime = stat.ModTime()
+	wt.size = stat.Size()
+	wt.Unlock()
+	return nil
+}
+
+// periodicWeightsUpdate rereads the weights file every reload interval, until the returned channel is closed.
+func (wt *weighted) periodicWeightsUpdate() chan bool {
+	parseChan := make(chan bool)
+
+	if wt.reload == 0 {
+		return parseChan
+	}
+
+	go func() {
+		ticker := time.NewTicker(wt.reload)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-parseChan:
+				return
+			case <-ticker.C:
+				if err := wt.readWeights(); err != nil {
+					log.Errorf("Failed to reload weights: %s", err)
+				}
+			}
+		}
+	}()
+	return parseChan
+}
+
+// parseWeights parses a weights file: a domain name on a line of its own, followed by the lines with the weights
+// of its addresses, an address and a weight each. Empty lines and comments, starting with #, are ignored.
+func parseWeights(r io.Reader) (map[string]map[string]int, error) {
+	weights := map[string]map[string]int{}
+	var name string
+
+	scanner := bufio.NewScanner(r)
+	for i := 1; scanner.Scan(); i++ {
+		line := scanner.Text()
+		if j := strings.Index(line, "#"); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+			if _, ok := dns.IsDomainName(fields[0]); !ok {
+				return nil, fmt.Errorf("line %d: invalid domain name %q", i, fields[0])
+			}
+			name = strings.ToLower(dns.Fqdn(fields[0]))
+			if _, ok := weights[name]; !ok {
+				weights[name] = map[string]int{}
+			}
+		default:
+			if name == "" {
+				return nil, fmt.Errorf("line %d: weight before any domain name", i)
+			}
+			addr, weight, err := parseWeight(fields)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", i, err)
+			}
+			weights[name][addr] = weight
+		}
+	}
+	return weights, scanner.Err()
+}
+
+// parseWeight parses an address and its weight.
+func parseWeight(fields []string) (string, int, error) {
+	if len(fields) != 2 {
+		return "", 0, fmt.Errorf("expected an address and a weight, got %q", strings.Join(fields, " "))
+	}
+	ip := net.ParseIP(fields[0])
+	if ip == nil {
+		return "", 0, fmt.Errorf("invalid address %q", fields[0])
+	}
+	weight, err := strconv.Atoi(fields[1])
+	if err != nil || weight < 0 || weight > 65535 {
+		return "", 0, fmt.Errorf("invalid weight %q", fields[1])
+	}
+	return ip.String(), weight, nil
+}
+
+// addressOf returns the address of an A or AAAA record.
+func addressOf(r dns.RR) string {
+	switch r := r.(type) {
+	case *dns.A:
+		return r.A.String()
+	case *dns.AAAA:
+		return r.AAAA.String()
+	}
+	return ""
+}
+
+// weightedShuffle orders records as in the selection algorithm of RFC 2782: the next record is picked at random
+// from the remaining ones, with a chance proportional to its weight. Records with a weight of 0 are put after the
+// others, in random order.
+func weightedShuffle(records []dns.RR, weight func(dns.RR) int) {
+	for i := 0; i < len(records)-1; i++ {
+		rest := records[i:]
+		sum := 0
+		for _, r := range rest {
+			sum += weight(r)
+		}
+		if sum == 0 {
+			rand.Shuffle(len(rest), func(a, b int) { rest[a], rest[b] = rest[b], rest[a] })
+			return
+		}
+
+		n := rand.Intn(sum) + 1
+		running := 0
+		for j, r := range rest {
+			if running += weight(r); running >= n {
+				rest[0], rest[j] = rest[j], rest[0]
+				break
+			}
+		}
+	}
+}
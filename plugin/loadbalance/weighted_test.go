@@ -0,0 +1,163 @@
+package loadbalance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const weightsFile = `
+# comment
+www.example.org
+10.0.0.1 3
+10.0.0.2 1 # trailing comment
+10.0.0.3 0
+
+Other.Example.org.
+::1 10
+`
+
+func TestParseWeights(t *testing.T) {
+	weights, err := parseWeights(strings.NewReader(weightsFile))
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if w := weights["www.example.org."]["10.0.0.1"]; w != 3 {
+		t.Errorf("Expected weight 3 for 10.0.0.1, got %d", w)
+	}
+	if w, ok := weights["www.example.org."]["10.0.0.3"]; !ok || w != 0 {
+		t.Errorf("Expected weight 0 for 10.0.0.3, got %d", w)
+	}
+	if w := weights["other.example.org."]["::1"]; w != 10 {
+		t.Errorf("Expected weight 10 for ::1, got %d", w)
+	}
+
+	for _, bad := range []string{
+		"10.0.0.1 1",                    // no domain name
+		"www.example.org\n10.0.0 1",     // invalid address
+		"www.example.org\n10.0.0.1 1 2", // too many fields
+		"www.example.org\n::1 -1",       // negative weight
+		"www.example.org\n::1 65536",    // too large
+	} {
+		if _, err := parseWeights(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q, got none", bad)
+		}
+	}
+}
+
+// firsts counts, for n shuffles of the response, how often each address comes first.
+func firsts(t *testing.T, lb RoundRobin, answer []dns.RR, n int) map[string]int {
+	count := map[string]int{}
+	for i := 0; i < n; i++ {
+		req := new(dns.Msg)
+		req.SetQuestion("www.example.org.", dns.TypeA)
+		req.Answer = append([]dns.RR{}, answer...)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := lb.ServeDNS(context.TODO(), rec, req); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		if len(rec.Msg.Answer) != len(answer) {
+			t.Fatalf("Expected %d records, got %d", len(answer), len(rec.Msg.Answer))
+		}
+		count[addressOf(rec.Msg.Answer[len(rec.Msg.Answer)-len(answer)+1])]++
+	}
+	return count
+}
+
+var weightedAnswer = []dns.RR{
+	test.CNAME("alias.example.org.	300	IN	CNAME	www.example.org."),
+	test.A("www.example.org.	300	IN	A	10.0.0.1"),
+	test.A("www.example.org.	300	IN	A	10.0.0.2"),
+	test.A("www.example.org.	300	IN	A	10.0.0.3"),
+}
+
+func TestWeightedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "weights")
+	if err := os.WriteFile(path, []byte(weightsFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wt := &weighted{path: path}
+	if err := wt.readWeights(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	lb := RoundRobin{Next: handler(), shuffle: wt.shuffle}
+
+	const n = 4000
+	count := firsts(t, lb, weightedAnswer, n)
+	// 10.0.0.1 has 3 times the weight of 10.0.0.2, 10.0.0.3 has none.
+	if c := count["10.0.0.1"]; c < n*65/100 || c > n*85/100 {
+		t.Errorf("Expected 10.0.0.1 first about 75%% of the time, got %d of %d", c, n)
+	}
+	if c := count["10.0.0.3"]; c > n/20 {
+		t.Errorf("Expected 10.0.0.3 first hardly ever, got %d of %d", c, n)
+	}
+}
+
+func TestWeightedTXT(t *testing.T) {
+	next := plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if r.Question[0].Qtype == dns.TypeTXT {
+			if r.Question[0].Name != "_weight.www.example.org." {
+				t.Errorf("Unexpected TXT lookup of %s", r.Question[0].Name)
+			}
+			m.Answer = []dns.RR{
+				test.TXT(`_weight.www.example.org. 300 IN TXT "10.0.0.1 0" "10.0.0.2 0"`),
+				test.TXT(`_weight.www.example.org. 300 IN TXT "10.0.0.3 100" "invalid"`),
+			}
+		} else {
+			m.Answer = r.Answer
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+	wt := &weighted{next: next}
+	lb := RoundRobin{Next: next, shuffle: wt.shuffle}
+
+	const n = 1000
+	count := firsts(t, lb, weightedAnswer, n)
+	if c := count["10.0.0.3"]; c < n*95/100 {
+		t.Errorf("Expected 10.0.0.3 first nearly always, got %d of %d", c, n)
+	}
+}
+
+func TestSRV(t *testing.T) {
+	answer := []dns.RR{
+		test.SRV("_http._tcp.example.org.	300	IN	SRV	20 1 80 c.example.org."),
+		test.SRV("_http._tcp.example.org.	300	IN	SRV	10 0 80 b.example.org."),
+		test.SRV("_http._tcp.example.org.	300	IN	SRV	10 9 80 a.example.org."),
+		test.SRV("_http._tcp.example.org.	300	IN	SRV	5 0 80 d.example.org."),
+	}
+	lb := RoundRobin{Next: handler(), shuffle: shuffleSRV}
+
+	const n = 1000
+	second := 0
+	for i := 0; i < n; i++ {
+		req := new(dns.Msg)
+		req.SetQuestion("_http._tcp.example.org.", dns.TypeSRV)
+		req.Answer = append([]dns.RR{}, answer...)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := lb.ServeDNS(context.TODO(), rec, req); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		got := rec.Msg.Answer
+		if got[0].(*dns.SRV).Target != "d.example.org." || got[3].(*dns.SRV).Target != "c.example.org." {
+			t.Fatalf("Expected SRV records ordered by priority, got %v", got)
+		}
+		if got[1].(*dns.SRV).Target == "a.example.org." {
+			second++
+		}
+	}
+	if second < n*80/100 {
+		t.Errorf("Expected a.example.org. before b.example.org. nearly always, got %d of %d", second, n)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/edns"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/request"
 
@@ -53,15 +54,32 @@ func (l *Loop) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (
 		log.Fatalf(`Loop (%s -> %s) detected for zone %q, see https://coredns.io/plugins/loop#troubleshooting. Query: "HINFO %s"`, state.RemoteAddr(), l.address(), l.zone, l.qname)
 	}
 
+	if state.Name() == l.qname && l.seen() > 1 && r.IsEdns0() != nil {
+		// Our query came back, the reply to it is the result of a loop.
+		w = &loopWriter{w}
+	}
+
 	return plugin.NextOrFailure(l.Name(), l.Next, ctx, w, r)
 }
 
+// loopWriter adds an Extended DNS Error to the reply of a query that looped.
+type loopWriter struct {
+	dns.ResponseWriter
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *loopWriter) WriteMsg(m *dns.Msg) error {
+	edns.SetExtendedError(m, dns.ExtendedErrorCodeOther, "forwarding loop detected")
+	return w.ResponseWriter.WriteMsg(m)
+}
+
 // Name implements the plugin.Handler interface.
 func (l *Loop) Name() string { return "loop" }
 
 func (l *Loop) exchange(addr string) (*dns.Msg, error) {
 	m := new(dns.Msg)
 	m.SetQuestion(l.qname, dns.TypeHINFO)
+	m.SetEdns0(dns.MinMsgSize, false) // so the reply to a query that looped can have an Extended DNS Error.
 
 	return dns.Exchange(m, addr)
 }
@@ -1,6 +1,16 @@
 package loop
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
 
 func TestLoop(t *testing.T) {
 	l := New(".")
@@ -9,3 +19,24 @@ func TestLoop(t *testing.T) {
 		t.Errorf("Failed to inc loop, expected %d, got %d", 1, l.seen())
 	}
 }
+
+func TestLoopExtendedError(t *testing.T) {
+	l := New(".")
+	l.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	for i, expectEDE := range []bool{false, true} {
+		m := new(dns.Msg)
+		m.SetQuestion(l.qname, dns.TypeHINFO)
+		m.SetEdns0(4096, false)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		l.ServeDNS(context.TODO(), rec, m)
+		if e := edns.ExtendedError(rec.Msg); (e != nil) != expectEDE {
+			t.Errorf("Test %d: expected extended error %t, got %v", i, expectEDE, e)
+		}
+	}
+}
@@ -27,18 +27,25 @@ func ContextWithMetadata(ctx context.Context) context.Context {
 
 // ServeDNS implements the plugin.Handler interface.
 func (m *Metadata) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	// The server already collected the metadata when the server block defines a view.
+	if ValueFuncs(ctx) == nil {
+		ctx = m.Collect(ctx, request.Request{W: w, Req: r})
+	}
 
-	ctx = ContextWithMetadata(ctx)
+	rcode, err := plugin.NextOrFailure(m.Name(), m.Next, ctx, w, r)
+
+	return rcode, err
+}
 
-	state := request.Request{W: w, Req: r}
+// Collect retrieves the metadata functions from each metadata provider and returns the updated context.
+// It implements the dnsserver.MetadataCollector interface.
+func (m *Metadata) Collect(ctx context.Context, state request.Request) context.Context {
+	ctx = ContextWithMetadata(ctx)
 	if plugin.Zones(m.Zones).Matches(state.Name()) != "" {
 		// Go through all Providers and collect metadata.
 		for _, p := range m.Providers {
 			ctx = p.Metadata(ctx, state)
 		}
 	}
-
-	rcode, err := plugin.NextOrFailure(m.Name(), m.Next, ctx, w, r)
-
-	return rcode, err
+	return ctx
 }
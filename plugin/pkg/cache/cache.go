@@ -1,6 +1,7 @@
 // Package cache implements a cache. The cache hold 256 shards, each shard
-// holds a cache: a map with a mutex. There is no fancy expunge algorithm, it
-// just randomly evicts elements when it gets full.
+// holds a cache: a map with a mutex. By default there is no fancy expunge
+// algorithm, it just randomly evicts elements when it gets full, other
+// eviction policies can be selected with NewWithPolicy.
 package cache
 
 import (
@@ -20,16 +21,20 @@ type Cache struct {
 	shards [shardSize]*shard
 }
 
-// shard is a cache with random eviction.
+// shard is a cache with random eviction, or the eviction of its policy.
 type shard struct {
-	items map[uint64]interface{}
-	size  int
+	items  map[uint64]interface{}
+	size   int
+	policy policy // nil for random eviction.
 
 	sync.RWMutex
 }
 
-// New returns a new cache.
-func New(size int) *Cache {
+// New returns a new cache with random eviction.
+func New(size int) *Cache { return NewWithPolicy(size, Random) }
+
+// NewWithPolicy returns a new cache that evicts elements according to p.
+func NewWithPolicy(size int, p Policy) *Cache {
 	ssize := size / shardSize
 	if ssize < 4 {
 		ssize = 4
@@ -39,7 +44,7 @@ func New(size int) *Cache {
 
 	// Initialize all the shards
 	for i := 0; i < shardSize; i++ {
-		c.shards[i] = newShard(ssize)
+		c.shards[i] = newShardWithPolicy(ssize, p)
 	}
 	return c
 }
@@ -80,13 +85,31 @@ func (c *Cache) Walk(f func(map[uint64]interface{}, uint64) bool) {
 }
 
 // newShard returns a new shard with size.
-func newShard(size int) *shard { return &shard{items: make(map[uint64]interface{}), size: size} }
+func newShard(size int) *shard { return newShardWithPolicy(size, Random) }
+
+// newShardWithPolicy returns a new shard with size, that evicts elements according to p.
+func newShardWithPolicy(size int, p Policy) *shard {
+	return &shard{items: make(map[uint64]interface{}), size: size, policy: newPolicy(p, size)}
+}
 
 // Add adds element indexed by key into the cache. Any existing element is overwritten
-// Returns true if an existing element was evicted to make room for this element.
+// Returns true if an existing element was evicted to make room for this element. The element
+// may not be added at all when the policy of the shard doesn't admit it.
 func (s *shard) Add(key uint64, el interface{}) bool {
 	eviction := false
 	s.Lock()
+	if s.policy != nil {
+		victim, evict, admit := s.policy.add(key)
+		if evict {
+			delete(s.items, victim)
+			eviction = true
+		}
+		if admit {
+			s.items[key] = el
+		}
+		s.Unlock()
+		return eviction
+	}
 	if len(s.items) >= s.size {
 		if _, ok := s.items[key]; !ok {
 			for k := range s.items {
@@ -105,6 +128,9 @@ func (s *shard) Add(key uint64, el interface{}) bool {
 func (s *shard) Remove(key uint64) {
 	s.Lock()
 	delete(s.items, key)
+	if s.policy != nil {
+		s.policy.remove(key)
+	}
 	s.Unlock()
 }
 
@@ -113,6 +139,9 @@ func (s *shard) Evict() {
 	s.Lock()
 	for k := range s.items {
 		delete(s.items, k)
+		if s.policy != nil {
+			s.policy.remove(k)
+		}
 		break
 	}
 	s.Unlock()
@@ -120,6 +149,14 @@ func (s *shard) Evict() {
 
 // Get looks up the element indexed under key.
 func (s *shard) Get(key uint64) (interface{}, bool) {
+	if s.policy != nil {
+		// The policy records the lookup, which needs the write lock.
+		s.Lock()
+		el, found := s.items[key]
+		s.policy.get(key, found)
+		s.Unlock()
+		return el, found
+	}
 	s.RLock()
 	el, found := s.items[key]
 	s.RUnlock()
@@ -134,19 +171,21 @@ func (s *shard) Len() int {
 	return l
 }
 
-// Walk walks the shard for each element the function f is executed while holding a write lock.
+// Walk walks the shard for each element the function f is executed while holding a write lock. The function
+// may delete the element from the map.
 func (s *shard) Walk(f func(map[uint64]interface{}, uint64) bool) {
-	items := make([]uint64, len(s.items))
 	s.RLock()
-	i := 0
+	items := make([]uint64, 0, len(s.items))
 	for k := range s.items {
-		items[i] = k
-		i++
+		items = append(items, k)
 	}
 	s.RUnlock()
 	for _, k := range items {
 		s.Lock()
 		ok := f(s.items, k)
+		if _, found := s.items[k]; !found && s.policy != nil {
+			s.policy.remove(k)
+		}
 		s.Unlock()
 		if !ok {
 			return
@@ -74,6 +74,25 @@ func TestCacheWalk(t *testing.T) {
 	}
 }
 
+func TestCacheWalkConcurrentAdd(t *testing.T) {
+	c := New(shardSize * 4)
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < shardSize*4; i++ {
+			c.Add(uint64(i), i)
+		}
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			c.Walk(func(map[uint64]interface{}, uint64) bool { return true })
+		}
+	}
+}
+
 func BenchmarkCache(b *testing.B) {
 	b.ReportAllocs()
 
@@ -0,0 +1,297 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+)
+
+// Policy is the eviction policy of a cache, it selects the element that is evicted when a shard is full.
+type Policy int
+
+const (
+	// Random evicts an arbitrary element. It is the cheapest policy, as lookups don't need to be recorded.
+	Random Policy = iota
+	// LRU evicts the least recently used element.
+	LRU
+	// LFU evicts the least recently used element, but only admits a new element when it has been looked up more
+	// often, recently, than the element it would evict (TinyLFU admission). This keeps one-off keys, like the
+	// names of a random subdomain attack, from evicting popular ones.
+	LFU
+	// ARC is the Adaptive Replacement Cache, it keeps the elements used once apart from the ones used more often
+	// and adapts the share of each to the workload, using the keys of the elements it recently evicted.
+	ARC
+)
+
+var policies = map[string]Policy{"random": Random, "lru": LRU, "lfu": LFU, "arc": ARC}
+
+// ParsePolicy returns the Policy named s: random, lru, lfu or arc.
+func ParsePolicy(s string) (Policy, error) {
+	p, ok := policies[s]
+	if !ok {
+		return Random, fmt.Errorf("unknown eviction policy: %q", s)
+	}
+	return p, nil
+}
+
+func (p Policy) String() string {
+	for s, x := range policies {
+		if x == p {
+			return s
+		}
+	}
+	return fmt.Sprintf("Policy(%d)", int(p))
+}
+
+// policy keeps track of the keys in a shard, to select the ones to evict. It is called with the shard's lock held.
+type policy interface {
+	// get records a lookup of key, found is true when key is in the shard.
+	get(key uint64, found bool)
+	// add records that key is added to, or overwritten in, the shard. It returns the key that must be evicted to
+	// make room for it, if any. When admit is false, key must not be added.
+	add(key uint64) (victim uint64, evict, admit bool)
+	// remove records that key has been removed from the shard.
+	remove(key uint64)
+}
+
+func newPolicy(p Policy, size int) policy {
+	switch p {
+	case LRU:
+		return &lru{size: size, keys: newOrdered()}
+	case LFU:
+		return &lfu{lru: lru{size: size, keys: newOrdered()}, sketch: newSketch(size)}
+	case ARC:
+		return &arc{size: size, t1: newOrdered(), t2: newOrdered(), b1: newOrdered(), b2: newOrdered()}
+	}
+	return nil
+}
+
+// ordered is a list of keys, most recently used first.
+type ordered struct {
+	ll  *list.List
+	els map[uint64]*list.Element
+}
+
+func newOrdered() *ordered { return &ordered{ll: list.New(), els: map[uint64]*list.Element{}} }
+
+func (o *ordered) len() int { return o.ll.Len() }
+
+func (o *ordered) has(key uint64) bool {
+	_, ok := o.els[key]
+	return ok
+}
+
+// front adds key to the front of o, or moves it there.
+func (o *ordered) front(key uint64) {
+	if e, ok := o.els[key]; ok {
+		o.ll.MoveToFront(e)
+		return
+	}
+	o.els[key] = o.ll.PushFront(key)
+}
+
+// back returns the least recently used key, o must not be empty.
+func (o *ordered) back() uint64 { return o.ll.Back().Value.(uint64) }
+
+func (o *ordered) remove(key uint64) bool {
+	e, ok := o.els[key]
+	if !ok {
+		return false
+	}
+	o.ll.Remove(e)
+	delete(o.els, key)
+	return true
+}
+
+// lru is the LRU policy.
+type lru struct {
+	size int
+	keys *ordered
+}
+
+func (l *lru) get(key uint64, found bool) {
+	if found {
+		l.keys.front(key)
+	}
+}
+
+func (l *lru) add(key uint64) (uint64, bool, bool) {
+	if l.keys.has(key) || l.keys.len() < l.size {
+		l.keys.front(key)
+		return 0, false, true
+	}
+	victim := l.keys.back()
+	l.keys.remove(victim)
+	l.keys.front(key)
+	return victim, true, true
+}
+
+func (l *lru) remove(key uint64) { l.keys.remove(key) }
+
+// lfu is the LFU policy: TinyLFU admission in front of an LRU.
+type lfu struct {
+	lru
+	sketch *sketch
+}
+
+func (l *lfu) get(key uint64, found bool) {
+	l.sketch.increment(key)
+	l.lru.get(key, found)
+}
+
+func (l *lfu) add(key uint64) (uint64, bool, bool) {
+	if !l.keys.has(key) && l.keys.len() >= l.size && l.sketch.estimate(key) <= l.sketch.estimate(l.keys.back()) {
+		return 0, false, false
+	}
+	return l.lru.add(key)
+}
+
+// sketch is a count-min sketch of the number of lookups per key, with counters that saturate at 15. All counters
+// are halved every time the number of lookups reaches ten times the size of the shard, so the popularity of keys
+// that are no longer looked up fades.
+type sketch struct {
+	rows  [4][]uint8
+	mask  uint64
+	count int
+	reset int
+}
+
+var sketchSeeds = [4]uint64{0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325}
+
+func newSketch(size int) *sketch {
+	width := 16
+	for width < size {
+		width <<= 1
+	}
+	s := &sketch{mask: uint64(width - 1), reset: 10 * size}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *sketch) index(i int, key uint64) uint64 {
+	h := (key ^ sketchSeeds[i]) * 0x9e3779b97f4a7c15
+	return (h >> 32) & s.mask
+}
+
+func (s *sketch) increment(key uint64) {
+	for i := range s.rows {
+		if j := s.index(i, key); s.rows[i][j] < 15 {
+			s.rows[i][j]++
+		}
+	}
+	s.count++
+	if s.count < s.reset {
+		return
+	}
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.count /= 2
+}
+
+func (s *sketch) estimate(key uint64) uint8 {
+	min := uint8(15)
+	for i := range s.rows {
+		if c := s.rows[i][s.index(i, key)]; c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// arc is the ARC policy, see "ARC: A Self-Tuning, Low Overhead Replacement Cache" by Megiddo and Modha. The keys
+// in the shard are in t1, when they have been used once, or t2. The ghost lists b1 and b2 hold the keys recently
+// evicted from t1 and t2; p is the target length of t1.
+type arc struct {
+	size           int
+	p              int
+	t1, t2, b1, b2 *ordered
+}
+
+func (a *arc) get(key uint64, found bool) {
+	if found && a.t1.remove(key) || a.t2.has(key) {
+		a.t2.front(key)
+	}
+}
+
+func (a *arc) add(key uint64) (uint64, bool, bool) {
+	if a.t1.remove(key) || a.t2.has(key) {
+		a.t2.front(key)
+		return 0, false, true
+	}
+
+	var (
+		victim uint64
+		evict  bool
+	)
+	switch {
+	case a.b1.has(key):
+		d := 1
+		if a.b2.len() > a.b1.len() {
+			d = a.b2.len() / a.b1.len()
+		}
+		if a.p += d; a.p > a.size {
+			a.p = a.size
+		}
+		victim, evict = a.replace(false)
+		a.b1.remove(key)
+		a.t2.front(key)
+		return victim, evict, true
+
+	case a.b2.has(key):
+		d := 1
+		if a.b1.len() > a.b2.len() {
+			d = a.b1.len() / a.b2.len()
+		}
+		if a.p -= d; a.p < 0 {
+			a.p = 0
+		}
+		victim, evict = a.replace(true)
+		a.b2.remove(key)
+		a.t2.front(key)
+		return victim, evict, true
+
+	case a.t1.len()+a.b1.len() >= a.size:
+		if a.b1.len() > 0 {
+			a.b1.remove(a.b1.back())
+			victim, evict = a.replace(false)
+		} else {
+			victim, evict = a.t1.back(), true
+			a.t1.remove(victim)
+		}
+
+	default:
+		if total := a.t1.len() + a.t2.len() + a.b1.len() + a.b2.len(); total >= 2*a.size && a.b2.len() > 0 {
+			a.b2.remove(a.b2.back())
+		}
+		victim, evict = a.replace(false)
+	}
+	a.t1.front(key)
+	return victim, evict, true
+}
+
+// replace evicts a key from t1 or t2, when the shard is full, and moves it to the matching ghost list.
+func (a *arc) replace(b2 bool) (uint64, bool) {
+	if a.t1.len()+a.t2.len() < a.size {
+		return 0, false
+	}
+	if a.t1.len() > 0 && (a.t2.len() == 0 || a.t1.len() > a.p || (b2 && a.t1.len() == a.p)) {
+		k := a.t1.back()
+		a.t1.remove(k)
+		a.b1.front(k)
+		return k, true
+	}
+	k := a.t2.back()
+	a.t2.remove(k)
+	a.b2.front(k)
+	return k, true
+}
+
+func (a *arc) remove(key uint64) {
+	if !a.t1.remove(key) {
+		a.t2.remove(key)
+	}
+}
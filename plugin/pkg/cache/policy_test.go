@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	for _, p := range []Policy{Random, LRU, LFU, ARC} {
+		x, err := ParsePolicy(p.String())
+		if err != nil {
+			t.Errorf("Failed to parse %s: %s", p, err)
+		}
+		if x != p {
+			t.Errorf("Expected %s, got %s", p, x)
+		}
+	}
+	if _, err := ParsePolicy("fifo"); err == nil {
+		t.Error("Expected error for unknown policy, got nil")
+	}
+}
+
+func TestShardLRU(t *testing.T) {
+	s := newShardWithPolicy(2, LRU)
+	s.Add(1, 1)
+	s.Add(2, 2)
+	s.Get(1)
+	if !s.Add(3, 3) {
+		t.Error("Expected an eviction")
+	}
+	if _, found := s.Get(2); found {
+		t.Error("Found the least recently used item, that should have been evicted")
+	}
+	if _, found := s.Get(1); !found {
+		t.Error("Failed to find recently used item")
+	}
+}
+
+func TestShardLFU(t *testing.T) {
+	s := newShardWithPolicy(2, LFU)
+	for _, k := range []uint64{1, 2} {
+		for i := 0; i < 3; i++ {
+			s.Get(k)
+		}
+		s.Add(k, k)
+	}
+
+	// A key that is looked up once is not admitted.
+	s.Get(3)
+	s.Add(3, 3)
+	if _, found := s.Get(3); found {
+		t.Error("Found item that should not have been admitted")
+	}
+	if s.Len() != 2 {
+		t.Errorf("Expected 2 items, got %d", s.Len())
+	}
+
+	// A key that is looked up more often than the least recently used one is.
+	for i := 0; i < 5; i++ {
+		s.Get(4)
+	}
+	s.Get(2) // 1 is now the least recently used.
+	if !s.Add(4, 4) {
+		t.Error("Expected an eviction")
+	}
+	if _, found := s.Get(4); !found {
+		t.Error("Failed to find admitted item")
+	}
+	if _, found := s.Get(1); found {
+		t.Error("Found item that should have been evicted")
+	}
+}
+
+func TestShardARC(t *testing.T) {
+	const size = 8
+	s := newShardWithPolicy(size, ARC)
+	// Keys 0-3 are used twice, and end up in the frequently used list.
+	for k := uint64(0); k < 4; k++ {
+		s.Add(k, k)
+		s.Get(k)
+	}
+	// A scan of keys used once doesn't evict them.
+	for k := uint64(100); k < 100+4*size; k++ {
+		s.Add(k, k)
+	}
+	for k := uint64(0); k < 4; k++ {
+		if _, found := s.Get(k); !found {
+			t.Errorf("Failed to find frequently used item %d", k)
+		}
+	}
+	if s.Len() != size {
+		t.Errorf("Expected %d items, got %d", size, s.Len())
+	}
+}
+
+func TestShardPolicyConsistent(t *testing.T) {
+	const size = 16
+	for _, p := range []Policy{LRU, LFU, ARC} {
+		s := newShardWithPolicy(size, p)
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 10000; i++ {
+			k := uint64(r.Intn(4 * size))
+			switch r.Intn(10) {
+			case 0:
+				s.Remove(k)
+			case 1:
+				s.Walk(func(items map[uint64]interface{}, key uint64) bool {
+					if key == k {
+						delete(items, key)
+					}
+					return true
+				})
+			default:
+				if _, found := s.Get(k); !found {
+					s.Add(k, k)
+				}
+			}
+			if l := s.Len(); l > size {
+				t.Fatalf("Policy %s: shard holds %d items, more than its size %d", p, l, size)
+			}
+		}
+		// After all the removals the shard must still fill up.
+		for k := uint64(1000); k < 1000+size; k++ {
+			for i := 0; i < 20; i++ {
+				s.Get(k)
+			}
+			s.Add(k, k)
+		}
+		if l := s.Len(); l != size {
+			t.Errorf("Policy %s: expected %d items, got %d", p, size, l)
+		}
+	}
+}
+
+// BenchmarkPolicy reports the hit rate of each policy for lookups of keys with a Zipfian popularity, and for the
+// same lookups mixed with as many lookups of unique keys, like in a random subdomain attack. A missed key is added.
+func BenchmarkPolicy(b *testing.B) {
+	const (
+		size = shardSize * 16
+		keys = 1 << 20
+	)
+	for _, attack := range []bool{false, true} {
+		for _, p := range []Policy{Random, LRU, LFU, ARC} {
+			name := "zipf/" + p.String()
+			if attack {
+				name = "zipf+attack/" + p.String()
+			}
+			b.Run(name, func(b *testing.B) {
+				c := NewWithPolicy(size, p)
+				r := rand.New(rand.NewSource(1))
+				zipf := rand.NewZipf(r, 1.1, 1, keys-1)
+				hits, lookups := 0, 0
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					k := zipf.Uint64() * 0x9e3779b97f4a7c15
+					if attack && i%2 == 1 {
+						k = r.Uint64()
+					} else {
+						lookups++
+					}
+					_, found := c.Get(k)
+					if !found {
+						c.Add(k, struct{}{})
+						continue
+					}
+					if !attack || i%2 == 0 {
+						hits++
+					}
+				}
+				if lookups > 0 {
+					b.ReportMetric(100*float64(hits)/float64(lookups), "hit%")
+				}
+			})
+		}
+	}
+}
@@ -19,37 +19,37 @@ const Path = "/dns-query"
 
 // NewRequest returns a new DoH request given a method, URL (without any paths, so exclude /dns-query) and dns.Msg.
 func NewRequest(method, url string, m *dns.Msg) (*http.Request, error) {
+	u := "https://" + url + Path
+	if method == http.MethodPost {
+		u += "?bla=foo:443"
+	}
+	return NewRequestURL(method, u, m)
+}
+
+// NewRequestURL returns a new DoH request given a method, a full URL (including the scheme and path)
+// and dns.Msg. This is the client side counterpart of RequestToMsg.
+func NewRequestURL(method, url string, m *dns.Msg) (*http.Request, error) {
 	buf, err := m.Pack()
 	if err != nil {
 		return nil, err
 	}
 
+	var req *http.Request
 	switch method {
 	case http.MethodGet:
-		b64 := base64.RawURLEncoding.EncodeToString(buf)
-
-		req, err := http.NewRequest(http.MethodGet, "https://"+url+Path+"?dns="+b64, nil)
-		if err != nil {
-			return req, err
-		}
-
-		req.Header.Set("content-type", MimeType)
-		req.Header.Set("accept", MimeType)
-		return req, nil
-
+		req, err = http.NewRequest(http.MethodGet, url+"?dns="+b64Enc.EncodeToString(buf), nil)
 	case http.MethodPost:
-		req, err := http.NewRequest(http.MethodPost, "https://"+url+Path+"?bla=foo:443", bytes.NewReader(buf))
-		if err != nil {
-			return req, err
-		}
-
-		req.Header.Set("content-type", MimeType)
-		req.Header.Set("accept", MimeType)
-		return req, nil
-
+		req, err = http.NewRequest(http.MethodPost, url, bytes.NewReader(buf))
 	default:
 		return nil, fmt.Errorf("method not allowed: %s", method)
 	}
+	if err != nil {
+		return req, err
+	}
+
+	req.Header.Set("content-type", MimeType)
+	req.Header.Set("accept", MimeType)
+	return req, nil
 }
 
 // ResponseToMsg converts a http.Response to a dns message.
@@ -50,3 +50,33 @@ func TestGetRequest(t *testing.T) {
 		t.Errorf("Qname expected %d, got %d", x, dns.TypeDNSKEY)
 	}
 }
+
+func TestNewRequestURL(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req, err := NewRequestURL(method, "https://example.org:8443/resolve", m)
+		if err != nil {
+			t.Fatalf("Failure to make %s request: %s", method, err)
+		}
+		if req.URL.Path != "/resolve" {
+			t.Errorf("Expected path %s, got %s", "/resolve", req.URL.Path)
+		}
+		if req.URL.Host != "example.org:8443" {
+			t.Errorf("Expected host %s, got %s", "example.org:8443", req.URL.Host)
+		}
+
+		m1, err := RequestToMsg(req)
+		if err != nil {
+			t.Fatalf("Failure to get message from %s request: %s", method, err)
+		}
+		if x := m1.Question[0].Name; x != "example.org." {
+			t.Errorf("Qname expected %s, got %s", "example.org.", x)
+		}
+	}
+
+	if _, err := NewRequestURL(http.MethodPut, "https://example.org/dns-query", m); err == nil {
+		t.Errorf("Expected error for method %s", http.MethodPut)
+	}
+}
@@ -0,0 +1,58 @@
+package edns
+
+import (
+	"errors"
+
+	"github.com/miekg/dns"
+)
+
+// SetExtendedError adds an Extended DNS Error option, see RFC 8914, with code and the extra text to the reply m. An
+// OPT record is added to m if it doesn't have one; when m is written through a request.ScrubWriter, the OPT record
+// is adjusted to the client's, or removed if the client doesn't do EDNS0.
+func SetExtendedError(m *dns.Msg, code uint16, extra string) {
+	o := m.IsEdns0()
+	if o == nil {
+		o = &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+		o.SetUDPSize(dns.DefaultMsgSize)
+		m.Extra = append(m.Extra, o)
+	}
+	o.Option = append(o.Option, &dns.EDNS0_EDE{InfoCode: code, ExtraText: extra})
+}
+
+// ExtendedError returns the first Extended DNS Error option in m, or nil if there is none.
+func ExtendedError(m *dns.Msg) *dns.EDNS0_EDE {
+	o := m.IsEdns0()
+	if o == nil {
+		return nil
+	}
+	for _, opt := range o.Option {
+		if e, ok := opt.(*dns.EDNS0_EDE); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// Error is an error with an Extended DNS Error code. When a plugin returns it together with an rcode that the
+// server writes to the client, see plugin.ClientWrite, the Extended DNS Error is added to that reply.
+type Error struct {
+	Code uint16
+	Err  error
+}
+
+// NewError returns err with the Extended DNS Error code.
+func NewError(code uint16, err error) error { return &Error{Code: code, Err: err} }
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error { return e.Err }
+
+// ErrorCode returns the Extended DNS Error code of err, and true if err is, or wraps, an Error.
+func ErrorCode(err error) (uint16, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code, true
+	}
+	return 0, false
+}
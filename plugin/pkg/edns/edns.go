@@ -72,3 +72,16 @@ func Size(proto string, size uint16) uint16 {
 	}
 	return size
 }
+
+// RemoveOPT removes the OPT records from rrs.
+func RemoveOPT(rrs []dns.RR) []dns.RR {
+	j := 0
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeOPT {
+			continue
+		}
+		rrs[j] = rr
+		j++
+	}
+	return rrs[:j]
+}
@@ -1,6 +1,9 @@
 package edns
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"testing"
 
 	"github.com/miekg/dns"
@@ -35,3 +38,72 @@ func ednsMsg() *dns.Msg {
 	m.Extra = append(m.Extra, o)
 	return m
 }
+
+func TestSetExtendedError(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+
+	if e := ExtendedError(m); e != nil {
+		t.Errorf("Expected no extended error, got %v", e)
+	}
+
+	SetExtendedError(m, dns.ExtendedErrorCodeBlocked, "blocked")
+	if len(m.Extra) != 1 {
+		t.Fatalf("Expected an OPT record, got %d records", len(m.Extra))
+	}
+	e := ExtendedError(m)
+	if e == nil || e.InfoCode != dns.ExtendedErrorCodeBlocked || e.ExtraText != "blocked" {
+		t.Errorf("Expected extended error %d, got %v", dns.ExtendedErrorCodeBlocked, e)
+	}
+
+	// A second one uses the same OPT record.
+	SetExtendedError(m, dns.ExtendedErrorCodeOther, "")
+	if len(m.Extra) != 1 || len(m.IsEdns0().Option) != 2 {
+		t.Errorf("Expected 2 options in a single OPT record, got %d records", len(m.Extra))
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	err := NewError(dns.ExtendedErrorCodeNoReachableAuthority, errors.New("no healthy proxies"))
+	if err.Error() != "no healthy proxies" {
+		t.Errorf("Expected the wrapped error's text, got %q", err)
+	}
+	if code, ok := ErrorCode(fmt.Errorf("wrapped: %w", err)); !ok || code != dns.ExtendedErrorCodeNoReachableAuthority {
+		t.Errorf("Expected code %d, got %d (%t)", dns.ExtendedErrorCodeNoReachableAuthority, code, ok)
+	}
+	if _, ok := ErrorCode(errors.New("plain")); ok {
+		t.Errorf("Expected no code for a plain error")
+	}
+}
+
+func TestSetSubnet(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+
+	if e := Subnet(m); e != nil {
+		t.Errorf("Expected no client subnet, got %v", e)
+	}
+
+	SetSubnet(m, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0")})
+	SetSubnet(m, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 16, Address: net.ParseIP("192.0.2.0")})
+	if len(m.Extra) != 1 || len(m.IsEdns0().Option) != 1 {
+		t.Fatalf("Expected a single option in a single OPT record, got %d records", len(m.Extra))
+	}
+	if e := Subnet(m); e == nil || e.SourceScope != 16 {
+		t.Errorf("Expected client subnet with scope 16, got %v", e)
+	}
+
+	RemoveSubnet(m)
+	if e := Subnet(m); e != nil {
+		t.Errorf("Expected no client subnet, got %v", e)
+	}
+}
+
+func TestRemoveOPT(t *testing.T) {
+	m := ednsMsg()
+	m.Extra = append(m.Extra, &dns.A{Hdr: dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeA, Class: dns.ClassINET}})
+	m.Extra = RemoveOPT(m.Extra)
+	if len(m.Extra) != 1 || m.Extra[0].Header().Rrtype != dns.TypeA {
+		t.Errorf("Expected only the A record, got %v", m.Extra)
+	}
+}
@@ -0,0 +1,49 @@
+package edns
+
+import "github.com/miekg/dns"
+
+// Subnet returns the EDNS Client Subnet option, see RFC 7871, in m, or nil if there is none.
+func Subnet(m *dns.Msg) *dns.EDNS0_SUBNET {
+	o := m.IsEdns0()
+	if o == nil {
+		return nil
+	}
+	for _, opt := range o.Option {
+		if e, ok := opt.(*dns.EDNS0_SUBNET); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// SetSubnet sets the EDNS Client Subnet option in m to e, replacing any existing one. An OPT record is added to m
+// if it doesn't have one.
+func SetSubnet(m *dns.Msg, e *dns.EDNS0_SUBNET) {
+	o := m.IsEdns0()
+	if o == nil {
+		o = &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+		o.SetUDPSize(dns.DefaultMsgSize)
+		m.Extra = append(m.Extra, o)
+	}
+	removeSubnet(o)
+	o.Option = append(o.Option, e)
+}
+
+// RemoveSubnet removes the EDNS Client Subnet option from m.
+func RemoveSubnet(m *dns.Msg) {
+	if o := m.IsEdns0(); o != nil {
+		removeSubnet(o)
+	}
+}
+
+func removeSubnet(o *dns.OPT) {
+	j := 0
+	for _, opt := range o.Option {
+		if opt.Option() == dns.EDNS0SUBNET {
+			continue
+		}
+		o.Option[j] = opt
+		j++
+	}
+	o.Option = o.Option[:j]
+}
@@ -0,0 +1,81 @@
+// Package expression provides the variables and functions that are available when an expression
+// is evaluated for a DNS request.
+package expression
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultEnv returns the default set of custom state variables and functions available for use in expression evaluation.
+func DefaultEnv(ctx context.Context, state *request.Request) map[string]interface{} {
+	return map[string]interface{}{
+		"incidr": func(ipStr, cidrStr string) (bool, error) {
+			ip := net.ParseIP(ipStr)
+			if ip == nil {
+				return false, errors.New("first argument is not an IP address")
+			}
+			_, cidr, err := net.ParseCIDR(cidrStr)
+			if err != nil {
+				return false, err
+			}
+			return cidr.Contains(ip), nil
+		},
+		"metadata": func(label string) string {
+			f := metadata.ValueFunc(ctx, label)
+			if f == nil {
+				return ""
+			}
+			return f()
+		},
+		"type":        state.Type,
+		"name":        state.Name,
+		"class":       state.Class,
+		"proto":       state.Proto,
+		"size":        state.Len,
+		"client_ip":   state.IP,
+		"port":        state.Port,
+		"id":          func() int { return int(state.Req.Id) },
+		"opcode":      func() int { return state.Req.Opcode },
+		"do":          state.Do,
+		"bufsize":     state.Size,
+		"server_ip":   state.LocalIP,
+		"server_port": state.LocalPort,
+		"transport":   func() string { return transport(ctx) },
+		"ecs":         func() string { return ecs(state) },
+	}
+}
+
+// transport returns the transport (dns, tls, quic, grpc or https) of the server handling the request. If there is
+// no server in the context "dns" is returned.
+func transport(ctx context.Context) string {
+	srv, ok := ctx.Value(dnsserver.Key{}).(*dnsserver.Server)
+	if !ok {
+		return "dns"
+	}
+	trans, _ := parse.Transport(srv.Addr)
+	return trans
+}
+
+// ecs returns the address of the EDNS0 client subnet option (RFC 7871) in the request, or the empty
+// string if there is none.
+func ecs(state *request.Request) string {
+	opt := state.Req.IsEdns0()
+	if opt == nil {
+		return ""
+	}
+	for _, o := range opt.Option {
+		if e, ok := o.(*dns.EDNS0_SUBNET); ok {
+			return e.Address.String()
+		}
+	}
+	return ""
+}
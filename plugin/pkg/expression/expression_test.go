@@ -0,0 +1,96 @@
+package expression
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestInCidr(t *testing.T) {
+	incidr := DefaultEnv(context.Background(), &request.Request{})["incidr"].(func(string, string) (bool, error))
+
+	cases := []struct {
+		ip        string
+		cidr      string
+		expected  bool
+		shouldErr bool
+	}{
+		// positive
+		{ip: "1.2.3.4", cidr: "1.2.0.0/16", expected: true, shouldErr: false},
+		{ip: "10.2.3.4", cidr: "1.2.0.0/16", expected: false, shouldErr: false},
+		{ip: "1:2::3:4", cidr: "1:2::/64", expected: true, shouldErr: false},
+		{ip: "A:2::3:4", cidr: "1:2::/64", expected: false, shouldErr: false},
+		// negative
+		{ip: "1.2.3.4", cidr: "invalid", shouldErr: true},
+		{ip: "invalid", cidr: "1.2.0.0/16", shouldErr: true},
+	}
+
+	for i, c := range cases {
+		r, err := incidr(c.ip, c.cidr)
+		if err != nil && !c.shouldErr {
+			t.Errorf("Test %d: unexpected error %v", i, err)
+			continue
+		}
+		if err == nil && c.shouldErr {
+			t.Errorf("Test %d: expected error", i)
+			continue
+		}
+		if c.shouldErr {
+			continue
+		}
+		if r != c.expected {
+			t.Errorf("Test %d: expected %v", i, c.expected)
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	ctx := metadata.ContextWithMetadata(context.Background())
+	metadata.SetValueFunc(ctx, "test/metadata", func() string { return "success" })
+	f := DefaultEnv(ctx, &request.Request{})["metadata"].(func(string) string)
+
+	if x := f("test/metadata"); x != "success" {
+		t.Errorf("Expected %q, got %q", "success", x)
+	}
+	if x := f("test/nonexistent"); x != "" {
+		t.Errorf("Expected empty string, got %q", x)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	f := DefaultEnv(context.Background(), &request.Request{})["transport"].(func() string)
+	if x := f(); x != "dns" {
+		t.Errorf("Expected %q, got %q", "dns", x)
+	}
+
+	ctx := context.WithValue(context.Background(), dnsserver.Key{}, &dnsserver.Server{Addr: "tls://:853"})
+	f = DefaultEnv(ctx, &request.Request{})["transport"].(func() string)
+	if x := f(); x != "tls" {
+		t.Errorf("Expected %q, got %q", "tls", x)
+	}
+}
+
+func TestECS(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	state := &request.Request{W: &test.ResponseWriter{}, Req: m}
+
+	f := DefaultEnv(context.Background(), state)["ecs"].(func() string)
+	if x := f(); x != "" {
+		t.Errorf("Expected empty string, got %q", x)
+	}
+
+	m.SetEdns0(4096, false)
+	o := m.IsEdns0()
+	o.Option = append(o.Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()})
+	if x := f(); x != "192.0.2.0" {
+		t.Errorf("Expected %q, got %q", "192.0.2.0", x)
+	}
+}
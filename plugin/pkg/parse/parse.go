@@ -6,33 +6,58 @@ import (
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
 )
 
 // TransferIn parses transfer statements: 'transfer from [address...]'.
 func TransferIn(c *caddy.Controller) (froms []string, err error) {
+	froms, key, err := TransferInWithKey(c)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		return nil, c.Errf("can't use 'key' in transfer from")
+	}
+	return froms, nil
+}
+
+// TransferInWithKey parses transfer statements with an optional TSIG key name:
+// 'transfer from [address...] [key NAME]'. The returned key name is fully qualified and lowercased.
+func TransferInWithKey(c *caddy.Controller) (froms []string, key string, err error) {
 	if !c.NextArg() {
-		return nil, c.ArgErr()
+		return nil, "", c.ArgErr()
 	}
 	value := c.Val()
 	switch value {
 	default:
-		return nil, c.Errf("unknown property %s", value)
+		return nil, "", c.Errf("unknown property %s", value)
 	case "from":
 		froms = c.RemainingArgs()
+		for i := range froms {
+			if froms[i] == "key" {
+				if i != len(froms)-2 {
+					return nil, "", c.ArgErr()
+				}
+				key = dns.CanonicalName(froms[i+1])
+				froms = froms[:i]
+				break
+			}
+		}
 		if len(froms) == 0 {
-			return nil, c.ArgErr()
+			return nil, "", c.ArgErr()
 		}
 		for i := range froms {
 			if froms[i] != "*" {
 				normalized, err := HostPort(froms[i], transport.Port)
 				if err != nil {
-					return nil, err
+					return nil, "", err
 				}
 				froms[i] = normalized
 			} else {
-				return nil, fmt.Errorf("can't use '*' in transfer from")
+				return nil, "", fmt.Errorf("can't use '*' in transfer from")
 			}
 		}
 	}
-	return froms, nil
+	return froms, key, nil
 }
@@ -57,3 +57,46 @@ func TestTransferIn(t *testing.T) {
 		}
 	}
 }
+
+func TestTransferInWithKey(t *testing.T) {
+	tests := []struct {
+		inputFileRules string
+		shouldErr      bool
+		expectedFrom   []string
+		expectedKey    string
+	}{
+		{`from 127.0.0.1`, false, []string{"127.0.0.1:53"}, ""},
+		{`from 127.0.0.1 127.0.0.2 key Secondary.Key`, false, []string{"127.0.0.1:53", "127.0.0.2:53"}, "secondary.key."},
+		// Bad, no key name
+		{`from 127.0.0.1 key`, true, nil, ""},
+		// Bad, key not at the end
+		{`from key secondary.key. 127.0.0.1`, true, nil, ""},
+		// Bad, no addresses
+		{`from key secondary.key.`, true, nil, ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.inputFileRules)
+		froms, key, err := TransferInWithKey(c)
+
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if key != test.expectedKey {
+			t.Errorf("Test %d expected key %q, got %q", i, test.expectedKey, key)
+		}
+		if len(froms) != len(test.expectedFrom) {
+			t.Fatalf("Test %d expected %v, got %v", i, test.expectedFrom, froms)
+		}
+		for j, got := range froms {
+			if got != test.expectedFrom[j] {
+				t.Errorf("Test %d expected %v, got %v", i, test.expectedFrom[j], got)
+			}
+		}
+	}
+}
@@ -15,6 +15,7 @@ func TestTransport(t *testing.T) {
 		{"2003::1/64.:53", transport.DNS},
 		{"grpc://example.org:1443 ", transport.GRPC},
 		{"tls://example.org ", transport.TLS},
+		{"quic://example.org ", transport.QUIC},
 		{"https://example.org ", transport.HTTPS},
 	} {
 		actual, _ := Transport(test.input)
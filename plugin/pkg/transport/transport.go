@@ -4,6 +4,7 @@ package transport
 const (
 	DNS   = "dns"
 	TLS   = "tls"
+	QUIC  = "quic"
 	GRPC  = "grpc"
 	HTTPS = "https"
 )
@@ -14,6 +15,8 @@ const (
 	Port = "53"
 	// TLSPort is the default port for DNS-over-TLS.
 	TLSPort = "853"
+	// QUICPort is the default port for DNS-over-QUIC.
+	QUICPort = "853"
 	// GRPCPort is the default port for DNS-over-gRPC.
 	GRPCPort = "443"
 	// HTTPSPort is the default port for DNS-over-HTTPS.
@@ -0,0 +1,53 @@
+// Package tsig contains helpers for defining TSIG (RFC 8945) keys.
+package tsig
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Key is a TSIG key.
+type Key struct {
+	Name      string // Name is the name of the key, lowercased and fully qualified.
+	Algorithm string // Algorithm is the HMAC algorithm, i.e. dns.HmacSHA256.
+	Secret    string // Secret is the base64 encoded secret.
+}
+
+// algorithms are the supported algorithms, keyed by the name used in the Corefile.
+var algorithms = map[string]string{
+	"hmac-sha256": dns.HmacSHA256,
+	"hmac-sha384": dns.HmacSHA384,
+	"hmac-sha512": dns.HmacSHA512,
+}
+
+// Parse parses the arguments of a key definition: NAME ALGORITHM SECRET.
+func Parse(args []string) (Key, error) {
+	if len(args) != 3 {
+		return Key{}, fmt.Errorf("key definition needs a name, algorithm and secret, got %d arguments", len(args))
+	}
+	name := dns.CanonicalName(args[0])
+	if _, ok := dns.IsDomainName(name); !ok {
+		return Key{}, fmt.Errorf("invalid key name: %q", args[0])
+	}
+	alg, ok := algorithms[strings.TrimSuffix(strings.ToLower(args[1]), ".")]
+	if !ok {
+		return Key{}, fmt.Errorf("unsupported algorithm for key %s: %q", name, args[1])
+	}
+	if _, err := base64.StdEncoding.DecodeString(args[2]); err != nil {
+		return Key{}, fmt.Errorf("invalid secret for key %s: %s", name, err)
+	}
+	return Key{Name: name, Algorithm: alg, Secret: args[2]}, nil
+}
+
+// Register adds k to secrets, which is map of key names to secrets as used by dns.Server and dns.Client.
+// It returns an error when a different key with the same name is already present.
+func Register(secrets map[string]string, k Key) error {
+	if s, ok := secrets[k.Name]; ok && s != k.Secret {
+		return fmt.Errorf("key %s is defined more than once with different secrets", k.Name)
+	}
+	secrets[k.Name] = k.Secret
+	return nil
+}
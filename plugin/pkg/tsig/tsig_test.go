@@ -0,0 +1,54 @@
+package tsig
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		args      []string
+		shouldErr bool
+		expected  Key
+	}{
+		{[]string{"Update.Example.org", "hmac-sha256", "c2VjcmV0"}, false, Key{"update.example.org.", dns.HmacSHA256, "c2VjcmV0"}},
+		{[]string{"update.", "HMAC-SHA512.", "c2VjcmV0"}, false, Key{"update.", dns.HmacSHA512, "c2VjcmV0"}},
+		{[]string{"update.", "hmac-md5", "c2VjcmV0"}, true, Key{}},
+		{[]string{"update.", "hmac-sha256", "not base64!"}, true, Key{}},
+		{[]string{"update.", "hmac-sha256"}, true, Key{}},
+		{[]string{"update..example", "hmac-sha256", "c2VjcmV0"}, true, Key{}},
+	}
+
+	for i, tc := range tests {
+		k, err := Parse(tc.args)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if k != tc.expected {
+			t.Errorf("Test %d: expected %v, got %v", i, tc.expected, k)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	secrets := map[string]string{}
+	k := Key{Name: "update.", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0"}
+	if err := Register(secrets, k); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if err := Register(secrets, k); err != nil {
+		t.Errorf("Expected no error registering the same key twice, got %s", err)
+	}
+	k.Secret = "b3RoZXI="
+	if err := Register(secrets, k); err == nil {
+		t.Errorf("Expected error registering a different secret for the same key, got none")
+	}
+}
@@ -0,0 +1,44 @@
+package validator
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/miekg/dns"
+)
+
+// rootAnchors are the DS records of the root zone's key signing keys, KSK-2017 and KSK-2024.
+var rootAnchors = []string{
+	". 172800 IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". 172800 IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+// RootAnchors returns the trust anchors of the root zone.
+func RootAnchors() []dns.RR {
+	rrs := make([]dns.RR, len(rootAnchors))
+	for i, s := range rootAnchors {
+		rrs[i], _ = dns.NewRR(s)
+	}
+	return rrs
+}
+
+// ParseAnchors reads trust anchors, DS or DNSKEY records in zone file format, from r.
+func ParseAnchors(r io.Reader, file string) ([]dns.RR, error) {
+	var rrs []dns.RR
+	zp := dns.NewZoneParser(r, ".", file)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		switch rr.(type) {
+		case *dns.DS, *dns.DNSKEY:
+			rrs = append(rrs, rr)
+		default:
+			return nil, fmt.Errorf("trust anchor must be a DS or DNSKEY record, got %s", dns.TypeToString[rr.Header().Rrtype])
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	if len(rrs) == 0 {
+		return nil, fmt.Errorf("no trust anchors in %s", file)
+	}
+	return rrs, nil
+}
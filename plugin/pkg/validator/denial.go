@@ -0,0 +1,343 @@
+package validator
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// proveDenial checks that the NSEC or NSEC3 records prove that qname (NXDOMAIN) or qtype at qname (NODATA) doesn't
+// exist. The result is Insecure for a DS query covered by an opt-out NSEC3 record.
+func proveDenial(qname string, qtype uint16, nxdomain bool, nsec []*dns.NSEC, nsec3 []*dns.NSEC3) Result {
+	if len(nsec) > 0 {
+		return proveNSEC(qname, qtype, nxdomain, nsec)
+	}
+	if len(nsec3) > 0 {
+		return proveNSEC3(qname, qtype, nxdomain, nsec3)
+	}
+	return Bogus
+}
+
+func proveNSEC(qname string, qtype uint16, nxdomain bool, nsec []*dns.NSEC) Result {
+	if nxdomain {
+		c := covering(nsec, qname)
+		if c == nil {
+			return Bogus
+		}
+		if covering(nsec, "*."+encloser(qname, c)) == nil {
+			return Bogus
+		}
+		return Secure
+	}
+
+	if n := matching(nsec, qname); n != nil {
+		if hasType(n.TypeBitMap, qtype) || hasType(n.TypeBitMap, dns.TypeCNAME) {
+			return Bogus
+		}
+		return Secure
+	}
+	c := covering(nsec, qname)
+	if c == nil {
+		return Bogus
+	}
+	// Empty non-terminal, the next name is below qname.
+	if dns.IsSubDomain(qname, strings.ToLower(c.NextDomain)) {
+		return Secure
+	}
+	// NODATA for a name that was synthesized from a wildcard.
+	if n := matching(nsec, "*."+encloser(qname, c)); n != nil {
+		if !hasType(n.TypeBitMap, qtype) && !hasType(n.TypeBitMap, dns.TypeCNAME) {
+			return Secure
+		}
+	}
+	return Bogus
+}
+
+func proveNSEC3(qname string, qtype uint16, nxdomain bool, nsec3 []*dns.NSEC3) Result {
+	if !nxdomain {
+		if n := matching3(nsec3, qname); n != nil {
+			if hasType(n.TypeBitMap, qtype) || hasType(n.TypeBitMap, dns.TypeCNAME) {
+				return Bogus
+			}
+			return Secure
+		}
+	}
+
+	ce, nc := closestEncloser(qname, nsec3)
+	if ce == "" {
+		return Bogus
+	}
+	if nxdomain {
+		if covering3(nsec3, "*."+ce) == nil {
+			return Bogus
+		}
+		return Secure
+	}
+	if n := matching3(nsec3, "*."+ce); n != nil {
+		if !hasType(n.TypeBitMap, qtype) && !hasType(n.TypeBitMap, dns.TypeCNAME) {
+			return Secure
+		}
+		return Bogus
+	}
+	if qtype == dns.TypeDS && optOut(covering3(nsec3, nc)) {
+		return Insecure
+	}
+	return Bogus
+}
+
+// wildcardProof checks that the name that was synthesized from a wildcard, as signed by sig, doesn't exist.
+func wildcardProof(sig *dns.RRSIG, nsec []*dns.NSEC, nsec3 []*dns.NSEC3) bool {
+	name := strings.ToLower(sig.Hdr.Name)
+	if len(nsec) > 0 {
+		return covering(nsec, name) != nil
+	}
+	labels := dns.SplitDomainName(name)
+	if int(sig.Labels) >= len(labels) {
+		return false
+	}
+	// The next closer name is the closest encloser, with one label of name prepended.
+	nc := dns.Fqdn(strings.Join(labels[len(labels)-int(sig.Labels)-1:], "."))
+	return covering3(nsec3, nc) != nil
+}
+
+// insecureDelegation returns true if the denial of existence in m proves that name is a delegation without DS records.
+func insecureDelegation(name string, m *dns.Msg) bool {
+	var nsec3 []*dns.NSEC3
+	for _, rr := range m.Ns {
+		switch x := rr.(type) {
+		case *dns.NSEC:
+			if strings.EqualFold(x.Hdr.Name, name) {
+				return delegation(x.TypeBitMap)
+			}
+		case *dns.NSEC3:
+			nsec3 = append(nsec3, x)
+		}
+	}
+	if len(nsec3) == 0 {
+		return false
+	}
+	if n := matching3(nsec3, name); n != nil {
+		return delegation(n.TypeBitMap)
+	}
+	_, nc := closestEncloser(name, nsec3)
+	return nc != "" && optOut(covering3(nsec3, nc))
+}
+
+func delegation(bitmap []uint16) bool {
+	return hasType(bitmap, dns.TypeNS) && !hasType(bitmap, dns.TypeDS) && !hasType(bitmap, dns.TypeSOA)
+}
+
+// matching returns the NSEC record for name.
+func matching(nsec []*dns.NSEC, name string) *dns.NSEC {
+	for _, n := range nsec {
+		if strings.EqualFold(n.Hdr.Name, name) {
+			return n
+		}
+	}
+	return nil
+}
+
+// covering returns the NSEC record that covers name, i.e. name sorts between its owner and next name.
+func covering(nsec []*dns.NSEC, name string) *dns.NSEC {
+	for _, n := range nsec {
+		owner, next := strings.ToLower(n.Hdr.Name), strings.ToLower(n.NextDomain)
+		if dns.IsSubDomain(owner, name) && owner != name && delegation(n.TypeBitMap) {
+			// Names below a delegation are not in this zone.
+			continue
+		}
+		after := Compare(owner, name) < 0
+		if Compare(owner, next) < 0 {
+			if after && Compare(name, next) < 0 {
+				return n
+			}
+			continue
+		}
+		// The last NSEC record in the zone, its next name is the apex.
+		if dns.IsSubDomain(next, name) && (after || Compare(name, next) < 0) {
+			return n
+		}
+	}
+	return nil
+}
+
+// encloser returns the closest encloser of name, derived from the NSEC record that covers it.
+func encloser(name string, n *dns.NSEC) string {
+	owner, next := strings.ToLower(n.Hdr.Name), strings.ToLower(n.NextDomain)
+	a, b := dns.CompareDomainName(name, owner), dns.CompareDomainName(name, next)
+	if b > a {
+		a = b
+	}
+	labels := dns.SplitDomainName(name)
+	if a == 0 {
+		return "."
+	}
+	return dns.Fqdn(strings.Join(labels[len(labels)-a:], "."))
+}
+
+func matching3(nsec3 []*dns.NSEC3, name string) *dns.NSEC3 {
+	for _, n := range nsec3 {
+		if n.Match(name) {
+			return n
+		}
+	}
+	return nil
+}
+
+// covering3 returns the NSEC3 record that covers name. Note that Cover also returns true for the owner name itself.
+func covering3(nsec3 []*dns.NSEC3, name string) *dns.NSEC3 {
+	for _, n := range nsec3 {
+		if n.Cover(name) && !n.Match(name) {
+			return n
+		}
+	}
+	return nil
+}
+
+// closestEncloser returns the closest encloser of name that has a matching NSEC3 record, and the next closer name,
+// the name one label below it, that is covered by an NSEC3 record, see RFC 5155, section 8.3.
+func closestEncloser(name string, nsec3 []*dns.NSEC3) (string, string) {
+	idx := dns.Split(name)
+	for i := 1; i < len(idx); i++ {
+		if matching3(nsec3, name[idx[i]:]) == nil {
+			continue
+		}
+		nc := name[idx[i-1]:]
+		if covering3(nsec3, nc) == nil {
+			return "", ""
+		}
+		return name[idx[i]:], nc
+	}
+	return "", ""
+}
+
+func optOut(n *dns.NSEC3) bool { return n != nil && n.Flags&1 == 1 }
+
+func hasType(bitmap []uint16, qtype uint16) bool {
+	for _, t := range bitmap {
+		if t == qtype {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare compares the names a and b in canonical order, see RFC 4034, section 6.1. The result is negative when a
+// sorts before b, zero when they are equal and positive otherwise.
+func Compare(a, b string) int {
+	la, lb := wireLabels(a), wireLabels(b)
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := bytes.Compare(la[i], lb[j]); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// wireLabels returns the labels of the lowercased name s in wire format.
+func wireLabels(s string) [][]byte {
+	buf := make([]byte, 256)
+	off, err := dns.PackDomainName(strings.ToLower(dns.Fqdn(s)), buf, 0, nil, false)
+	if err != nil {
+		return nil
+	}
+	var labels [][]byte
+	for i := 0; i < off && buf[i] != 0; i += int(buf[i]) + 1 {
+		labels = append(labels, bytes.ToLower(buf[i+1:i+1+int(buf[i])]))
+	}
+	return labels
+}
+
+// Deny returns the rcode, NXDOMAIN or NOERROR for NODATA, that the validated NSEC or NSEC3 records in rrs, all from
+// the same zone, prove for qtype at qname, for the aggressive use of a DNSSEC-validated cache, see RFC 8198. The
+// records that make up the proof are returned too; false is returned when there is no proof. Opt-out NSEC3 records
+// are not used, as they don't prove that a name doesn't exist.
+func Deny(qname string, qtype uint16, rrs []dns.RR) (int, []dns.RR, bool) {
+	qname = strings.ToLower(dns.Fqdn(qname))
+	// The names whose records may be part of the proof: qname, its ancestors and the wildcards below them.
+	names := []string{}
+	for off, end := 0, false; !end; off, end = dns.NextLabel(qname, off) {
+		names = append(names, qname[off:])
+		if qname[off:] != "." {
+			names = append(names, "*."+qname[off:])
+		} else {
+			names = append(names, "*.")
+		}
+	}
+
+	var (
+		proof []dns.RR
+		nsec  []*dns.NSEC
+		nsec3 []*dns.NSEC3
+	)
+	hashes := map[string]bool{}
+	for _, rr := range rrs {
+		switch x := rr.(type) {
+		case *dns.NSEC:
+			for _, name := range names {
+				if matching([]*dns.NSEC{x}, name) != nil || covering([]*dns.NSEC{x}, name) != nil {
+					nsec = append(nsec, x)
+					proof = append(proof, x)
+					break
+				}
+			}
+		case *dns.NSEC3:
+			if optOut(x) {
+				continue
+			}
+			if len(hashes) == 0 {
+				for _, name := range names {
+					hashes[dns.HashName(name, x.Hash, x.Iterations, x.Salt)] = true
+				}
+			}
+			for h := range hashes {
+				if cover3(x, h) {
+					nsec3 = append(nsec3, x)
+					proof = append(proof, x)
+					break
+				}
+			}
+		}
+	}
+	if len(nsec) > 0 && len(nsec3) > 0 {
+		return 0, nil, false
+	}
+	// Names at or below a zone cut or a DNAME are not in this zone, or are not what their records say.
+	for i := 0; i < len(names); i += 2 {
+		var bitmap []uint16
+		if n := matching(nsec, names[i]); n != nil {
+			bitmap = n.TypeBitMap
+		} else if n := matching3(nsec3, names[i]); n != nil {
+			bitmap = n.TypeBitMap
+		}
+		if delegation(bitmap) || (i > 0 && hasType(bitmap, dns.TypeDNAME)) {
+			return 0, nil, false
+		}
+	}
+
+	exists := matching(nsec, qname) != nil || matching3(nsec3, qname) != nil
+	if c := covering(nsec, qname); c != nil && dns.IsSubDomain(qname, strings.ToLower(c.NextDomain)) {
+		exists = true // an empty non-terminal
+	}
+	if !exists && proveDenial(qname, qtype, true, nsec, nsec3) == Secure {
+		return dns.RcodeNameError, proof, true
+	}
+	if qtype != dns.TypeDS && proveDenial(qname, qtype, false, nsec, nsec3) == Secure {
+		return dns.RcodeSuccess, proof, true
+	}
+	return 0, nil, false
+}
+
+// cover3 returns true if the hashed name h matches or is covered by the NSEC3 record n.
+func cover3(n *dns.NSEC3, h string) bool {
+	owner := strings.ToUpper(dns.SplitDomainName(n.Hdr.Name)[0])
+	next := strings.ToUpper(n.NextDomain)
+	if h == owner {
+		return true
+	}
+	if owner < next {
+		return owner < h && h < next
+	}
+	// The last NSEC3 record in the zone.
+	return h > owner || h < next
+}
@@ -0,0 +1,172 @@
+package validator
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// nsec3Chain returns the NSEC3 records for the names in the zone example. and their types.
+func nsec3Chain(names map[string][]uint16, optout bool) []*dns.NSEC3 {
+	type hashed struct {
+		hash  string
+		types []uint16
+	}
+	hs := []hashed{}
+	for name, types := range names {
+		hs = append(hs, hashed{dns.HashName(name, dns.SHA1, 0, "aabbccdd"), types})
+	}
+	sort.Slice(hs, func(i, j int) bool { return hs[i].hash < hs[j].hash })
+
+	var flags uint8
+	if optout {
+		flags = 1
+	}
+	nsec3 := make([]*dns.NSEC3, len(hs))
+	for i, h := range hs {
+		nsec3[i] = &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: strings.ToLower(h.hash) + ".example.", Rrtype: dns.TypeNSEC3, Class: dns.ClassINET},
+			Hash:       dns.SHA1,
+			Flags:      flags,
+			Salt:       "aabbccdd",
+			NextDomain: hs[(i+1)%len(hs)].hash,
+			TypeBitMap: h.types,
+		}
+	}
+	return nsec3
+}
+
+func TestProveNSEC3(t *testing.T) {
+	names := map[string][]uint16{
+		"example.":        {dns.TypeNS, dns.TypeSOA, dns.TypeRRSIG, dns.TypeDNSKEY, dns.TypeNSEC3PARAM},
+		"a.example.":      {dns.TypeA, dns.TypeRRSIG},
+		"sub.example.":    {dns.TypeNS},
+		"secure.example.": {dns.TypeNS, dns.TypeDS, dns.TypeRRSIG},
+	}
+	nsec3 := nsec3Chain(names, false)
+	optout := nsec3Chain(names, true)
+
+	tests := []struct {
+		qname    string
+		qtype    uint16
+		nxdomain bool
+		nsec3    []*dns.NSEC3
+		result   Result
+	}{
+		{"b.example.", dns.TypeA, true, nsec3, Secure},
+		{"x.b.example.", dns.TypeA, true, nsec3, Secure},
+		{"a.example.", dns.TypeA, true, nsec3, Bogus}, // a.example. exists
+		{"a.example.", dns.TypeAAAA, false, nsec3, Secure},
+		{"a.example.", dns.TypeA, false, nsec3, Bogus},
+		{"sub.example.", dns.TypeDS, false, nsec3, Secure},
+		{"secure.example.", dns.TypeDS, false, nsec3, Bogus},
+		{"unsigned.example.", dns.TypeDS, false, optout, Insecure},
+		{"unsigned.example.", dns.TypeDS, false, nsec3, Bogus},
+		{"b.example.", dns.TypeA, true, nil, Bogus},
+	}
+	for i, tc := range tests {
+		if res := proveDenial(tc.qname, tc.qtype, tc.nxdomain, nil, tc.nsec3); res != tc.result {
+			t.Errorf("Test %d: expected %s for %s %s, got %s", i, tc.result, tc.qname, dns.TypeToString[tc.qtype], res)
+		}
+	}
+}
+
+func TestInsecureDelegationNSEC3(t *testing.T) {
+	names := map[string][]uint16{
+		"example.":        {dns.TypeNS, dns.TypeSOA},
+		"sub.example.":    {dns.TypeNS},
+		"secure.example.": {dns.TypeNS, dns.TypeDS},
+	}
+	m := new(dns.Msg)
+	for _, n := range nsec3Chain(names, false) {
+		m.Ns = append(m.Ns, n)
+	}
+	if !insecureDelegation("sub.example.", m) {
+		t.Errorf("Expected sub.example. to be an insecure delegation")
+	}
+	if insecureDelegation("secure.example.", m) {
+		t.Errorf("Expected secure.example. not to be an insecure delegation")
+	}
+	if insecureDelegation("example.", m) {
+		t.Errorf("Expected example. not to be an insecure delegation")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// The example from RFC 4034, section 6.1.
+	names := []string{"example.", "a.example.", "yljkjljk.a.example.", "Z.a.example.", "zABC.a.EXAMPLE.", "z.example.", "\\001.z.example.", "*.z.example.", "\\200.z.example."}
+	for i := 0; i < len(names)-1; i++ {
+		if c := Compare(names[i], names[i+1]); c >= 0 {
+			t.Errorf("Expected %s to sort before %s, got %d", names[i], names[i+1], c)
+		}
+	}
+	if c := Compare("Example.", "example."); c != 0 {
+		t.Errorf("Expected names to be equal, got %d", c)
+	}
+}
+
+func TestDeny(t *testing.T) {
+	nsec := []dns.RR{
+		test.NSEC("example. 3600 IN NSEC a.example. NS SOA RRSIG NSEC DNSKEY"),
+		test.NSEC("a.example. 3600 IN NSEC c.example. A RRSIG NSEC"),
+		test.NSEC("c.example. 3600 IN NSEC d.example. A RRSIG NSEC"),
+		test.NSEC("d.example. 3600 IN NSEC x.e.example. NS NSEC"),
+		test.NSEC("x.e.example. 3600 IN NSEC example. A RRSIG NSEC"),
+	}
+	names := map[string][]uint16{
+		"example.":     {dns.TypeNS, dns.TypeSOA, dns.TypeRRSIG, dns.TypeDNSKEY, dns.TypeNSEC3PARAM},
+		"a.example.":   {dns.TypeA, dns.TypeRRSIG},
+		"sub.example.": {dns.TypeNS},
+	}
+	var nsec3, optout []dns.RR
+	for _, n := range nsec3Chain(names, false) {
+		nsec3 = append(nsec3, n)
+	}
+	for _, n := range nsec3Chain(names, true) {
+		optout = append(optout, n)
+	}
+
+	tests := []struct {
+		qname       string
+		qtype       uint16
+		rrs         []dns.RR
+		ok          bool
+		rcode       int
+		expectProof int
+	}{
+		{"b.example.", dns.TypeA, nsec, true, dns.RcodeNameError, 2},
+		{"B.example.", dns.TypeA, nsec, true, dns.RcodeNameError, 2},
+		{"a.example.", dns.TypeTXT, nsec, true, dns.RcodeSuccess, 2},
+		{"e.example.", dns.TypeA, nsec, true, dns.RcodeSuccess, 2}, // empty non-terminal
+		{"a.example.", dns.TypeA, nsec, false, 0, 0},
+		{"d.example.", dns.TypeA, nsec, false, 0, 0},   // delegation
+		{"x.d.example.", dns.TypeA, nsec, false, 0, 0}, // below a delegation
+		{"a.example.", dns.TypeDS, nsec, false, 0, 0},
+		{"b.example.", dns.TypeA, nsec3, true, dns.RcodeNameError, 3},
+		{"a.example.", dns.TypeTXT, nsec3, true, dns.RcodeSuccess, 3},
+		{"a.example.", dns.TypeA, nsec3, false, 0, 0},
+		{"x.sub.example.", dns.TypeA, nsec3, false, 0, 0},
+		{"b.example.", dns.TypeA, optout, false, 0, 0},
+		{"b.example.", dns.TypeA, nil, false, 0, 0},
+	}
+	for i, tc := range tests {
+		rcode, proof, ok := Deny(tc.qname, tc.qtype, tc.rrs)
+		if ok != tc.ok {
+			t.Errorf("Test %d: expected %t for %s %s, got %t", i, tc.ok, tc.qname, dns.TypeToString[tc.qtype], ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rcode])
+		}
+		if len(proof) > tc.expectProof || len(proof) == 0 {
+			t.Errorf("Test %d: expected at most %d records in the proof, got %d", i, tc.expectProof, len(proof))
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package validator
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// rrset is a set of records with the same owner name and type, and the signatures over them.
+type rrset struct {
+	name  string // lowercased owner name.
+	qtype uint16
+	rrs   []dns.RR
+	sigs  []*dns.RRSIG
+}
+
+// rrsets groups rrs into RRsets, in the order in which they first appear.
+func rrsets(rrs []dns.RR) []*rrset {
+	type key struct {
+		name  string
+		qtype uint16
+	}
+	sets := []*rrset{}
+	index := map[key]*rrset{}
+	get := func(name string, qtype uint16) *rrset {
+		k := key{strings.ToLower(name), qtype}
+		if s, ok := index[k]; ok {
+			return s
+		}
+		s := &rrset{name: k.name, qtype: qtype}
+		index[k] = s
+		sets = append(sets, s)
+		return s
+	}
+
+	for _, rr := range rrs {
+		switch x := rr.(type) {
+		case *dns.OPT:
+		case *dns.RRSIG:
+			s := get(x.Hdr.Name, x.TypeCovered)
+			s.sigs = append(s.sigs, x)
+		default:
+			s := get(x.Header().Name, x.Header().Rrtype)
+			s.rrs = append(s.rrs, x)
+		}
+	}
+
+	// Drop signatures without records, and unsigned CNAMEs that were synthesized from a DNAME, the DNAME itself
+	// is validated.
+	j := 0
+	for _, s := range sets {
+		if len(s.rrs) == 0 || (s.qtype == dns.TypeCNAME && len(s.sigs) == 0 && dname(sets, s.name)) {
+			continue
+		}
+		sets[j] = s
+		j++
+	}
+	return sets[:j]
+}
+
+// dname returns true if one of sets is a DNAME that name is below.
+func dname(sets []*rrset, name string) bool {
+	for _, s := range sets {
+		if s.qtype == dns.TypeDNAME && s.name != name && dns.IsSubDomain(s.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the RRset of qtype at name in rrs.
+func find(rrs []dns.RR, name string, qtype uint16) *rrset {
+	for _, s := range rrsets(rrs) {
+		if s.name == name && s.qtype == qtype {
+			return s
+		}
+	}
+	return nil
+}
+
+// target follows the CNAMEs in rrs, starting at qname, and returns the last name.
+func target(qname string, rrs []dns.RR) string {
+	for i := 0; i < len(rrs); i++ {
+		for _, rr := range rrs {
+			if c, ok := rr.(*dns.CNAME); ok && strings.EqualFold(c.Hdr.Name, qname) {
+				qname = strings.ToLower(c.Target)
+				break
+			}
+		}
+	}
+	return qname
+}
+
+// signed returns true if m contains signatures.
+func signed(m *dns.Msg) bool {
+	for _, rrs := range [][]dns.RR{m.Answer, m.Ns} {
+		for _, rr := range rrs {
+			if rr.Header().Rrtype == dns.TypeRRSIG {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Filter removes the DNSSEC records from rrs, unless they are of type qtype. It is used for the replies to
+// clients that didn't set the DO bit.
+func Filter(rrs []dns.RR, qtype uint16) []dns.RR {
+	j := 0
+	for _, rr := range rrs {
+		switch t := rr.Header().Rrtype; t {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+			if t != qtype {
+				continue
+			}
+		}
+		rrs[j] = rr
+		j++
+	}
+	return rrs[:j]
+}
@@ -0,0 +1,519 @@
+// Package validator implements DNSSEC validation of responses, see RFC 4033, RFC 4034, RFC 4035 and RFC 5155.
+//
+// A Validator checks the signatures in a response against the keys of the zone that signed them, and follows the
+// chain of DS and DNSKEY records up to one of its trust anchors. The records needed for that are looked up with
+// a Resolver, the keys that are found to be valid are cached.
+package validator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+)
+
+// Resolver looks up the records needed for validation. The responses must have the DNSSEC records
+// included, i.e. the queries are sent with the DO bit set.
+type Resolver interface {
+	Lookup(ctx context.Context, name string, qtype uint16) (*dns.Msg, error)
+}
+
+// Result is the outcome of validating a response.
+type Result int
+
+const (
+	// Insecure means there is no chain of trust for the response, it is not signed or there is no trust
+	// anchor for it.
+	Insecure Result = iota
+	// Secure means the response is signed and validated up to a trust anchor.
+	Secure
+	// Bogus means the response should be signed but it failed to validate.
+	Bogus
+)
+
+func (r Result) String() string {
+	switch r {
+	case Insecure:
+		return "insecure"
+	case Secure:
+		return "secure"
+	case Bogus:
+		return "bogus"
+	}
+	return ""
+}
+
+// Errors that are returned with a Bogus result.
+var (
+	ErrNoSignatures     = errors.New("missing signatures")
+	ErrSignature        = errors.New("invalid signature")
+	ErrSignatureExpired = errors.New("signature expired or not yet valid")
+	ErrNoDNSKEY         = errors.New("no valid DNSKEY")
+	ErrNoDenial         = errors.New("missing or invalid denial of existence")
+	ErrLookup           = errors.New("lookup failed")
+)
+
+// InfoCode returns the Extended DNS Error info code, see RFC 8914, for an error that is returned with a Bogus result.
+func InfoCode(err error) uint16 {
+	switch {
+	case errors.Is(err, ErrSignatureExpired):
+		return dns.ExtendedErrorCodeSignatureExpired
+	case errors.Is(err, ErrNoDNSKEY):
+		return dns.ExtendedErrorCodeDNSKEYMissing
+	case errors.Is(err, ErrNoSignatures):
+		return dns.ExtendedErrorCodeRRSIGsMissing
+	case errors.Is(err, ErrNoDenial):
+		return dns.ExtendedErrorCodeNSECMissing
+	}
+	return dns.ExtendedErrorCodeDNSBogus
+}
+
+// Validator validates responses.
+type Validator struct {
+	anchors  map[string][]dns.RR // DS or DNSKEY records, by owner name.
+	negative map[string]bool     // negative trust anchors, RFC 7646.
+	keys     *cache.Cache        // *zoneKeys by zone name.
+
+	now func() time.Time
+}
+
+// zoneKeys holds the validated keys of a zone, or none if the zone is insecure.
+type zoneKeys struct {
+	keys     []*dns.DNSKEY
+	insecure bool
+	expires  time.Time
+}
+
+const (
+	keyCacheSize = 10000
+	// maxKeyTTL caps the time validated keys are cached.
+	maxKeyTTL = 1 * time.Hour
+	// maxDepth limits the number of nested lookups for a single validation.
+	maxDepth = 16
+)
+
+// New returns a new validator that uses anchors as its trust anchors. These can be DS and DNSKEY records.
+func New(anchors []dns.RR) *Validator {
+	v := &Validator{anchors: map[string][]dns.RR{}, negative: map[string]bool{}, keys: cache.New(keyCacheSize), now: time.Now}
+	for _, rr := range anchors {
+		name := strings.ToLower(rr.Header().Name)
+		v.anchors[name] = append(v.anchors[name], rr)
+	}
+	return v
+}
+
+// SetNegativeAnchors sets the negative trust anchors, see RFC 7646. Responses for these names and the names below
+// them are not validated, and are treated as insecure.
+func (v *Validator) SetNegativeAnchors(names ...string) {
+	v.negative = map[string]bool{}
+	for _, name := range names {
+		v.negative[strings.ToLower(dns.Fqdn(name))] = true
+	}
+}
+
+// depthKey is the context key for the number of nested lookups done for a validation.
+type depthKey struct{}
+
+func deeper(ctx context.Context) (context.Context, error) {
+	depth, _ := ctx.Value(depthKey{}).(int)
+	if depth >= maxDepth {
+		return ctx, ErrLookup
+	}
+	return context.WithValue(ctx, depthKey{}, depth+1), nil
+}
+
+// Validate validates the response m with the records looked up with r. For a Bogus result the reason is
+// returned in the error.
+func (v *Validator) Validate(ctx context.Context, r Resolver, m *dns.Msg) (Result, error) {
+	if len(m.Question) == 0 || (m.Rcode != dns.RcodeSuccess && m.Rcode != dns.RcodeNameError) {
+		return Insecure, nil
+	}
+	q := m.Question[0]
+	qname := strings.ToLower(q.Name)
+	if v.anchor(qname) == "" {
+		return Insecure, nil
+	}
+
+	result := Secure
+	var expanded []*dns.RRSIG // signatures of records that were synthesized from a wildcard.
+	for _, set := range rrsets(m.Answer) {
+		res, sig, err := v.verify(ctx, r, set)
+		switch res {
+		case Bogus:
+			return Bogus, err
+		case Insecure:
+			result = Insecure
+		case Secure:
+			if int(sig.Labels) < dns.CountLabel(set.name) {
+				expanded = append(expanded, sig)
+			}
+		}
+	}
+
+	qname = target(qname, m.Answer)
+	answered := false
+	for _, rr := range m.Answer {
+		if strings.EqualFold(rr.Header().Name, qname) && (rr.Header().Rrtype == q.Qtype || q.Qtype == dns.TypeANY) {
+			answered = true
+			break
+		}
+	}
+	if answered && m.Rcode == dns.RcodeSuccess && len(expanded) == 0 {
+		return result, nil
+	}
+
+	// The response needs a denial of existence in the authority section.
+	var (
+		nsec   []*dns.NSEC
+		nsec3  []*dns.NSEC3
+		denial bool
+	)
+	for _, set := range rrsets(m.Ns) {
+		switch set.qtype {
+		case dns.TypeSOA, dns.TypeNSEC, dns.TypeNSEC3:
+		default:
+			continue
+		}
+		denial = true
+		res, _, err := v.verify(ctx, r, set)
+		switch res {
+		case Bogus:
+			return Bogus, err
+		case Insecure:
+			result = Insecure
+			continue
+		}
+		for _, rr := range set.rrs {
+			switch x := rr.(type) {
+			case *dns.NSEC:
+				nsec = append(nsec, x)
+			case *dns.NSEC3:
+				nsec3 = append(nsec3, x)
+			}
+		}
+	}
+	if !denial && result == Secure {
+		// Nothing in the authority section, this is fine if the name is in an insecure zone.
+		if ok, err := v.insecure(ctx, r, qname); !ok {
+			if err == nil {
+				err = ErrNoDenial
+			}
+			return Bogus, err
+		}
+		return Insecure, nil
+	}
+	if result == Insecure {
+		return Insecure, nil
+	}
+
+	for _, sig := range expanded {
+		if !wildcardProof(sig, nsec, nsec3) {
+			return Bogus, ErrNoDenial
+		}
+	}
+	if answered && m.Rcode == dns.RcodeSuccess {
+		return result, nil
+	}
+	switch proveDenial(qname, q.Qtype, m.Rcode == dns.RcodeNameError, nsec, nsec3) {
+	case Insecure:
+		return Insecure, nil
+	case Bogus:
+		return Bogus, ErrNoDenial
+	}
+	return Secure, nil
+}
+
+// verify checks the signatures of set and returns the signature that verified it.
+func (v *Validator) verify(ctx context.Context, r Resolver, set *rrset) (Result, *dns.RRSIG, error) {
+	if len(set.sigs) == 0 {
+		if ok, err := v.insecure(ctx, r, set.name); !ok {
+			if err == nil {
+				err = ErrNoSignatures
+			}
+			return Bogus, nil, err
+		}
+		return Insecure, nil, nil
+	}
+
+	var last error = ErrSignature
+	for _, sig := range set.sigs {
+		signer := strings.ToLower(sig.SignerName)
+		if !dns.IsSubDomain(signer, set.name) {
+			continue
+		}
+		keys, res, err := v.zoneKeys(ctx, r, signer)
+		switch res {
+		case Insecure:
+			return Insecure, nil, nil
+		case Bogus:
+			last = err
+			continue
+		}
+		if err := v.check(sig, keys, set.rrs); err != nil {
+			last = err
+			continue
+		}
+		return Secure, sig, nil
+	}
+	return Bogus, nil, last
+}
+
+// check checks sig over rrs with keys.
+func (v *Validator) check(sig *dns.RRSIG, keys []*dns.DNSKEY, rrs []dns.RR) error {
+	if !sig.ValidityPeriod(v.now()) {
+		return ErrSignatureExpired
+	}
+	for _, k := range keys {
+		if k.KeyTag() != sig.KeyTag || k.Algorithm != sig.Algorithm {
+			continue
+		}
+		if sig.Verify(k, rrs) == nil {
+			return nil
+		}
+	}
+	return ErrSignature
+}
+
+// zoneKeys returns the validated keys of zone.
+func (v *Validator) zoneKeys(ctx context.Context, r Resolver, zone string) ([]*dns.DNSKEY, Result, error) {
+	key := cache.Hash([]byte(zone))
+	if i, ok := v.keys.Get(key); ok {
+		zk := i.(*zoneKeys)
+		if v.now().Before(zk.expires) {
+			if zk.insecure {
+				return nil, Insecure, nil
+			}
+			return zk.keys, Secure, nil
+		}
+		v.keys.Remove(key)
+	}
+
+	anchor := v.anchor(zone)
+	if anchor == "" {
+		return nil, Insecure, nil
+	}
+	ctx, err := deeper(ctx)
+	if err != nil {
+		return nil, Bogus, err
+	}
+
+	trusted := v.anchors[zone]
+	ttl := maxKeyTTL
+	if zone != anchor {
+		m, err := r.Lookup(ctx, zone, dns.TypeDS)
+		if err != nil || m == nil {
+			return nil, Bogus, ErrLookup
+		}
+		set := find(m.Answer, zone, dns.TypeDS)
+		if set == nil {
+			// No DS, this must be an insecure delegation.
+			res, err := v.Validate(ctx, r, m)
+			switch res {
+			case Bogus:
+				return nil, Bogus, err
+			case Secure:
+				if !insecureDelegation(zone, m) {
+					return nil, Bogus, ErrNoDNSKEY
+				}
+			}
+			v.insecureZone(zone, m)
+			return nil, Insecure, nil
+		}
+		res, _, err := v.verify(ctx, r, set)
+		switch res {
+		case Bogus:
+			return nil, Bogus, err
+		case Insecure:
+			return nil, Insecure, nil
+		}
+		trusted = supported(set.rrs)
+		if len(trusted) == 0 {
+			// None of the DS records can be used, treat the zone as insecure, RFC 4035, section 5.2.
+			v.insecureZone(zone, m)
+			return nil, Insecure, nil
+		}
+		ttl = minTTL(ttl, set.rrs)
+	}
+
+	keys, kttl, err := v.fetchKeys(ctx, r, zone, trusted)
+	if err != nil {
+		return nil, Bogus, err
+	}
+	if kttl < ttl {
+		ttl = kttl
+	}
+	v.keys.Add(key, &zoneKeys{keys: keys, expires: v.now().Add(ttl)})
+	return keys, Secure, nil
+}
+
+// fetchKeys looks up the DNSKEY records of zone and returns them if they are signed with one of the keys that
+// match trusted.
+func (v *Validator) fetchKeys(ctx context.Context, r Resolver, zone string, trusted []dns.RR) ([]*dns.DNSKEY, time.Duration, error) {
+	m, err := r.Lookup(ctx, zone, dns.TypeDNSKEY)
+	if err != nil || m == nil {
+		return nil, 0, ErrLookup
+	}
+	set := find(m.Answer, zone, dns.TypeDNSKEY)
+	if set == nil {
+		return nil, 0, ErrNoDNSKEY
+	}
+
+	var keys, sep []*dns.DNSKEY
+	for _, rr := range set.rrs {
+		k, ok := rr.(*dns.DNSKEY)
+		if !ok || k.Flags&dns.ZONE == 0 {
+			continue
+		}
+		keys = append(keys, k)
+		if matches(k, trusted) {
+			sep = append(sep, k)
+		}
+	}
+	if len(sep) == 0 {
+		return nil, 0, ErrNoDNSKEY
+	}
+
+	err = ErrNoSignatures
+	for _, sig := range set.sigs {
+		if err = v.check(sig, sep, set.rrs); err == nil {
+			return keys, minTTL(maxKeyTTL, set.rrs), nil
+		}
+	}
+	return nil, 0, err
+}
+
+// insecure returns true if name is provably in an insecure zone, i.e. a zone that has no DS records in its
+// (secure) parent, or no trust anchor.
+func (v *Validator) insecure(ctx context.Context, r Resolver, name string) (bool, error) {
+	name = strings.ToLower(dns.Fqdn(name))
+	if v.anchor(name) == "" {
+		return true, nil
+	}
+	for off, end := 0, false; !end; off, end = dns.NextLabel(name, off) {
+		if i, ok := v.keys.Get(cache.Hash([]byte(name[off:]))); ok {
+			if zk := i.(*zoneKeys); zk.insecure && v.now().Before(zk.expires) {
+				return true, nil
+			}
+		}
+	}
+
+	ctx, err := deeper(ctx)
+	if err != nil {
+		return false, err
+	}
+	m, err := r.Lookup(ctx, name, dns.TypeDS)
+	if err != nil || m == nil {
+		return false, ErrLookup
+	}
+	if !signed(m) {
+		// The zone that answered is not signed, see if that zone is an insecure delegation.
+		for _, rr := range m.Ns {
+			soa, ok := rr.(*dns.SOA)
+			if !ok {
+				continue
+			}
+			zone := strings.ToLower(soa.Hdr.Name)
+			if zone == name || !dns.IsSubDomain(zone, name) {
+				return false, ErrNoSignatures
+			}
+			return v.insecure(ctx, r, zone)
+		}
+		return false, ErrNoSignatures
+	}
+
+	res, err := v.Validate(ctx, r, m)
+	switch res {
+	case Bogus:
+		return false, err
+	case Insecure:
+		return true, nil
+	}
+	if insecureDelegation(name, m) {
+		v.insecureZone(name, m)
+		return true, nil
+	}
+	return false, nil
+}
+
+// insecureZone caches zone as insecure for the lowest TTL of the records in m.
+func (v *Validator) insecureZone(zone string, m *dns.Msg) {
+	ttl := minTTL(maxKeyTTL, m.Ns)
+	v.keys.Add(cache.Hash([]byte(zone)), &zoneKeys{insecure: true, expires: v.now().Add(ttl)})
+}
+
+// anchor returns the name of the closest trust anchor of name, or the empty string if there is none or if a
+// negative trust anchor is closer.
+func (v *Validator) anchor(name string) string {
+	name = strings.ToLower(name)
+	for off, end := 0, false; !end; off, end = dns.NextLabel(name, off) {
+		if v.negative[name[off:]] {
+			return ""
+		}
+		if _, ok := v.anchors[name[off:]]; ok {
+			return name[off:]
+		}
+	}
+	if v.negative["."] {
+		return ""
+	}
+	if _, ok := v.anchors["."]; ok {
+		return "."
+	}
+	return ""
+}
+
+// matches returns true if k matches one of the DS or DNSKEY records in trusted.
+func matches(k *dns.DNSKEY, trusted []dns.RR) bool {
+	for _, rr := range trusted {
+		switch x := rr.(type) {
+		case *dns.DS:
+			if x.KeyTag != k.KeyTag() || x.Algorithm != k.Algorithm {
+				continue
+			}
+			if ds := k.ToDS(x.DigestType); ds != nil && strings.EqualFold(ds.Digest, x.Digest) {
+				return true
+			}
+		case *dns.DNSKEY:
+			if x.Algorithm == k.Algorithm && x.Flags == k.Flags && x.PublicKey == k.PublicKey {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// supported returns the DS records in rrs with a digest type and algorithm that can be validated.
+func supported(rrs []dns.RR) []dns.RR {
+	var ds []dns.RR
+	for _, rr := range rrs {
+		x, ok := rr.(*dns.DS)
+		if !ok {
+			continue
+		}
+		switch x.DigestType {
+		case dns.SHA1, dns.SHA256, dns.SHA384:
+		default:
+			continue
+		}
+		switch x.Algorithm {
+		case dns.RSASHA1, dns.RSASHA1NSEC3SHA1, dns.RSASHA256, dns.RSASHA512, dns.ECDSAP256SHA256, dns.ECDSAP384SHA384, dns.ED25519:
+		default:
+			continue
+		}
+		ds = append(ds, x)
+	}
+	return ds
+}
+
+func minTTL(max time.Duration, rrs []dns.RR) time.Duration {
+	for _, rr := range rrs {
+		if ttl := time.Duration(rr.Header().Ttl) * time.Second; ttl < max {
+			max = ttl
+		}
+	}
+	return max
+}
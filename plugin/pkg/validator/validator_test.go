@@ -0,0 +1,287 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// hierarchy is a signed root, org. and example.org. zone, and an unsigned insecure.org. zone.
+type hierarchy struct {
+	zones  map[string]*file.Zone
+	anchor dns.RR
+}
+
+func newHierarchy(t *testing.T) *hierarchy {
+	kRoot, kOrg, kExample := test.NewKey("."), test.NewKey("org."), test.NewKey("example.org.")
+
+	root := test.Sign(".", []dns.RR{
+		test.SOA(". 3600 IN SOA a.root-servers.test. hostmaster.root-servers.test. 1 3600 600 86400 300"),
+		test.NS(". 3600 IN NS a.root-servers.test."),
+		test.NS("org. 3600 IN NS ns.org."),
+		test.A("ns.org. 3600 IN A 127.0.0.2"),
+		kOrg.DS(),
+	}, kRoot)
+	org := test.Sign("org.", []dns.RR{
+		test.SOA("org. 3600 IN SOA ns.org. hostmaster.org. 1 3600 600 86400 300"),
+		test.NS("org. 3600 IN NS ns.org."),
+		test.A("ns.org. 3600 IN A 127.0.0.2"),
+		test.NS("example.org. 3600 IN NS ns.example.org."),
+		test.A("ns.example.org. 3600 IN A 127.0.0.3"),
+		kExample.DS(),
+		test.NS("insecure.org. 3600 IN NS ns.insecure.org."),
+		test.A("ns.insecure.org. 3600 IN A 127.0.0.4"),
+	}, kOrg)
+	example := test.Sign("example.org.", []dns.RR{
+		test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300"),
+		test.NS("example.org. 3600 IN NS ns.example.org."),
+		test.A("ns.example.org. 3600 IN A 127.0.0.3"),
+		test.A("www.example.org. 3600 IN A 192.0.2.1"),
+		test.A("*.wild.example.org. 3600 IN A 192.0.2.2"),
+		test.A("a.b.c.example.org. 3600 IN A 192.0.2.5"),
+		test.CNAME("alias.example.org. 3600 IN CNAME www.example.org."),
+	}, kExample)
+	insecure := []dns.RR{
+		test.SOA("insecure.org. 3600 IN SOA ns.insecure.org. hostmaster.insecure.org. 1 3600 600 86400 300"),
+		test.NS("insecure.org. 3600 IN NS ns.insecure.org."),
+		test.A("ns.insecure.org. 3600 IN A 127.0.0.4"),
+		test.A("www.insecure.org. 3600 IN A 192.0.2.3"),
+	}
+
+	h := &hierarchy{zones: map[string]*file.Zone{}, anchor: kRoot.DS()}
+	for origin, rrs := range map[string][]dns.RR{".": root, "org.": org, "example.org.": example, "insecure.org.": insecure} {
+		h.zones[origin] = parseZone(t, origin, rrs)
+	}
+	return h
+}
+
+func parseZone(t *testing.T, origin string, rrs []dns.RR) *file.Zone {
+	var sb strings.Builder
+	for _, rr := range rrs {
+		sb.WriteString(rr.String() + "\n")
+	}
+	z, err := file.Parse(strings.NewReader(sb.String()), origin, "stdin", 0)
+	if err != nil {
+		t.Fatalf("Failed to parse zone %s: %s", origin, err)
+	}
+	return z
+}
+
+// Lookup implements Resolver, it sends the query to the zone that holds name, or for DS queries its parent.
+func (h *hierarchy) Lookup(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	name = strings.ToLower(name)
+	zone := ""
+	for origin := range h.zones {
+		if !dns.IsSubDomain(origin, name) || len(origin) <= len(zone) {
+			continue
+		}
+		if qtype == dns.TypeDS && origin == name && name != "." {
+			continue
+		}
+		zone = origin
+	}
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.SetEdns0(4096, true)
+
+	f := file.File{Zones: file.Zones{Z: map[string]*file.Zone{zone: h.zones[zone]}, Names: []string{zone}}}
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := f.ServeDNS(ctx, rec, m); err != nil {
+		return nil, err
+	}
+	return rec.Msg, nil
+}
+
+func TestValidate(t *testing.T) {
+	h := newHierarchy(t)
+	v := New([]dns.RR{h.anchor})
+
+	tests := []struct {
+		qname  string
+		qtype  uint16
+		result Result
+	}{
+		{"www.example.org.", dns.TypeA, Secure},
+		{"WWW.example.org.", dns.TypeA, Secure},
+		{"www.example.org.", dns.TypeAAAA, Secure},    // NODATA
+		{"nope.example.org.", dns.TypeA, Secure},      // NXDOMAIN
+		{"c.example.org.", dns.TypeA, Secure},         // empty non-terminal
+		{"a.wild.example.org.", dns.TypeA, Secure},    // wildcard
+		{"example.org.", dns.TypeDNSKEY, Secure},      // keys
+		{"example.org.", dns.TypeDS, Secure},          // DS in the parent
+		{"www.insecure.org.", dns.TypeA, Insecure},    // insecure delegation
+		{"nope.insecure.org.", dns.TypeA, Insecure},   // insecure NXDOMAIN
+		{"insecure.org.", dns.TypeDS, Secure},         // no DS in the parent
+		{"aaa.org.", dns.TypeA, Secure},               // NXDOMAIN in the parent
+		{"alias.example.org.", dns.TypeA, Secure},     // CNAME
+		{"www.example.org.", dns.TypeCNAME, Secure},   // NODATA for the CNAME
+		{"www.insecure.org.", dns.TypeAAAA, Insecure}, // insecure NODATA
+	}
+	for i, tc := range tests {
+		m, err := h.Lookup(context.TODO(), tc.qname, tc.qtype)
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		res, err := v.Validate(context.TODO(), h, m)
+		if res != tc.result {
+			t.Errorf("Test %d: expected %s for %s %s, got %s (%v)", i, tc.result, tc.qname, dns.TypeToString[tc.qtype], res, err)
+		}
+	}
+}
+
+func TestValidateBogus(t *testing.T) {
+	h := newHierarchy(t)
+
+	tests := []struct {
+		qname  string
+		qtype  uint16
+		modify func(*dns.Msg)
+		err    error
+	}{
+		{
+			"www.example.org.", dns.TypeA,
+			func(m *dns.Msg) { m.Answer[0].(*dns.A).A = test.A("x. IN A 192.0.2.100").A },
+			ErrSignature,
+		},
+		{
+			"www.example.org.", dns.TypeA,
+			func(m *dns.Msg) { m.Answer = m.Answer[:1] },
+			ErrNoSignatures,
+		},
+		{
+			"nope.example.org.", dns.TypeA,
+			func(m *dns.Msg) { m.Ns = m.Ns[:2] }, // SOA and its signature
+			ErrNoDenial,
+		},
+		{
+			"www.example.org.", dns.TypeAAAA,
+			func(m *dns.Msg) { m.Ns = nil },
+			ErrNoDenial,
+		},
+		{
+			"a.wild.example.org.", dns.TypeA,
+			func(m *dns.Msg) { m.Ns = nil },
+			ErrNoDenial,
+		},
+		{
+			"www.example.org.", dns.TypeA,
+			func(m *dns.Msg) { m.Rcode = dns.RcodeNameError; m.Answer = nil },
+			ErrNoDenial,
+		},
+	}
+	for i, tc := range tests {
+		v := New([]dns.RR{h.anchor})
+		m, _ := h.Lookup(context.TODO(), tc.qname, tc.qtype)
+		tc.modify(m)
+		res, err := v.Validate(context.TODO(), h, m)
+		if res != Bogus {
+			t.Errorf("Test %d: expected bogus, got %s", i, res)
+			continue
+		}
+		if !errors.Is(err, tc.err) {
+			t.Errorf("Test %d: expected error %q, got %q", i, tc.err, err)
+		}
+	}
+}
+
+func TestValidateAnchors(t *testing.T) {
+	h := newHierarchy(t)
+	m, _ := h.Lookup(context.TODO(), "www.example.org.", dns.TypeA)
+
+	// No trust anchor.
+	v := New(nil)
+	if res, _ := v.Validate(context.TODO(), h, m); res != Insecure {
+		t.Errorf("Expected insecure without trust anchors, got %s", res)
+	}
+
+	// The wrong trust anchor.
+	v = New([]dns.RR{test.NewKey(".").DS()})
+	if res, err := v.Validate(context.TODO(), h, m); res != Bogus || err != ErrNoDNSKEY {
+		t.Errorf("Expected bogus with %q, got %s with %q", ErrNoDNSKEY, res, err)
+	}
+
+	// A negative trust anchor for example.org.
+	v.SetNegativeAnchors("Example.org")
+	if res, err := v.Validate(context.TODO(), h, m); res != Insecure {
+		t.Errorf("Expected insecure with a negative trust anchor, got %s (%v)", res, err)
+	}
+
+	// A trust anchor for example.org, as a DNSKEY.
+	k, _ := h.Lookup(context.TODO(), "example.org.", dns.TypeDNSKEY)
+	v = New([]dns.RR{k.Answer[0]})
+	if res, err := v.Validate(context.TODO(), h, m); res != Secure {
+		t.Errorf("Expected secure with a trust anchor for the zone, got %s (%v)", res, err)
+	}
+
+	// Signatures that expired.
+	v = New([]dns.RR{h.anchor})
+	v.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	if res, err := v.Validate(context.TODO(), h, m); res != Bogus || err != ErrSignatureExpired {
+		t.Errorf("Expected bogus with %q, got %s with %q", ErrSignatureExpired, res, err)
+	}
+}
+
+func TestValidateKeyCache(t *testing.T) {
+	h := newHierarchy(t)
+	v := New([]dns.RR{h.anchor})
+	m, _ := h.Lookup(context.TODO(), "www.example.org.", dns.TypeA)
+	if res, _ := v.Validate(context.TODO(), h, m); res != Secure {
+		t.Fatalf("Expected secure, got %s", res)
+	}
+	// ., org. and example.org.
+	if x := v.keys.Len(); x != 3 {
+		t.Errorf("Expected 3 zones in the key cache, got %d", x)
+	}
+
+	// With the keys cached, nothing needs to be looked up.
+	if res, _ := v.Validate(context.TODO(), nopResolver{}, m); res != Secure {
+		t.Errorf("Expected secure from the key cache, got %s", res)
+	}
+}
+
+func TestInfoCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code uint16
+	}{
+		{ErrSignatureExpired, dns.ExtendedErrorCodeSignatureExpired},
+		{ErrNoDNSKEY, dns.ExtendedErrorCodeDNSKEYMissing},
+		{ErrNoSignatures, dns.ExtendedErrorCodeRRSIGsMissing},
+		{ErrNoDenial, dns.ExtendedErrorCodeNSECMissing},
+		{ErrSignature, dns.ExtendedErrorCodeDNSBogus},
+		{ErrLookup, dns.ExtendedErrorCodeDNSBogus},
+	}
+	for i, tc := range tests {
+		if code := InfoCode(tc.err); code != tc.code {
+			t.Errorf("Test %d: expected info code %d, got %d", i, tc.code, code)
+		}
+	}
+}
+
+type nopResolver struct{}
+
+func (nopResolver) Lookup(context.Context, string, uint16) (*dns.Msg, error) {
+	return nil, errors.New("no lookups")
+}
+
+func TestFilter(t *testing.T) {
+	rrs := []dns.RR{
+		test.A("example.org. 3600 IN A 127.0.0.1"),
+		test.RRSIG("example.org. 3600 IN RRSIG A 13 2 3600 20240101000000 20230101000000 12345 example.org. c2ln"),
+		test.NSEC("example.org. 3600 IN NSEC www.example.org. A RRSIG NSEC"),
+	}
+	if got := Filter(append([]dns.RR{}, rrs...), dns.TypeA); len(got) != 1 || got[0].Header().Rrtype != dns.TypeA {
+		t.Errorf("Expected only the A record, got %v", got)
+	}
+	if got := Filter(append([]dns.RR{}, rrs...), dns.TypeNSEC); len(got) != 2 || got[1].Header().Rrtype != dns.TypeNSEC {
+		t.Errorf("Expected the A and NSEC records, got %v", got)
+	}
+}
@@ -0,0 +1,108 @@
+// Package recursive implements a plugin that resolves queries by iterating from the root name servers.
+package recursive
+
+import (
+	"context"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/validator"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("recursive")
+
+// Recursive resolves queries for Zones by following the delegations from the root name servers down.
+type Recursive struct {
+	Next  plugin.Handler
+	Zones []string
+
+	roots       []string             // addresses of the root name servers.
+	validator   *validator.Validator // nil when DNSSEC validation is disabled.
+	minimize    bool                 // QNAME minimisation, RFC 9156.
+	delegations *cache.Cache         // *delegation by zone name.
+	timeout     time.Duration        // timeout for a single query to a name server.
+
+	exchange func(ctx context.Context, m *dns.Msg, addr string) (*dns.Msg, error)
+}
+
+// New returns a new Recursive that uses the root hints, validates with the root trust anchors and does QNAME
+// minimisation.
+func New() *Recursive {
+	r := &Recursive{
+		roots:       rootHints(),
+		validator:   validator.New(validator.RootAnchors()),
+		minimize:    true,
+		delegations: cache.New(defaultCap),
+		timeout:     defaultTimeout,
+	}
+	r.exchange = r.exchangeUDP
+	return r
+}
+
+const (
+	defaultCap     = 10000
+	defaultTimeout = 2 * time.Second
+)
+
+// ServeDNS implements the plugin.Handler interface.
+func (r *Recursive) ServeDNS(ctx context.Context, w dns.ResponseWriter, req *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: req}
+	if plugin.Zones(r.Zones).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(r.Name(), r.Next, ctx, w, req)
+	}
+
+	resp, err := r.resolve(ctx, state.Name(), state.QType())
+	if err != nil {
+		return dns.RcodeServerFailure, edns.NewError(dns.ExtendedErrorCodeNoReachableAuthority, err)
+	}
+
+	secure := false
+	if r.validator != nil && !req.CheckingDisabled {
+		res, err := r.validator.Validate(ctx, r, resp)
+		switch res {
+		case validator.Bogus:
+			log.Debugf("Failed to validate %s %s: %s", state.Name(), state.Type(), err)
+			m := new(dns.Msg)
+			m.SetRcode(req, dns.RcodeServerFailure)
+			if req.IsEdns0() != nil {
+				code := validator.InfoCode(err)
+				edns.SetExtendedError(m, code, dns.ExtendedErrorCodeToString[code])
+				state.SizeAndDo(m)
+			}
+			w.WriteMsg(m)
+			return dns.RcodeSuccess, nil
+		case validator.Secure:
+			secure = true
+		}
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(req)
+	m.RecursionAvailable = true
+	m.Rcode = resp.Rcode
+	m.AuthenticatedData = secure && (state.Do() || req.AuthenticatedData)
+	m.Answer, m.Ns = resp.Answer, resp.Ns
+	if !state.Do() {
+		m.Answer = validator.Filter(m.Answer, state.QType())
+		m.Ns = validator.Filter(m.Ns, state.QType())
+	}
+
+	state.SizeAndDo(m)
+	m = state.Scrub(m)
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Lookup implements the validator.Resolver interface.
+func (r *Recursive) Lookup(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	return r.resolve(ctx, name, qtype)
+}
+
+// Name implements the plugin.Handler interface.
+func (r *Recursive) Name() string { return "recursive" }
@@ -0,0 +1,325 @@
+package recursive
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/pkg/validator"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// servers is a hierarchy of name servers, a root, the TLD org. and some zones below it. Each is a file plugin,
+// indexed by its address.
+type servers struct {
+	sync.Mutex
+	handlers map[string]file.File
+	queries  map[string][]string // the queries each server got.
+}
+
+func newServers(t *testing.T) (*servers, dns.RR) {
+	kRoot, kOrg, kExample, kBogus := test.NewKey("."), test.NewKey("org."), test.NewKey("example.org."), test.NewKey("bogus.org.")
+
+	root := test.Sign(".", []dns.RR{
+		test.SOA(". 3600 IN SOA a.root-servers.test. hostmaster.root-servers.test. 1 3600 600 86400 300"),
+		test.NS(". 3600 IN NS a.root-servers.test."),
+		test.NS("org. 3600 IN NS ns.org."),
+		test.A("ns.org. 3600 IN A 10.0.0.2"),
+		kOrg.DS(),
+	}, kRoot)
+	org := test.Sign("org.", []dns.RR{
+		test.SOA("org. 3600 IN SOA ns.org. hostmaster.org. 1 3600 600 86400 300"),
+		test.NS("org. 3600 IN NS ns.org."),
+		test.A("ns.org. 3600 IN A 10.0.0.2"),
+		test.NS("example.org. 3600 IN NS ns.example.org."),
+		test.A("ns.example.org. 3600 IN A 10.0.0.3"),
+		kExample.DS(),
+		test.NS("insecure.org. 3600 IN NS ns.insecure.org."),
+		test.A("ns.insecure.org. 3600 IN A 10.0.0.4"),
+		test.NS("glueless.org. 3600 IN NS ns.example.org."), // served by the example.org. name server
+		test.NS("v6only.org. 3600 IN NS ns6.example.org."),  // served by a name server with only an IPv6 address
+		test.NS("bogus.org. 3600 IN NS ns.bogus.org."),
+		test.A("ns.bogus.org. 3600 IN A 10.0.0.5"),
+		test.NewKey("bogus.org.").DS(), // a DS for another key
+	}, kOrg)
+	example := test.Sign("example.org.", []dns.RR{
+		test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300"),
+		test.NS("example.org. 3600 IN NS ns.example.org."),
+		test.A("ns.example.org. 3600 IN A 10.0.0.3"),
+		test.A("www.example.org. 3600 IN A 192.0.2.1"),
+		test.A("a.b.c.example.org. 3600 IN A 192.0.2.2"),
+		test.AAAA("ns6.example.org. 3600 IN AAAA 2001:db8::6"),
+		test.CNAME("insecure.example.org. 3600 IN CNAME www.insecure.org."),
+	}, kExample)
+	insecure := []dns.RR{
+		test.SOA("insecure.org. 3600 IN SOA ns.insecure.org. hostmaster.insecure.org. 1 3600 600 86400 300"),
+		test.NS("insecure.org. 3600 IN NS ns.insecure.org."),
+		test.A("ns.insecure.org. 3600 IN A 10.0.0.4"),
+		test.A("www.insecure.org. 3600 IN A 192.0.2.3"),
+	}
+	glueless := []dns.RR{
+		test.SOA("glueless.org. 3600 IN SOA ns.example.org. hostmaster.glueless.org. 1 3600 600 86400 300"),
+		test.NS("glueless.org. 3600 IN NS ns.example.org."),
+		test.A("www.glueless.org. 3600 IN A 192.0.2.4"),
+	}
+	v6only := []dns.RR{
+		test.SOA("v6only.org. 3600 IN SOA ns6.example.org. hostmaster.v6only.org. 1 3600 600 86400 300"),
+		test.NS("v6only.org. 3600 IN NS ns6.example.org."),
+		test.A("www.v6only.org. 3600 IN A 192.0.2.6"),
+	}
+	bogus := test.Sign("bogus.org.", []dns.RR{
+		test.SOA("bogus.org. 3600 IN SOA ns.bogus.org. hostmaster.bogus.org. 1 3600 600 86400 300"),
+		test.NS("bogus.org. 3600 IN NS ns.bogus.org."),
+		test.A("ns.bogus.org. 3600 IN A 10.0.0.5"),
+		test.A("www.bogus.org. 3600 IN A 192.0.2.5"),
+	}, kBogus)
+
+	s := &servers{handlers: map[string]file.File{}, queries: map[string][]string{}}
+	s.add(t, "10.0.0.1:53", map[string][]dns.RR{".": root})
+	s.add(t, "10.0.0.2:53", map[string][]dns.RR{"org.": org})
+	s.add(t, "10.0.0.3:53", map[string][]dns.RR{"example.org.": example, "glueless.org.": glueless})
+	s.add(t, "10.0.0.4:53", map[string][]dns.RR{"insecure.org.": insecure})
+	s.add(t, "10.0.0.5:53", map[string][]dns.RR{"bogus.org.": bogus})
+	s.add(t, "[2001:db8::6]:53", map[string][]dns.RR{"v6only.org.": v6only})
+	return s, kRoot.DS()
+}
+
+func (s *servers) add(t *testing.T, addr string, zones map[string][]dns.RR) {
+	f := file.File{Zones: file.Zones{Z: map[string]*file.Zone{}}}
+	for origin, rrs := range zones {
+		var sb strings.Builder
+		for _, rr := range rrs {
+			sb.WriteString(rr.String() + "\n")
+		}
+		z, err := file.Parse(strings.NewReader(sb.String()), origin, "stdin", 0)
+		if err != nil {
+			t.Fatalf("Failed to parse zone %s: %s", origin, err)
+		}
+		f.Zones.Z[origin] = z
+		f.Zones.Names = append(f.Zones.Names, origin)
+	}
+	s.handlers[addr] = f
+}
+
+func (s *servers) exchange(ctx context.Context, m *dns.Msg, addr string) (*dns.Msg, error) {
+	f, ok := s.handlers[addr]
+	if !ok {
+		return nil, errors.New("no server at " + addr)
+	}
+	s.Lock()
+	s.queries[addr] = append(s.queries[addr], m.Question[0].Name+" "+dns.TypeToString[m.Question[0].Qtype])
+	s.Unlock()
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := f.ServeDNS(ctx, rec, m.Copy()); err != nil {
+		return nil, err
+	}
+	if rec.Msg == nil {
+		// Not authoritative for the name, the file plugin leaves this to the next plugin.
+		m := m.Copy()
+		m.Response, m.Rcode = true, dns.RcodeRefused
+		return m, nil
+	}
+	return rec.Msg, nil
+}
+
+func newRecursive(s *servers, anchor dns.RR) *Recursive {
+	r := New()
+	r.Zones = []string{"."}
+	r.roots = []string{"10.0.0.1:53"}
+	r.validator = validator.New([]dns.RR{anchor})
+	r.exchange = s.exchange
+	return r
+}
+
+func TestRecursive(t *testing.T) {
+	s, anchor := newServers(t)
+	r := newRecursive(s, anchor)
+
+	tests := []struct {
+		qname  string
+		qtype  uint16
+		do     bool
+		rcode  int
+		ad     bool
+		answer []dns.RR
+	}{
+		{qname: "www.example.org.", qtype: dns.TypeA, do: true, ad: true, answer: []dns.RR{test.A("www.example.org. 3600 IN A 192.0.2.1")}},
+		{qname: "www.example.org.", qtype: dns.TypeA, answer: []dns.RR{test.A("www.example.org. 3600 IN A 192.0.2.1")}},
+		{qname: "a.b.c.example.org.", qtype: dns.TypeA, do: true, ad: true, answer: []dns.RR{test.A("a.b.c.example.org. 3600 IN A 192.0.2.2")}},
+		{qname: "nope.example.org.", qtype: dns.TypeA, do: true, ad: true, rcode: dns.RcodeNameError},
+		{qname: "www.example.org.", qtype: dns.TypeAAAA, do: true, ad: true},
+		{qname: "www.insecure.org.", qtype: dns.TypeA, do: true, answer: []dns.RR{test.A("www.insecure.org. 3600 IN A 192.0.2.3")}},
+		{qname: "www.glueless.org.", qtype: dns.TypeA, do: true, answer: []dns.RR{test.A("www.glueless.org. 3600 IN A 192.0.2.4")}},
+		{qname: "www.v6only.org.", qtype: dns.TypeA, do: true, answer: []dns.RR{test.A("www.v6only.org. 3600 IN A 192.0.2.6")}},
+		{qname: "insecure.example.org.", qtype: dns.TypeA, do: true, answer: []dns.RR{
+			test.CNAME("insecure.example.org. 3600 IN CNAME www.insecure.org."),
+			test.A("www.insecure.org. 3600 IN A 192.0.2.3"),
+		}},
+		{qname: "www.bogus.org.", qtype: dns.TypeA, do: true, rcode: dns.RcodeServerFailure},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		if tc.do {
+			m.SetEdns0(4096, true)
+		}
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+
+		resp := rec.Msg
+		if resp.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[resp.Rcode])
+		}
+		if tc.rcode == dns.RcodeServerFailure {
+			if e := edns.ExtendedError(resp); e == nil || e.InfoCode != dns.ExtendedErrorCodeDNSKEYMissing || e.ExtraText != "DNSKEY Missing" {
+				t.Errorf("Test %d: expected a DNSKEY missing extended error, got %v", i, e)
+			}
+			continue
+		}
+		if resp.AuthenticatedData != tc.ad {
+			t.Errorf("Test %d: expected AD %t, got %t", i, tc.ad, resp.AuthenticatedData)
+		}
+		if !resp.RecursionAvailable || resp.Authoritative {
+			t.Errorf("Test %d: expected RA and no AA, got %t, %t", i, resp.RecursionAvailable, resp.Authoritative)
+		}
+
+		var answer []dns.RR
+		sigs := 0
+		for _, rr := range resp.Answer {
+			if rr.Header().Rrtype == dns.TypeRRSIG {
+				sigs++
+				continue
+			}
+			answer = append(answer, rr)
+		}
+		if !tc.do && sigs > 0 {
+			t.Errorf("Test %d: expected no signatures without DO, got %d", i, sigs)
+		}
+		if tc.ad && len(tc.answer) > 0 && sigs == 0 {
+			t.Errorf("Test %d: expected signatures with DO", i)
+		}
+		if err := test.Section(test.Case{Answer: tc.answer}, test.Answer, answer); err != nil {
+			t.Errorf("Test %d: %s", i, err)
+		}
+	}
+}
+
+func TestRecursiveCheckingDisabled(t *testing.T) {
+	s, anchor := newServers(t)
+	r := newRecursive(s, anchor)
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.bogus.org.", dns.TypeA)
+	m.SetEdns0(4096, true)
+	m.CheckingDisabled = true
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if rec.Msg == nil || rec.Msg.Rcode != dns.RcodeSuccess || rec.Msg.AuthenticatedData {
+		t.Errorf("Expected an unvalidated answer with CD set, got %v", rec.Msg)
+	}
+}
+
+func TestRecursiveNoValidation(t *testing.T) {
+	s, anchor := newServers(t)
+	r := newRecursive(s, anchor)
+	r.validator = nil
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.bogus.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if rec.Msg == nil || len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected an answer without validation, got %v", rec.Msg)
+	}
+}
+
+func TestRecursiveQNameMinimization(t *testing.T) {
+	for _, minimize := range []bool{true, false} {
+		s, anchor := newServers(t)
+		r := newRecursive(s, anchor)
+		r.validator = nil
+		r.minimize = minimize
+
+		if _, err := r.resolve(context.TODO(), "a.b.c.example.org.", dns.TypeA); err != nil {
+			t.Fatalf("Expected no error, got %s", err)
+		}
+		root := strings.Join(s.queries["10.0.0.1:53"], ",")
+		tld := strings.Join(s.queries["10.0.0.2:53"], ",")
+		leaf := strings.Join(s.queries["10.0.0.3:53"], ",")
+		if minimize {
+			if root != "org. A" || tld != "example.org. A" || leaf != "c.example.org. A,b.c.example.org. A,a.b.c.example.org. A" {
+				t.Errorf("Expected minimized queries, got %q, %q and %q", root, tld, leaf)
+			}
+			continue
+		}
+		if root != "a.b.c.example.org. A" || tld != "a.b.c.example.org. A" || leaf != "a.b.c.example.org. A" {
+			t.Errorf("Expected full queries, got %q, %q and %q", root, tld, leaf)
+		}
+	}
+}
+
+func TestRecursiveDelegationCache(t *testing.T) {
+	s, anchor := newServers(t)
+	r := newRecursive(s, anchor)
+	r.validator = nil
+
+	if _, err := r.resolve(context.TODO(), "www.example.org.", dns.TypeA); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	for _, zone := range []string{"org.", "example.org."} {
+		if _, ok := r.delegations.Get(cache.Hash([]byte(zone))); !ok {
+			t.Errorf("Expected a cached delegation for %s", zone)
+		}
+	}
+
+	// The second query goes to the example.org. name server directly.
+	s.queries = map[string][]string{}
+	if _, err := r.resolve(context.TODO(), "nope.example.org.", dns.TypeA); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(s.queries) != 1 || len(s.queries["10.0.0.3:53"]) != 1 {
+		t.Errorf("Expected a single query to the example.org. name server, got %v", s.queries)
+	}
+}
+
+func TestRecursiveBailiwick(t *testing.T) {
+	s, anchor := newServers(t)
+	r := newRecursive(s, anchor)
+	r.validator = nil
+	// The example.org. name server adds a record for a name in insecure.org. to its answers.
+	r.exchange = func(ctx context.Context, m *dns.Msg, addr string) (*dns.Msg, error) {
+		resp, err := s.exchange(ctx, m, addr)
+		if err == nil && addr == "10.0.0.3:53" && len(resp.Answer) > 0 {
+			resp.Answer = append(resp.Answer, test.A("www.insecure.org. 3600 IN A 192.0.2.66"))
+		}
+		return resp, err
+	}
+
+	resp, err := r.resolve(context.TODO(), "insecure.example.org.", dns.TypeA)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	answer := []dns.RR{
+		test.CNAME("insecure.example.org. 3600 IN CNAME www.insecure.org."),
+		test.A("www.insecure.org. 3600 IN A 192.0.2.3"),
+	}
+	if err := test.Section(test.Case{Answer: answer}, test.Answer, resp.Answer); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,292 @@
+package recursive
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	errMaxDepth     = errors.New("too many nested lookups")
+	errMaxReferrals = errors.New("too many referrals")
+	errLame         = errors.New("lame delegation")
+	errNoServers    = errors.New("no name servers")
+)
+
+const (
+	// maxReferrals limits the number of queries sent for a single name.
+	maxReferrals = 32
+	// maxDepth limits the nested lookups for a query, for the addresses of name servers and CNAME targets.
+	maxDepth = 8
+)
+
+// depthKey is the context key for the number of nested lookups.
+type depthKey struct{}
+
+// resolve looks up qname and qtype, and follows the CNAMEs in the answer.
+func (r *Recursive) resolve(ctx context.Context, qname string, qtype uint16) (*dns.Msg, error) {
+	depth, _ := ctx.Value(depthKey{}).(int)
+	if depth >= maxDepth {
+		return nil, errMaxDepth
+	}
+	ctx = context.WithValue(ctx, depthKey{}, depth+1)
+
+	qname = strings.ToLower(dns.Fqdn(qname))
+	resp, err := r.iterate(ctx, qname, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{qname: true}
+	for qtype != dns.TypeCNAME && qtype != dns.TypeANY && resp.Rcode == dns.RcodeSuccess {
+		target := cnameTarget(qname, qtype, resp.Answer)
+		if target == "" || seen[target] || len(seen) > maxDepth {
+			break
+		}
+		seen[target] = true
+		next, err := r.iterate(ctx, target, qtype)
+		if err != nil {
+			return nil, err
+		}
+		resp.Answer = append(resp.Answer, next.Answer...)
+		resp.Ns = next.Ns
+		resp.Rcode = next.Rcode
+	}
+	resp.Question = []dns.Question{{Name: qname, Qtype: qtype, Qclass: dns.ClassINET}}
+	return resp, nil
+}
+
+// iterate looks up qname and qtype by following the delegations from the closest known zone.
+func (r *Recursive) iterate(ctx context.Context, qname string, qtype uint16) (*dns.Msg, error) {
+	zone := qname
+	if qtype == dns.TypeDS && qname != "." {
+		// DS records are in the parent zone.
+		i, _ := dns.NextLabel(qname, 0)
+		zone = qname[i:]
+	}
+	d := r.closest(zone)
+
+	minimize := r.minimize
+	labels := dns.CountLabel(d.zone) + 1
+	for i := 0; i < maxReferrals; i++ {
+		name, typ := qname, qtype
+		if minimize && labels < dns.CountLabel(qname) {
+			// Only ask for the name one label below the zone, RFC 9156.
+			idx := dns.Split(qname)
+			name, typ = qname[idx[len(idx)-labels]:], dns.TypeA
+		}
+
+		resp, err := r.query(ctx, d, name, typ)
+		if err != nil {
+			return nil, err
+		}
+
+		cut, ok, err := referral(resp, d.zone, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if d, err = r.delegate(ctx, cut, d.zone, resp); err != nil {
+				return nil, err
+			}
+			labels = dns.CountLabel(d.zone) + 1
+			continue
+		}
+
+		if name != qname {
+			if resp.Rcode != dns.RcodeSuccess {
+				// Some name servers don't handle empty non-terminals, ask for the full name instead.
+				minimize = false
+				continue
+			}
+			labels++
+			continue
+		}
+		// Only keep the records the name servers of d are authoritative for, a CNAME target outside of the zone
+		// is looked up by resolve.
+		resp.Answer = inBailiwick(resp.Answer, d.zone)
+		resp.Ns = inBailiwick(resp.Ns, d.zone)
+		return resp, nil
+	}
+	return nil, errMaxReferrals
+}
+
+// inBailiwick returns the records from rrs that have an owner name in zone.
+func inBailiwick(rrs []dns.RR, zone string) []dns.RR {
+	j := 0
+	for _, rr := range rrs {
+		if dns.IsSubDomain(zone, rr.Header().Name) {
+			rrs[j] = rr
+			j++
+		}
+	}
+	return rrs[:j]
+}
+
+// query sends the query for name and qtype to the name servers of d, until one of them answers.
+func (r *Recursive) query(ctx context.Context, d *delegation, name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.RecursionDesired = false
+	m.SetEdns0(4096, r.validator != nil)
+
+	err := errNoServers
+	for _, i := range rand.Perm(len(d.addrs)) {
+		resp, e := r.exchange(ctx, m, d.addrs[i])
+		if e != nil {
+			err = e
+			continue
+		}
+		if len(resp.Question) == 0 || !strings.EqualFold(resp.Question[0].Name, name) || resp.Question[0].Qtype != qtype {
+			continue
+		}
+		switch resp.Rcode {
+		case dns.RcodeServerFailure, dns.RcodeRefused, dns.RcodeNotImplemented, dns.RcodeFormatError:
+			err = errors.New(dns.RcodeToString[resp.Rcode] + " from " + d.addrs[i])
+			continue
+		}
+		return resp, nil
+	}
+	return nil, err
+}
+
+// exchangeUDP sends m to addr, and retries over TCP when the response is truncated.
+func (r *Recursive) exchangeUDP(ctx context.Context, m *dns.Msg, addr string) (*dns.Msg, error) {
+	c := &dns.Client{Net: "udp", Timeout: r.timeout}
+	resp, _, err := c.ExchangeContext(ctx, m, addr)
+	if err == nil && resp.Truncated {
+		c.Net = "tcp"
+		resp, _, err = c.ExchangeContext(ctx, m, addr)
+	}
+	return resp, err
+}
+
+// referral returns the zone that resp delegates to, if resp is a referral for name from zone.
+func referral(resp *dns.Msg, zone, name string) (string, bool, error) {
+	if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) > 0 || resp.Authoritative {
+		return "", false, nil
+	}
+	for _, rr := range resp.Ns {
+		if rr.Header().Rrtype != dns.TypeNS {
+			continue
+		}
+		cut := strings.ToLower(rr.Header().Name)
+		if cut == zone || !dns.IsSubDomain(zone, cut) || !dns.IsSubDomain(cut, name) {
+			return "", false, errLame
+		}
+		return cut, true, nil
+	}
+	return "", false, nil
+}
+
+// delegation holds the addresses of the name servers of a zone.
+type delegation struct {
+	zone    string
+	addrs   []string
+	expires time.Time
+}
+
+// closest returns the delegation of the closest zone of name that is cached, or the root.
+func (r *Recursive) closest(name string) *delegation {
+	for off, end := 0, false; !end; off, end = dns.NextLabel(name, off) {
+		if i, ok := r.delegations.Get(cache.Hash([]byte(name[off:]))); ok {
+			if d := i.(*delegation); time.Now().Before(d.expires) {
+				return d
+			}
+		}
+	}
+	return &delegation{zone: ".", addrs: r.roots}
+}
+
+// delegate returns the delegation to cut, from the referral resp sent by the name servers of parent. Glue
+// records are used if they are in the parent zone, otherwise the IPv4 and IPv6 addresses of the name servers are
+// looked up.
+func (r *Recursive) delegate(ctx context.Context, cut, parent string, resp *dns.Msg) (*delegation, error) {
+	ns := map[string]bool{}
+	ttl := uint32(0)
+	for _, rr := range resp.Ns {
+		if x, ok := rr.(*dns.NS); ok && strings.EqualFold(x.Hdr.Name, cut) {
+			ns[strings.ToLower(x.Ns)] = true
+			if ttl == 0 || x.Hdr.Ttl < ttl {
+				ttl = x.Hdr.Ttl
+			}
+		}
+	}
+
+	d := &delegation{zone: cut, expires: time.Now().Add(time.Duration(ttl) * time.Second)}
+	for _, rr := range resp.Extra {
+		name := strings.ToLower(rr.Header().Name)
+		if !ns[name] || !dns.IsSubDomain(parent, name) {
+			continue
+		}
+		switch x := rr.(type) {
+		case *dns.A:
+			d.addrs = append(d.addrs, net.JoinHostPort(x.A.String(), "53"))
+		case *dns.AAAA:
+			d.addrs = append(d.addrs, net.JoinHostPort(x.AAAA.String(), "53"))
+		}
+	}
+
+	if len(d.addrs) == 0 {
+		for name := range ns {
+			for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+				m, err := r.resolve(ctx, name, qtype)
+				if err != nil {
+					continue
+				}
+				for _, rr := range m.Answer {
+					switch x := rr.(type) {
+					case *dns.A:
+						d.addrs = append(d.addrs, net.JoinHostPort(x.A.String(), "53"))
+					case *dns.AAAA:
+						d.addrs = append(d.addrs, net.JoinHostPort(x.AAAA.String(), "53"))
+					}
+				}
+			}
+			if len(d.addrs) > 0 {
+				break
+			}
+		}
+	}
+	if len(d.addrs) == 0 {
+		return nil, errNoServers
+	}
+
+	r.delegations.Add(cache.Hash([]byte(cut)), d)
+	return d, nil
+}
+
+// cnameTarget returns the name at the end of the CNAME chain in rrs that starts at qname, if rrs don't hold the
+// records of qtype for that name.
+func cnameTarget(qname string, qtype uint16, rrs []dns.RR) string {
+	name := qname
+	for i := 0; i < len(rrs); i++ {
+		found := false
+		for _, rr := range rrs {
+			if c, ok := rr.(*dns.CNAME); ok && strings.EqualFold(c.Hdr.Name, name) {
+				name = strings.ToLower(c.Target)
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	if name == qname {
+		return ""
+	}
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == qtype && strings.EqualFold(rr.Header().Name, name) {
+			return ""
+		}
+	}
+	return name
+}
@@ -0,0 +1,134 @@
+package recursive
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/pkg/validator"
+
+	"github.com/miekg/dns"
+)
+
+func init() { plugin.Register("recursive", setup) }
+
+func setup(c *caddy.Controller) error {
+	r, err := parseRecursive(c)
+	if err != nil {
+		return plugin.Error("recursive", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		r.Next = next
+		return r
+	})
+
+	return nil
+}
+
+func parseRecursive(c *caddy.Controller) (*Recursive, error) {
+	r := New()
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		r.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		var (
+			anchors    []dns.RR
+			validation = true
+		)
+		for c.NextBlock() {
+			switch c.Val() {
+			case "roots":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				roots, err := parse.HostPortOrFile(args...)
+				if err != nil {
+					return nil, err
+				}
+				for i := range roots {
+					trans, addr := parse.Transport(roots[i])
+					if trans != transport.DNS {
+						return nil, c.Errf("only plain DNS is supported for roots: %s", roots[i])
+					}
+					roots[i] = addr
+				}
+				r.roots = roots
+
+			case "trust_anchor":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, fileName := range args {
+					if !filepath.IsAbs(fileName) && config.Root != "" {
+						fileName = filepath.Join(config.Root, fileName)
+					}
+					reader, err := os.Open(fileName)
+					if err != nil {
+						return nil, err
+					}
+					rrs, err := validator.ParseAnchors(reader, fileName)
+					reader.Close()
+					if err != nil {
+						return nil, c.Err(err.Error())
+					}
+					anchors = append(anchors, rrs...)
+				}
+
+			case "no_validation":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				validation = false
+
+			case "no_qname_minimization":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				r.minimize = false
+
+			case "capacity":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				n, err := strconv.Atoi(args[0])
+				if err != nil {
+					return nil, err
+				}
+				if n <= 0 {
+					return nil, c.Errf("capacity must be positive: %d", n)
+				}
+				r.delegations = cache.New(n)
+
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+
+		switch {
+		case !validation && len(anchors) > 0:
+			return nil, c.Err("trust_anchor can't be used with no_validation")
+		case !validation:
+			r.validator = nil
+		case len(anchors) > 0:
+			r.validator = validator.New(anchors)
+		}
+	}
+	return r, nil
+}
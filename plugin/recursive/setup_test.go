@@ -0,0 +1,103 @@
+package recursive
+
+import (
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/test"
+)
+
+func TestSetupRecursive(t *testing.T) {
+	anchors, rm, err := test.TempFile(".", ". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+	notAnchors, rm2, err := test.TempFile(".", ". IN A 127.0.0.1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm2()
+
+	tests := []struct {
+		input      string
+		shouldErr  bool
+		roots      []string
+		validation bool
+		minimize   bool
+	}{
+		{`recursive`, false, rootHints(), true, true},
+		{`recursive example.org {
+			roots 10.0.0.1 10.0.0.2:5353
+		}`, false, []string{"10.0.0.1:53", "10.0.0.2:5353"}, true, true},
+		{`recursive {
+			trust_anchor ` + anchors + `
+			no_qname_minimization
+			capacity 100
+		}`, false, rootHints(), true, false},
+		{`recursive {
+			no_validation
+		}`, false, rootHints(), false, true},
+		// errors
+		{`recursive {
+			roots
+		}`, true, nil, false, false},
+		{`recursive {
+			roots tls://10.0.0.1
+		}`, true, nil, false, false},
+		{`recursive {
+			roots not-an-address
+		}`, true, nil, false, false},
+		{`recursive {
+			trust_anchor /does/not/exist
+		}`, true, nil, false, false},
+		{`recursive {
+			trust_anchor ` + notAnchors + `
+		}`, true, nil, false, false},
+		{`recursive {
+			trust_anchor ` + anchors + `
+			no_validation
+		}`, true, nil, false, false},
+		{`recursive {
+			no_validation yes
+		}`, true, nil, false, false},
+		{`recursive {
+			capacity -1
+		}`, true, nil, false, false},
+		{`recursive {
+			blocklist
+		}`, true, nil, false, false},
+		{`recursive
+		recursive`, true, nil, false, false},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		r, err := parseRecursive(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if len(r.roots) != len(tc.roots) {
+			t.Errorf("Test %d: expected roots %v, got %v", i, tc.roots, r.roots)
+		} else {
+			for j := range tc.roots {
+				if r.roots[j] != tc.roots[j] {
+					t.Errorf("Test %d: expected roots %v, got %v", i, tc.roots, r.roots)
+				}
+			}
+		}
+		if (r.validator != nil) != tc.validation {
+			t.Errorf("Test %d: expected validation %t, got %t", i, tc.validation, r.validator != nil)
+		}
+		if r.minimize != tc.minimize {
+			t.Errorf("Test %d: expected QNAME minimization %t, got %t", i, tc.minimize, r.minimize)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package rpz
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HitCount is the number of queries that matched a trigger in a policy zone.
+	HitCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rpz",
+		Name:      "hits_total",
+		Help:      "Counter of queries that matched a trigger of a response policy zone.",
+	}, []string{"server", "policy", "trigger", "action"})
+)
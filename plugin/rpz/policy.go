@@ -0,0 +1,262 @@
+package rpz
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/infobloxopen/go-trees/iptree"
+	"github.com/miekg/dns"
+)
+
+// The labels that mark the triggers in a policy zone, other owner names are QNAME triggers.
+const (
+	clientIPLabel = "rpz-client-ip"
+	ipLabel       = "rpz-ip"
+	nsdnameLabel  = "rpz-nsdname"
+)
+
+// trigger is the kind of trigger that matched a query.
+type trigger int
+
+const (
+	triggerClientIP trigger = iota
+	triggerQName
+	triggerIP
+	triggerNSDName
+)
+
+func (t trigger) String() string {
+	switch t {
+	case triggerClientIP:
+		return "client-ip"
+	case triggerQName:
+		return "qname"
+	case triggerIP:
+		return "ip"
+	case triggerNSDName:
+		return "nsdname"
+	}
+	return ""
+}
+
+// action is what is done with a query that matched a trigger.
+type action int
+
+const (
+	actionNXDomain action = iota
+	actionNoData
+	actionPassthru
+	actionDrop
+	actionTCPOnly
+	actionLocalData
+)
+
+func (a action) String() string {
+	switch a {
+	case actionNXDomain:
+		return "nxdomain"
+	case actionNoData:
+		return "nodata"
+	case actionPassthru:
+		return "passthru"
+	case actionDrop:
+		return "drop"
+	case actionTCPOnly:
+		return "tcp-only"
+	case actionLocalData:
+		return "local-data"
+	}
+	return ""
+}
+
+// policy is a single response policy zone.
+type policy struct {
+	name string // name of the policy, the origin of the zone.
+	z    *file.Zone
+
+	mu  sync.Mutex
+	idx *index // index of the current tree of z, rebuilt when the zone changes.
+}
+
+// index holds the IP triggers of a policy zone, these can't be looked up in the zone's tree directly.
+type index struct {
+	tree     *tree.Tree
+	clientIP *iptree.Tree // client IP network to owner name.
+	ip       *iptree.Tree // response IP network to owner name.
+	ips      int          // number of response IP triggers.
+	nsdname  bool         // whether the zone has NSDNAME triggers.
+}
+
+// hit is a matched trigger in a policy zone.
+type hit struct {
+	policy  *policy
+	trigger trigger
+	action  action
+	elem    *tree.Elem // the records of the trigger.
+}
+
+func newPolicy(name string, z *file.Zone) *policy {
+	return &policy{name: name, z: z}
+}
+
+// snapshot returns the current tree of the policy zone and its index.
+func (p *policy) snapshot() (*tree.Tree, *index) {
+	p.z.RLock()
+	tr := p.z.Tree
+	soa := p.z.Apex.SOA
+	p.z.RUnlock()
+	if soa == nil {
+		return nil, nil // not loaded (yet).
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.idx == nil || p.idx.tree != tr {
+		p.idx = newIndex(p.name, tr)
+	}
+	return tr, p.idx
+}
+
+func newIndex(origin string, tr *tree.Tree) *index {
+	idx := &index{tree: tr, clientIP: iptree.NewTree(), ip: iptree.NewTree()}
+	clientIPSuffix := "." + clientIPLabel + "." + origin
+	ipSuffix := "." + ipLabel + "." + origin
+	nsdnameSuffix := "." + nsdnameLabel + "." + origin
+
+	tr.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		name := e.Name()
+		switch {
+		case strings.HasSuffix(name, clientIPSuffix):
+			if n := triggerNet(strings.TrimSuffix(name, clientIPSuffix)); n != nil {
+				idx.clientIP.InplaceInsertNet(n, name)
+			}
+		case strings.HasSuffix(name, ipSuffix):
+			if n := triggerNet(strings.TrimSuffix(name, ipSuffix)); n != nil {
+				idx.ip.InplaceInsertNet(n, name)
+				idx.ips++
+			}
+		case strings.HasSuffix(name, nsdnameSuffix):
+			idx.nsdname = true
+		}
+		return nil
+	})
+	return idx
+}
+
+// triggerNet parses the labels of an IP trigger, i.e. "24.0.2.0.192" for 192.0.2.0/24 or "64.zz.db8.2001" for
+// 2001:db8::/64. It returns nil if s isn't a valid IP trigger.
+func triggerNet(s string) *net.IPNet {
+	labels := strings.Split(s, ".")
+	if len(labels) < 2 {
+		return nil
+	}
+	prefix, err := strconv.Atoi(labels[0])
+	if err != nil {
+		return nil
+	}
+	addr := labels[1:]
+	for i, j := 0, len(addr)-1; i < j; i, j = i+1, j-1 {
+		addr[i], addr[j] = addr[j], addr[i]
+	}
+
+	bits := 32
+	ip := net.ParseIP(strings.Join(addr, ".")).To4()
+	if len(addr) != 4 || ip == nil {
+		// IPv6, "zz" stands for the longest run of zero groups.
+		for i := range addr {
+			if addr[i] == "zz" {
+				addr[i] = ""
+			}
+		}
+		s := strings.Join(addr, ":")
+		if strings.HasPrefix(s, ":") {
+			s = ":" + s
+		}
+		if strings.HasSuffix(s, ":") {
+			s += ":"
+		}
+		bits = 128
+		ip = net.ParseIP(s)
+		if ip == nil {
+			return nil
+		}
+	}
+	if prefix < 1 || prefix > bits {
+		return nil
+	}
+	mask := net.CIDRMask(prefix, bits)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+}
+
+// qname returns the QNAME trigger for name. An exact match is preferred over a wildcard, and a longer wildcard over
+// a shorter one.
+func (p *policy) qname(tr *tree.Tree, name string) *tree.Elem {
+	return p.lookupName(tr, name, "")
+}
+
+// nsdname returns the NSDNAME trigger for the name server name.
+func (p *policy) nsdname(tr *tree.Tree, name string) *tree.Elem {
+	return p.lookupName(tr, name, nsdnameLabel+".")
+}
+
+func (p *policy) lookupName(tr *tree.Tree, name, label string) *tree.Elem {
+	name = strings.ToLower(dns.Fqdn(name))
+	if e, ok := tr.Search(name + label + p.name); ok {
+		return e
+	}
+	for off, end := dns.NextLabel(name, 0); !end; off, end = dns.NextLabel(name, off) {
+		if e, ok := tr.Search("*." + name[off:] + label + p.name); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// ip returns the trigger in the iptree t that holds ip.
+func (p *policy) ip(tr *tree.Tree, t *iptree.Tree, ip net.IP) *tree.Elem {
+	if ip == nil {
+		return nil
+	}
+	v, ok := t.GetByIP(ip)
+	if !ok {
+		return nil
+	}
+	e, _ := tr.Search(v.(string))
+	return e
+}
+
+// actionOf returns the action encoded in the records of a trigger.
+func actionOf(e *tree.Elem) action {
+	cname := e.Type(dns.TypeCNAME)
+	if len(cname) != 1 || len(e.All()) != 1 {
+		return actionLocalData
+	}
+	switch target := cname[0].(*dns.CNAME).Target; target {
+	case ".":
+		return actionNXDomain
+	case "*.":
+		return actionNoData
+	case "rpz-passthru.":
+		return actionPassthru
+	case "rpz-drop.":
+		return actionDrop
+	case "rpz-tcp-only.":
+		return actionTCPOnly
+	}
+	return actionLocalData
+}
+
+// soa returns the SOA record of the policy zone, it is added to NXDOMAIN and NODATA responses.
+func (p *policy) soa() dns.RR {
+	p.z.RLock()
+	defer p.z.RUnlock()
+	if p.z.Apex.SOA == nil {
+		return nil
+	}
+	return dns.Copy(p.z.Apex.SOA)
+}
@@ -0,0 +1,102 @@
+package rpz
+
+import (
+	"testing"
+)
+
+func TestTriggerNet(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string // empty for an invalid trigger
+	}{
+		{"32.1.0.240.10", "10.240.0.1/32"},
+		{"24.0.2.0.192", "192.0.2.0/24"},
+		{"8.255.255.255.10", "10.0.0.0/8"},
+		{"128.1.zz.db8.2001", "2001:db8::1/128"},
+		{"64.zz.db8.2001", "2001:db8::/64"},
+		{"48.zz.1.0.0.0.db8.2001", "2001:db8::/48"},
+		{"33.1.0.240.10", ""},
+		{"0.1.0.240.10", ""},
+		{"a.1.0.240.10", ""},
+		{"32", ""},
+		{"32.256.0.240.10", ""},
+	}
+	for i, tc := range tests {
+		n := triggerNet(tc.in)
+		if tc.out == "" {
+			if n != nil {
+				t.Errorf("Test %d: expected no network for %q, got %s", i, tc.in, n)
+			}
+			continue
+		}
+		if n == nil {
+			t.Errorf("Test %d: expected %s for %q, got none", i, tc.out, tc.in)
+			continue
+		}
+		if n.String() != tc.out {
+			t.Errorf("Test %d: expected %s for %q, got %s", i, tc.out, tc.in, n)
+		}
+	}
+}
+
+func TestActionOf(t *testing.T) {
+	p := newPolicyZone(t, "rpz.example.", dbRPZ)
+	tr, _ := p.snapshot()
+
+	tests := []struct {
+		name   string
+		action action
+	}{
+		{"nxdomain.example.org.rpz.example.", actionNXDomain},
+		{"*.nodata.example.org.rpz.example.", actionNoData},
+		{"passthru.example.org.rpz.example.", actionPassthru},
+		{"drop.example.org.rpz.example.", actionDrop},
+		{"tcp.example.org.rpz.example.", actionTCPOnly},
+		{"local.example.org.rpz.example.", actionLocalData},
+		{"alias.example.org.rpz.example.", actionLocalData},
+	}
+	for i, tc := range tests {
+		e, ok := tr.Search(tc.name)
+		if !ok {
+			t.Fatalf("Test %d: expected %s in the policy zone", i, tc.name)
+		}
+		if a := actionOf(e); a != tc.action {
+			t.Errorf("Test %d: expected action %s, got %s", i, tc.action, a)
+		}
+	}
+}
+
+func TestPolicyQName(t *testing.T) {
+	db := dbRPZFirst + "*.example.net CNAME .\n*.www.example.net CNAME rpz-passthru.\nwww.example.net CNAME *.\n"
+	p := newPolicyZone(t, "first.rpz.example.", db)
+	tr, _ := p.snapshot()
+
+	tests := []struct {
+		qname string
+		owner string // empty for no trigger
+	}{
+		{"www.example.net.", "www.example.net.first.rpz.example."},
+		{"WWW.example.net.", "www.example.net.first.rpz.example."},
+		{"a.www.example.net.", "*.www.example.net.first.rpz.example."},
+		{"a.b.www.example.net.", "*.www.example.net.first.rpz.example."},
+		{"mail.example.net.", "*.example.net.first.rpz.example."},
+		{"example.net.", ""},
+		{"example.com.", ""},
+	}
+	for i, tc := range tests {
+		e := p.qname(tr, tc.qname)
+		if tc.owner == "" {
+			if e != nil {
+				t.Errorf("Test %d: expected no trigger for %s, got %s", i, tc.qname, e.Name())
+			}
+			continue
+		}
+		if e == nil {
+			t.Errorf("Test %d: expected trigger %s for %s, got none", i, tc.owner, tc.qname)
+			continue
+		}
+		if e.Name() != tc.owner {
+			t.Errorf("Test %d: expected trigger %s for %s, got %s", i, tc.owner, tc.qname, e.Name())
+		}
+	}
+}
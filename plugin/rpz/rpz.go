@@ -0,0 +1,268 @@
+// Package rpz implements a plugin that applies response policy zones (RPZ) to queries.
+package rpz
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file/tree"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin("rpz")
+
+// RPZ applies response policy zones to the queries for Zones.
+type RPZ struct {
+	Next  plugin.Handler
+	Zones []string
+
+	policies []*policy // in order of precedence.
+	upstream *upstream.Upstream
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (rpz *RPZ) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	if plugin.Zones(rpz.Zones).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(rpz.Name(), rpz.Next, ctx, w, r)
+	}
+
+	// The response is only needed for response IP and NSDNAME triggers, resolve lazily.
+	var (
+		resp     *dns.Msg
+		rcode    int
+		err      error
+		resolved bool
+	)
+	resolve := func() {
+		nw := nonwriter.New(w)
+		rcode, err = plugin.NextOrFailure(rpz.Name(), rpz.Next, ctx, nw, r)
+		resp = nw.Msg
+		resolved = true
+	}
+
+	var h *hit
+	clientIP := net.ParseIP(state.IP())
+	for _, p := range rpz.policies {
+		tr, idx := p.snapshot()
+		if tr == nil {
+			continue
+		}
+		if e := p.ip(tr, idx.clientIP, clientIP); e != nil {
+			h = &hit{policy: p, trigger: triggerClientIP, elem: e}
+			break
+		}
+		if e := p.qname(tr, state.Name()); e != nil {
+			h = &hit{policy: p, trigger: triggerQName, elem: e}
+			break
+		}
+		if idx.ips == 0 && !idx.nsdname {
+			continue
+		}
+		if !resolved {
+			resolve()
+		}
+		if resp == nil {
+			continue
+		}
+		if h = rpz.responseHit(ctx, state, p, tr, idx, resp); h != nil {
+			break
+		}
+	}
+
+	if h != nil {
+		h.action = actionOf(h.elem)
+		HitCount.WithLabelValues(metrics.WithServer(ctx), h.policy.name, h.trigger.String(), h.action.String()).Inc()
+		log.Debugf("Query for %s %s from %s matched %s trigger %s of policy %s: %s",
+			state.Name(), state.Type(), state.IP(), h.trigger, h.elem.Name(), h.policy.name, h.action)
+
+		switch h.action {
+		case actionDrop:
+			return dns.RcodeSuccess, nil
+		case actionNXDomain, actionNoData:
+			m := new(dns.Msg)
+			m.SetReply(r)
+			if h.action == actionNXDomain {
+				m.Rcode = dns.RcodeNameError
+			}
+			if soa := h.policy.soa(); soa != nil {
+				m.Ns = []dns.RR{soa}
+			}
+			if r.IsEdns0() != nil {
+				edns.SetExtendedError(m, dns.ExtendedErrorCodeBlocked, h.policy.name)
+			}
+			w.WriteMsg(m)
+			return dns.RcodeSuccess, nil
+		case actionTCPOnly:
+			if state.Proto() == "udp" {
+				m := new(dns.Msg)
+				m.SetReply(r)
+				m.Truncated = true
+				w.WriteMsg(m)
+				return dns.RcodeSuccess, nil
+			}
+		case actionLocalData:
+			w.WriteMsg(rpz.localData(ctx, state, h))
+			return dns.RcodeSuccess, nil
+		}
+		// actionPassthru and actionTCPOnly over TCP: answer as if there is no policy.
+	}
+
+	if !resolved {
+		return plugin.NextOrFailure(rpz.Name(), rpz.Next, ctx, w, r)
+	}
+	if resp != nil {
+		w.WriteMsg(resp)
+	}
+	return rcode, err
+}
+
+// responseHit returns the response IP or NSDNAME trigger of policy p that resp matches.
+func (rpz *RPZ) responseHit(ctx context.Context, state request.Request, p *policy, tr *tree.Tree, idx *index, resp *dns.Msg) *hit {
+	if idx.ips > 0 {
+		for _, rr := range resp.Answer {
+			var ip net.IP
+			switch x := rr.(type) {
+			case *dns.A:
+				ip = x.A
+			case *dns.AAAA:
+				ip = x.AAAA
+			default:
+				continue
+			}
+			if e := p.ip(tr, idx.ip, ip); e != nil {
+				return &hit{policy: p, trigger: triggerIP, elem: e}
+			}
+		}
+	}
+	if idx.nsdname {
+		for _, ns := range rpz.nameservers(ctx, state, resp) {
+			if e := p.nsdname(tr, ns); e != nil {
+				return &hit{policy: p, trigger: triggerNSDName, elem: e}
+			}
+		}
+	}
+	return nil
+}
+
+// nameservers returns the names of the name servers of the zone the query in state is in. These are taken from
+// resp, or if it has none, looked up.
+func (rpz *RPZ) nameservers(ctx context.Context, state request.Request, resp *dns.Msg) []string {
+	if ns := nsNames(resp.Ns); len(ns) > 0 {
+		return ns
+	}
+	zone := state.Name()
+	for _, rr := range resp.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			zone = soa.Hdr.Name
+		}
+	}
+	m := rpz.lookup(ctx, state, zone, dns.TypeNS)
+	if m == nil {
+		return nil
+	}
+	if ns := nsNames(m.Answer); len(ns) > 0 {
+		return ns
+	}
+	// Not the zone apex, ask again for the zone from the SOA in the authority section.
+	for _, rr := range m.Ns {
+		if soa, ok := rr.(*dns.SOA); ok && !strings.EqualFold(soa.Hdr.Name, zone) {
+			if m := rpz.lookup(ctx, state, soa.Hdr.Name, dns.TypeNS); m != nil {
+				return nsNames(m.Answer)
+			}
+		}
+	}
+	return nil
+}
+
+// lookup sends a query for name and qtype to the next plugin and returns the response.
+func (rpz *RPZ) lookup(ctx context.Context, state request.Request, name string, qtype uint16) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetQuestion(name, qtype)
+	req.SetEdns0(uint16(state.Size()), state.Do())
+
+	nw := nonwriter.New(state.W)
+	plugin.NextOrFailure(rpz.Name(), rpz.Next, ctx, nw, req)
+	return nw.Msg
+}
+
+func nsNames(rrs []dns.RR) []string {
+	var names []string
+	for _, rr := range rrs {
+		if ns, ok := rr.(*dns.NS); ok {
+			names = append(names, ns.Ns)
+		}
+	}
+	return names
+}
+
+// lookupDepthKey is the context key for the number of CNAME targets of local data being looked up.
+type lookupDepthKey struct{}
+
+// maxLookupDepth limits the CNAME targets of local data that are looked up for a single query, these may match a
+// policy again.
+const maxLookupDepth = 8
+
+// localData returns the response made up of the local data of the trigger in h.
+func (rpz *RPZ) localData(ctx context.Context, state request.Request, h *hit) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetReply(state.Req)
+	m.Authoritative = true
+	qname := state.QName()
+
+	if cname := h.elem.Type(dns.TypeCNAME); len(cname) == 1 {
+		target := cname[0].(*dns.CNAME).Target
+		if strings.HasPrefix(target, "*.") {
+			// Wildcard CNAME: the target is the query name with the rest appended.
+			target = qname + target[2:]
+		}
+		rr := &dns.CNAME{Hdr: dns.RR_Header{Name: qname, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: cname[0].Header().Ttl}, Target: target}
+		m.Answer = []dns.RR{rr}
+		if state.QType() == dns.TypeCNAME {
+			return m
+		}
+
+		depth, _ := ctx.Value(lookupDepthKey{}).(int)
+		if depth >= maxLookupDepth {
+			return m
+		}
+		ctx = context.WithValue(ctx, lookupDepthKey{}, depth+1)
+		if resp, err := rpz.upstream.Lookup(ctx, state, target, state.QType()); err == nil && resp != nil {
+			m.Answer = append(m.Answer, resp.Answer...)
+			m.Rcode = resp.Rcode
+		}
+		return m
+	}
+
+	var rrs []dns.RR
+	if state.QType() == dns.TypeANY {
+		rrs = h.elem.All()
+	} else {
+		rrs = h.elem.Type(state.QType())
+	}
+	if len(rrs) == 0 {
+		// NODATA
+		if soa := h.policy.soa(); soa != nil {
+			m.Ns = []dns.RR{soa}
+		}
+		return m
+	}
+	for _, rr := range rrs {
+		rr = dns.Copy(rr)
+		rr.Header().Name = qname
+		m.Answer = append(m.Answer, rr)
+	}
+	return m
+}
+
+// Name implements the plugin.Handler interface.
+func (rpz *RPZ) Name() string { return "rpz" }
@@ -0,0 +1,240 @@
+package rpz
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/edns"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const dbRPZ = `$TTL 300
+@                   IN SOA  rpz.example. hostmaster.rpz.example. 1 3600 600 86400 60
+                    IN NS   localhost.
+nxdomain.example.org          CNAME .
+*.nodata.example.org          CNAME *.
+passthru.example.org          CNAME rpz-passthru.
+drop.example.org              CNAME rpz-drop.
+tcp.example.org               CNAME rpz-tcp-only.
+local.example.org             A     10.0.0.1
+local.example.org             A     10.0.0.2
+alias.example.org             CNAME walled.garden.example.
+24.0.2.0.192.rpz-ip           A     10.0.0.3
+ns.evil.example.rpz-nsdname   CNAME .
+`
+
+const dbRPZFirst = `$TTL 300
+@                   IN SOA  first.rpz.example. hostmaster.rpz.example. 1 3600 600 86400 60
+                    IN NS   localhost.
+nxdomain.example.org          CNAME rpz-passthru.
+`
+
+func newPolicyZone(t *testing.T, origin, db string) *policy {
+	z, err := file.Parse(strings.NewReader(db), origin, "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %q", err)
+	}
+	return newPolicy(origin, z)
+}
+
+// next answers with A records, 192.0.2.1 for ip.example.org. All answers for names in evil.example have
+// ns.evil.example. in the authority section.
+func next() plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		qname := r.Question[0].Name
+		switch r.Question[0].Qtype {
+		case dns.TypeA:
+			ip := "127.0.0.1"
+			if qname == "ip.example.org." {
+				ip = "192.0.2.1"
+			}
+			m.Answer = []dns.RR{test.A(qname + " 300 IN A " + ip)}
+		}
+		if strings.HasSuffix(qname, "evil.example.") {
+			m.Ns = []dns.RR{test.NS("evil.example. 300 IN NS ns.evil.example.")}
+		}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+func TestRPZ(t *testing.T) {
+	rpz := &RPZ{
+		Next:     next(),
+		Zones:    []string{"."},
+		policies: []*policy{newPolicyZone(t, "rpz.example.", dbRPZ)},
+	}
+
+	tests := []struct {
+		qname  string
+		qtype  uint16
+		rcode  int
+		answer []dns.RR
+		ns     []dns.RR
+	}{
+		{
+			qname: "nxdomain.example.org.", qtype: dns.TypeA, rcode: dns.RcodeNameError,
+			ns: []dns.RR{test.SOA("rpz.example. 300 IN SOA rpz.example. hostmaster.rpz.example. 1 3600 600 86400 60")},
+		},
+		{
+			qname: "a.nodata.example.org.", qtype: dns.TypeA, rcode: dns.RcodeSuccess,
+			ns: []dns.RR{test.SOA("rpz.example. 300 IN SOA rpz.example. hostmaster.rpz.example. 1 3600 600 86400 60")},
+		},
+		{
+			// Wildcards don't match the name itself.
+			qname: "nodata.example.org.", qtype: dns.TypeA, rcode: dns.RcodeSuccess,
+			answer: []dns.RR{test.A("nodata.example.org. 300 IN A 127.0.0.1")},
+		},
+		{
+			qname: "passthru.example.org.", qtype: dns.TypeA, rcode: dns.RcodeSuccess,
+			answer: []dns.RR{test.A("passthru.example.org. 300 IN A 127.0.0.1")},
+		},
+		{
+			// TCP-only answers as usual over TCP, see TestRPZTCPOnly for UDP.
+			qname: "tcp.example.org.", qtype: dns.TypeA, rcode: dns.RcodeSuccess,
+			answer: []dns.RR{test.A("tcp.example.org. 300 IN A 127.0.0.1")},
+		},
+		{
+			qname: "Local.example.org.", qtype: dns.TypeA, rcode: dns.RcodeSuccess,
+			answer: []dns.RR{
+				test.A("Local.example.org. 300 IN A 10.0.0.1"),
+				test.A("Local.example.org. 300 IN A 10.0.0.2"),
+			},
+		},
+		{
+			qname: "local.example.org.", qtype: dns.TypeAAAA, rcode: dns.RcodeSuccess,
+			ns: []dns.RR{test.SOA("rpz.example. 300 IN SOA rpz.example. hostmaster.rpz.example. 1 3600 600 86400 60")},
+		},
+		{
+			qname: "alias.example.org.", qtype: dns.TypeCNAME, rcode: dns.RcodeSuccess,
+			answer: []dns.RR{test.CNAME("alias.example.org. 300 IN CNAME walled.garden.example.")},
+		},
+		{
+			qname: "ip.example.org.", qtype: dns.TypeA, rcode: dns.RcodeSuccess,
+			answer: []dns.RR{test.A("ip.example.org. 300 IN A 10.0.0.3")},
+		},
+		{
+			qname: "www.evil.example.", qtype: dns.TypeA, rcode: dns.RcodeNameError,
+			ns: []dns.RR{test.SOA("rpz.example. 300 IN SOA rpz.example. hostmaster.rpz.example. 1 3600 600 86400 60")},
+		},
+		{
+			qname: "www.example.org.", qtype: dns.TypeA, rcode: dns.RcodeSuccess,
+			answer: []dns.RR{test.A("www.example.org. 300 IN A 127.0.0.1")},
+		},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{TCP: true})
+		if _, err := rpz.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if rec.Msg == nil {
+			t.Fatalf("Test %d: expected a response", i)
+		}
+		tr := test.Case{Qname: tc.qname, Qtype: tc.qtype, Rcode: tc.rcode, Answer: tc.answer, Ns: tc.ns}
+		if err := test.SortAndCheck(rec.Msg, tr); err != nil {
+			t.Errorf("Test %d: %s", i, err)
+		}
+	}
+}
+
+func TestRPZDrop(t *testing.T) {
+	rpz := &RPZ{Next: next(), Zones: []string{"."}, policies: []*policy{newPolicyZone(t, "rpz.example.", dbRPZ)}}
+
+	m := new(dns.Msg)
+	m.SetQuestion("drop.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := rpz.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if rec.Msg != nil {
+		t.Errorf("Expected no response, got %s", rec.Msg)
+	}
+}
+
+func TestRPZExtendedError(t *testing.T) {
+	rpz := &RPZ{Next: next(), Zones: []string{"."}, policies: []*policy{newPolicyZone(t, "rpz.example.", dbRPZ)}}
+
+	m := new(dns.Msg)
+	m.SetQuestion("nxdomain.example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := rpz.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if e := edns.ExtendedError(rec.Msg); e == nil || e.InfoCode != dns.ExtendedErrorCodeBlocked || e.ExtraText != "rpz.example." {
+		t.Errorf("Expected a blocked extended error for policy rpz.example., got %v", e)
+	}
+}
+
+func TestRPZTCPOnly(t *testing.T) {
+	rpz := &RPZ{Next: next(), Zones: []string{"."}, policies: []*policy{newPolicyZone(t, "rpz.example.", dbRPZ)}}
+
+	m := new(dns.Msg)
+	m.SetQuestion("tcp.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := rpz.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !rec.Msg.Truncated || len(rec.Msg.Answer) != 0 {
+		t.Errorf("Expected truncated, empty response, got %s", rec.Msg)
+	}
+}
+
+func TestRPZPolicyOrder(t *testing.T) {
+	// The first policy passes the name through, so the second policy isn't used.
+	rpz := &RPZ{
+		Next:  next(),
+		Zones: []string{"."},
+		policies: []*policy{
+			newPolicyZone(t, "first.rpz.example.", dbRPZFirst),
+			newPolicyZone(t, "rpz.example.", dbRPZ),
+		},
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("nxdomain.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := rpz.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if rec.Msg.Rcode != dns.RcodeSuccess || len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected the query to be passed through, got %s", rec.Msg)
+	}
+}
+
+func TestRPZClientIP(t *testing.T) {
+	db := dbRPZFirst + "32.1.0.240.10.rpz-client-ip CNAME .\n"
+	rpz := &RPZ{Next: next(), Zones: []string{"example.org."}, policies: []*policy{newPolicyZone(t, "first.rpz.example.", db)}}
+
+	tests := []struct {
+		qname    string
+		remoteIP string
+		rcode    int
+	}{
+		{"www.example.org.", "10.240.0.1", dns.RcodeNameError},
+		{"www.example.org.", "10.240.0.2", dns.RcodeSuccess},
+		{"www.example.net.", "10.240.0.1", dns.RcodeSuccess}, // not in the zones of the plugin
+	}
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: tc.remoteIP})
+		if _, err := rpz.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rec.Msg.Rcode])
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package rpz
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+
+	"github.com/miekg/dns"
+)
+
+func init() { plugin.Register("rpz", setup) }
+
+func setup(c *caddy.Controller) error {
+	rpz, err := parseRPZ(c)
+	if err != nil {
+		return plugin.Error("rpz", err)
+	}
+
+	for _, p := range rpz.policies {
+		z := p.z
+		if len(z.TransferFrom) > 0 {
+			c.OnStartup(func() error {
+				z.StartupOnce.Do(func() {
+					go func() {
+						z.TransferIn()
+						z.Update()
+					}()
+				})
+				return nil
+			})
+			continue
+		}
+		c.OnShutdown(z.OnShutdown)
+		c.OnStartup(func() error {
+			z.StartupOnce.Do(func() { z.Reload(nil) })
+			return nil
+		})
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		rpz.Next = next
+		return rpz
+	})
+
+	return nil
+}
+
+func parseRPZ(c *caddy.Controller) (*RPZ, error) {
+	rpz := &RPZ{upstream: upstream.New()}
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		rpz.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		var (
+			reload   = 1 * time.Minute
+			keys     = map[string]tsig.Key{}
+			fromKeys = map[*file.Zone]string{}
+			origins  = map[string]bool{}
+		)
+		for c.NextBlock() {
+			switch c.Val() {
+			case "file":
+				// file ORIGIN DBFILE
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return nil, c.ArgErr()
+				}
+				origin := dns.CanonicalName(args[0])
+				fileName := args[1]
+				if !filepath.IsAbs(fileName) && config.Root != "" {
+					fileName = filepath.Join(config.Root, fileName)
+				}
+				reader, err := os.Open(fileName)
+				if err != nil {
+					return nil, err
+				}
+				z, err := file.Parse(reader, origin, fileName, 0)
+				reader.Close()
+				if err != nil {
+					return nil, err
+				}
+				if origins[origin] {
+					return nil, c.Errf("policy %q is defined more than once", origin)
+				}
+				origins[origin] = true
+				rpz.policies = append(rpz.policies, newPolicy(origin, z))
+
+			case "transfer":
+				// transfer ORIGIN from ADDRESS... [key NAME]
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				origin := dns.CanonicalName(c.Val())
+				from, key, err := parse.TransferInWithKey(c)
+				if err != nil {
+					return nil, err
+				}
+				z := file.NewZone(origin, "stdin")
+				z.TransferFrom = from
+				if key != "" {
+					fromKeys[z] = key
+				}
+				if origins[origin] {
+					return nil, c.Errf("policy %q is defined more than once", origin)
+				}
+				origins[origin] = true
+				rpz.policies = append(rpz.policies, newPolicy(origin, z))
+
+			case "key":
+				k, err := tsig.Parse(c.RemainingArgs())
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				keys[k.Name] = k
+
+			case "reload":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil {
+					return nil, c.Errf("invalid reload duration %q: %s", args[0], err)
+				}
+				reload = d
+
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+
+		if len(rpz.policies) == 0 {
+			return nil, c.Err("at least one policy zone is required, with 'file' or 'transfer'")
+		}
+		for _, p := range rpz.policies {
+			p.z.ReloadInterval = reload
+		}
+		for z, name := range fromKeys {
+			k, ok := keys[name]
+			if !ok {
+				return nil, c.Errf("key %q is not defined", name)
+			}
+			z.TransferKeys = make(map[string]tsig.Key, len(z.TransferFrom))
+			for _, from := range z.TransferFrom {
+				z.TransferKeys[from] = k
+			}
+		}
+	}
+	return rpz, nil
+}
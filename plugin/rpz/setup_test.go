@@ -0,0 +1,100 @@
+package rpz
+
+import (
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/test"
+)
+
+func TestSetupRPZ(t *testing.T) {
+	fileName, rm, err := test.TempFile(".", dbRPZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		zones     []string
+		policies  []string
+	}{
+		{`rpz {
+			file rpz.example ` + fileName + `
+		}`, false, []string{"."}, []string{"rpz.example."}},
+		{`rpz example.org {
+			transfer first.rpz.example from 10.0.0.1
+			file rpz.example ` + fileName + `
+			reload 10s
+		}`, false, []string{"example.org."}, []string{"first.rpz.example.", "rpz.example."}},
+		{`rpz {
+			transfer rpz.example from 10.0.0.1 key rpz.key
+			key rpz.key hmac-sha256 c2VjcmV0
+		}`, false, []string{"."}, []string{"rpz.example."}},
+		// errors
+		{`rpz`, true, nil, nil},
+		{`rpz {
+			file rpz.example
+		}`, true, nil, nil},
+		{`rpz {
+			file rpz.example /does/not/exist
+		}`, true, nil, nil},
+		{`rpz {
+			file rpz.example ` + fileName + `
+			transfer rpz.example from 10.0.0.1
+		}`, true, nil, nil},
+		{`rpz {
+			transfer rpz.example from 10.0.0.1 key rpz.key
+		}`, true, nil, nil},
+		{`rpz {
+			transfer rpz.example
+		}`, true, nil, nil},
+		{`rpz {
+			file rpz.example ` + fileName + `
+			reload forever
+		}`, true, nil, nil},
+		{`rpz {
+			file rpz.example ` + fileName + `
+			blocklist
+		}`, true, nil, nil},
+		{`rpz {
+			file rpz.example ` + fileName + `
+		}
+		rpz {
+			file rpz.example ` + fileName + `
+		}`, true, nil, nil},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		c.ServerBlockKeys = []string{"."}
+		rpz, err := parseRPZ(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if len(rpz.Zones) != len(tc.zones) {
+			t.Fatalf("Test %d: expected zones %v, got %v", i, tc.zones, rpz.Zones)
+		}
+		for j := range tc.zones {
+			if rpz.Zones[j] != tc.zones[j] {
+				t.Errorf("Test %d: expected zones %v, got %v", i, tc.zones, rpz.Zones)
+			}
+		}
+		if len(rpz.policies) != len(tc.policies) {
+			t.Fatalf("Test %d: expected %d policies, got %d", i, len(tc.policies), len(rpz.policies))
+		}
+		for j, p := range rpz.policies {
+			if p.name != tc.policies[j] {
+				t.Errorf("Test %d: expected policy %s, got %s", i, tc.policies[j], p.name)
+			}
+		}
+	}
+}
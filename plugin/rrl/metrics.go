@@ -0,0 +1,32 @@
+package rrl
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// DroppedCount is the number of responses dropped.
+	DroppedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rrl",
+		Name:      "dropped_responses_total",
+		Help:      "Counter of responses dropped because they exceeded the rate.",
+	}, []string{"server", "class"})
+	// SlippedCount is the number of truncated responses sent instead of responses that exceeded the rate.
+	SlippedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rrl",
+		Name:      "slipped_responses_total",
+		Help:      "Counter of truncated responses sent instead of responses that exceeded the rate.",
+	}, []string{"server", "class"})
+	// LoggedCount is the number of responses that exceeded the rate, but were sent in log only mode.
+	LoggedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "rrl",
+		Name:      "logged_responses_total",
+		Help:      "Counter of responses that exceeded the rate, but were sent in log only mode.",
+	}, []string{"server", "class"})
+)
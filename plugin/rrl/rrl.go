@@ -0,0 +1,179 @@
+// Package rrl implements response rate limiting, modelled on BIND's RRL.
+package rrl
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/response"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// RRL limits the rate of the responses sent to a client prefix, per response class.
+type RRL struct {
+	Next  plugin.Handler
+	Zones []string
+
+	window   time.Duration // how long exceeding the rate is remembered.
+	ipv4Mask net.IPMask
+	ipv6Mask net.IPMask
+	rates    [classes]float64 // responses per second per class, 0 means unlimited.
+	slip     int              // every slip-th limited response is sent truncated, 0 means never.
+	logOnly  bool
+
+	table *table
+	now   func() time.Time
+}
+
+// class is the class of a response, accounted separately.
+type class int
+
+const (
+	classAnswer class = iota
+	classReferral
+	classNoData
+	classNXDomain
+	classError
+	classes
+)
+
+var classNames = [classes]string{"answer", "referral", "nodata", "nxdomain", "error"}
+
+func (c class) String() string { return classNames[c] }
+
+// ServeDNS implements the plugin.Handler interface.
+func (rl *RRL) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	// Responses over TCP can't be used in reflection attacks.
+	if state.Proto() != "udp" || plugin.Zones(rl.Zones).Matches(state.Name()) == "" {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+
+	rw := &ResponseWriter{ResponseWriter: w, RRL: rl, state: state, server: metrics.WithServer(ctx)}
+	rcode, err := plugin.NextOrFailure(rl.Name(), rl.Next, ctx, rw, r)
+	if plugin.ClientWrite(rcode) {
+		return rcode, err
+	}
+
+	// The server writes the error response, it is accounted for here.
+	m := new(dns.Msg)
+	m.SetRcode(r, rcode)
+	if rw.allow(m) {
+		return rcode, err
+	}
+	return dns.RcodeSuccess, err
+}
+
+// Name implements the plugin.Handler interface.
+func (rl *RRL) Name() string { return "rrl" }
+
+// ResponseWriter accounts the responses written to the client, and drops or truncates the ones that exceed the rate.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	*RRL
+	state  request.Request
+	server string
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(m *dns.Msg) error {
+	if !w.allow(m) {
+		return nil
+	}
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// allow accounts the response m and returns true when it may be sent. When it may not, a truncated response is
+// sent instead, every slip-th time, or nothing at all.
+func (w *ResponseWriter) allow(m *dns.Msg) bool {
+	c, name := classify(m, w.now())
+	rate := w.rates[c]
+	if rate == 0 {
+		return true
+	}
+
+	prefix := w.prefix()
+	key := prefix + "/" + strconv.Itoa(int(c)) + "/" + name
+	limited, n := w.table.debit(key, rate, w.window, w.now())
+	if !limited {
+		if n > 0 {
+			log.Infof("Stop limiting %s responses for %q to %s, %d responses exceeded the rate", c, name, prefix, n)
+		}
+		return true
+	}
+
+	// Only log when the limiting starts, not for every response.
+	if w.logOnly {
+		LoggedCount.WithLabelValues(w.server, c.String()).Inc()
+		if n == 1 {
+			log.Infof("Would limit %s responses for %q to %s", c, name, prefix)
+		}
+		return true
+	}
+	if n == 1 {
+		log.Infof("Limit %s responses for %q to %s", c, name, prefix)
+	}
+	if w.slip > 0 && n%w.slip == 0 {
+		SlippedCount.WithLabelValues(w.server, c.String()).Inc()
+		tc := new(dns.Msg)
+		tc.SetReply(w.state.Req)
+		tc.Truncated = true
+		w.ResponseWriter.WriteMsg(tc)
+		return false
+	}
+	DroppedCount.WithLabelValues(w.server, c.String()).Inc()
+	return false
+}
+
+// prefix returns the network of the client, with the configured prefix length.
+func (w *ResponseWriter) prefix() string {
+	ip := net.ParseIP(w.state.IP())
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(w.ipv4Mask).String()
+	}
+	return ip.Mask(w.ipv6Mask).String()
+}
+
+// classify returns the class of the response m and the name it is accounted under: the query name and type for
+// answers, the zone for denials, the delegation for referrals, and nothing for errors.
+func classify(m *dns.Msg, now time.Time) (class, string) {
+	if len(m.Question) == 0 {
+		return classError, ""
+	}
+	t, _ := response.Typify(m, now)
+	switch response.Classify(t) {
+	case response.Success:
+		if t == response.Delegation {
+			return classReferral, owner(m.Ns, dns.TypeNS)
+		}
+		return classAnswer, strings.ToLower(m.Question[0].Name) + " " + dns.Type(m.Question[0].Qtype).String()
+	case response.Denial:
+		zone := owner(m.Ns, dns.TypeSOA)
+		if zone == "" {
+			zone = strings.ToLower(m.Question[0].Name)
+		}
+		if t == response.NameError {
+			return classNXDomain, zone
+		}
+		return classNoData, zone
+	}
+	return classError, ""
+}
+
+// owner returns the lowercased owner name of the first record of type qtype in rrs.
+func owner(rrs []dns.RR, qtype uint16) string {
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == qtype {
+			return strings.ToLower(rr.Header().Name)
+		}
+	}
+	return ""
+}
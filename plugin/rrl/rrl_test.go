@@ -0,0 +1,216 @@
+package rrl
+
+import (
+	"bytes"
+	"context"
+	golog "log"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// reply answers example.org. with an A record, and other names with NXDOMAIN; a.example.net. gets a SERVFAIL
+// that is left to the server to write.
+func reply() plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		if r.Question[0].Name == "a.example.net." {
+			return dns.RcodeServerFailure, nil
+		}
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if r.Question[0].Name == "example.org." {
+			m.Answer = []dns.RR{test.A("example.org. 300 IN A 127.0.0.1")}
+		} else {
+			m.Rcode = dns.RcodeNameError
+			m.Ns = []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 300")}
+		}
+		w.WriteMsg(m)
+		return m.Rcode, nil
+	})
+}
+
+func newRRL(rate float64, slip int, now *time.Time) *RRL {
+	return &RRL{
+		Next:     reply(),
+		Zones:    []string{"."},
+		window:   defaultWindow,
+		ipv4Mask: net.CIDRMask(24, 32),
+		ipv6Mask: net.CIDRMask(56, 128),
+		rates:    [classes]float64{rate, rate, rate, rate, rate},
+		slip:     slip,
+		table:    newTable(defaultMaxTableSize),
+		now:      func() time.Time { return *now },
+	}
+}
+
+// query sends a query for name from ip and returns the response written, nil if none.
+func query(rl *RRL, name, ip string, tcp bool) *dns.Msg {
+	r := new(dns.Msg)
+	r.SetQuestion(name, dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: ip, TCP: tcp})
+	rcode, _ := rl.ServeDNS(context.TODO(), rec, r)
+	if rec.Msg == nil && !plugin.ClientWrite(rcode) {
+		// The server writes the error response.
+		m := new(dns.Msg)
+		m.SetRcode(r, rcode)
+		return m
+	}
+	return rec.Msg
+}
+
+func TestRRL(t *testing.T) {
+	now := time.Now()
+	rl := newRRL(2, 2, &now)
+
+	// The first responses, up to the rate, are sent.
+	for i := 0; i < 2; i++ {
+		if m := query(rl, "example.org.", "10.0.0.1", false); m == nil || m.Truncated || len(m.Answer) != 1 {
+			t.Fatalf("Expected response %d to be sent, got %v", i, m)
+		}
+	}
+	// Then every second one is truncated, the others are dropped.
+	if m := query(rl, "example.org.", "10.0.0.2", false); m != nil {
+		t.Errorf("Expected response to be dropped, got %v", m)
+	}
+	if m := query(rl, "example.org.", "10.0.0.3", false); m == nil || !m.Truncated || len(m.Answer) != 0 {
+		t.Errorf("Expected truncated response, got %v", m)
+	}
+	// Responses to other networks, and over TCP, are not limited.
+	if m := query(rl, "example.org.", "10.0.1.1", false); m == nil || m.Truncated {
+		t.Errorf("Expected response to another network to be sent, got %v", m)
+	}
+	if m := query(rl, "example.org.", "10.0.0.1", true); m == nil || m.Truncated {
+		t.Errorf("Expected response over TCP to be sent, got %v", m)
+	}
+
+	// NXDOMAIN responses are accounted per zone, not per name.
+	for i, name := range []string{"a.example.org.", "b.example.org.", "c.example.org."} {
+		m := query(rl, name, "10.0.0.1", false)
+		if i < 2 && (m == nil || m.Rcode != dns.RcodeNameError) {
+			t.Errorf("Expected NXDOMAIN for %s, got %v", name, m)
+		}
+		if i == 2 && m != nil {
+			t.Errorf("Expected response for %s to be dropped, got %v", name, m)
+		}
+	}
+
+	// Errors the server writes are limited too.
+	for i := 0; i < 2; i++ {
+		if m := query(rl, "a.example.net.", "10.0.0.1", false); m == nil || m.Rcode != dns.RcodeServerFailure {
+			t.Fatalf("Expected SERVFAIL %d to be sent, got %v", i, m)
+		}
+	}
+	if m := query(rl, "a.example.net.", "10.0.0.1", false); m != nil {
+		t.Errorf("Expected SERVFAIL to be dropped, got %v", m)
+	}
+
+	// Once the debt of the limited responses is paid off, responses are sent again.
+	now = now.Add(2 * time.Second)
+	if m := query(rl, "example.org.", "10.0.0.1", false); m == nil || m.Truncated {
+		t.Errorf("Expected response to be sent after two seconds, got %v", m)
+	}
+}
+
+func TestRRLDebt(t *testing.T) {
+	now := time.Now()
+	rl := newRRL(1, 0, &now)
+	rl.window = 2 * time.Second
+
+	for i := 0; i < 100; i++ {
+		query(rl, "example.org.", "10.0.0.1", false)
+	}
+	// The debt is capped at the window times the rate, so it is paid off after a window plus a second.
+	now = now.Add(2 * time.Second)
+	if m := query(rl, "example.org.", "10.0.0.1", false); m != nil {
+		t.Errorf("Expected response to be dropped, got %v", m)
+	}
+	now = now.Add(3 * time.Second)
+	if m := query(rl, "example.org.", "10.0.0.1", false); m == nil {
+		t.Error("Expected response to be sent after the window")
+	}
+}
+
+func TestRRLLogOnly(t *testing.T) {
+	now := time.Now()
+	rl := newRRL(1, 0, &now)
+	rl.logOnly = true
+
+	var buf bytes.Buffer
+	golog.SetOutput(&buf)
+	defer golog.SetOutput(os.Stderr)
+
+	for i := 0; i < 5; i++ {
+		if m := query(rl, "example.org.", "10.0.0.1", false); m == nil || m.Truncated {
+			t.Fatalf("Expected response %d to be sent in log only mode, got %v", i, m)
+		}
+	}
+	// The account is paid off after the window.
+	now = now.Add(10 * time.Second)
+	query(rl, "example.org.", "10.0.0.1", false)
+
+	if n := strings.Count(buf.String(), "Would limit"); n != 1 {
+		t.Errorf("Expected the limiting to be logged once, got %d times: %s", n, buf.String())
+	}
+	if !strings.Contains(buf.String(), "Stop limiting answer responses for \"example.org. A\" to 10.0.0.0, 4 responses") {
+		t.Errorf("Expected the end of the limiting to be logged with the count, got: %s", buf.String())
+	}
+}
+
+func TestTableFull(t *testing.T) {
+	now := time.Now()
+	tb := newTable(2)
+	tb.debit("a", 1, time.Second, now)
+	tb.debit("b", 1, time.Second, now)
+
+	// The table is full, c takes the place of a, the least recently used account, and is still limited.
+	tb.debit("c", 1, time.Second, now)
+	if limited, _ := tb.debit("c", 1, time.Second, now); !limited {
+		t.Error("Expected c to be limited on a full table")
+	}
+	if l := tb.Len(); l != 2 {
+		t.Errorf("Expected 2 accounts, got %d", l)
+	}
+
+	// b is used again, so d takes the place of c.
+	tb.debit("b", 1, time.Second, now)
+	tb.debit("d", 1, time.Second, now)
+	if limited, _ := tb.debit("b", 1, time.Second, now); !limited {
+		t.Error("Expected b to be limited")
+	}
+	if _, ok := tb.accounts["c"]; ok {
+		t.Error("Expected c to be evicted")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		m     *dns.Msg
+		class class
+		name  string
+	}{
+		{&dns.Msg{Question: []dns.Question{{Name: "Example.org.", Qtype: dns.TypeA}},
+			Answer: []dns.RR{test.A("example.org. 300 IN A 127.0.0.1")}}, classAnswer, "example.org. A"},
+		{&dns.Msg{Question: []dns.Question{{Name: "a.example.org.", Qtype: dns.TypeA}},
+			Ns: []dns.RR{test.NS("example.org. 300 IN NS ns.example.org.")}}, classReferral, "example.org."},
+		{&dns.Msg{Question: []dns.Question{{Name: "example.org.", Qtype: dns.TypeMX}},
+			Ns: []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 300")}}, classNoData, "example.org."},
+		{&dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeNameError}, Question: []dns.Question{{Name: "a.example.org.", Qtype: dns.TypeA}},
+			Ns: []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 300")}}, classNXDomain, "example.org."},
+		{&dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeRefused}, Question: []dns.Question{{Name: "a.example.org.", Qtype: dns.TypeA}}}, classError, ""},
+		{&dns.Msg{MsgHdr: dns.MsgHdr{Rcode: dns.RcodeFormatError}}, classError, ""},
+	}
+	for i, tc := range tests {
+		c, name := classify(tc.m, time.Now())
+		if c != tc.class || name != tc.name {
+			t.Errorf("Test %d: expected %s %q, got %s %q", i, tc.class, tc.name, c, name)
+		}
+	}
+}
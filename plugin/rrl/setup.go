@@ -0,0 +1,172 @@
+package rrl
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+)
+
+var log = clog.NewWithPlugin("rrl")
+
+func init() { plugin.Register("rrl", setup) }
+
+const (
+	defaultWindow       = 15 * time.Second
+	defaultIPv4Prefix   = 24
+	defaultIPv6Prefix   = 56
+	defaultSlip         = 2
+	defaultMaxTableSize = 100000
+)
+
+func setup(c *caddy.Controller) error {
+	rl, err := rrlParse(c)
+	if err != nil {
+		return plugin.Error("rrl", err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		rl.Next = next
+		return rl
+	})
+
+	return nil
+}
+
+func rrlParse(c *caddy.Controller) (*RRL, error) {
+	rl := &RRL{
+		window:   defaultWindow,
+		ipv4Mask: net.CIDRMask(defaultIPv4Prefix, 32),
+		ipv6Mask: net.CIDRMask(defaultIPv6Prefix, 128),
+		slip:     defaultSlip,
+		now:      time.Now,
+	}
+	maxTableSize := defaultMaxTableSize
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+		rl.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		// Rates per class, -1 when not set: those default to responses_per_second.
+		rates := [classes]float64{-1, -1, -1, -1, -1}
+		for c.NextBlock() {
+			switch c.Val() {
+			case "window":
+				n, err := intArg(c, 1)
+				if err != nil {
+					return nil, err
+				}
+				rl.window = time.Duration(n) * time.Second
+			case "ipv4_prefix_length":
+				n, err := intArg(c, 0)
+				if err != nil {
+					return nil, err
+				}
+				if n > 32 {
+					return nil, c.Errf("ipv4_prefix_length must be at most 32, got %d", n)
+				}
+				rl.ipv4Mask = net.CIDRMask(n, 32)
+			case "ipv6_prefix_length":
+				n, err := intArg(c, 0)
+				if err != nil {
+					return nil, err
+				}
+				if n > 128 {
+					return nil, c.Errf("ipv6_prefix_length must be at most 128, got %d", n)
+				}
+				rl.ipv6Mask = net.CIDRMask(n, 128)
+			case "responses_per_second":
+				if err := rateArg(c, &rates[classAnswer]); err != nil {
+					return nil, err
+				}
+			case "referrals_per_second":
+				if err := rateArg(c, &rates[classReferral]); err != nil {
+					return nil, err
+				}
+			case "nodata_per_second":
+				if err := rateArg(c, &rates[classNoData]); err != nil {
+					return nil, err
+				}
+			case "nxdomains_per_second":
+				if err := rateArg(c, &rates[classNXDomain]); err != nil {
+					return nil, err
+				}
+			case "errors_per_second":
+				if err := rateArg(c, &rates[classError]); err != nil {
+					return nil, err
+				}
+			case "slip":
+				n, err := intArg(c, 0)
+				if err != nil {
+					return nil, err
+				}
+				rl.slip = n
+			case "log_only":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				rl.logOnly = true
+			case "max_table_size":
+				n, err := intArg(c, 1)
+				if err != nil {
+					return nil, err
+				}
+				maxTableSize = n
+			default:
+				return nil, c.Errf("unknown property %q", c.Val())
+			}
+		}
+
+		if rates[classAnswer] < 0 {
+			rates[classAnswer] = 0
+		}
+		for cl := range rates {
+			if rates[cl] < 0 {
+				rates[cl] = rates[classAnswer]
+			}
+		}
+		rl.rates = rates
+	}
+	rl.table = newTable(maxTableSize)
+	return rl, nil
+}
+
+// intArg parses the single argument of the current property as an integer of at least min.
+func intArg(c *caddy.Controller, min int) (int, error) {
+	name := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, c.Errf("%s: %s", name, err)
+	}
+	if n < min {
+		return 0, c.Errf("%s must be at least %d, got %d", name, min, n)
+	}
+	return n, nil
+}
+
+// rateArg parses the single argument of the current property as a rate of responses per second.
+func rateArg(c *caddy.Controller, rate *float64) error {
+	name := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return c.ArgErr()
+	}
+	r, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || r < 0 {
+		return c.Errf("invalid rate %q for %s", args[0], name)
+	}
+	*rate = r
+	return nil
+}
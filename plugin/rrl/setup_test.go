@@ -0,0 +1,94 @@
+package rrl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		window    time.Duration
+		v4, v6    int
+		rates     [classes]float64
+		slip      int
+		logOnly   bool
+	}{
+		{`rrl`, false, defaultWindow, 24, 56, [classes]float64{}, 2, false},
+		{`rrl example.org {
+			responses_per_second 10
+		}`, false, defaultWindow, 24, 56, [classes]float64{10, 10, 10, 10, 10}, 2, false},
+		{`rrl {
+			window 5
+			ipv4_prefix_length 32
+			ipv6_prefix_length 64
+			responses_per_second 10
+			nxdomains_per_second 2.5
+			errors_per_second 0
+			slip 0
+			log_only
+			max_table_size 10
+		}`, false, 5 * time.Second, 32, 64, [classes]float64{10, 10, 10, 2.5, 0}, 0, true},
+		{`rrl {
+			nodata_per_second 3
+		}`, false, defaultWindow, 24, 56, [classes]float64{0, 0, 3, 0, 0}, 2, false},
+		// fails
+		{`rrl
+		  rrl`, true, 0, 0, 0, [classes]float64{}, 0, false},
+		{`rrl {
+			window 0
+		}`, true, 0, 0, 0, [classes]float64{}, 0, false},
+		{`rrl {
+			ipv4_prefix_length 33
+		}`, true, 0, 0, 0, [classes]float64{}, 0, false},
+		{`rrl {
+			responses_per_second -1
+		}`, true, 0, 0, 0, [classes]float64{}, 0, false},
+		{`rrl {
+			slip
+		}`, true, 0, 0, 0, [classes]float64{}, 0, false},
+		{`rrl {
+			log_only yes
+		}`, true, 0, 0, 0, [classes]float64{}, 0, false},
+		{`rrl {
+			unknown
+		}`, true, 0, 0, 0, [classes]float64{}, 0, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		rl, err := rrlParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+		}
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			}
+			continue
+		}
+
+		if rl.window != test.window {
+			t.Errorf("Test %d: expected window %s, got %s", i, test.window, rl.window)
+		}
+		if ones, _ := rl.ipv4Mask.Size(); ones != test.v4 {
+			t.Errorf("Test %d: expected IPv4 prefix length %d, got %d", i, test.v4, ones)
+		}
+		if ones, _ := rl.ipv6Mask.Size(); ones != test.v6 {
+			t.Errorf("Test %d: expected IPv6 prefix length %d, got %d", i, test.v6, ones)
+		}
+		if rl.rates != test.rates {
+			t.Errorf("Test %d: expected rates %v, got %v", i, test.rates, rl.rates)
+		}
+		if rl.slip != test.slip {
+			t.Errorf("Test %d: expected slip %d, got %d", i, test.slip, rl.slip)
+		}
+		if rl.logOnly != test.logOnly {
+			t.Errorf("Test %d: expected log_only %t, got %t", i, test.logOnly, rl.logOnly)
+		}
+	}
+}
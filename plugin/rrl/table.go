@@ -0,0 +1,83 @@
+package rrl
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// table holds the accounts of the responses sent, keyed by client prefix, class and name. The accounts are kept
+// in least recently used order, when the table is full the least recently used one is evicted.
+type table struct {
+	sync.Mutex
+	accounts map[string]*list.Element
+	lru      *list.List // of *account, the most recently used first.
+	max      int
+}
+
+// account is the balance of responses that may be sent. It is credited with the rate every second, up to the rate,
+// and debited with every response, down to the rate times the window.
+type account struct {
+	key     string
+	balance float64
+	last    time.Time
+	limited int // number of responses limited in a row.
+}
+
+func newTable(max int) *table {
+	return &table{accounts: map[string]*list.Element{}, lru: list.New(), max: max}
+}
+
+// debit takes a response off the account key and returns true when that exceeds the rate, with the number of
+// responses that did so in a row. When it doesn't, the number of responses that exceeded the rate before it, in the
+// run that now ends, is returned.
+func (t *table) debit(key string, rate float64, window time.Duration, now time.Time) (bool, int) {
+	t.Lock()
+	defer t.Unlock()
+
+	var a *account
+	if e, ok := t.accounts[key]; ok {
+		t.lru.MoveToFront(e)
+		a = e.Value.(*account)
+	} else {
+		if t.lru.Len() >= t.max {
+			t.evict()
+		}
+		a = &account{key: key, balance: rate, last: now}
+		t.accounts[key] = t.lru.PushFront(a)
+	}
+
+	a.balance += now.Sub(a.last).Seconds() * rate
+	if a.balance > rate {
+		a.balance = rate
+	}
+	a.last = now
+	a.balance--
+	if min := -window.Seconds() * rate; a.balance < min {
+		a.balance = min
+	}
+	if a.balance >= 0 {
+		n := a.limited
+		a.limited = 0
+		return false, n
+	}
+	a.limited++
+	return true, a.limited
+}
+
+// evict removes the least recently used account.
+func (t *table) evict() {
+	e := t.lru.Back()
+	if e == nil {
+		return
+	}
+	t.lru.Remove(e)
+	delete(t.accounts, e.Value.(*account).key)
+}
+
+// Len returns the number of accounts in the table.
+func (t *table) Len() int {
+	t.Lock()
+	defer t.Unlock()
+	return len(t.accounts)
+}
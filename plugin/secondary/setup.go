@@ -1,11 +1,14 @@
 package secondary
 
 import (
+	"strconv"
+
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/pkg/parse"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 )
 
@@ -53,17 +56,45 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 				names = append(names, origins[i])
 			}
 
+			keys := map[string]tsig.Key{}
+			fromKeys := map[string]string{}
 			for c.NextBlock() {
 
 				f := []string{}
 
 				switch c.Val() {
 				case "transfer":
-					var err error
-					f, err = parse.TransferIn(c)
+					var (
+						key string
+						err error
+					)
+					f, key, err = parse.TransferInWithKey(c)
 					if err != nil {
 						return file.Zones{}, err
 					}
+					if key != "" {
+						for _, from := range f {
+							fromKeys[from] = key
+						}
+					}
+				case "key":
+					k, err := tsig.Parse(c.RemainingArgs())
+					if err != nil {
+						return file.Zones{}, c.Err(err.Error())
+					}
+					keys[k.Name] = k
+				case "ixfr":
+					args := c.RemainingArgs()
+					if len(args) != 1 {
+						return file.Zones{}, c.ArgErr()
+					}
+					n, err := strconv.Atoi(args[0])
+					if err != nil || n < 0 {
+						return file.Zones{}, c.Errf("invalid ixfr history size %q", args[0])
+					}
+					for _, origin := range origins {
+						z[origin].IXFRHistory = n
+					}
 				default:
 					return file.Zones{}, c.Errf("unknown property '%s'", c.Val())
 				}
@@ -75,6 +106,27 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 					z[origin].Upstream = upstream.New()
 				}
 			}
+
+			for from, name := range fromKeys {
+				k, ok := keys[name]
+				if !ok {
+					return file.Zones{}, c.Errf("key %q is not defined", name)
+				}
+				// Register the key, so notifies signed with it can be verified.
+				config := dnsserver.GetConfig(c)
+				if config.TsigSecret == nil {
+					config.TsigSecret = make(map[string]string)
+				}
+				if err := tsig.Register(config.TsigSecret, k); err != nil {
+					return file.Zones{}, c.Err(err.Error())
+				}
+				for _, origin := range origins {
+					if z[origin].TransferKeys == nil {
+						z[origin].TransferKeys = make(map[string]tsig.Key)
+					}
+					z[origin].TransferKeys[from] = k
+				}
+			}
 		}
 	}
 	return file.Zones{Z: z, Names: names}, nil
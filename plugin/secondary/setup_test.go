@@ -35,6 +35,24 @@ func TestSecondaryParse(t *testing.T) {
 			"127.0.0.1:53",
 			[]string{"example.org."},
 		},
+		{
+			`secondary example.org {
+				transfer from 127.0.0.1
+				ixfr 5
+			}`,
+			false,
+			"127.0.0.1:53",
+			[]string{"example.org."},
+		},
+		{
+			`secondary example.org {
+				transfer from 127.0.0.1
+				ixfr -1
+			}`,
+			true,
+			"",
+			nil,
+		},
 	}
 
 	for i, test := range tests {
@@ -61,3 +79,49 @@ func TestSecondaryParse(t *testing.T) {
 		}
 	}
 }
+
+func TestSecondaryParseKey(t *testing.T) {
+	const secret = "c2VjcmV0LXNlY3JldC1zZWNyZXQ="
+	tests := []struct {
+		input     string
+		shouldErr bool
+		key       string
+	}{
+		{`secondary example.org {
+			key xfr.key. hmac-sha256 ` + secret + `
+			transfer from 127.0.0.1 key xfr.key.
+		}`, false, "xfr.key."},
+		{`secondary example.org {
+			transfer from 127.0.0.1 key Xfr.Key
+			key xfr.key. hmac-sha256 ` + secret + `
+		}`, false, "xfr.key."},
+		{`secondary example.org {
+			transfer from 127.0.0.1
+		}`, false, ""},
+		// errors
+		{`secondary example.org {
+			transfer from 127.0.0.1 key xfr.key.
+		}`, true, ""},
+		{`secondary example.org {
+			key xfr.key. hmac-md5 ` + secret + `
+			transfer from 127.0.0.1 key xfr.key.
+		}`, true, ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := secondaryParse(c)
+		if err == nil && test.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		} else if err != nil && !test.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		k := s.Z["example.org."].TransferKeys["127.0.0.1:53"]
+		if k.Name != test.key {
+			t.Errorf("Test %d expected key %q, got %q", i, test.key, k.Name)
+		}
+	}
+}
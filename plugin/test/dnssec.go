@@ -0,0 +1,122 @@
+package test
+
+import (
+	"crypto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Key is a DNSSEC key used to sign zones in tests.
+type Key struct {
+	DNSKEY  *dns.DNSKEY
+	Private crypto.Signer
+}
+
+// NewKey returns a new ECDSAP256SHA256 key for the zone origin. It has the SEP flag set
+// and is used as both KSK and ZSK.
+func NewKey(origin string) *Key {
+	k := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: dns.Fqdn(origin), Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     dns.ZONE | dns.SEP,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := k.Generate(256)
+	if err != nil {
+		panic(err)
+	}
+	return &Key{DNSKEY: k, Private: priv.(crypto.Signer)}
+}
+
+// DS returns the SHA256 DS record of k.
+func (k *Key) DS() *dns.DS { return k.DNSKEY.ToDS(dns.SHA256) }
+
+// SignRRs returns the RRSIG of rrs made with k, valid between inception and expiration.
+func (k *Key) SignRRs(rrs []dns.RR, inception, expiration time.Time) *dns.RRSIG {
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: rrs[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: rrs[0].Header().Ttl},
+		Algorithm:  k.DNSKEY.Algorithm,
+		SignerName: k.DNSKEY.Hdr.Name,
+		KeyTag:     k.DNSKEY.KeyTag(),
+		Inception:  uint32(inception.Unix()),
+		Expiration: uint32(expiration.Unix()),
+	}
+	if err := sig.Sign(k.Private, rrs); err != nil {
+		panic(err)
+	}
+	return sig
+}
+
+// Sign signs the zone origin made up of rrs with k. It returns rrs with the DNSKEY of k, an NSEC chain
+// and the RRSIGs added. The NS records of delegations and glue are not signed, as is done in a real zone.
+func Sign(origin string, rrs []dns.RR, k *Key) []dns.RR {
+	origin = dns.Fqdn(strings.ToLower(origin))
+	rrs = append(rrs, dns.Copy(k.DNSKEY))
+
+	sets := map[string]map[uint16][]dns.RR{}
+	cuts := map[string]bool{}
+	for _, rr := range rrs {
+		name := strings.ToLower(rr.Header().Name)
+		if sets[name] == nil {
+			sets[name] = map[uint16][]dns.RR{}
+		}
+		sets[name][rr.Header().Rrtype] = append(sets[name][rr.Header().Rrtype], rr)
+		if rr.Header().Rrtype == dns.TypeNS && name != origin {
+			cuts[name] = true
+		}
+	}
+
+	names := []string{}
+	for name := range sets {
+		if glue(name, cuts) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return canonicalLess(names[i], names[j]) })
+
+	inception, expiration := time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour)
+	signed := append([]dns.RR{}, rrs...)
+	for i, name := range names {
+		types := []uint16{dns.TypeRRSIG, dns.TypeNSEC}
+		for t, set := range sets[name] {
+			types = append(types, t)
+			if cuts[name] && t != dns.TypeDS {
+				continue
+			}
+			signed = append(signed, k.SignRRs(set, inception, expiration))
+		}
+		sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+		nsec := &dns.NSEC{
+			Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+			NextDomain: names[(i+1)%len(names)],
+			TypeBitMap: types,
+		}
+		signed = append(signed, nsec, k.SignRRs([]dns.RR{nsec}, inception, expiration))
+	}
+	return signed
+}
+
+// glue returns true if name is below one of the delegations in cuts.
+func glue(name string, cuts map[string]bool) bool {
+	for cut := range cuts {
+		if name != cut && dns.IsSubDomain(cut, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalLess orders a and b in canonical order, see RFC 4034, section 6.1. The names must be lowercase.
+func canonicalLess(a, b string) bool {
+	la, lb := dns.SplitDomainName(a), dns.SplitDomainName(b)
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if la[i] != lb[j] {
+			return la[i] < lb[j]
+		}
+	}
+	return len(la) < len(lb)
+}
@@ -2,8 +2,10 @@ package transfer
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/coredns/coredns/plugin/pkg/rcode"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 
 	"github.com/miekg/dns"
 )
@@ -15,21 +17,22 @@ func (t *Transfer) Notify(zone string) error {
 		return nil
 	}
 
-	m := new(dns.Msg)
-	m.SetNotify(zone)
-	c := new(dns.Client)
-
 	x := longestMatch(t.xfrs, zone)
 	if x == nil {
 		return fmt.Errorf("no such zone registred in the transfer plugin: %s", zone)
 	}
 
 	var err1 error
-	for _, t := range x.to {
-		if t == "*" {
+	for _, to := range x.to {
+		if to == "*" {
 			continue
 		}
-		if err := sendNotify(c, m, t); err != nil {
+		var k *tsig.Key
+		if name := x.keys[to]; name != "" {
+			key := t.keys[name]
+			k = &key
+		}
+		if err := sendNotify(zone, to, k); err != nil {
 			err1 = err
 		}
 	}
@@ -37,12 +40,25 @@ func (t *Transfer) Notify(zone string) error {
 	return err1 // this only captures the last error
 }
 
-func sendNotify(c *dns.Client, m *dns.Msg, s string) error {
+// sendNotify sends a notify for zone to s. If k is not nil the notify is signed with it.
+func sendNotify(zone, s string, k *tsig.Key) error {
 	var err error
 
+	m := new(dns.Msg)
+	m.SetNotify(zone)
+	c := new(dns.Client)
+	if k != nil {
+		c.TsigSecret = map[string]string{k.Name: k.Secret}
+	}
+
 	code := dns.RcodeServerFailure
 	for i := 0; i < 3; i++ {
-		ret, _, err := c.Exchange(m, s)
+		if k != nil {
+			// The client strips the signature after an exchange, so sign the message again for every attempt.
+			m.SetTsig(k.Name, k.Algorithm, 300, time.Now().Unix())
+		}
+		var ret *dns.Msg
+		ret, _, err = c.Exchange(m, s)
 		if err != nil {
 			continue
 		}
@@ -52,7 +68,7 @@ func sendNotify(c *dns.Client, m *dns.Msg, s string) error {
 		}
 	}
 	if err != nil {
-		return fmt.Errorf("notify for zone %q was not accepted by %q: %q", m.Question[0].Name, s, err)
+		return fmt.Errorf("notify for zone %q was not accepted by %q: %q", zone, s, err)
 	}
-	return fmt.Errorf("notify for zone %q was not accepted by %q: rcode was %q", m.Question[0].Name, s, rcode.ToString(code))
+	return fmt.Errorf("notify for zone %q was not accepted by %q: rcode was %q", zone, s, rcode.ToString(code))
 }
@@ -6,6 +6,9 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/parse"
 	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
+
+	"github.com/miekg/dns"
 )
 
 func init() {
@@ -44,20 +47,29 @@ func setup(c *caddy.Controller) error {
 }
 
 func parseTransfer(c *caddy.Controller) (*Transfer, error) {
-	t := &Transfer{}
+	t := &Transfer{keys: map[string]tsig.Key{}}
 	for c.Next() {
-		x := &xfr{}
+		x := &xfr{keys: map[string]string{}}
 		x.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
 		for c.NextBlock() {
 			switch c.Val() {
 			case "to":
 				args := c.RemainingArgs()
+				key := ""
+				if i := indexOf(args, "key"); i >= 0 {
+					if i != len(args)-2 {
+						return nil, c.ArgErr()
+					}
+					key = dns.CanonicalName(args[i+1])
+					args = args[:i]
+				}
 				if len(args) == 0 {
 					return nil, c.ArgErr()
 				}
 				for _, host := range args {
 					if host == "*" {
 						x.to = append(x.to, host)
+						x.keys[host] = key
 						continue
 					}
 					normalized, err := parse.HostPort(host, transport.Port)
@@ -65,7 +77,14 @@ func parseTransfer(c *caddy.Controller) (*Transfer, error) {
 						return nil, err
 					}
 					x.to = append(x.to, normalized)
+					x.keys[normalized] = key
 				}
+			case "key":
+				k, err := tsig.Parse(c.RemainingArgs())
+				if err != nil {
+					return nil, plugin.Error("transfer", c.Err(err.Error()))
+				}
+				t.keys[k.Name] = k
 			default:
 				return nil, plugin.Error("transfer", c.Errf("unknown property %q", c.Val()))
 			}
@@ -75,5 +94,33 @@ func parseTransfer(c *caddy.Controller) (*Transfer, error) {
 		}
 		t.xfrs = append(t.xfrs, x)
 	}
+
+	config := dnsserver.GetConfig(c)
+	for _, x := range t.xfrs {
+		for _, key := range x.keys {
+			if key == "" {
+				continue
+			}
+			k, ok := t.keys[key]
+			if !ok {
+				return nil, plugin.Error("transfer", c.Errf("key %q is not defined", key))
+			}
+			if config.TsigSecret == nil {
+				config.TsigSecret = make(map[string]string)
+			}
+			if err := tsig.Register(config.TsigSecret, k); err != nil {
+				return nil, plugin.Error("transfer", c.Err(err.Error()))
+			}
+		}
+	}
 	return t, nil
 }
+
+func indexOf(args []string, s string) int {
+	for i := range args {
+		if args[i] == s {
+			return i
+		}
+	}
+	return -1
+}
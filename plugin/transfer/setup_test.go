@@ -115,6 +115,63 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseKey(t *testing.T) {
+	const secret = "c2VjcmV0LXNlY3JldC1zZWNyZXQ="
+	tests := []struct {
+		input     string
+		shouldErr bool
+		keys      map[string]string
+	}{
+		{`transfer example.org {
+			key xfr.key. hmac-sha256 ` + secret + `
+			to 1.2.3.4 key xfr.key.
+			to 5.6.7.8
+		}`, false, map[string]string{"1.2.3.4:53": "xfr.key.", "5.6.7.8:53": ""}},
+		{`transfer example.org {
+			to * key Xfr.Key
+			key xfr.key. hmac-sha512 ` + secret + `
+		}`, false, map[string]string{"*": "xfr.key."}},
+		// errors
+		{`transfer example.org {
+			to 1.2.3.4 key xfr.key.
+		}`, true, nil},
+		{`transfer example.org {
+			key xfr.key. hmac-sha256 ` + secret + `
+			to 1.2.3.4 key
+		}`, true, nil},
+		{`transfer example.org {
+			key xfr.key. hmac-sha256 ` + secret + `
+			to key xfr.key.
+		}`, true, nil},
+		{`transfer example.org {
+			key xfr.key. hmac-sha256 ` + secret + `
+			to 1.2.3.4 key xfr.key. 5.6.7.8
+		}`, true, nil},
+		{`transfer example.org {
+			key xfr.key. hmac-sha256 not-base64
+			to 1.2.3.4 key xfr.key.
+		}`, true, nil},
+	}
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		transfer, err := parseTransfer(c)
+		if err == nil && tc.shouldErr {
+			t.Fatalf("Test %d expected errors, but got no error", i)
+		}
+		if err != nil && !tc.shouldErr {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		if tc.shouldErr {
+			continue
+		}
+		for to, key := range tc.keys {
+			if x := transfer.xfrs[0].keys[to]; x != key {
+				t.Errorf("Test %d expected key %q for %s, got %q", i, key, to, x)
+			}
+		}
+	}
+}
+
 func TestSetup(t *testing.T) {
 	c := caddy.NewTestController("dns", "transfer")
 	if err := setup(c); err == nil {
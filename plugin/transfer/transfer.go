@@ -3,10 +3,14 @@ package transfer
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
+	"time"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -18,12 +22,14 @@ var log = clog.NewWithPlugin("transfer")
 type Transfer struct {
 	Transferers []Transferer // List of plugins that implement Transferer
 	xfrs        []*xfr
+	keys        map[string]tsig.Key // TSIG keys, keyed by name.
 	Next        plugin.Handler
 }
 
 type xfr struct {
 	Zones []string
 	to    []string
+	keys  map[string]string // Name of the TSIG key transfers to, and notifies for, a host in to are signed with.
 }
 
 // Transferer may be implemented by plugins to enable zone transfers
@@ -41,8 +47,10 @@ type Transferer interface {
 	//
 	// If serial is not 0, it will be handled as an IXFR request. If the serial is equal to or greater (newer) than
 	// the current serial for the zone, send a single SOA record to the channel and then close it.
-	// If the serial is less (older) than the current serial for the zone, perform an AXFR fallback
-	// by proceeding as if an AXFR was requested (as above).
+	// If the serial is less (older) than the current serial for the zone, and the plugin knows the changes since
+	// that serial, it may send an incremental transfer (RFC 1995): the current SOA, then for each change the old
+	// SOA, the deleted records, the new SOA and the added records, and finally the current SOA again. Otherwise
+	// perform an AXFR fallback by proceeding as if an AXFR was requested (as above).
 	Transfer(zone string, serial uint32) (<-chan []dns.RR, error)
 }
 
@@ -67,13 +75,21 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		return plugin.NextOrFailure(t.Name(), t.Next, ctx, w, r)
 	}
 
-	if !x.allowed(state) {
+	key, ok := x.allowed(state)
+	if !ok {
 		// write msg here, so logging will pick it up
 		m := new(dns.Msg)
 		m.SetRcode(r, dns.RcodeRefused)
 		w.WriteMsg(m)
 		return 0, nil
 	}
+	if err := authenticate(ctx, w, r, key); err != nil {
+		log.Warningf("Refusing transfer of zone %q to %s: %s", state.QName(), state.IP(), err)
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNotAuth)
+		w.WriteMsg(m)
+		return 0, nil
+	}
 
 	// Get serial from request if this is an IXFR.
 	var serial uint32
@@ -121,10 +137,20 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 	rrs := []dns.RR{}
 	l := 0
 	var soa *dns.SOA
+	// An incremental transfer is told apart from a full one by its second record: the SOA of the first change,
+	// with an older serial than the one sent first.
+	incremental := false
+	n := 0
 	for records := range pchan {
 		if x, ok := records[0].(*dns.SOA); ok && soa == nil {
 			soa = x
 		}
+		if n < 2 && n+len(records) >= 2 {
+			if x, ok := records[1-n].(*dns.SOA); ok && soa != nil && x.Serial != soa.Serial {
+				incremental = true
+			}
+		}
+		n += len(records)
 		rrs = append(rrs, records...)
 		if len(rrs) > 500 {
 			select {
@@ -150,6 +176,9 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		m := new(dns.Msg)
 		m.SetReply(r)
 		m.Answer = []dns.RR{soa}
+		if t := r.IsTsig(); t != nil {
+			m.SetTsig(t.Hdr.Name, t.Algorithm, t.Fudge, time.Now().Unix())
+		}
 		w.WriteMsg(m)
 
 		log.Infof("Outgoing noop, incremental transfer for up to date zone %q to %s for %d SOA serial", state.QName(), state.IP(), soa.Serial)
@@ -176,25 +205,56 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 	if soa != nil {
 		logserial = soa.Serial
 	}
+	if incremental {
+		log.Infof("Outgoing incremental transfer of %d records of zone %q to %s for %d SOA serial", l, state.QName(), state.IP(), logserial)
+		return 0, nil
+	}
 	log.Infof("Outgoing transfer of %d records of zone %q to %s for %d SOA serial", l, state.QName(), state.IP(), logserial)
 	return 0, nil
 }
 
-func (x xfr) allowed(state request.Request) bool {
+// allowed returns true if a transfer to the remote of state is allowed, together with the name of the TSIG key
+// the request must be signed with. The name is empty if no signature is needed.
+func (x xfr) allowed(state request.Request) (string, bool) {
 	for _, h := range x.to {
 		if h == "*" {
-			return true
+			return x.keys[h], true
 		}
 		to, _, err := net.SplitHostPort(h)
 		if err != nil {
-			return false
+			return "", false
 		}
 		// If remote IP matches we accept. TODO(): make this works with ranges
 		if to == state.IP() {
-			return true
+			return x.keys[h], true
 		}
 	}
-	return false
+	return "", false
+}
+
+// authenticate checks the TSIG signature of r. If key is not empty r must be signed with it, otherwise a signature is
+// optional, but must be valid when present.
+func authenticate(ctx context.Context, w dns.ResponseWriter, r *dns.Msg, key string) error {
+	t := r.IsTsig()
+	if t == nil {
+		if key != "" {
+			return fmt.Errorf("request is not signed with key %s", key)
+		}
+		return nil
+	}
+	if !dnsserver.TsigVerified(ctx) {
+		if key != "" {
+			return fmt.Errorf("transport does not verify TSIG")
+		}
+		return nil
+	}
+	if err := w.TsigStatus(); err != nil {
+		return fmt.Errorf("bad signature with key %s: %s", t.Hdr.Name, err)
+	}
+	if key != "" && dns.CanonicalName(t.Hdr.Name) != key {
+		return fmt.Errorf("request is signed with key %s, not %s", t.Hdr.Name, key)
+	}
+	return nil
 }
 
 // Find the first transfer instance for which the queried zone is the longest match. When nothing
@@ -1,12 +1,19 @@
 package transfer
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	golog "log"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/tsig"
 	"github.com/coredns/coredns/plugin/test"
 
 	"github.com/miekg/dns"
@@ -50,6 +57,22 @@ func (p *transfererPlugin) Transfer(zone string, serial uint32) (<-chan []dns.RR
 	return ch, nil
 }
 
+// ixfrTransferer implements transfer.Transferer, it returns an incremental transfer from serial 1 to 2.
+type ixfrTransferer struct{}
+
+func (ixfrTransferer) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	ch := make(chan []dns.RR, 4)
+	defer close(ch)
+	ch <- []dns.RR{test.SOA(fmt.Sprintf("%s 100 IN SOA ns.dns.%s hostmaster.%s 2 7200 1800 86400 100", zone, zone, zone))}
+	ch <- []dns.RR{test.SOA(fmt.Sprintf("%s 100 IN SOA ns.dns.%s hostmaster.%s 1 7200 1800 86400 100", zone, zone, zone))}
+	ch <- []dns.RR{
+		test.SOA(fmt.Sprintf("%s 100 IN SOA ns.dns.%s hostmaster.%s 2 7200 1800 86400 100", zone, zone, zone)),
+		test.A(fmt.Sprintf("a.%s 100 IN A 1.2.3.4", zone)),
+	}
+	ch <- []dns.RR{test.SOA(fmt.Sprintf("%s 100 IN SOA ns.dns.%s hostmaster.%s 2 7200 1800 86400 100", zone, zone, zone))}
+	return ch, nil
+}
+
 type terminatingPlugin struct{}
 
 // Name implements plugin.Handler.
@@ -276,3 +299,82 @@ func TestTransferNotAllowed(t *testing.T) {
 		t.Errorf("Expected REFUSED response code, got %s", dns.RcodeToString[w.Msg.Rcode])
 	}
 }
+
+func TestTransferTSIG(t *testing.T) {
+	transfer := newTestTransfer()
+	transfer.keys = map[string]tsig.Key{
+		"xfr.key.": {Name: "xfr.key.", Algorithm: dns.HmacSHA256, Secret: "c2VjcmV0LXNlY3JldC1zZWNyZXQ="},
+	}
+	transfer.xfrs[0].keys = map[string]string{"*": "xfr.key."}
+
+	tests := []struct {
+		key      string
+		verified bool // the server verified the signature
+		rcode    int
+	}{
+		{"", true, dns.RcodeNotAuth},
+		{"other.key.", true, dns.RcodeNotAuth},
+		{"xfr.key.", true, dns.RcodeSuccess},
+		{"xfr.key.", false, dns.RcodeNotAuth},
+	}
+
+	for i, tc := range tests {
+		w := dnstest.NewMultiRecorder(&test.ResponseWriter{TCP: true})
+		m := &dns.Msg{}
+		m.SetAxfr(transfer.xfrs[0].Zones[0])
+		if tc.key != "" {
+			m.SetTsig(tc.key, dns.HmacSHA256, 300, time.Now().Unix())
+		}
+
+		ctx := context.TODO()
+		if tc.verified {
+			ctx = context.WithValue(ctx, dnsserver.TsigKey{}, true)
+		}
+		if _, err := transfer.ServeDNS(ctx, w, m); err != nil {
+			t.Error(err)
+		}
+		if len(w.Msgs) == 0 {
+			t.Fatalf("Test %d: got no messages", i)
+		}
+		if x := w.Msgs[0].Rcode; x != tc.rcode {
+			t.Errorf("Test %d: expected %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[x])
+		}
+	}
+}
+
+func TestTransferLog(t *testing.T) {
+	var buf bytes.Buffer
+	golog.SetOutput(&buf)
+	defer golog.SetOutput(os.Stderr)
+
+	tests := []struct {
+		transferer Transferer
+		qtype      uint16
+		expect     string
+	}{
+		{&transfererPlugin{Zone: "example.org.", Serial: 12345}, dns.TypeAXFR, "Outgoing transfer of 4 records"},
+		{&transfererPlugin{Zone: "example.org.", Serial: 12345}, dns.TypeIXFR, "Outgoing transfer of 4 records"},
+		{ixfrTransferer{}, dns.TypeIXFR, "Outgoing incremental transfer of 5 records"},
+	}
+
+	for i, tc := range tests {
+		buf.Reset()
+		transfer := &Transfer{
+			Transferers: []Transferer{tc.transferer},
+			xfrs:        []*xfr{{Zones: []string{"example.org."}, to: []string{"*"}}},
+		}
+		w := dnstest.NewMultiRecorder(&test.ResponseWriter{TCP: true})
+		m := &dns.Msg{}
+		if tc.qtype == dns.TypeIXFR {
+			m.SetIxfr("example.org.", 1, "ns.dns.example.org.", "hostmaster.example.org.")
+		} else {
+			m.SetAxfr("example.org.")
+		}
+		if _, err := transfer.ServeDNS(context.TODO(), w, m); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), tc.expect) {
+			t.Errorf("Test %d: expected log %q, got: %s", i, tc.expect, buf.String())
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package view
+
+import (
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		progCount int
+	}{
+		{"view example {\n expr name() == 'example.com.'\n}", false, 1},
+		{"view example {\n expr incidr(client_ip(), '10.0.0.0/24')\n}", false, 1},
+		{"view example {\n expr name() == 'example.com.'\n expr name() == 'example2.com.'\n}", false, 2},
+		{"view", true, 0},
+		{"view example {\n expr invalid expression\n}", true, 0},
+		{"view example {\n expr name()\n}", true, 0},
+		{"view example {\n unknown name() == 'example.com.'\n}", true, 0},
+		{"view example {\n expr\n}", true, 0},
+		{"view example extra", true, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		v, err := parse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+		}
+		if err != nil {
+			continue
+		}
+		if len(v.progs) != test.progCount {
+			t.Errorf("Test %d: Expected %d programs, got %d", i, test.progCount, len(v.progs))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package view
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		input  string
+		qname  string
+		qtype  uint16
+		expect bool
+	}{
+		{"view v {\n expr name() == 'example.org.'\n}", "example.org.", dns.TypeA, true},
+		{"view v {\n expr name() == 'example.org.'\n}", "example.net.", dns.TypeA, false},
+		{"view v {\n expr incidr(client_ip(), '10.240.0.0/24')\n}", "example.org.", dns.TypeA, true},
+		{"view v {\n expr incidr(client_ip(), '10.0.0.0/24')\n}", "example.org.", dns.TypeA, false},
+		{"view v {\n expr type() == 'AAAA'\n expr name() == 'example.org.'\n}", "example.org.", dns.TypeAAAA, true},
+		{"view v {\n expr type() == 'AAAA'\n expr name() == 'example.org.'\n}", "example.org.", dns.TypeA, false},
+		{"view v {\n expr incidr(name(), '10.0.0.0/24')\n}", "example.org.", dns.TypeA, false}, // runtime error
+	}
+
+	for i, tc := range tests {
+		v, err := parse(caddy.NewTestController("dns", tc.input))
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error: %s", i, err)
+		}
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		// test.ResponseWriter has a remote address of 10.240.0.1.
+		state := &request.Request{W: &test.ResponseWriter{}, Req: m}
+
+		if got := v.Filter(context.Background(), state); got != tc.expect {
+			t.Errorf("Test %d: expected %t, got %t", i, tc.expect, got)
+		}
+	}
+}
+
+func TestServeDNS(t *testing.T) {
+	v, err := parse(caddy.NewTestController("dns", "view v {\n expr true\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	if v.ViewName() != "v" {
+		t.Errorf("Expected view name %q, got %q", "v", v.ViewName())
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if rcode, _ := v.ServeDNS(context.Background(), rec, m); rcode != dns.RcodeSuccess {
+		t.Errorf("Expected rcode %d, got %d", dns.RcodeSuccess, rcode)
+	}
+}
@@ -1,6 +1,10 @@
 package request
 
-import "github.com/miekg/dns"
+import (
+	"github.com/coredns/coredns/plugin/pkg/edns"
+
+	"github.com/miekg/dns"
+)
 
 // ScrubWriter will, when writing the message, call scrub to make it fit the client's buffer.
 type ScrubWriter struct {
@@ -15,7 +19,14 @@ func NewScrubWriter(req *dns.Msg, w dns.ResponseWriter) *ScrubWriter { return &S
 // scrub on the message m and will then write it to the client.
 func (s *ScrubWriter) WriteMsg(m *dns.Msg) error {
 	state := Request{Req: s.req, W: s.ResponseWriter}
-	state.SizeAndDo(m)
+	if !state.SizeAndDo(m) {
+		// The client doesn't do EDNS0, so an OPT record that was added by a plugin, e.g. for an Extended
+		// DNS Error, can't be sent.
+		m.Extra = edns.RemoveOPT(m.Extra)
+	} else if edns.Subnet(s.req) == nil {
+		// An EDNS Client Subnet option is only sent to clients that sent one, RFC 7871.
+		edns.RemoveSubnet(m)
+	}
 	state.Scrub(m)
 	return s.ResponseWriter.WriteMsg(m)
 }
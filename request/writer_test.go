@@ -0,0 +1,75 @@
+package request
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+type recordWriter struct {
+	test.ResponseWriter
+	msg *dns.Msg
+}
+
+func (r *recordWriter) WriteMsg(m *dns.Msg) error {
+	r.msg = m
+	return nil
+}
+
+func TestScrubWriterExtendedError(t *testing.T) {
+	for _, edns := range []bool{true, false} {
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		if edns {
+			req.SetEdns0(4096, false)
+		}
+
+		m := new(dns.Msg)
+		m.SetRcode(req, dns.RcodeServerFailure)
+		m.SetEdns0(dns.DefaultMsgSize, false)
+		o := m.IsEdns0()
+		o.Option = append(o.Option, &dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeNoReachableAuthority})
+
+		rw := &recordWriter{}
+		NewScrubWriter(req, rw).WriteMsg(m)
+
+		o = rw.msg.IsEdns0()
+		if !edns {
+			if o != nil {
+				t.Errorf("Expected no OPT record for a client without EDNS0, got %s", o)
+			}
+			continue
+		}
+		if o == nil || len(o.Option) != 1 || o.Option[0].Option() != dns.EDNS0EDE {
+			t.Errorf("Expected the OPT record to keep the extended error, got %v", o)
+		}
+	}
+}
+
+func TestScrubWriterSubnet(t *testing.T) {
+	for _, subnet := range []bool{true, false} {
+		req := new(dns.Msg)
+		req.SetQuestion("example.org.", dns.TypeA)
+		req.SetEdns0(4096, false)
+		if subnet {
+			o := req.IsEdns0()
+			o.Option = append(o.Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0")})
+		}
+
+		m := new(dns.Msg)
+		m.SetReply(req)
+		m.SetEdns0(dns.DefaultMsgSize, false)
+		o := m.IsEdns0()
+		o.Option = append(o.Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 24, Address: net.ParseIP("192.0.2.0")})
+
+		rw := &recordWriter{}
+		NewScrubWriter(req, rw).WriteMsg(m)
+
+		if o := rw.msg.IsEdns0(); o == nil || (len(o.Option) == 1) != subnet {
+			t.Errorf("Expected client subnet in the reply %t, got %v", subnet, o)
+		}
+	}
+}
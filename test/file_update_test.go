@@ -0,0 +1,78 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestFileUpdate(t *testing.T) {
+	name, rm, err := test.TempFile(".", exampleOrg)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+
+	corefile := `example.org:0 {
+		file ` + name + ` {
+			key update.example.org. hmac-sha256 c2VjcmV0IGtleSBmb3IgdXBkYXRlcw==
+			update update.example.org.
+		}
+	}`
+
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{test.A("new.example.org. 3600 IN A 127.0.0.3")})
+
+	// Unsigned updates are refused.
+	resp, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if resp.Rcode != dns.RcodeRefused {
+		t.Errorf("Expected REFUSED for unsigned update, got %s", dns.RcodeToString[resp.Rcode])
+	}
+
+	// Updates with a bad signature get NOTAUTH.
+	c := &dns.Client{TsigSecret: map[string]string{"update.example.org.": "b3RoZXIgc2VjcmV0"}}
+	m.SetTsig("update.example.org.", dns.HmacSHA256, 300, time.Now().Unix())
+	resp, _, err = c.Exchange(m, udp)
+	if resp == nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if resp.Rcode != dns.RcodeNotAuth {
+		t.Errorf("Expected NOTAUTH for badly signed update, got %s", dns.RcodeToString[resp.Rcode])
+	}
+
+	c.TsigSecret["update.example.org."] = "c2VjcmV0IGtleSBmb3IgdXBkYXRlcw=="
+	m.SetTsig("update.example.org.", dns.HmacSHA256, 300, time.Now().Unix())
+	resp, _, err = c.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		t.Fatalf("Expected NOERROR for signed update, got %s", dns.RcodeToString[resp.Rcode])
+	}
+
+	m = new(dns.Msg)
+	m.SetQuestion("new.example.org.", dns.TypeA)
+	resp, err = dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if len(resp.Answer) != 1 {
+		t.Fatalf("Expected 1 RR in the answer section, got %d", len(resp.Answer))
+	}
+	if a := resp.Answer[0].(*dns.A).A.String(); a != "127.0.0.3" {
+		t.Errorf("Expected 127.0.0.3, got %s", a)
+	}
+}
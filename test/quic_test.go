@@ -0,0 +1,140 @@
+package test
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+var quicCorefile = `quic://.:0 {
+		tls ../plugin/tls/test_cert.pem ../plugin/tls/test_key.pem ../plugin/tls/test_ca.pem
+		whoami
+	}`
+
+func TestQUIC(t *testing.T) {
+	q, udp, _, err := CoreDNSServerAndPorts(quicCorefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer q.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := quic.DialAddr(ctx, convertAddress(udp), generateTLSConfig(), nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+	defer conn.CloseWithError(0, "")
+
+	m := new(dns.Msg)
+	m.SetQuestion("whoami.example.org.", dns.TypeA)
+	m.Id = 0
+	msg, _ := m.Pack()
+
+	streamSync, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+
+	if _, err := streamSync.Write(addPrefix(msg)); err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+	streamSync.Close()
+
+	sizeBuf := make([]byte, 2)
+	if _, err := io.ReadFull(streamSync, sizeBuf); err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+
+	size := binary.BigEndian.Uint16(sizeBuf)
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(streamSync, buf); err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+
+	d := new(dns.Msg)
+	if err := d.Unpack(buf); err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+
+	if d.Rcode != dns.RcodeSuccess {
+		t.Errorf("Expected success but got %d", d.Rcode)
+	}
+	if d.Id != 0 {
+		t.Errorf("Expected message ID 0, but got %d", d.Id)
+	}
+	if len(d.Extra) != 2 {
+		t.Errorf("Expected 2 RRs in additional section, but got %d", len(d.Extra))
+	}
+}
+
+func TestQUICProtocolError(t *testing.T) {
+	q, udp, _, err := CoreDNSServerAndPorts(quicCorefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer q.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := quic.DialAddr(ctx, convertAddress(udp), generateTLSConfig(), nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion("whoami.example.org.", dns.TypeA)
+	m.Id = 1 // RFC 9250 requires the ID to be 0.
+	msg, _ := m.Pack()
+
+	streamSync, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %s", err)
+	}
+	streamSync.Write(addPrefix(msg))
+	streamSync.Close()
+
+	_, err = io.ReadFull(streamSync, make([]byte, 2))
+	var appErr *quic.ApplicationError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("Expected an application error, but got: %v", err)
+	}
+	if appErr.ErrorCode != 2 {
+		t.Errorf("Expected DOQ_PROTOCOL_ERROR (2), but got %d", appErr.ErrorCode)
+	}
+}
+
+func addPrefix(b []byte) []byte {
+	m := make([]byte, 2+len(b))
+	binary.BigEndian.PutUint16(m, uint16(len(b)))
+	copy(m[2:], b)
+	return m
+}
+
+// convertAddress transforms the address given in CoreDNSServerAndPorts to a format
+// that quic.DialAddr can read. It is unable to use [::]:61799, see
+// "INTERNAL_ERROR: write udp [::]:50676->[::]:61799: sendmsg: no route to host"
+// So it transforms it to localhost:61799.
+func convertAddress(address string) string {
+	if _, port, err := net.SplitHostPort(address); err == nil {
+		return net.JoinHostPort("localhost", port)
+	}
+	return address
+}
+
+func generateTLSConfig() *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"doq"},
+	}
+}
@@ -132,3 +132,66 @@ func TestIxfrResponse(t *testing.T) {
 		t.Fatalf("Serial should be %d, got %d", 2015082541, soa.Serial)
 	}
 }
+
+func TestSecondaryZoneTransferTSIG(t *testing.T) {
+	name, rm, err := test.TempFile(".", exampleOrg)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+
+	const secret = "c2VjcmV0LXNlY3JldC1zZWNyZXQ="
+	corefile := `example.org:0 {
+		file ` + name + `
+		transfer {
+			key xfr.key. hmac-sha256 ` + secret + `
+			to * key xfr.key.
+		}
+	}`
+
+	i, _, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	// Unsigned transfers are not authorized.
+	m := new(dns.Msg)
+	m.SetAxfr("example.org.")
+	c := new(dns.Client)
+	c.Net = "tcp"
+	r, _, err := c.Exchange(m, tcp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if r.Rcode != dns.RcodeNotAuth {
+		t.Fatalf("Expected NOTAUTH for unsigned transfer, got %s", dns.RcodeToString[r.Rcode])
+	}
+
+	corefile = `example.org:0 {
+		secondary {
+			key xfr.key. hmac-sha256 ` + secret + `
+			transfer from ` + tcp + ` key xfr.key.
+		}
+	}`
+
+	i1, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i1.Stop()
+
+	m = new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeSOA)
+	// This is async; we need to wait for it to be transferred.
+	for i := 0; i < 20; i++ {
+		r, _ = dns.Exchange(m, udp)
+		if r != nil && len(r.Answer) != 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if r == nil || len(r.Answer) == 0 {
+		t.Fatalf("Expected answer section")
+	}
+}
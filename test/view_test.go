@@ -0,0 +1,84 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestView(t *testing.T) {
+	corefile := `example.org:0 {
+		view aaaa {
+			expr type() == 'AAAA'
+		}
+		bind 127.0.0.1
+		hosts {
+			::1 test.example.org
+		}
+	}
+	example.org:0 {
+		view local {
+			expr incidr(client_ip(), '127.0.0.0/24')
+		}
+		bind 127.0.0.1
+		hosts {
+			1.1.1.1 test.example.org
+		}
+	}
+	example.org:0 {
+		bind 127.0.0.1
+		hosts {
+			2.2.2.2 test.example.org
+		}
+	}`
+
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("test.example.org.", dns.TypeA)
+	resp, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if len(resp.Answer) == 0 {
+		t.Fatal("Expected to at least one RR in the answer section, got none")
+	}
+	if resp.Answer[0].(*dns.A).A.String() != "1.1.1.1" {
+		t.Errorf("Expected 1.1.1.1, got: %s", resp.Answer[0].(*dns.A).A.String())
+	}
+
+	m.SetQuestion("test.example.org.", dns.TypeAAAA)
+	resp, err = dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if len(resp.Answer) == 0 {
+		t.Fatal("Expected to at least one RR in the answer section, got none")
+	}
+	if resp.Answer[0].(*dns.AAAA).AAAA.String() != "::1" {
+		t.Errorf("Expected ::1, got: %s", resp.Answer[0].(*dns.AAAA).AAAA.String())
+	}
+}
+
+func TestViewUnfilteredFirst(t *testing.T) {
+	// A view after an unfiltered server block for the same zone would never be used.
+	corefile := `example.org:0 {
+		whoami
+	}
+	example.org:0 {
+		view local {
+			expr incidr(client_ip(), '127.0.0.0/24')
+		}
+		whoami
+	}`
+
+	i, err := CoreDNSServer(corefile)
+	if err == nil {
+		i.Stop()
+		t.Fatal("Expected an error for a view defined after an unfiltered server block, got none")
+	}
+}